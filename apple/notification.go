@@ -0,0 +1,282 @@
+package apple
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// ProviderType is the identifier for Sign in with Apple
+	//   - REFS : https://developer.apple.com/documentation/sign_in_with_apple
+	ProviderType oauth2.ProviderType = "apple"
+
+	// Issuer is the "iss" claim of tokens and notifications signed by Apple
+	Issuer = "https://appleid.apple.com"
+
+	// KeysURL is the endpoint serving Apple's public signing keys (JWKS)
+	KeysURL = "https://appleid.apple.com/auth/keys"
+
+	// DefaultNotificationMaxAge is how long after its iat a notification is accepted,
+	// older ones are rejected as replays
+	DefaultNotificationMaxAge = 5 * time.Minute
+
+	// notificationKeysTTL is how long Apple's keys are cached before they are refetched
+	notificationKeysTTL = time.Hour
+
+	// notificationClockSkew tolerates an iat slightly ahead of the local clock
+	notificationClockSkew = time.Minute
+)
+
+// Server-to-server notification event types
+const (
+	EventEmailDisabled  = "email-disabled"
+	EventEmailEnabled   = "email-enabled"
+	EventConsentRevoked = "consent-revoked"
+	EventAccountDelete  = "account-delete"
+)
+
+type (
+	// NotificationEvent is the event carried by a verified server-to-server notification
+	NotificationEvent struct {
+		Type      string `json:"type"`
+		Subject   string `json:"sub"`
+		Email     string `json:"email"`
+		EventTime int64  `json:"event_time"`
+
+		// Audience is the client ID the notification was sent for
+		Audience string `json:"-"`
+	}
+
+	// NotificationVerifier verifies server-to-server notifications against Apple's public keys.
+	// Keys are cached in an oauth2.JWKSCache, so unknown key IDs sent to the public notification
+	// endpoint refetch them at most once per oauth2.DefaultJWKSMinRefreshInterval
+	NotificationVerifier struct {
+		keys     *oauth2.JWKSCache
+		clientID string
+		maxAge   time.Duration
+	}
+
+	// NotificationOption customizes a NotificationVerifier
+	NotificationOption func(*NotificationVerifier)
+
+	// notificationBody is the JSON body Apple posts to the notification endpoint
+	notificationBody struct {
+		Payload string `json:"payload"`
+	}
+
+	// notificationClaims are the claims of the signed notification payload
+	notificationClaims struct {
+		Issuer   string          `json:"iss"`
+		Audience string          `json:"aud"`
+		IssuedAt int64           `json:"iat"`
+		Events   json.RawMessage `json:"events"`
+	}
+
+	// jwtHeader is the protected header of a compact JWT
+	jwtHeader struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+)
+
+// defaultVerifier backs VerifyServerNotification
+var defaultVerifier = NewNotificationVerifier(oauth2.ProviderSetting{})
+
+// WithMaxAge overrides DefaultNotificationMaxAge, how long after its iat a notification is accepted
+func WithMaxAge(maxAge time.Duration) NotificationOption {
+	return func(v *NotificationVerifier) {
+		v.maxAge = maxAge
+	}
+}
+
+// NewNotificationVerifier creates a verifier fetching Apple's keys with setting.Client
+// (nil uses the default client). Notifications must be addressed to setting.ClientID,
+// Verify fails with ErrClientIDNotSet when it is empty
+func NewNotificationVerifier(setting oauth2.ProviderSetting, opts ...NotificationOption) *NotificationVerifier {
+	v := &NotificationVerifier{
+		keys:     oauth2.NewJWKSCache(KeysURL, setting.Client, notificationKeysTTL),
+		clientID: setting.ClientID,
+		maxAge:   DefaultNotificationMaxAge,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// VerifyServerNotification verifies a notification body addressed to clientID (the Services ID
+// or bundle ID) with the default client. An empty clientID fails with ErrClientIDNotSet
+//
+//	example:
+//	body, _ := io.ReadAll(r.Body)
+//	event, err := apple.VerifyServerNotification(r.Context(), "com.example.app", body)
+//	if err != nil { ... }
+//	if event.Type == apple.EventAccountDelete {
+//	    users.Delete(event.Subject)
+//	}
+func VerifyServerNotification(ctx context.Context, clientID string, body []byte) (*NotificationEvent, error) {
+	return defaultVerifier.verify(ctx, clientID, body)
+}
+
+// Verify checks the RS256 signature, issuer, audience and age of a notification body
+// and returns the event it carries
+//   - fails with ErrClientIDNotSet when the verifier has no client ID
+//   - a notification issued longer ago than the max age (or with no iat) fails with ErrInvalidNotification,
+//     so a captured notification cannot be replayed
+func (v *NotificationVerifier) Verify(
+	ctx context.Context,
+	body []byte,
+) (*NotificationEvent, error) {
+	return v.verify(ctx, v.clientID, body)
+}
+
+// verify is Verify checking the audience against clientID
+func (v *NotificationVerifier) verify(
+	ctx context.Context,
+	clientID string,
+	body []byte,
+) (*NotificationEvent, error) {
+	if clientID == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "notification audience")
+	}
+
+	var notification notificationBody
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrInvalidNotification,
+			err.Error(),
+		)
+	}
+
+	if err := v.verifySignature(ctx, notification.Payload); err != nil {
+		return nil, err
+	}
+
+	var claims notificationClaims
+	if err := oauth2.DecodeJWTClaims(notification.Payload, &claims); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrInvalidNotification, err)
+	}
+	if claims.Issuer != Issuer {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrInvalidNotification,
+			"unexpected issuer",
+		)
+	}
+	if claims.Audience != clientID {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrInvalidNotification,
+			"unexpected audience",
+		)
+	}
+	if err := v.checkIssuedAt(claims.IssuedAt, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Apple sends the events claim as a JSON encoded string
+	events := claims.Events
+	var encoded string
+	if err := json.Unmarshal(events, &encoded); err == nil {
+		events = []byte(encoded)
+	}
+
+	var event NotificationEvent
+	if err := json.Unmarshal(events, &event); err != nil || event.Type == "" {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrInvalidNotification,
+			"events claim is missing",
+		)
+	}
+	event.Audience = claims.Audience
+
+	return &event, nil
+}
+
+// verifySignature checks the RS256 signature of a compact JWT against Apple's keys
+func (v *NotificationVerifier) verifySignature(ctx context.Context, token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrInvalidNotification,
+			"malformed JWT",
+		)
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrInvalidNotification, err.Error())
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrInvalidNotification, err.Error())
+	}
+	if header.Algorithm != "RS256" {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrInvalidNotification,
+			"unsupported alg "+header.Algorithm,
+		)
+	}
+
+	key, err := v.key(ctx, header.KeyID)
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrInvalidNotification, err.Error())
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrInvalidNotification, err)
+	}
+
+	return nil
+}
+
+// key returns Apple's RSA public key for kid from the key cache
+func (v *NotificationVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	key, err := v.keys.Key(ctx, kid)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrInvalidNotification, err)
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrInvalidNotification,
+			"key "+kid+" is not an RSA key",
+		)
+	}
+	return rsaKey, nil
+}
+
+// checkIssuedAt rejects a missing iat, one older than the max age and one ahead of now
+func (v *NotificationVerifier) checkIssuedAt(issuedAt int64, now time.Time) error {
+	if issuedAt == 0 {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrInvalidNotification, "iat is missing")
+	}
+
+	issued := time.Unix(issuedAt, 0)
+	switch {
+	case issued.After(now.Add(notificationClockSkew)):
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrInvalidNotification, "issued in the future")
+	case now.Sub(issued) > v.maxAge:
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrInvalidNotification, "notification is stale")
+	}
+	return nil
+}
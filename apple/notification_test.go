@@ -0,0 +1,213 @@
+package apple_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/apple"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newMockClient(fn roundTripperFunc) *http.Client {
+	return &http.Client{Transport: fn}
+}
+
+// newKeysClient serves key as the only key of Apple's JWKS
+func newKeysClient(t *testing.T, kid string, key *rsa.PublicKey) *http.Client {
+	return newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, apple.KeysURL, req.URL.String())
+		body, _ := json.Marshal(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": kid,
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+		}, nil
+	})
+}
+
+// signNotification builds the notification body Apple would post
+func signNotification(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) []byte {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+
+	body, _ := json.Marshal(map[string]string{
+		"payload": signingInput + "." + base64.RawURLEncoding.EncodeToString(signature),
+	})
+	return body
+}
+
+func TestNotificationVerifier_UnknownKeyThrottled(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var fetches atomic.Int32
+	keysClient := newKeysClient(t, "key-1", &key.PublicKey)
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		fetches.Add(1)
+		return keysClient.Transport.RoundTrip(req)
+	})
+	verifier := apple.NewNotificationVerifier(oauth2.ProviderSetting{Client: client, ClientID: "com.example.app"})
+
+	claims := map[string]any{"iss": apple.Issuer, "aud": "com.example.app", "iat": time.Now().Unix()}
+	for i := range 20 {
+		_, err := verifier.Verify(context.Background(), signNotification(t, key, fmt.Sprintf("random-%d", i), claims))
+		assert.ErrorIs(t, err, oauth2.ErrInvalidNotification)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "unknown key IDs must not refetch the keys on every notification")
+}
+
+func TestNotificationVerifier_Verify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	events, _ := json.Marshal(map[string]any{
+		"type":       apple.EventAccountDelete,
+		"sub":        "001234.abcd",
+		"event_time": 1700000000000,
+	})
+	claims := map[string]any{
+		"iss":    apple.Issuer,
+		"aud":    "com.example.app",
+		"iat":    time.Now().Unix(),
+		"jti":    "jti",
+		"events": string(events),
+	}
+
+	verifier := apple.NewNotificationVerifier(oauth2.ProviderSetting{
+		Client:   newKeysClient(t, "key-1", &key.PublicKey),
+		ClientID: "com.example.app",
+	})
+
+	t.Run("valid notification", func(t *testing.T) {
+		body := signNotification(t, key, "key-1", claims)
+
+		event, err := verifier.Verify(context.Background(), body)
+		assert.NoError(t, err)
+		assert.Equal(t, apple.EventAccountDelete, event.Type)
+		assert.Equal(t, "001234.abcd", event.Subject)
+		assert.Equal(t, int64(1700000000000), event.EventTime)
+		assert.Equal(t, "com.example.app", event.Audience)
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		body := signNotification(t, otherKey, "key-1", claims)
+
+		_, err := verifier.Verify(context.Background(), body)
+		assert.ErrorIs(t, err, oauth2.ErrInvalidNotification)
+		assert.ErrorIs(t, err, rsa.ErrVerification)
+	})
+
+	t.Run("unknown key ID", func(t *testing.T) {
+		_, err := verifier.Verify(context.Background(), signNotification(t, key, "key-2", claims))
+		assert.ErrorIs(t, err, oauth2.ErrInvalidNotification)
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		invalid := map[string]any{
+			"iss":    "https://evil.example.com",
+			"aud":    "com.example.app",
+			"events": string(events),
+		}
+		body := signNotification(t, key, "key-1", invalid)
+
+		_, err := verifier.Verify(context.Background(), body)
+		assert.ErrorIs(t, err, oauth2.ErrInvalidNotification)
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		invalid := map[string]any{
+			"iss":    apple.Issuer,
+			"aud":    "com.other.app",
+			"events": string(events),
+		}
+		body := signNotification(t, key, "key-1", invalid)
+
+		_, err := verifier.Verify(context.Background(), body)
+		assert.ErrorIs(t, err, oauth2.ErrInvalidNotification)
+	})
+
+	t.Run("stale, future or missing iat", func(t *testing.T) {
+		for _, iat := range []any{
+			time.Now().Add(-apple.DefaultNotificationMaxAge - time.Minute).Unix(),
+			time.Now().Add(time.Hour).Unix(),
+			nil,
+		} {
+			replayed := maps.Clone(claims)
+			replayed["iat"] = iat
+			if iat == nil {
+				delete(replayed, "iat")
+			}
+
+			_, err := verifier.Verify(context.Background(), signNotification(t, key, "key-1", replayed))
+			assert.ErrorIs(t, err, oauth2.ErrInvalidNotification, iat)
+		}
+	})
+
+	t.Run("max age option", func(t *testing.T) {
+		older := maps.Clone(claims)
+		older["iat"] = time.Now().Add(-time.Hour).Unix()
+		verifier := apple.NewNotificationVerifier(oauth2.ProviderSetting{
+			Client:   newKeysClient(t, "key-1", &key.PublicKey),
+			ClientID: "com.example.app",
+		}, apple.WithMaxAge(2*time.Hour))
+
+		_, err := verifier.Verify(context.Background(), signNotification(t, key, "key-1", older))
+		assert.NoError(t, err)
+	})
+
+	t.Run("audience is required", func(t *testing.T) {
+		body := signNotification(t, key, "key-1", claims)
+		verifier := apple.NewNotificationVerifier(oauth2.ProviderSetting{
+			Client: newKeysClient(t, "key-1", &key.PublicKey),
+		})
+
+		_, err := verifier.Verify(context.Background(), body)
+		assert.ErrorIs(t, err, oauth2.ErrClientIDNotSet)
+
+		_, err = apple.VerifyServerNotification(context.Background(), "", body)
+		assert.ErrorIs(t, err, oauth2.ErrClientIDNotSet)
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		for _, body := range []string{"", "{}", `{"payload":"a.b"}`, `{"payload":"a.b.c"}`} {
+			_, err := verifier.Verify(context.Background(), []byte(body))
+			assert.ErrorIs(t, err, oauth2.ErrInvalidNotification, body)
+		}
+	})
+}
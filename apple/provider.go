@@ -0,0 +1,641 @@
+package apple
+
+import (
+	"cmp"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// AuthURL is the endpoint to start the Sign in with Apple flow
+	AuthURL = "https://appleid.apple.com/auth/authorize"
+
+	// TokenURL is the endpoint to exchange the authorization code for tokens
+	TokenURL = "https://appleid.apple.com/auth/token"
+
+	// RevokeURL is the endpoint to revoke an access or refresh token
+	RevokeURL = "https://appleid.apple.com/auth/revoke"
+
+	// MaxClientSecretTTL is the longest lifetime Apple accepts for a client secret (6 months)
+	MaxClientSecretTTL = 15777000 * time.Second
+
+	// clientSecretRefreshWindow renews the cached client secret this long before it expires
+	clientSecretRefreshWindow = 5 * time.Minute
+)
+
+// DefaultScopes are requested when ProviderSetting.Scopes is empty, WithScopes adds to them.
+// Replace it during initialization, before any provider is used
+var DefaultScopes = []string{"name", "email"}
+
+var (
+	errNoPEMBlock = fmt.Errorf("no PEM block found")
+	errNotP256    = fmt.Errorf("not an ECDSA P-256 key")
+)
+
+type (
+	// Setting extends oauth2.ProviderSetting with the key material used to sign the client secret.
+	// ClientID is the Services ID and ClientSecret is ignored, the secret is generated instead
+	Setting struct {
+		oauth2.ProviderSetting
+
+		// TeamID is the Apple developer team ID, used as the client secret issuer
+		TeamID string
+
+		// KeyID is the ID of the Sign in with Apple private key
+		KeyID string
+
+		// PrivateKey is the PEM encoded PKCS8 key (.p8 file) downloaded from Apple
+		PrivateKey []byte
+
+		// ClientSecretTTL is the lifetime of generated client secrets,
+		// defaults to and is capped at MaxClientSecretTTL
+		ClientSecretTTL time.Duration
+	}
+
+	// provider holds the configuration for Sign in with Apple
+	provider struct {
+		requester   *oauth2.Requester
+		clientID    string
+		redirectURL string
+
+		teamID          string
+		keyID           string
+		privateKey      *ecdsa.PrivateKey
+		clientSecretTTL time.Duration
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		mu                    sync.Mutex
+		clientSecret          string
+		clientSecretExpiresAt time.Time
+	}
+
+	// userInfo represents the user built from Apple's id_token
+	userInfo struct {
+		ID            string
+		Email         string
+		Name          string
+		EmailVerified bool
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
+	}
+
+	// tokenInfo represents the token information returned from Apple
+	tokenInfo struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+		IDToken      string `json:"id_token"`
+
+		issuedAt time.Time
+	}
+
+	// idTokenClaims represents the id_token claims used to identify the user
+	idTokenClaims struct {
+		Issuer    string `json:"iss"`
+		Audience  string `json:"aud"`
+		Subject   string `json:"sub"`
+		ExpiresAt int64  `json:"exp"`
+		Email     string `json:"email"`
+
+		EmailVerified oauth2.BoolClaim `json:"email_verified"`
+	}
+
+	// clientSecretClaims are the claims of the generated client secret JWT
+	clientSecretClaims struct {
+		Issuer    string `json:"iss"`
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Audience  string `json:"aud"`
+		Subject   string `json:"sub"`
+	}
+
+	// FormUser is the "user" JSON Apple posts to the redirect URL on the first authorization only
+	FormUser struct {
+		Name struct {
+			FirstName string `json:"firstName"`
+			LastName  string `json:"lastName"`
+		} `json:"name"`
+		Email string `json:"email"`
+	}
+)
+
+// provider must keep implementing oauth2.Provider and the id_token login
+var (
+	_ oauth2.Provider        = (*provider)(nil)
+	_ oauth2.IDTokenProvider = (*provider)(nil)
+)
+
+// NewProvider initializes and returns a new Sign in with Apple provider.
+// It fails with ErrInvalidPrivateKey when PrivateKey is not a PKCS8 P-256 key
+// and with ErrInvalidRedirectURL when ProviderSetting.Validate rejects the redirect URL
+func NewProvider(setting Setting) (oauth2.Provider, error) {
+	if err := setting.Validate(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+
+	privateKey, err := parsePrivateKey(setting.PrivateKey)
+	if err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrInvalidPrivateKey, err.Error())
+	}
+
+	ttl := MaxClientSecretTTL
+	if setting.ClientSecretTTL > 0 && setting.ClientSecretTTL < MaxClientSecretTTL {
+		ttl = setting.ClientSecretTTL
+	}
+
+	return &provider{
+		requester:   oauth2.NewRequester(setting.ProviderSetting),
+		clientID:    setting.ClientID,
+		redirectURL: setting.RedirectURL,
+
+		teamID:          setting.TeamID,
+		keyID:           setting.KeyID,
+		privateKey:      privateKey,
+		clientSecretTTL: ttl,
+
+		strictTokenType:  setting.StrictTokenType,
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodPost),
+
+		authURL:  cmp.Or(setting.AuthURL, AuthURL),
+		tokenURL: cmp.Or(setting.TokenURL, TokenURL),
+	}, nil
+}
+
+// parsePrivateKey decodes a PEM encoded PKCS8 P-256 private key
+func parsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errNoPEMBlock
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, errNotP256
+	}
+	return ecKey, nil
+}
+
+// GetUserInfo is not supported since Apple has no userinfo endpoint,
+// authenticate with oauth2.WithIDTokenOnly so the user is built from the id_token
+func (a *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
+	return nil, oauth2.WrapProviderError(
+		ProviderType,
+		oauth2.ErrUnsupportedOperation,
+		"no userinfo endpoint, use the id_token",
+	)
+}
+
+// UserInfoFromIDToken builds the user from the id_token returned with the token.
+// It must come straight from the token endpoint, so the claims are checked but not the signature.
+// The name is never part of the id_token, see UserInfoWithForm
+func (a *provider) UserInfoFromIDToken(token oauth2.TokenInfo) (oauth2.UserInfo, error) {
+	if token == nil || token.GetIDToken() == "" {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrInvalidIDToken,
+			"id_token is missing",
+		)
+	}
+
+	var claims idTokenClaims
+	if err := oauth2.DecodeJWTClaims(token.GetIDToken(), &claims); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	var reason string
+	switch {
+	case claims.Issuer != Issuer:
+		reason = "unexpected issuer"
+	case claims.Audience != a.clientID:
+		reason = "unexpected audience"
+	case time.Now().Unix() >= claims.ExpiresAt:
+		reason = "token expired"
+	case claims.Subject == "":
+		reason = "subject is missing"
+	}
+	if reason != "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrInvalidIDToken, reason)
+	}
+
+	var raw map[string]any
+	_ = oauth2.DecodeJWTClaims(token.GetIDToken(), &raw)
+
+	return &userInfo{
+		ID:    claims.Subject,
+		Email: claims.Email,
+
+		EmailVerified: bool(claims.EmailVerified),
+
+		raw:          raw,
+		nameStrategy: a.nameStrategy,
+	}, nil
+}
+
+// ParseFormUser parses the "user" field Apple posts with the code on the first authorization,
+// nil when it is absent (every later sign in)
+func ParseFormUser(form url.Values) (*FormUser, error) {
+	raw := form.Get("user")
+	if raw == "" {
+		return nil, nil
+	}
+
+	var user FormUser
+	if err := json.Unmarshal([]byte(raw), &user); err != nil {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrUserInfoRequestFailed,
+			err.Error(),
+		)
+	}
+	return &user, nil
+}
+
+// FullName returns the first and last name separated by a space
+func (u FormUser) FullName() string {
+	return strings.TrimSpace(u.Name.FirstName + " " + u.Name.LastName)
+}
+
+// UserInfoWithForm fills the name Apple posts only on the first authorization into a user
+// built by UserInfoFromIDToken. Persist it then, Apple never sends it again
+//
+//	example:
+//	result, err := client.Authenticate(ctx, apple.ProviderType, r.PostFormValue("code"), oauth2.WithIDTokenOnly())
+//	if err != nil { ... }
+//	user, err := apple.UserInfoWithForm(result.User, r.PostForm)
+func UserInfoWithForm(user oauth2.UserInfo, form url.Values) (oauth2.UserInfo, error) {
+	formUser, err := ParseFormUser(form)
+	if err != nil {
+		return nil, err
+	}
+
+	appleUser, ok := user.(*userInfo)
+	if !ok || formUser == nil {
+		return user, nil
+	}
+
+	merged := *appleUser
+	merged.Name = formUser.FullName()
+	merged.Email = cmp.Or(merged.Email, formUser.Email)
+	return &merged, nil
+}
+
+// GetAuthURL constructs the Sign in with Apple authorization URL
+//   - name and email are requested unless ProviderSetting.Scopes replaces them, which requires
+//     response_mode=form_post: the callback receives code, state and user as a POST form
+//   - WithOfflineAccess and WithPrompt are ignored, Apple supports neither
+func (a *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
+	if a.redirectURL == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
+	}
+	if a.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+
+	options := oauth2.NewAuthOptions(opts...)
+	if err := oauth2.ValidatePrompts(options.Prompts); err != nil {
+		return "", oauth2.WrapProviderError(ProviderType, err, strings.Join(options.Prompts, " "))
+	}
+
+	scopes := a.scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	query := url.Values{}
+	query.Set("client_id", a.clientID)
+	query.Set("redirect_uri", a.redirectURL)
+	query.Set("response_type", "code")
+	query.Set("response_mode", "form_post")
+	query.Set("scope", strings.Join(oauth2.NormalizeScopes(scopes, options.Scopes), " "))
+	query.Set("state", state)
+	oauth2.SetNonce(query, options.Nonce)
+	oauth2.SetAuthParams(query, options.AuthParams)
+
+	return oauth2.BuildAuthURL(ProviderType, a.authURL, query, a.authURLLimits)
+}
+
+// GetToken exchanges the authorization code for tokens, signing a client secret when needed.
+// PKCE is not supported, so WithPKCE is ignored
+func (a *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	if code == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
+	}
+	ctx = oauth2.WithoutRetry(ctx)
+
+	form := url.Values{}
+	form.Set("code", code)
+	form.Set("redirect_uri", a.redirectURL)
+	form.Set("grant_type", "authorization_code")
+
+	return a.requestToken(ctx, oauth2.OpGetToken, form)
+}
+
+// RefreshToken exchanges a refresh token for a new access token from Apple
+func (a *provider) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (oauth2.TokenInfo, error) {
+	if refreshToken == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyRefreshToken, "")
+	}
+
+	form := url.Values{}
+	form.Set("refresh_token", refreshToken)
+	form.Set("grant_type", "refresh_token")
+
+	return a.requestToken(ctx, oauth2.OpRefreshToken, form)
+}
+
+// requestToken posts form with the client credentials to the token endpoint
+func (a *provider) requestToken(ctx context.Context, op string, form url.Values) (oauth2.TokenInfo, error) {
+	var tokenInfo tokenInfo
+
+	clientSecret, err := a.getClientSecret()
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRequestFailed,
+			err.Error(),
+		)
+	}
+	form.Set("client_id", a.clientID)
+	form.Set("client_secret", clientSecret)
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, a.tokenURL, form)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRequestFailed,
+			err.Error(),
+		)
+	}
+
+	end := a.requester.Observe(ctx, ProviderType, op)
+	resp, err := a.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
+			ProviderType,
+			op,
+			oauth2.ErrTokenRequestFailed,
+			resp,
+		)
+	}
+
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	// a refresh keeps the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = form.Get("refresh_token")
+	}
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, a.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
+	return tokenInfo, nil
+}
+
+// RevokeToken revokes an access or refresh token with Apple
+func (a *provider) RevokeToken(ctx context.Context, token string) error {
+	if token == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRevocationFailed, "token is empty")
+	}
+
+	clientSecret, err := a.getClientSecret()
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	form := url.Values{}
+	form.Set("client_id", a.clientID)
+	form.Set("client_secret", clientSecret)
+	form.Set("token", token)
+
+	req, err := oauth2.NewFormRequest(ctx, a.revocationMethod, RevokeURL, form)
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	resp, err := a.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRevocationFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpRevokeToken,
+			oauth2.ErrTokenRevocationFailed,
+			resp,
+		)
+	}
+
+	return nil
+}
+
+// getClientSecret returns the cached client secret, signing a new one when it is close to expiry
+func (a *provider) getClientSecret() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.clientSecret != "" && now.Add(clientSecretRefreshWindow).Before(a.clientSecretExpiresAt) {
+		return a.clientSecret, nil
+	}
+
+	expiresAt := now.Add(a.clientSecretTTL)
+	secret, err := a.signClientSecret(now, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	a.clientSecret = secret
+	a.clientSecretExpiresAt = expiresAt
+	return secret, nil
+}
+
+// signClientSecret builds the ES256 client secret JWT
+//   - REFS : https://developer.apple.com/documentation/accountorganizationaldatasharing/creating-a-client-secret
+func (a *provider) signClientSecret(issuedAt time.Time, expiresAt time.Time) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: "ES256", KeyID: a.keyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(clientSecretClaims{
+		Issuer:    a.teamID,
+		IssuedAt:  issuedAt.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		Audience:  Issuer,
+		Subject:   a.clientID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, a.privateKey, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	// JWS encodes the ES256 signature as the fixed size R || S
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// CanRefresh reports whether token still holds a refresh token
+func (a *provider) CanRefresh(token oauth2.TokenInfo) bool { return oauth2.CanRefresh(token) }
+
+// SigningKeys fetches the id_token signing keys served at KeysURL
+func (a *provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	keys, err := a.requester.FetchJWKS(ctx, KeysURL)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrSigningKeysFailed, err)
+	}
+	return keys, nil
+}
+
+// UsesFormPost reports true, Apple posts code, state and user to the redirect URL
+func (a *provider) UsesFormPost() bool { return true }
+
+// GetClientCredentialsToken is not supported since Sign in with Apple only issues user tokens
+func (a *provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrGrantNotSupported, "client_credentials")
+}
+
+// Unlink is not supported, revoking the refresh token with RevokeToken disconnects the user
+func (a *provider) Unlink(ctx context.Context, accessToken string) error {
+	return oauth2.WrapProviderError(ProviderType, oauth2.ErrUnsupportedOperation, "unlink")
+}
+
+// GetProvider returns the provider type ("apple")
+func (a *provider) GetProvider() oauth2.ProviderType { return ProviderType }
+
+// GetRedirectURL returns the configured redirect URL
+func (a *provider) GetRedirectURL() string { return a.redirectURL }
+
+// GetID returns the user's stable Apple ID (the id_token "sub")
+func (a userInfo) GetID() string { return a.ID }
+
+// GetEmail returns the user's email address, possibly a private relay address
+func (a userInfo) GetEmail() string { return a.Email }
+
+// GetName returns the name posted on the first authorization, or the email depending on the NameStrategy
+func (a userInfo) GetName() string { return oauth2.SelectName(a.nameStrategy, a.Name, "", a.Email) }
+
+// GetGender returns an empty string since Apple does not share gender
+func (a userInfo) GetGender() string { return "" }
+
+// GetGenderNormalized returns oauth2.GenderUnknown since there is no gender
+func (a userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.GenderUnknown }
+
+// GetProfileImage returns an empty string since Apple does not share a profile image
+func (a userInfo) GetProfileImage() string { return "" }
+
+// GetPhoneNumber returns an empty string since Apple does not share phone numbers
+func (a userInfo) GetPhoneNumber() string { return "" }
+
+// GetBirthday returns an empty string since Apple does not share birthdays
+func (a userInfo) GetBirthday() string { return "" }
+
+// GetLocale returns an empty string since Apple does not share a locale
+func (a userInfo) GetLocale() string { return "" }
+
+// IsEmailVerified reports the id_token email_verified claim, which Apple sends as a string
+func (a userInfo) IsEmailVerified() bool { return a.EmailVerified }
+
+// GetRaw returns the id_token claims (e.g. "email_verified", "is_private_email")
+func (a userInfo) GetRaw() map[string]any { return a.raw }
+
+// GetAccessToken returns the OAuth2 access token
+func (a tokenInfo) GetAccessToken() string { return a.AccessToken }
+
+// GetRefreshToken returns the OAuth2 refresh token, only issued by the code exchange
+func (a tokenInfo) GetRefreshToken() string { return a.RefreshToken }
+
+// GetExpiry returns the token expiration time in seconds
+func (a tokenInfo) GetExpiry() int { return a.ExpiresIn }
+
+// HasRefreshToken reports whether a refresh token was issued
+func (a tokenInfo) HasRefreshToken() bool { return a.RefreshToken != "" }
+
+// GetIDToken returns the id_token identifying the user
+func (a tokenInfo) GetIDToken() string { return a.IDToken }
+
+// HasExpiry reports whether the access token expires
+func (a tokenInfo) HasExpiry() bool { return a.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (a tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(a.issuedAt, a.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (a tokenInfo) IsExpired() bool { return oauth2.Expired(a.GetExpiresAt()) }
+
+// GetScope returns an empty string since Apple does not report the granted scope
+func (a tokenInfo) GetScope() string { return "" }
+
+// GetTokenType returns the token type (e.g. "Bearer")
+func (a tokenInfo) GetTokenType() string { return a.TokenType }
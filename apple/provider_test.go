@@ -0,0 +1,257 @@
+package apple_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/apple"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPrivateKey returns a P-256 key and its PEM encoded PKCS8 form, like a downloaded .p8 file
+func newPrivateKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// unsignedJWT builds a compact JWT with claims, as returned in the id_token
+func unsignedJWT(claims map[string]any) string {
+	payload, _ := json.Marshal(claims)
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+// decodeSegment decodes a base64url JWT segment into v
+func decodeSegment(t *testing.T, segment string, v any) {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(raw, v))
+}
+
+func TestAppleProvider_ClientSecret(t *testing.T) {
+	key, pemKey := newPrivateKey(t)
+
+	var secrets []string
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, apple.TokenURL, req.URL.String())
+		assert.NoError(t, req.ParseForm())
+		assert.Equal(t, "com.example.service", req.PostForm.Get("client_id"))
+		secrets = append(secrets, req.PostForm.Get("client_secret"))
+
+		body := `{"access_token":"access","token_type":"Bearer","expires_in":3600,` +
+			`"refresh_token":"refresh","id_token":"id"}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	provider, err := apple.NewProvider(apple.Setting{
+		ProviderSetting: oauth2.ProviderSetting{
+			Client:      client,
+			ClientID:    "com.example.service",
+			RedirectURL: "https://app.example.com/callback",
+		},
+		TeamID:     "TEAM123456",
+		KeyID:      "KEY1234567",
+		PrivateKey: pemKey,
+	})
+	assert.NoError(t, err)
+
+	token, err := provider.GetToken(context.Background(), "code")
+	assert.NoError(t, err)
+	assert.Equal(t, "access", token.GetAccessToken())
+	assert.True(t, token.HasRefreshToken())
+
+	_, err = provider.RefreshToken(context.Background(), "refresh")
+	assert.NoError(t, err)
+
+	assert.Len(t, secrets, 2)
+	assert.Equal(t, secrets[0], secrets[1], "client secret is cached")
+
+	parts := strings.Split(secrets[0], ".")
+	assert.Len(t, parts, 3)
+
+	var header map[string]string
+	decodeSegment(t, parts[0], &header)
+	assert.Equal(t, "ES256", header["alg"])
+	assert.Equal(t, "KEY1234567", header["kid"])
+
+	var claims struct {
+		Issuer    string `json:"iss"`
+		Subject   string `json:"sub"`
+		Audience  string `json:"aud"`
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+	}
+	decodeSegment(t, parts[1], &claims)
+	assert.Equal(t, "TEAM123456", claims.Issuer)
+	assert.Equal(t, "com.example.service", claims.Subject)
+	assert.Equal(t, apple.Issuer, claims.Audience)
+	assert.Equal(t, int64(apple.MaxClientSecretTTL.Seconds()), claims.ExpiresAt-claims.IssuedAt)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+	assert.Len(t, signature, 64)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	assert.True(t, ecdsa.Verify(&key.PublicKey, digest[:], r, s))
+}
+
+func TestAppleProvider_InvalidPrivateKey(t *testing.T) {
+	_, err := apple.NewProvider(apple.Setting{PrivateKey: []byte("not a key")})
+	assert.ErrorIs(t, err, oauth2.ErrInvalidPrivateKey)
+}
+
+func TestAppleProvider_UserInfoFromIDToken(t *testing.T) {
+	_, pemKey := newPrivateKey(t)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name         string
+		claims       map[string]any
+		wantVerified bool
+		wantErr      error
+	}{
+		{
+			name: "valid",
+			claims: map[string]any{
+				"iss": apple.Issuer, "aud": "com.example.service", "sub": "001234.abcd",
+				"exp": exp, "email": "relay@privaterelay.appleid.com", "email_verified": "true",
+			},
+			wantVerified: true,
+		},
+		{
+			name: "unverified email",
+			claims: map[string]any{
+				"iss": apple.Issuer, "aud": "com.example.service", "sub": "001234.abcd",
+				"exp": exp, "email": "relay@privaterelay.appleid.com", "email_verified": "false",
+			},
+		},
+		{
+			name:    "wrong audience",
+			claims:  map[string]any{"iss": apple.Issuer, "aud": "other", "sub": "001234.abcd", "exp": exp},
+			wantErr: oauth2.ErrInvalidIDToken,
+		},
+		{
+			name: "expired",
+			claims: map[string]any{
+				"iss": apple.Issuer, "aud": "com.example.service", "sub": "001234.abcd",
+				"exp": time.Now().Add(-time.Hour).Unix(),
+			},
+			wantErr: oauth2.ErrInvalidIDToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idToken := unsignedJWT(tt.claims)
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				body, _ := json.Marshal(map[string]any{
+					"access_token": "access",
+					"token_type":   "Bearer",
+					"id_token":     idToken,
+				})
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader(body)),
+				}, nil
+			})
+			provider, err := apple.NewProvider(apple.Setting{
+				ProviderSetting: oauth2.ProviderSetting{
+					Client:   client,
+					ClientID: "com.example.service",
+				},
+				PrivateKey: pemKey,
+			})
+			assert.NoError(t, err)
+
+			result, err := oauth2.NewClient(provider).Authenticate(
+				context.Background(),
+				apple.ProviderType,
+				"code",
+				oauth2.WithIDTokenOnly(),
+			)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "001234.abcd", result.User.GetID())
+			assert.Equal(t, "relay@privaterelay.appleid.com", result.User.GetEmail())
+			assert.Equal(t, tt.wantVerified, result.User.IsEmailVerified())
+
+			form := url.Values{}
+			form.Set("user", `{"name":{"firstName":"Jane","lastName":"Appleseed"},"email":"jane@example.com"}`)
+			user, err := apple.UserInfoWithForm(result.User, form)
+			assert.NoError(t, err)
+			assert.Equal(t, "Jane Appleseed", user.GetName())
+			assert.Equal(t, "relay@privaterelay.appleid.com", user.GetEmail())
+
+			user, err = apple.UserInfoWithForm(result.User, url.Values{})
+			assert.NoError(t, err)
+			assert.Equal(t, "relay@privaterelay.appleid.com", user.GetName())
+		})
+	}
+}
+
+func TestAppleProvider_GetAuthURL(t *testing.T) {
+	_, pemKey := newPrivateKey(t)
+	provider, err := apple.NewProvider(apple.Setting{
+		ProviderSetting: oauth2.ProviderSetting{
+			ClientID:    "com.example.service",
+			RedirectURL: "https://app.example.com/callback",
+		},
+		PrivateKey: pemKey,
+	})
+	assert.NoError(t, err)
+
+	authURL, err := provider.GetAuthURL(context.Background(), "xyz", oauth2.WithNonce("nonce-123"))
+	assert.NoError(t, err)
+
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "com.example.service", q.Get("client_id"))
+	assert.Equal(t, "form_post", q.Get("response_mode"))
+	formPost, ok := provider.(oauth2.FormPostProvider)
+	assert.True(t, ok && formPost.UsesFormPost(), "the state cookie must survive the cross-site POST")
+	assert.Equal(t, "name email", q.Get("scope"))
+	assert.Equal(t, "xyz", q.Get("state"))
+	assert.Equal(t, "nonce-123", q.Get("nonce"))
+
+	configured, err := apple.NewProvider(apple.Setting{
+		ProviderSetting: oauth2.ProviderSetting{
+			ClientID:    "com.example.service",
+			RedirectURL: "https://app.example.com/callback",
+			Scopes:      []string{"email"},
+		},
+		PrivateKey: pemKey,
+	})
+	assert.NoError(t, err)
+
+	authURL, err = configured.GetAuthURL(context.Background(), "xyz")
+	assert.NoError(t, err)
+	u, err = url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "email", u.Query().Get("scope"))
+}
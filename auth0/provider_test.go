@@ -0,0 +1,144 @@
+package auth0_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/auth0"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newMockClient(fn roundTripperFunc) *http.Client {
+	return &http.Client{Transport: fn}
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func newSetting(client *http.Client) oauth2.ProviderSetting {
+	return oauth2.ProviderSetting{
+		Client:       client,
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectURL:  "https://app.example.com/callback",
+	}
+}
+
+func TestAuth0Provider_DomainEndpoints(t *testing.T) {
+	ctx := context.Background()
+
+	var urls []string
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		urls = append(urls, req.URL.String())
+		if req.URL.Path == "/oauth/token" {
+			return jsonResponse(http.StatusOK, `{"access_token":"at","refresh_token":"rt",`+
+				`"token_type":"Bearer","expires_in":86400}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"sub":"auth0|42","name":"Octo Cat"}`), nil
+	})
+
+	for _, domain := range []string{"myorg.us.auth0.com", "https://myorg.us.auth0.com/"} {
+		t.Run(domain, func(t *testing.T) {
+			urls = nil
+			provider, err := auth0.NewProvider(newSetting(client), domain)
+			assert.NoError(t, err)
+
+			authURL, err := provider.GetAuthURL(ctx, "state")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(authURL, "https://myorg.us.auth0.com/authorize?"), authURL)
+
+			_, err = provider.GetToken(ctx, "code")
+			assert.NoError(t, err)
+			_, err = provider.GetUserInfo(ctx, "at")
+			assert.NoError(t, err)
+			assert.NoError(t, provider.RevokeToken(ctx, "rt"))
+
+			assert.Equal(t, []string{
+				"https://myorg.us.auth0.com/oauth/token",
+				"https://myorg.us.auth0.com/userinfo",
+				"https://myorg.us.auth0.com/oauth/revoke",
+			}, urls)
+		})
+	}
+}
+
+func TestAuth0Provider_Audience(t *testing.T) {
+	ctx := context.Background()
+	api := "https://api.example.com"
+
+	tests := []struct {
+		name     string
+		opts     []auth0.Option
+		authOpts []oauth2.AuthOption
+		want     []string
+	}{
+		{name: "omitted by default"},
+		{name: "configured", opts: []auth0.Option{auth0.WithAudience(api)}, want: []string{api}},
+		{
+			name:     "per call",
+			authOpts: []oauth2.AuthOption{oauth2.WithAuthParams(url.Values{"audience": {"https://other"}})},
+			want:     []string{"https://other"},
+		},
+		{
+			name:     "per call takes precedence",
+			opts:     []auth0.Option{auth0.WithAudience(api)},
+			authOpts: []oauth2.AuthOption{oauth2.WithAuthParams(url.Values{"audience": {"https://other"}})},
+			want:     []string{"https://other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := auth0.NewProvider(newSetting(nil), "myorg.us.auth0.com", tt.opts...)
+			assert.NoError(t, err)
+
+			authURL, err := provider.GetAuthURL(ctx, "state", tt.authOpts...)
+			assert.NoError(t, err)
+			parsed, err := url.Parse(authURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, parsed.Query()["audience"])
+		})
+	}
+}
+
+func TestAuth0Provider_GetUserInfo(t *testing.T) {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusOK, `{"sub":"google-oauth2|1001","name":"Octo Cat",`+
+			`"nickname":"octo","email":"octo@example.com","email_verified":true,`+
+			`"picture":"https://img","locale":"en"}`), nil
+	})
+	provider, err := auth0.NewProvider(newSetting(client), "myorg.us.auth0.com")
+	assert.NoError(t, err)
+
+	user, err := provider.GetUserInfo(context.Background(), "at")
+	assert.NoError(t, err)
+	assert.Equal(t, "google-oauth2|1001", user.GetID())
+	assert.Equal(t, "Octo Cat", user.GetName())
+	assert.Equal(t, "octo@example.com", user.GetEmail())
+	assert.True(t, user.IsEmailVerified())
+	assert.Equal(t, "https://img", user.GetProfileImage())
+	assert.Equal(t, "en", user.GetLocale())
+}
+
+func TestNewProvider_EmptyDomain(t *testing.T) {
+	provider, err := auth0.NewProvider(newSetting(nil), "")
+	assert.Nil(t, provider)
+	assert.True(t, errors.Is(err, oauth2.ErrEndpointNotSet), err)
+}
@@ -0,0 +1,54 @@
+package oauth2
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+)
+
+const (
+	// DefaultMaxAuthURLLength is the conservative URL length accepted by browsers and providers alike
+	DefaultMaxAuthURLLength = 2048
+
+	// DefaultMaxAuthParamLength bounds a single (unescaped) authorization query parameter
+	DefaultMaxAuthParamLength = 1024
+)
+
+// AuthURLLimits bounds the authorization URLs built by providers, zero fields use the defaults
+type AuthURLLimits struct {
+	MaxURLLength   int
+	MaxParamLength int
+}
+
+// BuildAuthURL appends query to endpoint, failing with ErrAuthURLTooLong when a parameter
+// or the whole URL exceeds limits, so an incompatible config surfaces before the redirect
+func BuildAuthURL(
+	provider ProviderType,
+	endpoint string,
+	query url.Values,
+	limits AuthURLLimits,
+) (string, error) {
+	maxParam := cmp.Or(limits.MaxParamLength, DefaultMaxAuthParamLength)
+	for key, values := range query {
+		for _, value := range values {
+			if len(value) > maxParam {
+				return "", WrapProviderError(
+					provider,
+					ErrAuthURLTooLong,
+					fmt.Sprintf("%s is %d bytes, limit %d", key, len(value), maxParam),
+				)
+			}
+		}
+	}
+
+	authURL := endpoint + "?" + query.Encode()
+	if maxURL := cmp.Or(limits.MaxURLLength, DefaultMaxAuthURLLength); len(authURL) > maxURL {
+		return "", WrapProviderError(
+			provider,
+			ErrAuthURLTooLong,
+			fmt.Sprintf("URL is %d bytes, limit %d", len(authURL), maxURL),
+		)
+	}
+
+	return authURL, nil
+}
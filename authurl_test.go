@@ -0,0 +1,41 @@
+package oauth2_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildAuthURL(t *testing.T) {
+	query := url.Values{}
+	query.Set("client_id", "client-id")
+
+	authURL, err := oauth2.BuildAuthURL("google", "https://example.com/auth", query, oauth2.AuthURLLimits{})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/auth?client_id=client-id", authURL)
+
+	t.Run("oversized param", func(t *testing.T) {
+		query := url.Values{}
+		query.Set("state", strings.Repeat("s", 101))
+
+		_, err := oauth2.BuildAuthURL("google", "https://example.com/auth", query, oauth2.AuthURLLimits{
+			MaxParamLength: 100,
+		})
+		assert.ErrorIs(t, err, oauth2.ErrAuthURLTooLong)
+		assert.Contains(t, err.Error(), "state")
+	})
+
+	t.Run("oversized URL", func(t *testing.T) {
+		query := url.Values{}
+		query.Set("a", strings.Repeat("a", 50))
+		query.Set("b", strings.Repeat("b", 50))
+
+		_, err := oauth2.BuildAuthURL("google", "https://example.com/auth", query, oauth2.AuthURLLimits{
+			MaxURLLength: 100,
+		})
+		assert.ErrorIs(t, err, oauth2.ErrAuthURLTooLong)
+	})
+}
@@ -0,0 +1,74 @@
+package oauth2
+
+import (
+	"context"
+	"sync"
+)
+
+// UserInfoBatchConcurrency bounds the userinfo requests RequestUserInfoBatch sends at once.
+// Replace it during initialization, before any batch is requested
+var UserInfoBatchConcurrency = 8
+
+// UserInfoResult is the outcome of one access token of RequestUserInfoBatch
+type UserInfoResult struct {
+	AccessToken string
+	UserInfo    UserInfo
+	Err         error
+}
+
+// RequestUserInfoBatch retrieves the user of every access token, sending at most
+// UserInfoBatchConcurrency requests at once. Results keep the order of accessTokens
+// and carry the error of their own token, a failed token does not stop the others.
+// When ctx ends, in-flight requests are cancelled and tokens not yet sent get the context error
+// as their Err, which is also returned along with the results. Fails with ErrProviderNotSet
+// when provider is not registered
+//
+//	example:
+//	results, err := client.RequestUserInfoBatch(ctx, google.ProviderType, tokens)
+//	for _, result := range results {
+//	    if result.Err != nil {
+//	        continue
+//	    }
+//	    fmt.Println(result.UserInfo.GetID())
+//	}
+func (c *oauth2Client) RequestUserInfoBatch(
+	ctx context.Context,
+	provider ProviderType,
+	accessTokens []string,
+) ([]UserInfoResult, error) {
+	if _, ok := c.lookup(provider); !ok {
+		return nil, ErrProviderNotSet
+	}
+
+	results := make([]UserInfoResult, len(accessTokens))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(max(UserInfoBatchConcurrency, 1), len(accessTokens)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				user, err := c.RequestUserInfo(ctx, provider, accessTokens[i])
+				results[i] = UserInfoResult{AccessToken: accessTokens[i], UserInfo: user, Err: err}
+			}
+		}()
+	}
+
+	sent := 0
+feed:
+	for ; sent < len(accessTokens); sent++ {
+		select {
+		case indexes <- sent:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i := sent; i < len(accessTokens); i++ {
+		results[i] = UserInfoResult{AccessToken: accessTokens[i], Err: ctx.Err()}
+	}
+	return results, ctx.Err()
+}
@@ -0,0 +1,113 @@
+package oauth2_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+type idUser struct {
+	dummyUser
+	id string
+}
+
+func (u idUser) GetID() string { return u.id }
+
+// delayingProvider answers userinfo after delay, tracking the highest number of concurrent calls
+type delayingProvider struct {
+	oauth2.Provider
+	delay   time.Duration
+	active  atomic.Int32
+	peak    atomic.Int32
+	started atomic.Int32
+}
+
+func (p *delayingProvider) GetProvider() oauth2.ProviderType { return "google" }
+
+func (p *delayingProvider) GetUserInfo(ctx context.Context, token string) (oauth2.UserInfo, error) {
+	p.started.Add(1)
+	active := p.active.Add(1)
+	defer p.active.Add(-1)
+	for peak := p.peak.Load(); active > peak && !p.peak.CompareAndSwap(peak, active); {
+		peak = p.peak.Load()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(p.delay):
+	}
+	if token == "bad" {
+		return nil, oauth2.ErrUserInfoRequestFailed
+	}
+	return idUser{id: token}, nil
+}
+
+func TestOAuth2Client_RequestUserInfoBatch(t *testing.T) {
+	defer func(concurrency int) { oauth2.UserInfoBatchConcurrency = concurrency }(oauth2.UserInfoBatchConcurrency)
+	oauth2.UserInfoBatchConcurrency = 3
+
+	provider := &delayingProvider{delay: 10 * time.Millisecond}
+	client := oauth2.NewClient(provider)
+	tokens := []string{"t0", "t1", "bad", "t3", "", "t5", "t6", "t7", "t8", "t9"}
+
+	results, err := client.RequestUserInfoBatch(context.Background(), "google", tokens)
+	assert.NoError(t, err)
+	assert.Len(t, results, len(tokens))
+	assert.Equal(t, int32(3), provider.peak.Load())
+
+	for i, result := range results {
+		assert.Equal(t, tokens[i], result.AccessToken)
+		switch tokens[i] {
+		case "bad":
+			assert.ErrorIs(t, result.Err, oauth2.ErrUserInfoRequestFailed)
+		case "":
+			assert.ErrorIs(t, result.Err, oauth2.ErrEmptyAccessToken)
+		default:
+			assert.NoError(t, result.Err)
+			assert.Equal(t, tokens[i], result.UserInfo.GetID())
+		}
+	}
+
+	_, err = client.RequestUserInfoBatch(context.Background(), "kakao", tokens)
+	assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+}
+
+func TestOAuth2Client_RequestUserInfoBatchCancel(t *testing.T) {
+	defer func(concurrency int) { oauth2.UserInfoBatchConcurrency = concurrency }(oauth2.UserInfoBatchConcurrency)
+	oauth2.UserInfoBatchConcurrency = 2
+
+	provider := &delayingProvider{delay: time.Hour}
+	client := oauth2.NewClient(provider)
+	tokens := []string{"t0", "t1", "t2", "t3", "t4"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	var results []oauth2.UserInfoResult
+	var err error
+	go func() {
+		results, err = client.RequestUserInfoBatch(ctx, "google", tokens)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("batch did not stop on cancellation")
+	}
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Equal(t, int32(2), provider.started.Load())
+	assert.Len(t, results, len(tokens))
+	for i, result := range results {
+		assert.Equal(t, tokens[i], result.AccessToken)
+		assert.ErrorIs(t, result.Err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,147 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// CacheStats are the counters of a userinfo cache since it was created
+	CacheStats struct {
+		Hits      uint64
+		Misses    uint64
+		Evictions uint64
+	}
+
+	// CachingProvider is a Provider whose userinfo lookups are cached, see WithUserInfoCache
+	CachingProvider interface {
+		Provider
+		Stats() CacheStats
+	}
+
+	// cachedProvider caches the GetUserInfo results of the wrapped provider per access token
+	cachedProvider struct {
+		Provider
+		ttl        time.Duration
+		maxEntries int
+
+		mu      sync.Mutex
+		entries map[[sha256.Size]byte]cacheEntry
+
+		hits      atomic.Uint64
+		misses    atomic.Uint64
+		evictions atomic.Uint64
+	}
+
+	// cachedIDTokenProvider keeps IDTokenProvider available when the wrapped provider has it
+	cachedIDTokenProvider struct {
+		*cachedProvider
+		IDTokenProvider
+	}
+
+	// cacheEntry is a cached user and when it stops being served
+	cacheEntry struct {
+		user      UserInfo
+		expiresAt time.Time
+	}
+)
+
+// WithUserInfoCache wraps provider so GetUserInfo results are reused for ttl per access token,
+// keeping at most maxEntries users (the entry closest to expiry is evicted first).
+// Errors are not cached and access tokens are only kept as SHA-256 keys
+//
+//	example:
+//	cached := oauth2.WithUserInfoCache(google.NewProvider(setting), time.Minute, 10000)
+//	client := oauth2.NewClient(cached)
+//	...
+//	stats := cached.Stats()
+//	log.Printf("userinfo cache: %d hits, %d misses", stats.Hits, stats.Misses)
+func WithUserInfoCache(provider Provider, ttl time.Duration, maxEntries int) CachingProvider {
+	cached := &cachedProvider{
+		Provider:   provider,
+		ttl:        ttl,
+		maxEntries: max(maxEntries, 1),
+		entries:    make(map[[sha256.Size]byte]cacheEntry),
+	}
+	if idTokenProvider, ok := provider.(IDTokenProvider); ok {
+		return &cachedIDTokenProvider{
+			cachedProvider:  cached,
+			IDTokenProvider: idTokenProvider,
+		}
+	}
+	return cached
+}
+
+// GetUserInfo serves the cached user for accessToken, fetching and caching it on a miss
+func (p *cachedProvider) GetUserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	key := sha256.Sum256([]byte(accessToken))
+	now := time.Now()
+
+	p.mu.Lock()
+	entry, ok := p.entries[key]
+	if ok && !now.Before(entry.expiresAt) {
+		delete(p.entries, key)
+		p.evictions.Add(1)
+		ok = false
+	}
+	p.mu.Unlock()
+
+	if ok {
+		p.hits.Add(1)
+		return entry.user, nil
+	}
+	p.misses.Add(1)
+
+	user, err := p.Provider.GetUserInfo(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.entries[key]; !exists && len(p.entries) >= p.maxEntries {
+		p.evict(now)
+	}
+	p.entries[key] = cacheEntry{user: user, expiresAt: now.Add(p.ttl)}
+
+	return user, nil
+}
+
+// evict drops expired entries, or the one closest to expiry when none has expired.
+// It must be called with p.mu held
+func (p *cachedProvider) evict(now time.Time) {
+	var (
+		oldestKey [sha256.Size]byte
+		oldest    time.Time
+	)
+	for key, entry := range p.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(p.entries, key)
+			p.evictions.Add(1)
+			continue
+		}
+		if oldest.IsZero() || entry.expiresAt.Before(oldest) {
+			oldestKey, oldest = key, entry.expiresAt
+		}
+	}
+
+	if len(p.entries) >= p.maxEntries {
+		delete(p.entries, oldestKey)
+		p.evictions.Add(1)
+	}
+}
+
+// Stats returns the hit, miss and eviction counters, safe to call concurrently with lookups
+func (p *cachedProvider) Stats() CacheStats {
+	return CacheStats{
+		Hits:      p.hits.Load(),
+		Misses:    p.misses.Load(),
+		Evictions: p.evictions.Load(),
+	}
+}
+
+// UsesFormPost reports whether the wrapped provider is a FormPostProvider posting its callback
+func (p *cachedProvider) UsesFormPost() bool { return usesFormPost(p.Provider) }
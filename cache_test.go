@@ -0,0 +1,107 @@
+package oauth2_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingProvider counts the userinfo calls reaching the wrapped mock
+type countingProvider struct {
+	mockProvider
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *countingProvider) GetUserInfo(ctx context.Context, token string) (oauth2.UserInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	return c.mockProvider.GetUserInfo(ctx, token)
+}
+
+func TestWithUserInfoCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("hits and misses across repeated lookups", func(t *testing.T) {
+		inner := &countingProvider{mockProvider: mockProvider{typ: "kakao", returnUserInfo: dummyUser{}}}
+		cached := oauth2.WithUserInfoCache(inner, time.Minute, 10)
+
+		for range 3 {
+			user, err := cached.GetUserInfo(ctx, "token-a")
+			assert.NoError(t, err)
+			assert.Equal(t, "id", user.GetID())
+		}
+		_, err := cached.GetUserInfo(ctx, "token-b")
+		assert.NoError(t, err)
+
+		assert.Equal(t, oauth2.CacheStats{Hits: 2, Misses: 2}, cached.Stats())
+		assert.Equal(t, 2, inner.calls)
+	})
+
+	t.Run("expired entries are evicted", func(t *testing.T) {
+		inner := &countingProvider{mockProvider: mockProvider{typ: "kakao", returnUserInfo: dummyUser{}}}
+		cached := oauth2.WithUserInfoCache(inner, 10*time.Millisecond, 10)
+
+		_, _ = cached.GetUserInfo(ctx, "token")
+		time.Sleep(20 * time.Millisecond)
+		_, _ = cached.GetUserInfo(ctx, "token")
+
+		assert.Equal(t, oauth2.CacheStats{Misses: 2, Evictions: 1}, cached.Stats())
+	})
+
+	t.Run("capacity evicts an entry", func(t *testing.T) {
+		inner := &countingProvider{mockProvider: mockProvider{typ: "kakao", returnUserInfo: dummyUser{}}}
+		cached := oauth2.WithUserInfoCache(inner, time.Minute, 1)
+
+		_, _ = cached.GetUserInfo(ctx, "token-a")
+		_, _ = cached.GetUserInfo(ctx, "token-b")
+		_, _ = cached.GetUserInfo(ctx, "token-a")
+
+		assert.Equal(t, oauth2.CacheStats{Misses: 3, Evictions: 2}, cached.Stats())
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		inner := &countingProvider{mockProvider: mockProvider{
+			typ:         "kakao",
+			errUserInfo: oauth2.ErrUserInfoRequestFailed,
+		}}
+		cached := oauth2.WithUserInfoCache(inner, time.Minute, 10)
+
+		for range 2 {
+			_, err := cached.GetUserInfo(ctx, "token")
+			assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+		}
+		assert.Equal(t, oauth2.CacheStats{Misses: 2}, cached.Stats())
+	})
+
+	t.Run("stats are race-safe", func(t *testing.T) {
+		inner := &countingProvider{mockProvider: mockProvider{typ: "kakao", returnUserInfo: dummyUser{}}}
+		cached := oauth2.WithUserInfoCache(inner, time.Minute, 10)
+
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = cached.GetUserInfo(ctx, "token")
+				_ = cached.Stats()
+			}()
+		}
+		wg.Wait()
+
+		stats := cached.Stats()
+		assert.Equal(t, uint64(50), stats.Hits+stats.Misses)
+	})
+
+	t.Run("id_token support is kept", func(t *testing.T) {
+		cached := oauth2.WithUserInfoCache(google.NewProvider(oauth2.ProviderSetting{}), time.Minute, 10)
+		_, ok := cached.(oauth2.IDTokenProvider)
+		assert.True(t, ok)
+	})
+}
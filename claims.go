@@ -0,0 +1,28 @@
+package oauth2
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+)
+
+// SetClaimsRequest adds the compacted claims JSON to an authorization query as the claims parameter,
+// leaving it untouched when claims is empty. It fails with ErrInvalidClaimsRequest unless
+// claims is a JSON object
+func SetClaimsRequest(query url.Values, claims json.RawMessage) error {
+	if len(claims) == 0 {
+		return nil
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(claims, &members); err != nil || members == nil {
+		return ErrInvalidClaimsRequest
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, claims); err != nil {
+		return ErrInvalidClaimsRequest
+	}
+	query.Set("claims", compact.String())
+	return nil
+}
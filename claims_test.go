@@ -0,0 +1,47 @@
+package oauth2_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetClaimsRequest(t *testing.T) {
+	t.Run("claims are compacted and URL-encoded", func(t *testing.T) {
+		options := oauth2.NewAuthOptions(oauth2.WithClaimsRequest(json.RawMessage(`{
+			"id_token": {"email_verified": {"essential": true}},
+			"userinfo": {"verified_claims": {"claims": {"given_name": null}}}
+		}`)))
+
+		query := url.Values{"response_type": {"code"}}
+		assert.NoError(t, oauth2.SetClaimsRequest(query, options.ClaimsRequest))
+
+		want := `{"id_token":{"email_verified":{"essential":true}},"userinfo":{"verified_claims":{"claims":{"given_name":null}}}}`
+		assert.Equal(t, want, query.Get("claims"))
+
+		encoded := query.Encode()
+		assert.Contains(t, encoded, "claims=%7B%22id_token%22")
+		decoded, err := url.ParseQuery(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, want, decoded.Get("claims"))
+	})
+
+	t.Run("no claims", func(t *testing.T) {
+		query := url.Values{}
+		assert.NoError(t, oauth2.SetClaimsRequest(query, nil))
+		assert.False(t, query.Has("claims"))
+	})
+
+	for _, claims := range []string{"[1]", "null", `"id_token"`, "{broken"} {
+		t.Run("invalid "+strings.TrimSpace(claims), func(t *testing.T) {
+			query := url.Values{}
+			err := oauth2.SetClaimsRequest(query, json.RawMessage(claims))
+			assert.ErrorIs(t, err, oauth2.ErrInvalidClaimsRequest)
+			assert.False(t, query.Has("claims"))
+		})
+	}
+}
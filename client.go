@@ -2,7 +2,13 @@ package oauth2
 
 import (
 	"context"
+	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
 type (
@@ -13,22 +19,94 @@ type (
 			provider ProviderType,
 			accessToken string,
 		) (UserInfo, error)
-		RequestAuthURL(ctx context.Context, provider ProviderType, state string) string
-		RequestToken(ctx context.Context, provider ProviderType, code string) (TokenInfo, error)
+		RequestUserInfoWithToken(
+			ctx context.Context,
+			provider ProviderType,
+			token TokenInfo,
+		) (UserInfo, error)
+		RequestUserInfoBatch(
+			ctx context.Context,
+			provider ProviderType,
+			accessTokens []string,
+		) ([]UserInfoResult, error)
+		RequestAuthURL(
+			ctx context.Context,
+			provider ProviderType,
+			state string,
+			opts ...AuthOption,
+		) string
+		RequestAuthURLWithError(
+			ctx context.Context,
+			provider ProviderType,
+			state string,
+			opts ...AuthOption,
+		) (string, error)
+		RequestToken(
+			ctx context.Context,
+			provider ProviderType,
+			code string,
+			opts ...AuthOption,
+		) (TokenInfo, error)
 		RequestRefreshToken(
 			ctx context.Context,
 			provider ProviderType,
 			refreshToken string,
 		) (TokenInfo, error)
+		RequestRevokeToken(ctx context.Context, provider ProviderType, token string) error
+		RequestUnlink(ctx context.Context, provider ProviderType, accessToken string) error
+		RequestClientToken(
+			ctx context.Context,
+			provider ProviderType,
+			scopes ...string,
+		) (TokenInfo, error)
+		Authenticate(
+			ctx context.Context,
+			provider ProviderType,
+			code string,
+			opts ...AuthenticateOption,
+		) (*AuthResult, error)
+		RedirectURLFor(provider ProviderType) (string, error)
+		BeginLogin(
+			ctx context.Context,
+			provider ProviderType,
+			opts ...AuthOption,
+		) (string, *http.Cookie, error)
+		BeginLink(
+			ctx context.Context,
+			provider ProviderType,
+			opts ...AuthOption,
+		) (string, *http.Cookie, error)
+		RegisterProvider(provider Provider)
+		UnregisterProvider(provider ProviderType)
+		Providers() []ProviderType
+		LastError(provider ProviderType) (time.Time, error)
+		RecentErrors(provider ProviderType) []ErrorRecord
+		WithContextDefaults(ctx context.Context) Client
 	}
 
 	// Provider defines the behavior that all OAuth2 providers must implement
 	Provider interface {
 		GetUserInfo(ctx context.Context, accessToken string) (UserInfo, error)
-		GetAuthURL(ctx context.Context, state string) (string, error)
-		GetToken(ctx context.Context, code string) (TokenInfo, error)
+		GetAuthURL(ctx context.Context, state string, opts ...AuthOption) (string, error)
+		GetToken(ctx context.Context, code string, opts ...AuthOption) (TokenInfo, error)
 		GetProvider() ProviderType
+		GetRedirectURL() string
+
+		// RefreshToken exchanges refreshToken for a new access token. GetRefreshToken of the result
+		// returns the rotated refresh token when the provider issued one and refreshToken otherwise,
+		// so callers can always persist it
 		RefreshToken(ctx context.Context, refreshToken string) (TokenInfo, error)
+		RevokeToken(ctx context.Context, token string) error
+		CanRefresh(token TokenInfo) bool
+		SigningKeys(ctx context.Context) ([]PublicKeyInfo, error)
+
+		// GetClientCredentialsToken requests an app-level token with the client credentials grant,
+		// providers without it fail with ErrGrantNotSupported
+		GetClientCredentialsToken(ctx context.Context, scopes ...string) (TokenInfo, error)
+
+		// Unlink disconnects the user from the app on the provider side, which also revokes the
+		// app's tokens. Providers without it fail with ErrUnsupportedOperation
+		Unlink(ctx context.Context, accessToken string) error
 	}
 
 	// UserInfo defines the required fields retrieved from the OAuth2 provider
@@ -37,14 +115,108 @@ type (
 		GetEmail() string
 		GetName() string
 		GetGender() string
+
+		// GetGenderNormalized maps GetGender to a Gender shared by all providers,
+		// GenderUnknown when the provider has none or the value is not recognized
+		GetGenderNormalized() Gender
 		GetProfileImage() string
+
+		// GetPhoneNumber returns the phone number in the provider's format, empty when the
+		// provider has none or the user did not consent to share it
+		GetPhoneNumber() string
+
+		// GetBirthday returns the birthday as YYYY-MM-DD, or MM-DD when the birth year is unknown,
+		// empty when the provider has none or the user did not consent to share it
+		GetBirthday() string
+
+		// GetLocale returns the user's preferred locale (e.g. "en-US"), empty when the provider has none
+		GetLocale() string
+
+		// IsEmailVerified reports whether the provider asserts that the user owns GetEmail,
+		// false when it does not say. Only trust an email for account linking when it is true
+		IsEmailVerified() bool
+
+		// GetRaw returns the decoded provider response (or id_token claims) the user was built from,
+		// for provider-specific fields without a getter. It is nil when the user has no raw source
+		GetRaw() map[string]any
+	}
+
+	// NumericIDUser is implemented by users of providers with numeric IDs (e.g. Kakao)
+	NumericIDUser interface {
+		GetNumericID() (int64, bool)
 	}
 
 	// TokenInfo defines the token information returned from the provider
 	TokenInfo interface {
 		GetAccessToken() string
 		GetRefreshToken() string
+
+		// GetExpiry returns expires_in as sent by the provider, in seconds from when the token was
+		// issued. It is kept for compatibility, GetExpiresAt needs no bookkeeping by the caller
 		GetExpiry() int
+		HasRefreshToken() bool
+
+		// GetExpiresAt returns when the access token expires, computed from the time the
+		// response was decoded plus expires_in. It is the zero time when expires_in was not sent
+		GetExpiresAt() time.Time
+
+		// IsExpired reports whether GetExpiresAt has passed, always false without an expiry
+		IsExpired() bool
+
+		// GetIDToken returns the OpenID Connect id_token, empty when the provider did not send one
+		GetIDToken() string
+
+		// GetScope returns the scope granted by the provider as sent, empty when it was not reported.
+		// GrantedScopes splits it into scopes
+		GetScope() string
+
+		// GetTokenType returns the token_type as sent by the provider (e.g. "Bearer")
+		GetTokenType() string
+	}
+
+	// ExpiringToken adds HasExpiry to the expiry methods of TokenInfo.
+	// A token issued without expires_in does not expire: HasExpiry and IsExpired report false
+	// and GetExpiresAt returns the zero time
+	ExpiringToken interface {
+		HasExpiry() bool
+		GetExpiresAt() time.Time
+		IsExpired() bool
+	}
+
+	// RefreshExpiringToken is implemented by tokens whose refresh token lifetime is known.
+	// A zero time means the provider did not report one
+	RefreshExpiringToken interface {
+		GetRefreshTokenExpiresAt() time.Time
+	}
+
+	// ScopedToken is kept for compatibility, every TokenInfo now has GetScope
+	ScopedToken interface {
+		GetScope() string
+	}
+
+	// IDTokenCarrier is kept for compatibility, every TokenInfo now has GetIDToken
+	IDTokenCarrier interface {
+		GetIDToken() string
+	}
+
+	// IDTokenProvider is implemented by providers that can build UserInfo from the id_token
+	// returned with the token, without an extra HTTP call
+	IDTokenProvider interface {
+		UserInfoFromIDToken(token TokenInfo) (UserInfo, error)
+	}
+
+	// FormPostProvider is implemented by providers redirecting back with response_mode=form_post.
+	// Their state cookie is issued SameSite=None; Secure so the cross-site POST still carries it
+	FormPostProvider interface {
+		UsesFormPost() bool
+	}
+
+	// AuthResult holds everything obtained from a completed authorization code login
+	AuthResult struct {
+		Provider      ProviderType
+		Token         TokenInfo
+		User          UserInfo
+		GrantedScopes []string
 	}
 
 	// ProviderType is a named string for the provider key (e.g. "google", "kakao")
@@ -52,15 +224,84 @@ type (
 
 	// ProviderSetting is used to initialize a provider with required values
 	ProviderSetting struct {
+		// Client sends the provider requests, nil uses http.DefaultTransport with DefaultClientTimeout.
+		// Its Transport may be any http.RoundTripper decorating the requests, see WithUserAgent and WithHeader
 		Client       *http.Client
 		ClientID     string
 		ClientSecret string
 		RedirectURL  string
+
+		// FollowRedirects lets provider calls follow 3xx responses, by default a redirect from a
+		// token/userinfo endpoint (e.g. to an HTML login page) fails with ErrUnexpectedRedirect
+		FollowRedirects bool
+
+		// StrictTokenType rejects token responses whose token_type is not Bearer with
+		// ErrUnsupportedTokenType, by default any token_type is accepted
+		StrictTokenType bool
+
+		// NameStrategy selects the field returned by UserInfo.GetName (default PreferRealName)
+		NameStrategy NameStrategy
+
+		// AuthURL and TokenURL override the provider's authorization and token endpoints,
+		// e.g. to point it at a mock server in integration tests
+		AuthURL  string
+		TokenURL string
+
+		// UserInfoURL overrides the provider's userinfo endpoint
+		UserInfoURL string
+
+		// UserInfoFallbackURLs are tried in order when the userinfo endpoint fails to connect
+		// or answers with a 5xx, failover is disabled when empty
+		UserInfoFallbackURLs []string
+
+		// AuthURLLimits bounds the generated authorization URL, GetAuthURL fails with
+		// ErrAuthURLTooLong instead of redirecting to a URL the provider would reject
+		AuthURLLimits AuthURLLimits
+
+		// RevocationMethod overrides the HTTP method used by RevokeToken (e.g. POST for Naver),
+		// parameters are sent as the query for GET and as a form body otherwise
+		RevocationMethod string
+
+		// Scopes replace the provider's default scopes in every authorization URL,
+		// WithScopes adds to them per request (e.g. Kakao's account_email)
+		Scopes []string
+
+		// Logger receives the warnings of Validate, nil disables them
+		Logger *slog.Logger
+
+		// MaxRetries retries requests failing with a network error or 429/500/502/503/504,
+		// waiting RetryBackoff (default DefaultRetryBackoff) doubled per attempt or the
+		// provider's Retry-After. Retries are disabled when 0, authorization code exchanges are never
+		// retried since a code can only be redeemed once
+		MaxRetries   int
+		RetryBackoff time.Duration
+
+		// MaxConcurrentRequests caps the in-flight HTTP calls of the provider across all of its
+		// operations, further calls wait for a slot or their context. Unlimited when 0
+		MaxConcurrentRequests int
+
+		// RequestTimeout bounds every provider call, retries and waiting for a slot included,
+		// unless the caller's context has an earlier deadline. Disabled when 0
+		RequestTimeout time.Duration
+
+		// MaxResponseBytes caps the response body read from the provider, a longer body fails with
+		// ErrResponseTooLarge instead of exhausting memory. DefaultMaxResponseBytes when 0, unlimited when negative
+		MaxResponseBytes int64
+
+		// RateLimit paces every HTTP call of the provider, blocking until allowed or the context
+		// is done. Unlike WithRateLimit it also covers retries and JWKS fetches. Unlimited when nil
+		RateLimit *RateLimitConfig
+
+		// Observer is notified around every token endpoint call (code exchange, refresh, client
+		// credentials, device flow) and userinfo call, e.g. to emit metrics and traces. Disabled when nil
+		Observer Observer
 	}
 
 	// oauth2Client holds the registered providers
 	oauth2Client struct {
+		mu        sync.RWMutex
 		providers map[ProviderType]Provider
+		errors    *errorLog
 	}
 )
 
@@ -90,50 +331,141 @@ func NewClient(providers ...Provider) Client {
 	for _, provider := range providers {
 		oauthClient.providers[provider.GetProvider()] = provider
 	}
+	oauthClient.errors = newErrorLog(oauthClient.providers)
 
 	return oauthClient
 }
 
+// RegisterProvider adds provider at runtime, replacing the provider of the same type.
+// It is safe to call while other goroutines use the client
+//
+//	example:
+//	client.RegisterProvider(naver.NewProvider(setting))
+func (c *oauth2Client) RegisterProvider(provider Provider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.providers[provider.GetProvider()] = provider
+	c.errors.add(provider.GetProvider())
+}
+
+// UnregisterProvider removes the provider and its recorded errors, later calls for it
+// fail with ErrProviderNotSet. Unknown providers are ignored
+func (c *oauth2Client) UnregisterProvider(provider ProviderType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.providers, provider)
+	c.errors.remove(provider)
+}
+
+// Providers returns the registered provider types in sorted order,
+// e.g. to render the buttons of a login page
+func (c *oauth2Client) Providers() []ProviderType {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(c.providers))
+}
+
+// lookup returns the registered provider of the given type
+func (c *oauth2Client) lookup(provider ProviderType) (Provider, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	oauthProvider, ok := c.providers[provider]
+	return oauthProvider, ok
+}
+
 // RequestUserInfo retrieves user information using the given access token
+//   - an empty access token fails with ErrEmptyAccessToken without calling the provider
 func (c *oauth2Client) RequestUserInfo(
 	ctx context.Context,
 	provider ProviderType,
 	accessToken string,
 ) (UserInfo, error) {
-	if oauthProvider, ok := c.providers[provider]; ok {
-		return oauthProvider.GetUserInfo(ctx, accessToken)
+	if oauthProvider, ok := c.lookup(provider); ok {
+		if accessToken == "" {
+			return nil, c.errors.record(
+				provider,
+				WrapProviderError(provider, ErrEmptyAccessToken, ""),
+			)
+		}
+		user, err := oauthProvider.GetUserInfo(ctx, accessToken)
+		return user, c.errors.record(provider, err)
 	}
 
 	return nil, ErrProviderNotSet
 }
 
-// RequestAuthURL generates the provider's authorization URL for user redirection
+// RequestUserInfoWithToken retrieves user information using a stored TokenInfo.
+// It does not refresh the token itself, since the rotated refresh token would be lost to the caller
+func (c *oauth2Client) RequestUserInfoWithToken(
+	ctx context.Context,
+	provider ProviderType,
+	token TokenInfo,
+) (UserInfo, error) {
+	if token == nil {
+		return nil, c.errors.record(
+			provider,
+			WrapProviderError(provider, ErrUserInfoRequestFailed, "token is nil"),
+		)
+	}
+
+	return c.RequestUserInfo(ctx, provider, token.GetAccessToken())
+}
+
+// RequestAuthURL generates the provider's authorization URL for user redirection,
+// empty on failure. Use RequestAuthURLWithError to find out why
 func (c *oauth2Client) RequestAuthURL(
 	ctx context.Context,
 	provider ProviderType,
 	state string,
+	opts ...AuthOption,
 ) string {
-	if oauthProvider, ok := c.providers[provider]; ok {
-		authURL, err := oauthProvider.GetAuthURL(ctx, state)
+	authURL, _ := c.RequestAuthURLWithError(ctx, provider, state, opts...)
+	return authURL
+}
+
+// RequestAuthURLWithError generates the provider's authorization URL like RequestAuthURL,
+// returning ErrProviderNotSet for an unregistered provider or the provider's error
+// (e.g. ErrRedirectURLNotSet)
+//
+//	example:
+//	authURL, err := client.RequestAuthURLWithError(ctx, google.ProviderType, state)
+//	if errors.Is(err, oauth2.ErrProviderNotSet) {
+//	    http.Error(w, "unknown provider", http.StatusNotFound)
+//	    return
+//	}
+func (c *oauth2Client) RequestAuthURLWithError(
+	ctx context.Context,
+	provider ProviderType,
+	state string,
+	opts ...AuthOption,
+) (string, error) {
+	if oauthProvider, ok := c.lookup(provider); ok {
+		authURL, err := oauthProvider.GetAuthURL(ctx, state, opts...)
 		if err != nil {
-			return ""
+			return "", c.errors.record(provider, err)
 		}
-		return authURL
+		return authURL, nil
 	}
 
-	return ""
+	return "", ErrProviderNotSet
 }
 
 // RequestToken exchanges the authorization code for an access token
+//   - WithPKCE sends the code_verifier matching the challenge of the authorization request
 func (c *oauth2Client) RequestToken(
 	ctx context.Context,
 	provider ProviderType,
 	code string,
+	opts ...AuthOption,
 ) (TokenInfo, error) {
-	if oauthProvider, ok := c.providers[provider]; ok {
-		token, err := oauthProvider.GetToken(ctx, code)
+	if oauthProvider, ok := c.lookup(provider); ok {
+		token, err := oauthProvider.GetToken(ctx, code, opts...)
 		if err != nil {
-			return nil, err
+			return nil, c.errors.record(provider, err)
 		}
 		return token, nil
 	}
@@ -141,19 +473,222 @@ func (c *oauth2Client) RequestToken(
 	return nil, ErrProviderNotSet
 }
 
-// RequestRefreshToken refreshes the access token using the refresh token
+// RequestRefreshToken refreshes the access token using the refresh token.
+// Persist GetRefreshToken of the result, it is the rotated refresh token or the one passed in
 func (c *oauth2Client) RequestRefreshToken(
 	ctx context.Context,
 	provider ProviderType,
 	refreshToken string,
 ) (TokenInfo, error) {
-	if oauthProvider, ok := c.providers[provider]; ok {
+	if oauthProvider, ok := c.lookup(provider); ok {
 		token, err := oauthProvider.RefreshToken(ctx, refreshToken)
 		if err != nil {
-			return nil, err
+			return nil, c.errors.record(provider, err)
+		}
+		return token, nil
+	}
+
+	return nil, ErrProviderNotSet
+}
+
+// RequestClientToken requests an app-level token for server-to-server calls with the
+// client credentials grant. Providers without the grant fail with ErrGrantNotSupported
+func (c *oauth2Client) RequestClientToken(
+	ctx context.Context,
+	provider ProviderType,
+	scopes ...string,
+) (TokenInfo, error) {
+	if oauthProvider, ok := c.lookup(provider); ok {
+		token, err := oauthProvider.GetClientCredentialsToken(ctx, scopes...)
+		if err != nil {
+			return nil, c.errors.record(provider, err)
 		}
 		return token, nil
 	}
 
 	return nil, ErrProviderNotSet
 }
+
+// RequestRevokeToken revokes the access or refresh token with the provider, e.g. on logout.
+// Failures wrap ErrTokenRevocationFailed
+func (c *oauth2Client) RequestRevokeToken(
+	ctx context.Context,
+	provider ProviderType,
+	token string,
+) error {
+	if oauthProvider, ok := c.lookup(provider); ok {
+		return c.errors.record(provider, oauthProvider.RevokeToken(ctx, token))
+	}
+
+	return ErrProviderNotSet
+}
+
+// RequestUnlink disconnects the user of the access token from the app, e.g. on account deletion.
+// Failures wrap ErrUnlinkFailed, providers without unlink fail with ErrUnsupportedOperation
+func (c *oauth2Client) RequestUnlink(
+	ctx context.Context,
+	provider ProviderType,
+	accessToken string,
+) error {
+	if oauthProvider, ok := c.lookup(provider); ok {
+		return c.errors.record(provider, oauthProvider.Unlink(ctx, accessToken))
+	}
+
+	return ErrProviderNotSet
+}
+
+// Authenticate exchanges the authorization code and fetches the user info in one call.
+// GrantedScopes reflects the scope returned on the token, so the UI can show exactly what was granted
+//   - WithIDTokenOnly builds the user from the id_token claims instead of the userinfo endpoint
+//   - WithPKCEVerifier sends the PKCE code_verifier with the code exchange
+//   - WithUserInfoRequirements fails with ErrIncompleteProfile when a required field is empty
+func (c *oauth2Client) Authenticate(
+	ctx context.Context,
+	provider ProviderType,
+	code string,
+	opts ...AuthenticateOption,
+) (*AuthResult, error) {
+	options := NewAuthenticateOptions(opts...)
+
+	oauthProvider, ok := c.lookup(provider)
+	if !ok {
+		return nil, ErrProviderNotSet
+	}
+	idTokenProvider, canUseIDToken := oauthProvider.(IDTokenProvider)
+	if options.IDTokenOnly && !canUseIDToken {
+		return nil, c.errors.record(provider, WrapProviderError(
+			provider,
+			ErrUnsupportedOperation,
+			"id_token only authentication",
+		))
+	}
+
+	token, err := c.RequestToken(ctx, provider, code, WithPKCE(options.CodeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	var user UserInfo
+	if options.IDTokenOnly {
+		user, err = idTokenProvider.UserInfoFromIDToken(token)
+		c.errors.record(provider, err)
+	} else {
+		user, err = c.RequestUserInfo(ctx, provider, token.GetAccessToken())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if missing := options.Requirements.Missing(user); len(missing) > 0 {
+		return nil, c.errors.record(provider, WrapProviderError(
+			provider,
+			ErrIncompleteProfile,
+			strings.Join(missing, ", "),
+		))
+	}
+
+	return &AuthResult{
+		Provider:      provider,
+		Token:         token,
+		User:          user,
+		GrantedScopes: GrantedScopes(token),
+	}, nil
+}
+
+// RedirectURLFor returns the redirect URL configured for the provider,
+// so login and callback routing can be derived from the same configuration
+func (c *oauth2Client) RedirectURLFor(provider ProviderType) (string, error) {
+	if oauthProvider, ok := c.lookup(provider); ok {
+		return oauthProvider.GetRedirectURL(), nil
+	}
+
+	return "", ErrProviderNotSet
+}
+
+// BeginLogin generates a secure state, builds the auth URL and returns the state cookie to set.
+// The cookie is verified by NewCallbackHandler when the provider redirects back
+//
+//	example:
+//	authURL, cookie, err := client.BeginLogin(r.Context(), google.ProviderType)
+//	if err != nil { ... }
+//	http.SetCookie(w, cookie)
+//	http.Redirect(w, r, authURL, http.StatusFound)
+func (c *oauth2Client) BeginLogin(
+	ctx context.Context,
+	provider ProviderType,
+	opts ...AuthOption,
+) (string, *http.Cookie, error) {
+	return c.begin(ctx, provider, GenerateState, opts...)
+}
+
+// BeginLink is BeginLogin for linking another provider to an already signed-in user.
+// The state carries the link intent so the callback can route it to LinkAccount
+// instead of replacing the current session
+func (c *oauth2Client) BeginLink(
+	ctx context.Context,
+	provider ProviderType,
+	opts ...AuthOption,
+) (string, *http.Cookie, error) {
+	return c.begin(ctx, provider, GenerateLinkState, opts...)
+}
+
+// begin builds the auth URL and state cookie with a state from generate
+func (c *oauth2Client) begin(
+	ctx context.Context,
+	provider ProviderType,
+	generate func() (string, error),
+	opts ...AuthOption,
+) (string, *http.Cookie, error) {
+	oauthProvider, ok := c.lookup(provider)
+	if !ok {
+		return "", nil, ErrProviderNotSet
+	}
+
+	state, err := generate()
+	if err != nil {
+		return "", nil, err
+	}
+
+	authURL, err := oauthProvider.GetAuthURL(ctx, state, opts...)
+	if err != nil {
+		return "", nil, c.errors.record(provider, err)
+	}
+
+	cookie := newStateCookie(state, oauthProvider.GetRedirectURL())
+	if usesFormPost(oauthProvider) {
+		crossSiteCookie(cookie)
+	}
+	return authURL, cookie, nil
+}
+
+// usesFormPost reports whether provider implements FormPostProvider and posts its callback
+func usesFormPost(provider Provider) bool {
+	formPost, ok := provider.(FormPostProvider)
+	return ok && formPost.UsesFormPost()
+}
+
+// LastError returns when the most recent error recorded for the provider happened and the error,
+// the zero time and nil when none was recorded
+func (c *oauth2Client) LastError(provider ProviderType) (time.Time, error) {
+	record, ok := c.errors.last(provider)
+	if !ok {
+		return time.Time{}, nil
+	}
+	return record.Time, record.Err
+}
+
+// WithContextDefaults returns a client whose calls also end when ctx is cancelled or its deadline
+// passes, so every OAuth call made while serving a request shares that request's deadline
+//
+//	example:
+//	reqClient := client.WithContextDefaults(r.Context())
+//	result, err := reqClient.Authenticate(context.Background(), google.ProviderType, code)
+func (c *oauth2Client) WithContextDefaults(ctx context.Context) Client {
+	return &contextClient{Client: c, defaults: ctx}
+}
+
+// RecentErrors returns the last errors recorded for the provider, newest first.
+// Only a bounded number of errors is kept per registered provider
+func (c *oauth2Client) RecentErrors(provider ProviderType) []ErrorRecord {
+	return c.errors.recent(provider)
+}
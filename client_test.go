@@ -3,9 +3,14 @@ package oauth2_test
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/url"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,38 +22,147 @@ type mockProvider struct {
 	errToken       error
 	authURL        string
 	authErr        error
+	redirectURL    string
 	typ            oauth2.ProviderType
+	gotAccessToken string
+	errRevoke      error
+	revokedToken   string
+	gotScopes      []string
+	errUnlink      error
+	unlinkedToken  string
+	gotTokenOpts   oauth2.AuthOptions
+	mu             sync.Mutex
 }
 
 func (m *mockProvider) GetUserInfo(ctx context.Context, token string) (oauth2.UserInfo, error) {
+	m.gotAccessToken = token
 	return m.returnUserInfo, m.errUserInfo
 }
 
-func (m *mockProvider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+func (m *mockProvider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gotTokenOpts = oauth2.NewAuthOptions(opts...)
 	return m.returnToken, m.errToken
 }
 
-func (m *mockProvider) GetAuthURL(ctx context.Context, state string) (string, error) {
+func (m *mockProvider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
 	return m.authURL, m.authErr
 }
 
+func (m *mockProvider) RevokeToken(ctx context.Context, token string) error {
+	m.revokedToken = token
+	return m.errRevoke
+}
+
+func (m *mockProvider) GetClientCredentialsToken(
+	ctx context.Context,
+	scopes ...string,
+) (oauth2.TokenInfo, error) {
+	m.gotScopes = scopes
+	return m.returnToken, m.errToken
+}
+
+func (m *mockProvider) Unlink(ctx context.Context, accessToken string) error {
+	m.unlinkedToken = accessToken
+	return m.errUnlink
+}
+
 func (m *mockProvider) GetProvider() oauth2.ProviderType {
 	return m.typ
 }
 
+func (m *mockProvider) GetRedirectURL() string {
+	return m.redirectURL
+}
+
 type dummyUser struct{}
 
-func (d dummyUser) GetID() string           { return "id" }
-func (d dummyUser) GetEmail() string        { return "email" }
-func (d dummyUser) GetName() string         { return "name" }
-func (d dummyUser) GetGender() string       { return "gender" }
-func (d dummyUser) GetProfileImage() string { return "image" }
+func (d dummyUser) GetID() string                      { return "id" }
+func (d dummyUser) GetEmail() string                   { return "email" }
+func (d dummyUser) GetName() string                    { return "name" }
+func (d dummyUser) GetGender() string                  { return "gender" }
+func (d dummyUser) GetGenderNormalized() oauth2.Gender { return oauth2.GenderUnknown }
+func (d dummyUser) GetProfileImage() string            { return "image" }
+func (d dummyUser) GetPhoneNumber() string             { return "" }
+func (d dummyUser) GetBirthday() string                { return "" }
+func (d dummyUser) GetLocale() string                  { return "" }
+func (d dummyUser) IsEmailVerified() bool              { return false }
+func (d dummyUser) GetRaw() map[string]any             { return nil }
 
 type dummyToken struct{}
 
 func (d dummyToken) GetAccessToken() string  { return "access-token" }
 func (d dummyToken) GetRefreshToken() string { return "refresh-token" }
 func (d dummyToken) GetExpiry() int          { return 3600 }
+func (d dummyToken) HasRefreshToken() bool   { return true }
+func (d dummyToken) GetExpiresAt() time.Time { return time.Time{} }
+func (d dummyToken) IsExpired() bool         { return false }
+func (d dummyToken) GetIDToken() string      { return "" }
+func (d dummyToken) GetScope() string        { return "" }
+func (d dummyToken) GetTokenType() string    { return "Bearer" }
+
+type scopedToken struct {
+	dummyToken
+	scope string
+}
+
+func (s scopedToken) GetScope() string { return s.scope }
+
+func TestOAuth2Client_Authenticate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("propagates granted scopes", func(t *testing.T) {
+		client := oauth2.NewClient(&mockProvider{
+			typ:            "google",
+			returnToken:    scopedToken{scope: "openid email profile"},
+			returnUserInfo: dummyUser{},
+		})
+
+		result, err := client.Authenticate(ctx, "google", "code")
+		assert.NoError(t, err)
+		assert.Equal(t, oauth2.ProviderType("google"), result.Provider)
+		assert.Equal(t, "id", result.User.GetID())
+		assert.Equal(t, "access-token", result.Token.GetAccessToken())
+		assert.Equal(t, []string{"openid", "email", "profile"}, result.GrantedScopes)
+	})
+
+	t.Run("token without scope", func(t *testing.T) {
+		client := oauth2.NewClient(&mockProvider{
+			typ:            "naver",
+			returnToken:    dummyToken{},
+			returnUserInfo: dummyUser{},
+		})
+
+		result, err := client.Authenticate(ctx, "naver", "code")
+		assert.NoError(t, err)
+		assert.Nil(t, result.GrantedScopes)
+	})
+
+	t.Run("user info failure", func(t *testing.T) {
+		client := oauth2.NewClient(&mockProvider{
+			typ:         "kakao",
+			returnToken: dummyToken{},
+			errUserInfo: oauth2.ErrUserInfoRequestFailed,
+		})
+
+		_, err := client.Authenticate(ctx, "kakao", "code")
+		assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+	})
+
+	t.Run("provider not set", func(t *testing.T) {
+		_, err := oauth2.NewClient().Authenticate(ctx, "google", "code")
+		assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+	})
+}
 
 func TestOAuth2Client_RequestUserInfo(t *testing.T) {
 	client := oauth2.NewClient(&mockProvider{
@@ -65,6 +179,35 @@ func TestOAuth2Client_RequestUserInfo(t *testing.T) {
 	assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
 }
 
+func TestOAuth2Client_RequestUserInfoEmptyToken(t *testing.T) {
+	provider := &mockProvider{typ: "google", returnUserInfo: dummyUser{}}
+	client := oauth2.NewClient(provider)
+
+	_, err := client.RequestUserInfo(context.Background(), "google", "")
+	assert.ErrorIs(t, err, oauth2.ErrEmptyAccessToken)
+	assert.Empty(t, provider.gotAccessToken, "provider must not be called")
+
+	_, lastErr := client.LastError("google")
+	assert.ErrorIs(t, lastErr, oauth2.ErrEmptyAccessToken)
+}
+
+func TestOAuth2Client_RequestUserInfoWithToken(t *testing.T) {
+	provider := &mockProvider{typ: "google", returnUserInfo: dummyUser{}}
+	client := oauth2.NewClient(provider)
+	ctx := context.Background()
+
+	user, err := client.RequestUserInfoWithToken(ctx, "google", dummyToken{})
+	assert.NoError(t, err)
+	assert.Equal(t, "id", user.GetID())
+	assert.Equal(t, "access-token", provider.gotAccessToken)
+
+	_, err = client.RequestUserInfoWithToken(ctx, "google", nil)
+	assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+
+	_, err = client.RequestUserInfoWithToken(ctx, "kakao", dummyToken{})
+	assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+}
+
 func TestOAuth2Client_RequestAccessToken(t *testing.T) {
 	client := oauth2.NewClient(&mockProvider{
 		typ:         "kakao",
@@ -80,6 +223,63 @@ func TestOAuth2Client_RequestAccessToken(t *testing.T) {
 	assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
 }
 
+func TestOAuth2Client_RequestRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	provider := &mockProvider{typ: "kakao"}
+	client := oauth2.NewClient(provider)
+
+	assert.NoError(t, client.RequestRevokeToken(ctx, "kakao", "access-token"))
+	assert.Equal(t, "access-token", provider.revokedToken)
+
+	provider.errRevoke = oauth2.ErrTokenRevocationFailed
+	err := client.RequestRevokeToken(ctx, "kakao", "access-token")
+	assert.ErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+
+	_, lastErr := client.LastError("kakao")
+	assert.ErrorIs(t, lastErr, oauth2.ErrTokenRevocationFailed)
+
+	assert.ErrorIs(t, client.RequestRevokeToken(ctx, "naver", "token"), oauth2.ErrProviderNotSet)
+}
+
+func TestOAuth2Client_RequestUnlink(t *testing.T) {
+	ctx := context.Background()
+	provider := &mockProvider{typ: "kakao"}
+	client := oauth2.NewClient(provider)
+
+	assert.NoError(t, client.RequestUnlink(ctx, "kakao", "access-token"))
+	assert.Equal(t, "access-token", provider.unlinkedToken)
+
+	provider.errUnlink = oauth2.ErrUnsupportedOperation
+	err := client.RequestUnlink(ctx, "kakao", "access-token")
+	assert.ErrorIs(t, err, oauth2.ErrUnsupportedOperation)
+
+	_, lastErr := client.LastError("kakao")
+	assert.ErrorIs(t, lastErr, oauth2.ErrUnsupportedOperation)
+
+	assert.ErrorIs(t, client.RequestUnlink(ctx, "naver", "token"), oauth2.ErrProviderNotSet)
+}
+
+func TestOAuth2Client_RequestClientToken(t *testing.T) {
+	ctx := context.Background()
+	provider := &mockProvider{typ: "kakao", returnToken: dummyToken{}}
+	client := oauth2.NewClient(provider)
+
+	token, err := client.RequestClientToken(ctx, "kakao", "talk_message")
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token", token.GetAccessToken())
+	assert.Equal(t, []string{"talk_message"}, provider.gotScopes)
+
+	provider.errToken = oauth2.ErrGrantNotSupported
+	_, err = client.RequestClientToken(ctx, "kakao")
+	assert.ErrorIs(t, err, oauth2.ErrGrantNotSupported)
+
+	_, lastErr := client.LastError("kakao")
+	assert.ErrorIs(t, lastErr, oauth2.ErrGrantNotSupported)
+
+	_, err = client.RequestClientToken(ctx, "naver")
+	assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+}
+
 func TestOAuth2Client_RequestAuthURL(t *testing.T) {
 	client := oauth2.NewClient(&mockProvider{
 		typ:     "naver",
@@ -99,3 +299,185 @@ func TestOAuth2Client_RequestAuthURL(t *testing.T) {
 	})
 	assert.Empty(t, clientWithError.RequestAuthURL(ctx, "google", "state"))
 }
+
+func TestOAuth2Client_RequestAuthURLWithError(t *testing.T) {
+	ctx := context.Background()
+	client := oauth2.NewClient(
+		&mockProvider{typ: "naver", authURL: "http://naver.com/auth"},
+		google.NewProvider(oauth2.ProviderSetting{ClientID: "client-id"}),
+	)
+
+	authURL, err := client.RequestAuthURLWithError(ctx, "naver", "state")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://naver.com/auth", authURL)
+
+	authURL, err = client.RequestAuthURLWithError(ctx, "kakao", "state")
+	assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+	assert.Empty(t, authURL)
+
+	authURL, err = client.RequestAuthURLWithError(ctx, google.ProviderType, "state")
+	assert.ErrorIs(t, err, oauth2.ErrRedirectURLNotSet)
+	assert.Empty(t, authURL)
+
+	_, lastErr := client.LastError(google.ProviderType)
+	assert.ErrorIs(t, lastErr, oauth2.ErrRedirectURLNotSet)
+}
+
+func TestOAuth2Client_RedirectURLFor(t *testing.T) {
+	client := oauth2.NewClient(
+		&mockProvider{typ: "google", redirectURL: "https://app.example.com/callback/google"},
+		&mockProvider{typ: "kakao", redirectURL: "https://app.example.com/callback/kakao"},
+	)
+
+	redirectURL, err := client.RedirectURLFor("google")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.example.com/callback/google", redirectURL)
+
+	redirectURL, err = client.RedirectURLFor("kakao")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.example.com/callback/kakao", redirectURL)
+
+	_, err = client.RedirectURLFor("naver")
+	assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+}
+
+func TestOAuth2Client_BeginLogin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("cookie matches state in auth URL", func(t *testing.T) {
+		client := oauth2.NewClient(google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "https://app.example.com/callback",
+		}))
+
+		authURL, cookie, err := client.BeginLogin(ctx, google.ProviderType)
+		assert.NoError(t, err)
+
+		parsedURL, err := url.Parse(authURL)
+		assert.NoError(t, err)
+		state := parsedURL.Query().Get("state")
+
+		assert.NotEmpty(t, state)
+		assert.Equal(t, state, cookie.Value)
+		assert.Equal(t, oauth2.StateCookieName, cookie.Name)
+		assert.True(t, cookie.HttpOnly)
+		assert.True(t, cookie.Secure)
+		assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+		assert.Positive(t, cookie.MaxAge)
+	})
+
+	t.Run("state is unique per login", func(t *testing.T) {
+		client := oauth2.NewClient(google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		}))
+
+		_, first, err := client.BeginLogin(ctx, google.ProviderType)
+		assert.NoError(t, err)
+		_, second, err := client.BeginLogin(ctx, google.ProviderType)
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, first.Value, second.Value)
+		assert.False(t, first.Secure)
+	})
+
+	t.Run("provider not set", func(t *testing.T) {
+		_, _, err := oauth2.NewClient().BeginLogin(ctx, "google")
+		assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+	})
+
+	t.Run("auth URL error", func(t *testing.T) {
+		client := oauth2.NewClient(&mockProvider{typ: "kakao", authErr: oauth2.ErrRedirectURLNotSet})
+
+		_, cookie, err := client.BeginLogin(ctx, "kakao")
+		assert.ErrorIs(t, err, oauth2.ErrRedirectURLNotSet)
+		assert.Nil(t, cookie)
+	})
+}
+
+func TestOAuth2Client_AuthenticateIDTokenOnlyUnsupported(t *testing.T) {
+	provider := &mockProvider{typ: "kakao", returnToken: dummyToken{}}
+	client := oauth2.NewClient(provider)
+
+	_, err := client.Authenticate(context.Background(), "kakao", "code", oauth2.WithIDTokenOnly())
+	assert.ErrorIs(t, err, oauth2.ErrUnsupportedOperation)
+}
+
+func TestOAuth2Client_RegisterProvider(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("registers and unregisters at runtime", func(t *testing.T) {
+		client := oauth2.NewClient()
+
+		_, err := client.RedirectURLFor("kakao")
+		assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+
+		client.RegisterProvider(&mockProvider{typ: "kakao", redirectURL: "https://app.example.com/kakao"})
+		redirectURL, err := client.RedirectURLFor("kakao")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://app.example.com/kakao", redirectURL)
+
+		client.RegisterProvider(&mockProvider{typ: "kakao", redirectURL: "https://app.example.com/kakao/v2"})
+		redirectURL, err = client.RedirectURLFor("kakao")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://app.example.com/kakao/v2", redirectURL)
+
+		client.UnregisterProvider("kakao")
+		_, err = client.RedirectURLFor("kakao")
+		assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+	})
+
+	t.Run("records errors of registered providers", func(t *testing.T) {
+		client := oauth2.NewClient()
+		client.RegisterProvider(&mockProvider{typ: "kakao", errRevoke: oauth2.ErrTokenRevocationFailed})
+
+		assert.Error(t, client.RequestRevokeToken(ctx, "kakao", "token"))
+		_, lastErr := client.LastError("kakao")
+		assert.ErrorIs(t, lastErr, oauth2.ErrTokenRevocationFailed)
+
+		client.UnregisterProvider("kakao")
+		assert.Empty(t, client.RecentErrors("kakao"))
+	})
+
+	t.Run("concurrent registration and reads", func(t *testing.T) {
+		client := oauth2.NewClient(&mockProvider{typ: "google", redirectURL: "https://app.example.com/google"})
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					client.RegisterProvider(&mockProvider{typ: "kakao"})
+					client.UnregisterProvider("kakao")
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					redirectURL, err := client.RedirectURLFor("google")
+					assert.NoError(t, err)
+					assert.Equal(t, "https://app.example.com/google", redirectURL)
+					_, _ = client.RedirectURLFor("kakao")
+					client.LastError(oauth2.ProviderType("kakao"))
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestOAuth2Client_Providers(t *testing.T) {
+	client := oauth2.NewClient(
+		&mockProvider{typ: "naver"},
+		&mockProvider{typ: "google"},
+		&mockProvider{typ: "kakao"},
+	)
+	assert.Equal(t, []oauth2.ProviderType{"google", "kakao", "naver"}, client.Providers())
+
+	client.RegisterProvider(&mockProvider{typ: "apple"})
+	client.UnregisterProvider("kakao")
+	assert.Equal(t, []oauth2.ProviderType{"apple", "google", "naver"}, client.Providers())
+
+	assert.Empty(t, oauth2.NewClient().Providers())
+}
@@ -2,8 +2,7 @@ package main
 
 import (
 	"bytes"
-	"crypto/rand"
-	"encoding/hex"
+	"errors"
 	"html/template"
 	"io"
 	"log"
@@ -59,7 +58,9 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handleHome)
 	mux.HandleFunc("/login", handleLogin)
-	mux.HandleFunc("/callback", handleCallback)
+	mux.Handle("/callback", oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+		OnSuccess: renderProfile,
+	}))
 
 	log.Println("✅ Server started at: http://localhost:8080")
 	log.Fatal(http.ListenAndServe(":8080", loggingMiddleware(mux)))
@@ -72,63 +73,30 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleLogin(w http.ResponseWriter, r *http.Request) {
-
 	provider := oauth2.ProviderType(r.URL.Query().Get("provider"))
 	if provider == "" {
 		http.Error(w, "provider query param is required", http.StatusBadRequest)
 		return
 	}
 
-	state := generateRandomState()
-	setOAuthStateCookie(w, state)
-
-	authURL := client.RequestAuthURL(r.Context(), provider, state)
-	if authURL == "" {
-		http.Error(w, "failed to generate auth URL", http.StatusInternalServerError)
+	authURL, stateCookie, err := client.BeginLogin(r.Context(), provider)
+	if errors.Is(err, oauth2.ErrProviderNotSet) {
+		http.Error(w, "unknown provider: "+string(provider), http.StatusNotFound)
 		return
 	}
-
-	http.Redirect(w, r, authURL, http.StatusFound)
-}
-
-func handleCallback(w http.ResponseWriter, r *http.Request) {
-	provider := oauth2.ProviderType(r.URL.Query().Get("provider"))
-	if provider == "" {
-		http.Error(w, "provider query param is required", http.StatusBadRequest)
-		return
-	}
-
-	queryState := r.URL.Query().Get("state")
-	if queryState == "" {
-		http.Error(w, "state query param is required", http.StatusBadRequest)
-		return
-	}
-
-	cookieState, err := getOAuthStateCookie(r)
-	if err != nil || queryState != cookieState {
-		http.Error(w, "state mismatch (possible CSRF)", http.StatusForbidden)
-		return
-	}
-
-	code := r.URL.Query().Get("code")
-	if code == "" {
-		http.Error(w, "code query param is required", http.StatusBadRequest)
-		return
-	}
-
-	accessToken, err := client.RequestToken(r.Context(), provider, code)
 	if err != nil {
-		http.Error(w, "failed to get access token: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "failed to generate auth URL: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	log.Printf("[OAuth] AccessToken received: %s", accessToken)
 
-	user, err := client.RequestUserInfo(r.Context(), provider, accessToken.GetAccessToken())
-	if err != nil {
-		http.Error(w, "failed to get user info: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
+	http.SetCookie(w, stateCookie)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
 
+func renderProfile(w http.ResponseWriter, r *http.Request, result *oauth2.AuthResult) error {
+	user := result.User
+	log.Printf("[OAuth] AccessToken received: %s", result.Token.GetAccessToken())
+	log.Printf("[OAuth] Granted scopes: %v", result.GrantedScopes)
 	log.Printf(
 		"[OAuth] User info: ID=%s, Name=%s, Email=%s, Gender=%s",
 		user.GetID(),
@@ -144,36 +112,7 @@ func handleCallback(w http.ResponseWriter, r *http.Request) {
 		Picture: user.GetProfileImage(),
 	}
 
-	if err := tmpl.ExecuteTemplate(w, "profile.html", view); err != nil {
-		http.Error(w, "failed to render profile", http.StatusInternalServerError)
-	}
-}
-
-func setOAuthStateCookie(w http.ResponseWriter, state string) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "oauth_state",
-		Value:    state,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   300,
-	})
-}
-
-func getOAuthStateCookie(r *http.Request) (string, error) {
-	cookie, err := r.Cookie("oauth_state")
-	if err != nil {
-		return "", err
-	}
-	return cookie.Value, nil
-}
-
-func generateRandomState() string {
-	b := make([]byte, 16)
-	if _, err := rand.Read(b); err != nil {
-		return "fallback-state"
-	}
-	return hex.EncodeToString(b)
+	return tmpl.ExecuteTemplate(w, "profile.html", view)
 }
 
 // ─────────────────────────────────────
@@ -0,0 +1,52 @@
+package oauth2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// maxContentSnippetBytes bounds how much of an unexpected body ErrUnexpectedContentType quotes
+const maxContentSnippetBytes = 64
+
+// CheckJSONResponse fails with ErrUnexpectedContentType, quoting the first bytes of the body,
+// when resp declares a Content-Type other than JSON (e.g. the HTML error page of a proxy).
+// A missing Content-Type, a +json or JavaScript type, and a JSON object or array mislabeled
+// with another type are accepted since some providers send them
+func CheckJSONResponse(resp *Response) error {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" || isJSONContentType(contentType) {
+		return nil
+	}
+
+	trimmed := bytes.TrimSpace(resp.Body)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return nil
+	}
+
+	snippet := resp.Body[:min(len(resp.Body), maxContentSnippetBytes)]
+	return fmt.Errorf("%w: %s: %q", ErrUnexpectedContentType, contentType, snippet)
+}
+
+// DecodeJSONResponse checks resp with CheckJSONResponse and unmarshals its body into v
+func DecodeJSONResponse(resp *Response, v any) error {
+	if err := CheckJSONResponse(resp); err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Body, v)
+}
+
+// isJSONContentType reports whether contentType is application/json, a +json type or JavaScript
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/json", "text/json", "application/javascript", "text/javascript":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json")
+}
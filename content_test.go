@@ -0,0 +1,120 @@
+package oauth2_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/facebook"
+	"github.com/dings-things/oauth2/github"
+	"github.com/dings-things/oauth2/gitlab"
+	"github.com/dings-things/oauth2/google"
+	"github.com/dings-things/oauth2/kakao"
+	"github.com/dings-things/oauth2/keycloak"
+	"github.com/dings-things/oauth2/naver"
+	"github.com/dings-things/oauth2/slack"
+	"github.com/dings-things/oauth2/twitch"
+	"github.com/stretchr/testify/assert"
+)
+
+const proxyErrorPage = `<html><head><title>502 Bad Gateway</title></head><body>upstream unavailable</body></html>`
+
+func TestCheckJSONResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{name: "json", contentType: "application/json", body: `{"ok":true}`},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", body: `{"ok":true}`},
+		{name: "json suffix", contentType: "application/problem+json", body: `{"ok":true}`},
+		{name: "javascript", contentType: "text/javascript; charset=UTF-8", body: `{"ok":true}`},
+		{name: "missing", body: `{"ok":true}`},
+		{name: "mislabeled json", contentType: "text/plain", body: ` {"ok":true}`},
+		{name: "html", contentType: "text/html; charset=utf-8", body: proxyErrorPage, wantErr: true},
+		{name: "html starting with a brace", contentType: "text/html", body: `{oops}`, wantErr: true},
+		{name: "malformed", contentType: "text/html;;", body: proxyErrorPage, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &oauth2.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {tt.contentType}},
+				Body:       []byte(tt.body),
+			}
+
+			err := oauth2.CheckJSONResponse(resp)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, oauth2.ErrUnexpectedContentType)
+			assert.Contains(t, err.Error(), tt.body[:min(len(tt.body), 16)])
+			assert.NotContains(t, err.Error(), "upstream unavailable", "only the first bytes are quoted")
+		})
+	}
+}
+
+func TestDecodeJSONResponse(t *testing.T) {
+	var v struct {
+		OK bool `json:"ok"`
+	}
+	resp := &oauth2.Response{
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   []byte(`{"ok":true}`),
+	}
+	assert.NoError(t, oauth2.DecodeJSONResponse(resp, &v))
+	assert.True(t, v.OK)
+
+	resp.Header.Set("Content-Type", "text/html")
+	resp.Body = []byte(proxyErrorPage)
+	assert.ErrorIs(t, oauth2.DecodeJSONResponse(resp, &v), oauth2.ErrUnexpectedContentType)
+}
+
+func TestProviders_UnexpectedContentType(t *testing.T) {
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+			Body:       io.NopCloser(strings.NewReader(proxyErrorPage)),
+		}, nil
+	})}
+	setting := oauth2.ProviderSetting{
+		Client:       client,
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectURL:  "https://app.example.com/callback",
+	}
+	keycloakProvider, err := keycloak.NewProvider(setting, "https://sso.example.com", "main")
+	assert.NoError(t, err)
+
+	providers := []oauth2.Provider{
+		google.NewProvider(setting),
+		kakao.NewProvider(setting),
+		naver.NewProvider(setting),
+		github.NewProvider(setting),
+		gitlab.NewProvider(setting),
+		facebook.NewProvider(setting),
+		slack.NewProvider(setting),
+		twitch.NewProvider(setting),
+		keycloakProvider,
+	}
+
+	ctx := context.Background()
+	for _, provider := range providers {
+		t.Run(string(provider.GetProvider()), func(t *testing.T) {
+			_, err := provider.GetToken(ctx, "code")
+			assert.ErrorIs(t, err, oauth2.ErrUnexpectedContentType)
+			assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+
+			_, err = provider.GetUserInfo(ctx, "token")
+			assert.ErrorIs(t, err, oauth2.ErrUnexpectedContentType)
+			assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+		})
+	}
+}
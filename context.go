@@ -0,0 +1,189 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// contextClient applies the cancellation and deadline of defaults to every call of the wrapped client
+type contextClient struct {
+	Client
+	defaults context.Context
+}
+
+// merge derives a context ending when either ctx or the default context ends
+func (c *contextClient) merge(ctx context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancelCause(ctx)
+
+	cancelDeadline := context.CancelFunc(func() {})
+	deadline, hasDeadline := c.defaults.Deadline()
+	if hasDeadline {
+		merged, cancelDeadline = context.WithDeadline(merged, deadline)
+	}
+
+	// an expired deadline is reported by the merged deadline itself as DeadlineExceeded
+	stop := context.AfterFunc(c.defaults, func() {
+		if !hasDeadline || !errors.Is(c.defaults.Err(), context.DeadlineExceeded) {
+			cancel(context.Cause(c.defaults))
+		}
+	})
+
+	return merged, func() {
+		stop()
+		cancelDeadline()
+		cancel(context.Canceled)
+	}
+}
+
+// RequestUserInfo retrieves user information within the default context
+func (c *contextClient) RequestUserInfo(
+	ctx context.Context,
+	provider ProviderType,
+	accessToken string,
+) (UserInfo, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestUserInfo(ctx, provider, accessToken)
+}
+
+// RequestUserInfoWithToken retrieves user information within the default context
+func (c *contextClient) RequestUserInfoWithToken(
+	ctx context.Context,
+	provider ProviderType,
+	token TokenInfo,
+) (UserInfo, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestUserInfoWithToken(ctx, provider, token)
+}
+
+// RequestUserInfoBatch retrieves the users of the access tokens within the default context
+func (c *contextClient) RequestUserInfoBatch(
+	ctx context.Context,
+	provider ProviderType,
+	accessTokens []string,
+) ([]UserInfoResult, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestUserInfoBatch(ctx, provider, accessTokens)
+}
+
+// RequestAuthURL generates the authorization URL within the default context
+func (c *contextClient) RequestAuthURL(
+	ctx context.Context,
+	provider ProviderType,
+	state string,
+	opts ...AuthOption,
+) string {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestAuthURL(ctx, provider, state, opts...)
+}
+
+// RequestAuthURLWithError generates the authorization URL within the default context
+func (c *contextClient) RequestAuthURLWithError(
+	ctx context.Context,
+	provider ProviderType,
+	state string,
+	opts ...AuthOption,
+) (string, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestAuthURLWithError(ctx, provider, state, opts...)
+}
+
+// RequestToken exchanges the authorization code within the default context
+func (c *contextClient) RequestToken(
+	ctx context.Context,
+	provider ProviderType,
+	code string,
+	opts ...AuthOption,
+) (TokenInfo, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestToken(ctx, provider, code, opts...)
+}
+
+// RequestRefreshToken refreshes the access token within the default context
+func (c *contextClient) RequestRefreshToken(
+	ctx context.Context,
+	provider ProviderType,
+	refreshToken string,
+) (TokenInfo, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestRefreshToken(ctx, provider, refreshToken)
+}
+
+// RequestRevokeToken revokes the token within the default context
+func (c *contextClient) RequestRevokeToken(
+	ctx context.Context,
+	provider ProviderType,
+	token string,
+) error {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestRevokeToken(ctx, provider, token)
+}
+
+// RequestUnlink disconnects the user from the app within the default context
+func (c *contextClient) RequestUnlink(
+	ctx context.Context,
+	provider ProviderType,
+	accessToken string,
+) error {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestUnlink(ctx, provider, accessToken)
+}
+
+// RequestClientToken requests an app-level token within the default context
+func (c *contextClient) RequestClientToken(
+	ctx context.Context,
+	provider ProviderType,
+	scopes ...string,
+) (TokenInfo, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.RequestClientToken(ctx, provider, scopes...)
+}
+
+// Authenticate exchanges the code and fetches the user info within the default context
+func (c *contextClient) Authenticate(
+	ctx context.Context,
+	provider ProviderType,
+	code string,
+	opts ...AuthenticateOption,
+) (*AuthResult, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.Authenticate(ctx, provider, code, opts...)
+}
+
+// BeginLogin builds the auth URL and state cookie within the default context
+func (c *contextClient) BeginLogin(
+	ctx context.Context,
+	provider ProviderType,
+	opts ...AuthOption,
+) (string, *http.Cookie, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.BeginLogin(ctx, provider, opts...)
+}
+
+// BeginLink builds the link auth URL and state cookie within the default context
+func (c *contextClient) BeginLink(
+	ctx context.Context,
+	provider ProviderType,
+	opts ...AuthOption,
+) (string, *http.Cookie, error) {
+	ctx, cancel := c.merge(ctx)
+	defer cancel()
+	return c.Client.BeginLink(ctx, provider, opts...)
+}
+
+// WithContextDefaults returns a client bound to both the current and the given default context
+func (c *contextClient) WithContextDefaults(ctx context.Context) Client {
+	return &contextClient{Client: c, defaults: ctx}
+}
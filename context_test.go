@@ -0,0 +1,64 @@
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBlockingClient returns an OAuth2 client whose provider calls block until their context ends
+func newBlockingClient() oauth2.Client {
+	httpClient := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})}
+
+	return oauth2.NewClient(google.NewProvider(oauth2.ProviderSetting{
+		Client:      httpClient,
+		ClientID:    "client-id",
+		RedirectURL: "http://localhost/callback",
+	}))
+}
+
+func TestOAuth2Client_WithContextDefaults(t *testing.T) {
+	t.Run("cancellation propagates", func(t *testing.T) {
+		requestCtx, cancel := context.WithCancel(context.Background())
+		client := newBlockingClient().WithContextDefaults(requestCtx)
+
+		time.AfterFunc(20*time.Millisecond, cancel)
+		_, err := client.RequestToken(context.Background(), google.ProviderType, "code")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("deadline propagates", func(t *testing.T) {
+		requestCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		client := newBlockingClient().WithContextDefaults(requestCtx)
+
+		_, err := client.Authenticate(context.Background(), google.ProviderType, "code")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("call context still applies", func(t *testing.T) {
+		client := newBlockingClient().WithContextDefaults(context.Background())
+
+		callCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := client.RequestUserInfo(callCtx, google.ProviderType, "token")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("calls succeed while the default context is alive", func(t *testing.T) {
+		client := oauth2.NewClient(&mockProvider{typ: "google", returnUserInfo: dummyUser{}}).
+			WithContextDefaults(context.Background())
+
+		user, err := client.RequestUserInfo(context.Background(), "google", "token")
+		assert.NoError(t, err)
+		assert.Equal(t, "id", user.GetID())
+	})
+}
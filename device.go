@@ -0,0 +1,146 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DeviceCodeGrantType is the grant_type polling the token endpoint in the device flow (RFC 8628)
+	DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// DefaultDeviceInterval is the polling interval used when the provider does not send one
+	DefaultDeviceInterval = 5 * time.Second
+
+	// deviceSlowDownStep is added to the polling interval on every slow_down response
+	deviceSlowDownStep = 5 * time.Second
+)
+
+type (
+	// DeviceFlowProvider is implemented by providers supporting the device authorization grant
+	// (RFC 8628), for CLIs and TVs that cannot receive a redirect
+	DeviceFlowProvider interface {
+		// StartDeviceFlow requests a device and user code, show UserCode and VerificationURL to the user
+		StartDeviceFlow(ctx context.Context) (DeviceAuth, error)
+
+		// PollDeviceToken polls the token endpoint until the user approves or denies the request,
+		// the device code expires or ctx is done
+		PollDeviceToken(ctx context.Context, deviceCode string) (TokenInfo, error)
+	}
+
+	// DeviceAuth is the response of the device authorization endpoint
+	DeviceAuth struct {
+		// DeviceCode identifies the request when polling with PollDeviceToken
+		DeviceCode string
+
+		// UserCode is the code the user enters at VerificationURL
+		UserCode string
+
+		// VerificationURL is where the user approves the request
+		VerificationURL string
+
+		// VerificationURLComplete embeds the user code (e.g. for a QR code), empty when not sent
+		VerificationURLComplete string
+
+		// Interval is the minimum wait between polls, DefaultDeviceInterval when not sent
+		Interval time.Duration
+
+		// ExpiresIn is how long DeviceCode and UserCode stay valid
+		ExpiresIn time.Duration
+	}
+
+	// deviceAuthResponse is the RFC 8628 device authorization response,
+	// Google names the verification URI verification_url
+	deviceAuthResponse struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURL         string `json:"verification_url"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+)
+
+// errMissingDeviceCode reports a device authorization response without device or user code
+var errMissingDeviceCode = errors.New("device_code or user_code is missing")
+
+// DecodeDeviceAuth decodes a device authorization response, which must carry both codes
+func DecodeDeviceAuth(body []byte) (DeviceAuth, error) {
+	var resp deviceAuthResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return DeviceAuth{}, err
+	}
+	if resp.DeviceCode == "" || resp.UserCode == "" {
+		return DeviceAuth{}, errMissingDeviceCode
+	}
+
+	auth := DeviceAuth{
+		DeviceCode:              resp.DeviceCode,
+		UserCode:                resp.UserCode,
+		VerificationURL:         resp.VerificationURI,
+		VerificationURLComplete: resp.VerificationURIComplete,
+		Interval:                DefaultDeviceInterval,
+		ExpiresIn:               time.Duration(resp.ExpiresIn) * time.Second,
+	}
+	if auth.VerificationURL == "" {
+		auth.VerificationURL = resp.VerificationURL
+	}
+	if resp.Interval > 0 {
+		auth.Interval = time.Duration(resp.Interval) * time.Second
+	}
+	return auth, nil
+}
+
+// WrapDeviceTokenError maps the error response of a device token poll (RFC 8628 3.5)
+//   - authorization_pending wraps ErrAuthorizationPending and slow_down wraps ErrSlowDown
+//   - access_denied wraps ErrAccessDenied and expired_token wraps ErrDeviceCodeExpired
+//   - any other response wraps ErrTokenRequestFailed
+func WrapDeviceTokenError(provider ProviderType, resp *Response) error {
+	base := ErrTokenRequestFailed
+	switch code, _ := responseError(resp.Body); code {
+	case "authorization_pending":
+		base = ErrAuthorizationPending
+	case "slow_down":
+		base = ErrSlowDown
+	case "access_denied":
+		base = ErrAccessDenied
+	case "expired_token":
+		base = ErrDeviceCodeExpired
+	}
+	return WrapResponseError(provider, OpPollDeviceToken, base, resp)
+}
+
+// PollDevice calls poll, waiting interval before each retry, until it returns anything but
+// ErrAuthorizationPending or ErrSlowDown. Every ErrSlowDown adds 5 seconds to the interval.
+// When ctx is done first, the last poll error is returned wrapped with ctx.Err()
+func PollDevice(
+	ctx context.Context,
+	interval time.Duration,
+	poll func(ctx context.Context) (TokenInfo, error),
+) (TokenInfo, error) {
+	if interval <= 0 {
+		interval = DefaultDeviceInterval
+	}
+
+	for {
+		token, err := poll(ctx)
+		switch {
+		case errors.Is(err, ErrSlowDown):
+			interval += deviceSlowDownStep
+		case !errors.Is(err, ErrAuthorizationPending):
+			return token, err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: %w", err, ctx.Err())
+		}
+	}
+}
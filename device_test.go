@@ -0,0 +1,83 @@
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeDeviceAuth(t *testing.T) {
+	auth, err := oauth2.DecodeDeviceAuth([]byte(`{
+		"device_code":"device-code",
+		"user_code":"WDJB-MJHT",
+		"verification_uri":"https://example.com/device",
+		"verification_uri_complete":"https://example.com/device?user_code=WDJB-MJHT",
+		"expires_in":1800
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, oauth2.DeviceAuth{
+		DeviceCode:              "device-code",
+		UserCode:                "WDJB-MJHT",
+		VerificationURL:         "https://example.com/device",
+		VerificationURLComplete: "https://example.com/device?user_code=WDJB-MJHT",
+		Interval:                oauth2.DefaultDeviceInterval,
+		ExpiresIn:               30 * time.Minute,
+	}, auth)
+
+	_, err = oauth2.DecodeDeviceAuth([]byte(`{"device_code":"device-code"}`))
+	assert.Error(t, err)
+	_, err = oauth2.DecodeDeviceAuth([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestWrapDeviceTokenError(t *testing.T) {
+	tests := map[string]error{
+		`{"error":"authorization_pending"}`: oauth2.ErrAuthorizationPending,
+		`{"error":"slow_down"}`:             oauth2.ErrSlowDown,
+		`{"error":"access_denied"}`:         oauth2.ErrAccessDenied,
+		`{"error":"expired_token"}`:         oauth2.ErrDeviceCodeExpired,
+		`{"error":"invalid_grant"}`:         oauth2.ErrTokenRequestFailed,
+	}
+	for body, wantErr := range tests {
+		err := oauth2.WrapDeviceTokenError("test", &oauth2.Response{StatusCode: http.StatusBadRequest, Body: []byte(body)})
+		assert.ErrorIs(t, err, wantErr, body)
+	}
+}
+
+func TestPollDevice(t *testing.T) {
+	t.Run("polls until the user approves", func(t *testing.T) {
+		polls := 0
+		token, err := oauth2.PollDevice(context.Background(), time.Millisecond, func(ctx context.Context) (oauth2.TokenInfo, error) {
+			polls++
+			if polls < 3 {
+				return nil, oauth2.ErrAuthorizationPending
+			}
+			return dummyToken{}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token", token.GetAccessToken())
+		assert.Equal(t, 3, polls)
+	})
+
+	t.Run("stops on any other error", func(t *testing.T) {
+		_, err := oauth2.PollDevice(context.Background(), time.Millisecond, func(ctx context.Context) (oauth2.TokenInfo, error) {
+			return nil, oauth2.ErrAccessDenied
+		})
+		assert.ErrorIs(t, err, oauth2.ErrAccessDenied)
+	})
+
+	t.Run("gives up when ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := oauth2.PollDevice(ctx, time.Millisecond, func(ctx context.Context) (oauth2.TokenInfo, error) {
+			return nil, oauth2.ErrAuthorizationPending
+		})
+		assert.ErrorIs(t, err, oauth2.ErrAuthorizationPending)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
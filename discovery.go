@@ -0,0 +1,59 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// discoveryPath is appended to the issuer to locate its OpenID Connect discovery document
+const discoveryPath = "/.well-known/openid-configuration"
+
+// Endpoints are the endpoints of an OpenID Connect provider, see DiscoverEndpoints
+type Endpoints struct {
+	Issuer        string `json:"issuer"`
+	AuthURL       string `json:"authorization_endpoint"`
+	TokenURL      string `json:"token_endpoint"`
+	UserInfoURL   string `json:"userinfo_endpoint"`
+	JWKSURL       string `json:"jwks_uri"`
+	RevocationURL string `json:"revocation_endpoint"`
+}
+
+// DiscoverEndpoints reads the endpoints from {issuer}/.well-known/openid-configuration
+// with client (nil uses the default client). It fails with ErrDiscoveryFailed when the document
+// cannot be fetched, names another issuer or lacks the authorization or token endpoint
+//
+//	example:
+//	endpoints, err := oauth2.DiscoverEndpoints(ctx, "https://dev-123.okta.com", httpClient)
+//	if err != nil { ... }
+//	provider, err := generic.NewProvider(generic.Setting{ProviderSetting: setting, Type: "okta", Endpoints: endpoints})
+func DiscoverEndpoints(ctx context.Context, issuer string, client *http.Client) (Endpoints, error) {
+	discoveryURL := strings.TrimSuffix(issuer, "/") + discoveryPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return Endpoints{}, fmt.Errorf("%w: %w", ErrDiscoveryFailed, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := NewRequester(ProviderSetting{Client: client}).Do(req)
+	if err != nil {
+		return Endpoints{}, fmt.Errorf("%w: %w", ErrDiscoveryFailed, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Endpoints{}, fmt.Errorf("%w: status %d: %s", ErrDiscoveryFailed, resp.StatusCode, resp.Body)
+	}
+
+	var endpoints Endpoints
+	if err := DecodeJSONResponse(resp, &endpoints); err != nil {
+		return Endpoints{}, fmt.Errorf("%w: %w", ErrDiscoveryFailed, err)
+	}
+
+	switch {
+	case strings.TrimSuffix(endpoints.Issuer, "/") != strings.TrimSuffix(issuer, "/"):
+		return Endpoints{}, fmt.Errorf("%w: document is for issuer %q", ErrDiscoveryFailed, endpoints.Issuer)
+	case endpoints.AuthURL == "" || endpoints.TokenURL == "":
+		return Endpoints{}, fmt.Errorf("%w: authorization or token endpoint is missing", ErrDiscoveryFailed)
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,106 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+const discoveryDocument = `{
+	"issuer": "https://idp.example.com",
+	"authorization_endpoint": "https://idp.example.com/authorize",
+	"token_endpoint": "https://idp.example.com/token",
+	"userinfo_endpoint": "https://idp.example.com/userinfo",
+	"jwks_uri": "https://idp.example.com/jwks",
+	"revocation_endpoint": "https://idp.example.com/revoke",
+	"response_types_supported": ["code"]
+}`
+
+// discoveryClient serves body for every request and records the requested URL
+func discoveryClient(status int, body string, gotURL *string) *http.Client {
+	return &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*gotURL = req.URL.String()
+		return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+	})}
+}
+
+func TestDiscoverEndpoints(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reads the endpoints", func(t *testing.T) {
+		var gotURL string
+		client := discoveryClient(http.StatusOK, discoveryDocument, &gotURL)
+
+		endpoints, err := oauth2.DiscoverEndpoints(ctx, "https://idp.example.com/", client)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://idp.example.com/.well-known/openid-configuration", gotURL)
+		assert.Equal(t, oauth2.Endpoints{
+			Issuer:        "https://idp.example.com",
+			AuthURL:       "https://idp.example.com/authorize",
+			TokenURL:      "https://idp.example.com/token",
+			UserInfoURL:   "https://idp.example.com/userinfo",
+			JWKSURL:       "https://idp.example.com/jwks",
+			RevocationURL: "https://idp.example.com/revoke",
+		}, endpoints)
+	})
+
+	tests := []struct {
+		name   string
+		issuer string
+		status int
+		body   string
+		reason string
+	}{
+		{
+			name:   "issuer mismatch",
+			issuer: "https://other.example.com",
+			status: http.StatusOK,
+			body:   discoveryDocument,
+			reason: "document is for issuer",
+		},
+		{
+			name:   "missing token endpoint",
+			issuer: "https://idp.example.com",
+			status: http.StatusOK,
+			body:   `{"issuer":"https://idp.example.com","authorization_endpoint":"https://idp.example.com/authorize"}`,
+			reason: "endpoint is missing",
+		},
+		{
+			name:   "not found",
+			issuer: "https://idp.example.com",
+			status: http.StatusNotFound,
+			body:   "not found",
+			reason: "status 404",
+		},
+		{
+			name:   "malformed document",
+			issuer: "https://idp.example.com",
+			status: http.StatusOK,
+			body:   "<html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotURL string
+			_, err := oauth2.DiscoverEndpoints(ctx, tt.issuer, discoveryClient(tt.status, tt.body, &gotURL))
+			assert.ErrorIs(t, err, oauth2.ErrDiscoveryFailed)
+			assert.ErrorContains(t, err, tt.reason)
+		})
+	}
+
+	t.Run("network error", func(t *testing.T) {
+		client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("network down")
+		})}
+
+		_, err := oauth2.DiscoverEndpoints(ctx, "https://idp.example.com", client)
+		assert.ErrorIs(t, err, oauth2.ErrDiscoveryFailed)
+	})
+}
@@ -0,0 +1,107 @@
+package oauth2
+
+import (
+	"sync"
+	"time"
+)
+
+// errorLogSize is the number of recent errors kept per provider
+const errorLogSize = 16
+
+type (
+	// ErrorRecord is a failed client operation kept for diagnostics (e.g. admin dashboards)
+	ErrorRecord struct {
+		Err  error
+		Time time.Time
+	}
+
+	// errorLog keeps the last errorLogSize errors of each registered provider in a ring buffer
+	errorLog struct {
+		mu      sync.RWMutex
+		records map[ProviderType]*errorRing
+	}
+
+	// errorRing is a fixed size ring buffer, next is the slot the next record is written to
+	errorRing struct {
+		records [errorLogSize]ErrorRecord
+		next    int
+		count   int
+	}
+)
+
+// newErrorLog creates a log for the given providers, errors of other providers are not recorded
+// so unknown provider names (e.g. from a callback query) cannot grow it
+func newErrorLog(providers map[ProviderType]Provider) *errorLog {
+	log := &errorLog{records: make(map[ProviderType]*errorRing, len(providers))}
+	for providerType := range providers {
+		log.records[providerType] = &errorRing{}
+	}
+	return log
+}
+
+// add starts recording errors of provider, keeping the records it already has
+func (l *errorLog) add(provider ProviderType) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.records[provider]; !ok {
+		l.records[provider] = &errorRing{}
+	}
+}
+
+// remove drops provider and its records
+func (l *errorLog) remove(provider ProviderType) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.records, provider)
+}
+
+// record stores err for provider and returns it unchanged, nil errors are ignored
+func (l *errorLog) record(provider ProviderType, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ring, ok := l.records[provider]
+	if !ok {
+		return err
+	}
+	ring.records[ring.next] = ErrorRecord{Err: err, Time: time.Now()}
+	ring.next = (ring.next + 1) % errorLogSize
+	ring.count = min(ring.count+1, errorLogSize)
+
+	return err
+}
+
+// last returns the most recent record of provider
+func (l *errorLog) last(provider ProviderType) (ErrorRecord, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ring, ok := l.records[provider]
+	if !ok || ring.count == 0 {
+		return ErrorRecord{}, false
+	}
+	return ring.records[(ring.next+errorLogSize-1)%errorLogSize], true
+}
+
+// recent returns the records of provider, newest first
+func (l *errorLog) recent(provider ProviderType) []ErrorRecord {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ring, ok := l.records[provider]
+	if !ok {
+		return nil
+	}
+
+	records := make([]ErrorRecord, 0, ring.count)
+	for i := 1; i <= ring.count; i++ {
+		records = append(records, ring.records[(ring.next+errorLogSize-i)%errorLogSize])
+	}
+	return records
+}
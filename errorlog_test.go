@@ -0,0 +1,91 @@
+package oauth2_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuth2Client_LastError(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no errors recorded", func(t *testing.T) {
+		client := oauth2.NewClient(&mockProvider{typ: "google"})
+
+		at, err := client.LastError("google")
+		assert.NoError(t, err)
+		assert.True(t, at.IsZero())
+		assert.Empty(t, client.RecentErrors("google"))
+	})
+
+	t.Run("failures are recorded newest first", func(t *testing.T) {
+		provider := &mockProvider{typ: "google"}
+		client := oauth2.NewClient(provider)
+
+		provider.errToken = errors.New("token failure")
+		_, _ = client.RequestToken(ctx, "google", "code")
+		provider.errUserInfo = errors.New("userinfo failure")
+		_, _ = client.RequestUserInfo(ctx, "google", "access-token")
+
+		at, err := client.LastError("google")
+		assert.EqualError(t, err, "userinfo failure")
+		assert.WithinDuration(t, time.Now(), at, time.Second)
+
+		recent := client.RecentErrors("google")
+		assert.Len(t, recent, 2)
+		assert.EqualError(t, recent[0].Err, "userinfo failure")
+		assert.EqualError(t, recent[1].Err, "token failure")
+	})
+
+	t.Run("buffer is bounded", func(t *testing.T) {
+		provider := &mockProvider{typ: "google"}
+		client := oauth2.NewClient(provider)
+
+		for i := range 100 {
+			provider.errToken = fmt.Errorf("failure %d", i)
+			_, _ = client.RequestToken(ctx, "google", "code")
+		}
+
+		recent := client.RecentErrors("google")
+		assert.Less(t, len(recent), 100)
+		assert.EqualError(t, recent[0].Err, "failure 99")
+	})
+
+	t.Run("unregistered providers are not recorded", func(t *testing.T) {
+		client := oauth2.NewClient(&mockProvider{typ: "google"})
+
+		_, err := client.RequestToken(ctx, "unknown", "code")
+		assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+
+		_, err = client.LastError("unknown")
+		assert.NoError(t, err)
+	})
+
+	t.Run("concurrent failures", func(t *testing.T) {
+		provider := &mockProvider{typ: "google", errToken: errors.New("token failure")}
+		client := oauth2.NewClient(provider)
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 50 {
+					_, _ = client.RequestToken(ctx, "google", "code")
+					_, _ = client.LastError("google")
+					_ = client.RecentErrors("google")
+				}
+			}()
+		}
+		wg.Wait()
+
+		_, err := client.LastError("google")
+		assert.EqualError(t, err, "token failure")
+	})
+}
@@ -1,18 +1,189 @@
 package oauth2
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
+	"strings"
+)
+
+// Provider operations recorded in ProviderError.Op
+const (
+	OpGetToken          = "GetToken"
+	OpRefreshToken      = "RefreshToken"
+	OpGetUserInfo       = "GetUserInfo"
+	OpRevokeToken       = "RevokeToken"
+	OpClientCredentials = "GetClientCredentialsToken"
+	OpUnlink            = "Unlink"
+	OpStartDeviceFlow   = "StartDeviceFlow"
+	OpPollDeviceToken   = "PollDeviceToken"
 )
 
 var (
 	ErrProviderNotSet        = fmt.Errorf("provider not set")
 	ErrRedirectURLNotSet     = fmt.Errorf("redirect URL is not set for provider")
+	ErrClientIDNotSet        = fmt.Errorf("client ID is not set for provider")
+	ErrClientSecretNotSet    = fmt.Errorf("client secret is not set for provider")
 	ErrEmptyAuthCode         = fmt.Errorf("authorization code is empty")
 	ErrTokenRequestFailed    = fmt.Errorf("failed to get access token")
 	ErrUserInfoRequestFailed = fmt.Errorf("failed to get user info")
 	ErrEmptyRefreshToken     = fmt.Errorf("refresh token is empty")
+	ErrEmptyAccessToken      = fmt.Errorf("access token is empty")
+	ErrTokenRevocationFailed = fmt.Errorf("failed to revoke token")
+	ErrUnsupportedTokenType  = fmt.Errorf("unsupported token type")
+	ErrUnexpectedRedirect    = fmt.Errorf("unexpected redirect from provider endpoint")
+	ErrInvalidPrompt         = fmt.Errorf("invalid prompt")
+	ErrConflictingPrompts    = fmt.Errorf("conflicting prompt values")
+	ErrAuthURLTooLong        = fmt.Errorf("authorization URL too long")
+	ErrInvalidIDToken        = fmt.Errorf("invalid id_token")
+	ErrInvalidNotification   = fmt.Errorf("invalid provider notification")
+	ErrUnsupportedOperation  = fmt.Errorf("operation not supported by provider")
+	ErrProviderNotRegistered = fmt.Errorf("provider constructor not registered")
+	ErrStateMismatch         = fmt.Errorf("state mismatch (possible CSRF)")
+	ErrStateExpired          = fmt.Errorf("state expired")
+	ErrAccessDenied          = fmt.Errorf("access denied by user")
+	ErrInteractionRequired   = fmt.Errorf("user interaction required")
+	ErrAuthorizationFailed   = fmt.Errorf("authorization failed")
+	ErrUnsafeRedirect        = fmt.Errorf("redirect target is not allowed")
+	ErrNotLinkState          = fmt.Errorf("state does not carry a link intent")
+	ErrInvalidPrivateKey     = fmt.Errorf("invalid private key")
+	ErrIncompleteProfile     = fmt.Errorf("user profile is missing required fields")
+	ErrSigningKeysFailed     = fmt.Errorf("failed to get signing keys")
+	ErrSigningKeyNotFound    = fmt.Errorf("signing key not found")
+	ErrInvalidResource       = fmt.Errorf("invalid resource indicator")
+	ErrInvalidRedirectURL    = fmt.Errorf("invalid redirect URL")
+	ErrInvalidClaimsRequest  = fmt.Errorf("invalid claims request")
+	ErrInvalidFlowSession    = fmt.Errorf("invalid flow session")
+	ErrDiscoveryFailed       = fmt.Errorf("failed to discover provider endpoints")
+	ErrEndpointNotSet        = fmt.Errorf("endpoint is not set for provider")
+	ErrGrantNotSupported     = fmt.Errorf("grant type not supported by provider")
+	ErrUnlinkFailed          = fmt.Errorf("failed to unlink user")
+	ErrDeviceFlowFailed      = fmt.Errorf("failed to start device authorization")
+	ErrAuthorizationPending  = fmt.Errorf("authorization pending")
+	ErrSlowDown              = fmt.Errorf("polling too fast")
+	ErrDeviceCodeExpired     = fmt.Errorf("device code expired")
+	ErrTokenExpired          = fmt.Errorf("access token expired and cannot be refreshed")
+	ErrHostedDomainMismatch  = fmt.Errorf("user is not in the hosted domain")
+	ErrResponseTooLarge      = fmt.Errorf("provider response too large")
+	ErrUnexpectedContentType = fmt.Errorf("unexpected provider response content type")
+	ErrCodeVerifierNotSet    = fmt.Errorf("PKCE code verifier is not set")
 )
 
+// ProviderError is the error returned by providers, inspect it with errors.As
+//
+//	example:
+//	var providerErr *oauth2.ProviderError
+//	if errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusUnauthorized { ... }
+type ProviderError struct {
+	// Provider is the provider that failed
+	Provider ProviderType
+
+	// Op is the failing operation (e.g. OpGetToken), set when the error comes from a provider response
+	Op string
+
+	// StatusCode is the HTTP status of the provider response, 0 when no response was received
+	StatusCode int
+
+	// Code is the OAuth error code of the response body (e.g. invalid_grant), empty when absent
+	Code string
+
+	// Description is the error_description (or error message) of the response body,
+	// the whole trimmed body when it is not JSON and empty when absent
+	Description string
+
+	// Body is the provider response body, empty when no response was received
+	Body string
+
+	// Err wraps the sentinel (e.g. ErrTokenRequestFailed) and the underlying cause, if any
+	Err error
+}
+
+// Error keeps the "<provider> provider: <sentinel>: <details>" format
+func (e *ProviderError) Error() string {
+	msg := fmt.Sprintf("%s provider: %v", e.Provider, e.Err)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Unwrap returns Err so errors.Is matches the sentinel and the cause
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// ErrorCode returns the OAuth error code of the response body (e.g. invalid_grant)
+func (e *ProviderError) ErrorCode() string { return e.Code }
+
+// ErrorDescription returns the error description of the response body, see Description
+func (e *ProviderError) ErrorDescription() string { return e.Description }
+
+// WrapProviderError returns a *ProviderError wrapping base, with context describing the failure
 func WrapProviderError(provider ProviderType, base error, context string) error {
-	return fmt.Errorf("%s provider: %w: %s", provider, base, context)
+	err := base
+	if context != "" {
+		err = fmt.Errorf("%w: %s", base, context)
+	}
+	return &ProviderError{Provider: provider, Err: err}
+}
+
+// WrapProviderCause wraps base like WrapProviderError while keeping cause inspectable,
+// so both errors.Is(err, base) and errors.Is(err, cause) hold
+func WrapProviderCause(provider ProviderType, base error, cause error) error {
+	return &ProviderError{Provider: provider, Err: fmt.Errorf("%w: %w", base, cause)}
+}
+
+// WrapResponseError returns a *ProviderError wrapping base for an unexpected provider response,
+// carrying its status code, body and OAuth error code and description
+func WrapResponseError(provider ProviderType, op string, base error, resp *Response) error {
+	code, description := responseError(resp.Body)
+	return &ProviderError{
+		Provider:    provider,
+		Op:          op,
+		StatusCode:  resp.StatusCode,
+		Code:        code,
+		Description: description,
+		Body:        string(resp.Body),
+		Err:         base,
+	}
+}
+
+// responseError reads the error code and description of an error body, either the RFC 6749
+// "error" and "error_description" strings or an "error" object with a "code" and "message"
+// (e.g. Facebook's Graph API errors). A body that is not a JSON object is returned as the description
+func responseError(body []byte) (string, string) {
+	var payload struct {
+		Error       json.RawMessage `json:"error"`
+		Description string          `json:"error_description"`
+		Message     string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", strings.TrimSpace(string(body))
+	}
+	if len(payload.Error) == 0 {
+		return "", cmp.Or(payload.Description, payload.Message)
+	}
+
+	var code string
+	if err := json.Unmarshal(payload.Error, &code); err == nil {
+		return code, cmp.Or(payload.Description, payload.Message)
+	}
+
+	var object struct {
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(payload.Error, &object); err != nil {
+		return "", cmp.Or(payload.Description, payload.Message)
+	}
+	description := cmp.Or(payload.Description, object.Message, payload.Message)
+	if len(object.Code) == 0 {
+		return "", description
+	}
+	if err := json.Unmarshal(object.Code, &code); err == nil {
+		return code, description
+	}
+	var number json.Number
+	if err := json.Unmarshal(object.Code, &number); err == nil {
+		return number.String(), description
+	}
+	return "", description
 }
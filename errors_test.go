@@ -0,0 +1,128 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderError_Response(t *testing.T) {
+	body := `{"error":"invalid_client","error_description":"The OAuth client was not found."}`
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+		}, nil
+	})}
+	provider := google.NewProvider(oauth2.ProviderSetting{
+		Client:      client,
+		RedirectURL: "https://example.com/callback",
+	})
+
+	_, err := provider.GetToken(context.Background(), "code")
+	assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+
+	var providerErr *oauth2.ProviderError
+	if assert.True(t, errors.As(err, &providerErr)) {
+		assert.Equal(t, google.ProviderType, providerErr.Provider)
+		assert.Equal(t, oauth2.OpGetToken, providerErr.Op)
+		assert.Equal(t, http.StatusUnauthorized, providerErr.StatusCode)
+		assert.Equal(t, "invalid_client", providerErr.Code)
+		assert.Equal(t, "invalid_client", providerErr.ErrorCode())
+		assert.Equal(t, "The OAuth client was not found.", providerErr.ErrorDescription())
+		assert.Equal(t, body, providerErr.Body)
+	}
+	assert.Equal(t, "google provider: failed to get access token: "+body, err.Error())
+}
+
+func TestProviderError_Code(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "oauth error", body: `{"error":"invalid_grant"}`, want: "invalid_grant"},
+		{name: "numeric code object", body: `{"error":{"message":"Invalid token","code":190}}`, want: "190"},
+		{name: "string code object", body: `{"error":{"code":"unauthorized"}}`, want: "unauthorized"},
+		{name: "no error member", body: `{"code":-401}`, want: ""},
+		{name: "not json", body: `Unauthorized`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &oauth2.Response{StatusCode: http.StatusBadRequest, Body: []byte(tt.body)}
+			err := oauth2.WrapResponseError("test", oauth2.OpRevokeToken, oauth2.ErrTokenRevocationFailed, resp)
+
+			var providerErr *oauth2.ProviderError
+			if assert.ErrorAs(t, err, &providerErr) {
+				assert.Equal(t, tt.want, providerErr.Code)
+			}
+		})
+	}
+}
+
+func TestProviderError_Description(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode string
+		want     string
+	}{
+		{
+			name:     "invalid_grant",
+			body:     `{"error":"invalid_grant","error_description":"Bad Request"}`,
+			wantCode: "invalid_grant",
+			want:     "Bad Request",
+		},
+		{
+			name:     "error object message",
+			body:     `{"error":{"message":"Invalid OAuth access token.","code":190}}`,
+			wantCode: "190",
+			want:     "Invalid OAuth access token.",
+		},
+		{name: "top-level message", body: `{"status":400,"message":"Invalid authorization code"}`, want: "Invalid authorization code"},
+		{name: "code without description", body: `{"error":"access_denied"}`, wantCode: "access_denied"},
+		{name: "plain text", body: "Service Unavailable\n", want: "Service Unavailable"},
+		{name: "empty body", body: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &oauth2.Response{StatusCode: http.StatusBadRequest, Body: []byte(tt.body)}
+			err := oauth2.WrapResponseError("test", oauth2.OpGetToken, oauth2.ErrTokenRequestFailed, resp)
+
+			var providerErr *oauth2.ProviderError
+			if assert.ErrorAs(t, err, &providerErr) {
+				assert.Equal(t, tt.wantCode, providerErr.ErrorCode())
+				assert.Equal(t, tt.want, providerErr.ErrorDescription())
+				assert.Equal(t, tt.body, providerErr.Body, "the raw body is kept")
+			}
+		})
+	}
+}
+
+func TestWrapProviderError(t *testing.T) {
+	cause := errors.New("connection reset")
+
+	err := oauth2.WrapProviderCause("test", oauth2.ErrUserInfoRequestFailed, cause)
+	assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "test provider: failed to get user info: connection reset", err.Error())
+
+	var providerErr *oauth2.ProviderError
+	if assert.ErrorAs(t, err, &providerErr) {
+		assert.Zero(t, providerErr.StatusCode)
+		assert.Empty(t, providerErr.Op)
+	}
+
+	err = oauth2.WrapProviderError("test", oauth2.ErrEmptyAccessToken, "")
+	assert.ErrorIs(t, err, oauth2.ErrEmptyAccessToken)
+	assert.Equal(t, "test provider: "+oauth2.ErrEmptyAccessToken.Error(), err.Error())
+}
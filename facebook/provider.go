@@ -0,0 +1,452 @@
+package facebook
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// ProviderType is the identifier for the Facebook Login provider
+	//   - REFS : https://developers.facebook.com/docs/facebook-login/guides/advanced/manual-flow
+	ProviderType oauth2.ProviderType = "facebook"
+
+	// DefaultVersion is the Graph API version used unless WithVersion overrides it
+	DefaultVersion = "v19.0"
+
+	// UserInfoFields are the fields requested from /me
+	UserInfoFields = "id,name,email,picture"
+
+	// KeysURL is the JWKS endpoint serving the Limited Login id_token signing keys
+	KeysURL = "https://limited.facebook.com/.well-known/oauth/openid/jwks/"
+
+	// dialogHost serves the login dialog, graphHost the Graph API
+	dialogHost = "https://www.facebook.com/"
+	graphHost  = "https://graph.facebook.com/"
+)
+
+// DefaultScopes are requested when ProviderSetting.Scopes is empty, WithScopes adds to them.
+// Replace it during initialization, before any provider is used
+var DefaultScopes = []string{"email", "public_profile"}
+
+type (
+	// Option customizes the Facebook provider
+	Option func(*provider)
+
+	// provider holds the configuration for Facebook Login
+	provider struct {
+		requester    *oauth2.Requester
+		clientID     string
+		clientSecret string
+		redirectURL  string
+		version      string
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
+	}
+
+	// userInfo represents the /me response for UserInfoFields
+	userInfo struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Email   string `json:"email"`
+		Picture struct {
+			Data struct {
+				URL string `json:"url"`
+			} `json:"data"`
+		} `json:"picture"`
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
+	}
+
+	// tokenInfo represents the token information returned from Facebook.
+	// Facebook issues no refresh tokens, short-lived tokens are exchanged for long-lived ones instead
+	tokenInfo struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+
+		issuedAt time.Time
+	}
+)
+
+// provider must keep implementing oauth2.Provider
+var _ oauth2.Provider = (*provider)(nil)
+
+func init() {
+	oauth2.RegisterConstructor(ProviderType, func(setting oauth2.ProviderSetting) oauth2.Provider {
+		return NewProvider(setting)
+	})
+}
+
+// WithVersion pins the Graph API version (e.g. "v20.0") of every endpoint, default DefaultVersion
+func WithVersion(version string) Option {
+	return func(p *provider) {
+		p.version = version
+	}
+}
+
+// NewProvider initializes and returns a new Facebook Login provider
+//
+//	example:
+//	provider := facebook.NewProvider(setting, facebook.WithVersion("v20.0"))
+func NewProvider(setting oauth2.ProviderSetting, opts ...Option) oauth2.Provider {
+	p := &provider{
+		requester:    oauth2.NewRequester(setting),
+		clientID:     setting.ClientID,
+		clientSecret: setting.ClientSecret,
+		redirectURL:  setting.RedirectURL,
+		version:      DefaultVersion,
+
+		strictTokenType:  setting.StrictTokenType,
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodDelete),
+
+		userInfoFallbackURLs: setting.UserInfoFallbackURLs,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.authURL = cmp.Or(setting.AuthURL, AuthURL(p.version))
+	p.tokenURL = cmp.Or(setting.TokenURL, TokenURL(p.version))
+	p.userInfoURL = cmp.Or(setting.UserInfoURL, p.graphURL("me"))
+
+	return p
+}
+
+// NewProviderWithError is NewProvider failing with ErrClientIDNotSet, ErrClientSecretNotSet
+// or ErrInvalidRedirectURL instead of building a provider whose calls cannot succeed
+func NewProviderWithError(setting oauth2.ProviderSetting, opts ...Option) (oauth2.Provider, error) {
+	if err := setting.ValidateCredentials(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+	return NewProvider(setting, opts...), nil
+}
+
+// AuthURL returns the login dialog endpoint of the given Graph API version
+func AuthURL(version string) string { return dialogHost + version + "/dialog/oauth" }
+
+// TokenURL returns the code exchange endpoint of the given Graph API version
+func TokenURL(version string) string { return graphHost + version + "/oauth/access_token" }
+
+// graphURL returns the Graph API endpoint for path at the configured version
+func (f *provider) graphURL(path string) string { return graphHost + f.version + "/" + path }
+
+// GetUserInfo retrieves the Facebook user's profile, asking /me for UserInfoFields
+func (f *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
+	if accessToken == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAccessToken, "")
+	}
+
+	endpoint, err := url.Parse(f.userInfoURL)
+	if err != nil {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrUserInfoRequestFailed,
+			err.Error(),
+		)
+	}
+	query := endpoint.Query()
+	if query.Get("fields") == "" {
+		query.Set("fields", UserInfoFields)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrUserInfoRequestFailed,
+			err.Error(),
+		)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	oauth2.SetAcceptLanguage(req)
+
+	end := f.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+	resp, err := f.requester.DoWithFallback(req, f.userInfoFallbackURLs)
+	end(resp, err)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpGetUserInfo,
+			oauth2.ErrUserInfoRequestFailed,
+			resp,
+		)
+	}
+
+	var userInfo userInfo
+	if err := oauth2.DecodeJSONResponse(resp, &userInfo); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	userInfo.raw = oauth2.DecodeRawUserInfo(resp.Body)
+	userInfo.nameStrategy = f.nameStrategy
+
+	return &userInfo, nil
+}
+
+// GetAuthURL constructs the Facebook login dialog URL
+//   - ProviderSetting.Scopes replaces the email public_profile defaults, WithScopes adds to them
+//   - WithPKCE adds the S256 code_challenge
+//   - WithOfflineAccess and WithPrompt are ignored, Facebook supports neither
+func (f *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
+	if f.redirectURL == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
+	}
+	if f.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+
+	options := oauth2.NewAuthOptions(opts...)
+	if err := oauth2.ValidatePrompts(options.Prompts); err != nil {
+		return "", oauth2.WrapProviderError(ProviderType, err, strings.Join(options.Prompts, " "))
+	}
+
+	scopes := f.scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	query := url.Values{}
+	query.Set("client_id", f.clientID)
+	query.Set("redirect_uri", f.redirectURL)
+	query.Set("response_type", "code")
+	query.Set("scope", strings.Join(oauth2.NormalizeScopes(scopes, options.Scopes), ","))
+	query.Set("state", state)
+	oauth2.SetCodeChallenge(query, options.CodeVerifier)
+	oauth2.SetAuthParams(query, options.AuthParams)
+
+	return oauth2.BuildAuthURL(ProviderType, f.authURL, query, f.authURLLimits)
+}
+
+// GetToken exchanges the authorization code for a short-lived access token from Facebook
+func (f *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	var tokenInfo tokenInfo
+	if code == "" {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
+	}
+	ctx = oauth2.WithoutRetry(ctx)
+
+	form := url.Values{}
+	form.Set("code", code)
+	form.Set("client_id", f.clientID)
+	form.Set("client_secret", f.clientSecret)
+	form.Set("redirect_uri", f.redirectURL)
+	oauth2.SetCodeVerifier(form, oauth2.NewAuthOptions(opts...).CodeVerifier)
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, f.tokenURL, form)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRequestFailed,
+			err.Error(),
+		)
+	}
+
+	end := f.requester.Observe(ctx, ProviderType, oauth2.OpGetToken)
+	resp, err := f.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpGetToken,
+			oauth2.ErrTokenRequestFailed,
+			resp,
+		)
+	}
+
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, f.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
+	return tokenInfo, nil
+}
+
+// RefreshToken is not supported since Facebook issues no refresh tokens
+func (f *provider) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (oauth2.TokenInfo, error) {
+	return tokenInfo{}, oauth2.WrapProviderError(
+		ProviderType,
+		oauth2.ErrUnsupportedOperation,
+		"no refresh tokens",
+	)
+}
+
+// RevokeToken removes the app's permissions for the user of the access token,
+// which invalidates every token the user granted the app
+func (f *provider) RevokeToken(ctx context.Context, accessToken string) error {
+	return f.removePermissions(ctx, accessToken, oauth2.OpRevokeToken, oauth2.ErrTokenRevocationFailed)
+}
+
+// Unlink deauthorizes the app for the user, the same call as RevokeToken
+func (f *provider) Unlink(ctx context.Context, accessToken string) error {
+	return f.removePermissions(ctx, accessToken, oauth2.OpUnlink, oauth2.ErrUnlinkFailed)
+}
+
+// removePermissions deletes me/permissions for the user of the access token, failing with base
+func (f *provider) removePermissions(ctx context.Context, accessToken string, op string, base error) error {
+	if accessToken == "" {
+		return oauth2.WrapProviderError(ProviderType, base, "token is empty")
+	}
+
+	form := url.Values{}
+	form.Set("access_token", accessToken)
+
+	req, err := oauth2.NewFormRequest(ctx, f.revocationMethod, f.graphURL("me/permissions"), form)
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			base,
+			err.Error(),
+		)
+	}
+
+	resp, err := f.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, base, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return oauth2.WrapResponseError(
+			ProviderType,
+			op,
+			base,
+			resp,
+		)
+	}
+
+	return nil
+}
+
+// CanRefresh is always false since Facebook issues no refresh tokens
+func (f provider) CanRefresh(token oauth2.TokenInfo) bool { return false }
+
+// SigningKeys fetches the Limited Login id_token signing keys served at KeysURL
+func (f *provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	keys, err := f.requester.FetchJWKS(ctx, KeysURL)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrSigningKeysFailed, err)
+	}
+	return keys, nil
+}
+
+// GetClientCredentialsToken is not supported, Graph API app calls take the app access token
+// "{app-id}|{app-secret}" directly
+func (f provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrGrantNotSupported, "client_credentials")
+}
+
+// GetProvider returns the provider type ("facebook")
+func (f provider) GetProvider() oauth2.ProviderType { return ProviderType }
+
+// GetRedirectURL returns the configured redirect URL
+func (f provider) GetRedirectURL() string { return f.redirectURL }
+
+// GetID returns the user's app-scoped Facebook ID
+func (f userInfo) GetID() string { return f.ID }
+
+// GetEmail returns the user's primary email, empty when the email permission was declined
+func (f userInfo) GetEmail() string { return f.Email }
+
+// GetName returns the user's name, or the email depending on the NameStrategy.
+// Facebook has no nickname, so PreferNickname behaves like PreferRealName
+func (f userInfo) GetName() string { return oauth2.SelectName(f.nameStrategy, f.Name, "", f.Email) }
+
+// GetGender returns an empty string since the gender field is not requested
+func (f userInfo) GetGender() string { return "" }
+
+// GetGenderNormalized returns oauth2.GenderUnknown since there is no gender
+func (f userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.GenderUnknown }
+
+// GetProfileImage returns the URL of the user's profile picture (picture.data.url)
+func (f userInfo) GetProfileImage() string { return f.Picture.Data.URL }
+
+// GetPhoneNumber returns an empty string since the Graph API does not share phone numbers
+func (f userInfo) GetPhoneNumber() string { return "" }
+
+// GetBirthday returns an empty string since the user_birthday permission is not requested
+func (f userInfo) GetBirthday() string { return "" }
+
+// GetLocale returns an empty string since the Graph API no longer returns the locale field
+func (f userInfo) GetLocale() string { return "" }
+
+// IsEmailVerified is always false, Facebook does not say whether the email was verified
+func (f userInfo) IsEmailVerified() bool { return false }
+
+// GetRaw returns the decoded /me response
+func (f userInfo) GetRaw() map[string]any { return f.raw }
+
+// GetAccessToken returns the OAuth2 access token
+func (f tokenInfo) GetAccessToken() string { return f.AccessToken }
+
+// GetRefreshToken returns an empty string since Facebook issues no refresh tokens
+func (f tokenInfo) GetRefreshToken() string { return "" }
+
+// GetExpiry returns the token expiration time in seconds
+func (f tokenInfo) GetExpiry() int { return f.ExpiresIn }
+
+// HasRefreshToken is always false since Facebook issues no refresh tokens
+func (f tokenInfo) HasRefreshToken() bool { return false }
+
+// GetRefreshTokenExpiresAt returns the zero time since Facebook issues no refresh tokens
+func (f tokenInfo) GetRefreshTokenExpiresAt() time.Time { return time.Time{} }
+
+// HasExpiry reports whether the access token expires, false when expires_in was not returned
+func (f tokenInfo) HasExpiry() bool { return f.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (f tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(f.issuedAt, f.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (f tokenInfo) IsExpired() bool { return oauth2.Expired(f.GetExpiresAt()) }
+
+// GetScope returns an empty string since Facebook does not echo the granted scopes
+func (f tokenInfo) GetScope() string { return "" }
+
+// GetTokenType returns the token type (e.g. "bearer")
+func (f tokenInfo) GetTokenType() string { return f.TokenType }
+
+// GetIDToken is always empty since the code flow returns no id_token
+func (f tokenInfo) GetIDToken() string { return "" }
@@ -0,0 +1,201 @@
+package facebook_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/facebook"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newMockClient(fn roundTripperFunc) *http.Client {
+	return &http.Client{Transport: fn}
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewBufferString(body))}
+}
+
+func TestFacebookProvider_GetUserInfo(t *testing.T) {
+	t.Run("nested picture and requested fields", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "https://graph.facebook.com/v19.0/me", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+			assert.Equal(t, facebook.UserInfoFields, req.URL.Query().Get("fields"))
+			assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+			return jsonResponse(http.StatusOK, `{
+				"id": "10158",
+				"name": "Face Book",
+				"email": "fb@example.com",
+				"picture": {"data": {"height": 50, "is_silhouette": false, "url": "https://platform-lookaside.fbsbx.com/pic", "width": 50}}
+			}`), nil
+		})
+		provider := facebook.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		user, err := provider.GetUserInfo(context.Background(), "token")
+		assert.NoError(t, err)
+		assert.Equal(t, "10158", user.GetID())
+		assert.Equal(t, "Face Book", user.GetName())
+		assert.Equal(t, "fb@example.com", user.GetEmail())
+		assert.Equal(t, "https://platform-lookaside.fbsbx.com/pic", user.GetProfileImage())
+		assert.False(t, user.IsEmailVerified())
+		assert.NotNil(t, user.GetRaw()["picture"])
+	})
+
+	t.Run("configured version", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "/v20.0/me", req.URL.Path)
+			return jsonResponse(http.StatusOK, `{"id":"1"}`), nil
+		})
+		provider := facebook.NewProvider(oauth2.ProviderSetting{Client: client}, facebook.WithVersion("v20.0"))
+
+		user, err := provider.GetUserInfo(context.Background(), "token")
+		assert.NoError(t, err)
+		assert.Empty(t, user.GetProfileImage())
+	})
+
+	t.Run("graph error", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusBadRequest,
+				`{"error":{"message":"Invalid OAuth access token.","type":"OAuthException","code":190}}`), nil
+		})
+		provider := facebook.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetUserInfo(context.Background(), "token")
+		assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+		assert.ErrorContains(t, err, "Invalid OAuth access token.")
+
+		var providerErr *oauth2.ProviderError
+		if assert.ErrorAs(t, err, &providerErr) {
+			assert.Equal(t, http.StatusBadRequest, providerErr.StatusCode)
+			assert.Equal(t, "190", providerErr.Code)
+		}
+	})
+
+	t.Run("empty access token", func(t *testing.T) {
+		provider := facebook.NewProvider(oauth2.ProviderSetting{})
+
+		_, err := provider.GetUserInfo(context.Background(), "")
+		assert.ErrorIs(t, err, oauth2.ErrEmptyAccessToken)
+	})
+}
+
+func TestFacebookProvider_GetToken(t *testing.T) {
+	t.Run("successful token exchange", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, facebook.TokenURL(facebook.DefaultVersion), req.URL.String())
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "code", req.PostForm.Get("code"))
+			assert.Equal(t, "id", req.PostForm.Get("client_id"))
+			assert.Equal(t, "secret", req.PostForm.Get("client_secret"))
+			assert.Equal(t, "https://app.example.com/callback", req.PostForm.Get("redirect_uri"))
+			return jsonResponse(http.StatusOK,
+				`{"access_token":"access-token","token_type":"bearer","expires_in":5183944}`), nil
+		})
+		provider := facebook.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+			RedirectURL:  "https://app.example.com/callback",
+		})
+
+		token, err := provider.GetToken(context.Background(), "code")
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token", token.GetAccessToken())
+		assert.Equal(t, 5183944, token.GetExpiry())
+		assert.False(t, token.HasRefreshToken())
+		assert.False(t, provider.CanRefresh(token))
+	})
+
+	t.Run("graph error", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusBadRequest,
+				`{"error":{"message":"This authorization code has been used.","type":"OAuthException","code":100}}`), nil
+		})
+		provider := facebook.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetToken(context.Background(), "code")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.ErrorContains(t, err, "authorization code has been used")
+	})
+
+	t.Run("refresh is unsupported", func(t *testing.T) {
+		provider := facebook.NewProvider(oauth2.ProviderSetting{})
+
+		_, err := provider.RefreshToken(context.Background(), "refresh")
+		assert.ErrorIs(t, err, oauth2.ErrUnsupportedOperation)
+	})
+
+	t.Run("code exchange is not retried", func(t *testing.T) {
+		calls := 0
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, facebook.TokenURL(facebook.DefaultVersion), req.URL.String())
+			calls++
+			return jsonResponse(http.StatusServiceUnavailable, `{}`), nil
+		})
+		provider := facebook.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+			RedirectURL:  "https://app.example.com/callback",
+			MaxRetries:   3,
+			RetryBackoff: time.Millisecond,
+		})
+
+		_, err := provider.GetToken(context.Background(), "code")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.Equal(t, 1, calls, "the single use code must not be replayed")
+	})
+}
+
+func TestFacebookProvider_GetAuthURL(t *testing.T) {
+	provider := facebook.NewProvider(oauth2.ProviderSetting{
+		ClientID:    "id",
+		RedirectURL: "https://app.example.com/callback",
+	}, facebook.WithVersion("v20.0"))
+
+	authURL, err := provider.GetAuthURL(context.Background(), "state", oauth2.WithScopes("user_birthday"))
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, facebook.AuthURL("v20.0"), parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "email,public_profile,user_birthday", parsed.Query().Get("scope"))
+	assert.Equal(t, "state", parsed.Query().Get("state"))
+	assert.Equal(t, "code", parsed.Query().Get("response_type"))
+}
+
+func TestFacebookProvider_RevokeToken(t *testing.T) {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, http.MethodDelete, req.Method)
+		assert.Equal(t, "/v19.0/me/permissions", req.URL.Path)
+		assert.Equal(t, "access-token", req.URL.Query().Get("access_token"))
+		return jsonResponse(http.StatusOK, `{"success":true}`), nil
+	})
+	provider := facebook.NewProvider(oauth2.ProviderSetting{Client: client})
+
+	assert.NoError(t, provider.RevokeToken(context.Background(), "access-token"))
+}
+
+func TestFacebookProvider_Unlink(t *testing.T) {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, http.MethodDelete, req.Method)
+		assert.Equal(t, "/v19.0/me/permissions", req.URL.Path)
+		return jsonResponse(http.StatusBadRequest, `{"error":{"message":"Invalid OAuth access token.","code":190}}`), nil
+	})
+	provider := facebook.NewProvider(oauth2.ProviderSetting{Client: client})
+
+	err := provider.Unlink(context.Background(), "access-token")
+	assert.ErrorIs(t, err, oauth2.ErrUnlinkFailed)
+}
@@ -0,0 +1,150 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// minFlowSessionKeyBytes is the shortest HMAC key accepted for flow sessions
+const minFlowSessionKeyBytes = 32
+
+type (
+	// FlowSessionOptions configures NewFlowSession
+	FlowSessionOptions struct {
+		// Key signs the session with HMAC-SHA256, it must be at least 32 bytes
+		Key []byte
+
+		// PKCE generates a code verifier to send with WithPKCE
+		PKCE bool
+
+		// Nonce generates an OpenID Connect nonce to send with WithNonce and check with VerifyNonce
+		Nonce bool
+
+		// ReturnURL is where to send the user after the callback
+		ReturnURL string
+	}
+
+	// FlowSession is everything a login needs to remember between the redirect and the callback
+	FlowSession struct {
+		State        string    `json:"state"`
+		CodeVerifier string    `json:"cv,omitempty"`
+		Nonce        string    `json:"nonce,omitempty"`
+		ReturnURL    string    `json:"ret,omitempty"`
+		IssuedAt     time.Time `json:"iat"`
+	}
+
+	// flowSessionContextKey carries the verified FlowSession on the callback request
+	flowSessionContextKey struct{}
+)
+
+// NewFlowSession generates the state (plus a PKCE verifier and nonce when asked) of a login and
+// returns them with an opaque, HMAC-signed token encoding them, the only value to persist.
+// The token is signed, not encrypted, so keep it in an HttpOnly cookie (see NewFlowSessionCookie)
+// rather than a URL since it carries the PKCE verifier
+//
+//	example:
+//	token, session, err := oauth2.NewFlowSession(oauth2.FlowSessionOptions{Key: key, PKCE: true, ReturnURL: "/settings"})
+//	if err != nil { ... }
+//	authURL := client.RequestAuthURL(ctx, google.ProviderType, session.State, oauth2.WithPKCE(session.CodeVerifier))
+//	http.SetCookie(w, oauth2.NewFlowSessionCookie(token, redirectURL))
+//	http.Redirect(w, r, authURL, http.StatusFound)
+func NewFlowSession(opts FlowSessionOptions) (string, FlowSession, error) {
+	if len(opts.Key) < minFlowSessionKeyBytes {
+		return "", FlowSession{}, ErrInvalidFlowSession
+	}
+
+	state, err := GenerateState()
+	if err != nil {
+		return "", FlowSession{}, err
+	}
+	session := FlowSession{
+		State:     state,
+		ReturnURL: opts.ReturnURL,
+		IssuedAt:  time.Now().Truncate(time.Second),
+	}
+	if opts.PKCE {
+		if session.CodeVerifier, err = GenerateCodeVerifier(); err != nil {
+			return "", FlowSession{}, err
+		}
+	}
+	if opts.Nonce {
+		if session.Nonce, err = GenerateNonce(); err != nil {
+			return "", FlowSession{}, err
+		}
+	}
+
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return "", FlowSession{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + signPayload(opts.Key, encoded), session, nil
+}
+
+// VerifyFlowSession recovers the session from a token issued by NewFlowSession with the same key
+//   - a malformed or tampered token, or a key shorter than 32 bytes, fails with ErrInvalidFlowSession
+//   - when ttl is positive, a session older than ttl fails with ErrStateExpired
+func VerifyFlowSession(key []byte, token string, ttl time.Duration) (FlowSession, error) {
+	if len(key) < minFlowSessionKeyBytes {
+		return FlowSession{}, ErrInvalidFlowSession
+	}
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signPayload(key, encoded))) {
+		return FlowSession{}, ErrInvalidFlowSession
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return FlowSession{}, ErrInvalidFlowSession
+	}
+	var session FlowSession
+	if err := json.Unmarshal(payload, &session); err != nil || session.State == "" {
+		return FlowSession{}, ErrInvalidFlowSession
+	}
+
+	if ttl > 0 && time.Since(session.IssuedAt) > ttl {
+		return FlowSession{}, ErrStateExpired
+	}
+	return session, nil
+}
+
+// NewFlowSessionCookie builds the state cookie carrying a flow session token to the callback,
+// read by NewCallbackHandler when CallbackConfig.FlowSessionKey is set.
+// For a FormPostProvider use NewFormPostFlowSessionCookie instead
+func NewFlowSessionCookie(token string, redirectURL string) *http.Cookie {
+	return newStateCookie(token, redirectURL)
+}
+
+// NewFormPostFlowSessionCookie is NewFlowSessionCookie issued SameSite=None; Secure, so it reaches
+// the callback of a provider posting it cross-site with response_mode=form_post (e.g. Apple)
+func NewFormPostFlowSessionCookie(token string, redirectURL string) *http.Cookie {
+	cookie := newStateCookie(token, redirectURL)
+	crossSiteCookie(cookie)
+	return cookie
+}
+
+// FlowSessionFromRequest returns the session verified by NewCallbackHandler,
+// e.g. to check the id_token nonce in OnSuccess
+func FlowSessionFromRequest(r *http.Request) (FlowSession, bool) {
+	session, ok := r.Context().Value(flowSessionContextKey{}).(FlowSession)
+	return session, ok
+}
+
+// withFlowSession attaches session to the callback request
+func withFlowSession(r *http.Request, session FlowSession) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), flowSessionContextKey{}, session))
+}
+
+// signPayload returns the base64url HMAC-SHA256 of payload, for flow sessions and signed states
+func signPayload(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
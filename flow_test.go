@@ -0,0 +1,137 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+var flowKey = bytes.Repeat([]byte("k"), 32)
+
+func TestFlowSession(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		token, session, err := oauth2.NewFlowSession(oauth2.FlowSessionOptions{
+			Key:       flowKey,
+			PKCE:      true,
+			Nonce:     true,
+			ReturnURL: "/settings",
+		})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, session.State)
+		assert.Len(t, session.CodeVerifier, 43)
+		assert.NotEmpty(t, session.Nonce)
+
+		got, err := oauth2.VerifyFlowSession(flowKey, token, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, session.State, got.State)
+		assert.Equal(t, session.CodeVerifier, got.CodeVerifier)
+		assert.Equal(t, session.Nonce, got.Nonce)
+		assert.Equal(t, "/settings", got.ReturnURL)
+		assert.True(t, session.IssuedAt.Equal(got.IssuedAt))
+	})
+
+	t.Run("optional values are omitted", func(t *testing.T) {
+		_, session, err := oauth2.NewFlowSession(oauth2.FlowSessionOptions{Key: flowKey})
+		assert.NoError(t, err)
+		assert.Empty(t, session.CodeVerifier)
+		assert.Empty(t, session.Nonce)
+	})
+
+	t.Run("tampering is detected", func(t *testing.T) {
+		token, _, err := oauth2.NewFlowSession(oauth2.FlowSessionOptions{Key: flowKey, ReturnURL: "/settings"})
+		assert.NoError(t, err)
+		payload, signature, _ := strings.Cut(token, ".")
+
+		forged := base64.RawURLEncoding.EncodeToString(
+			[]byte(`{"state":"attacker","ret":"https://evil.example.com"}`),
+		)
+		for name, tampered := range map[string]string{
+			"payload":      forged + "." + signature,
+			"signature":    payload + "." + signature[:len(signature)-2] + "AA",
+			"no signature": payload,
+			"empty":        "",
+		} {
+			_, err := oauth2.VerifyFlowSession(flowKey, tampered, 0)
+			assert.ErrorIs(t, err, oauth2.ErrInvalidFlowSession, name)
+		}
+
+		_, err = oauth2.VerifyFlowSession(bytes.Repeat([]byte("x"), 32), token, 0)
+		assert.ErrorIs(t, err, oauth2.ErrInvalidFlowSession, "other key")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		token, _, err := oauth2.NewFlowSession(oauth2.FlowSessionOptions{Key: flowKey})
+		assert.NoError(t, err)
+		time.Sleep(time.Millisecond)
+
+		_, err = oauth2.VerifyFlowSession(flowKey, token, time.Nanosecond)
+		assert.ErrorIs(t, err, oauth2.ErrStateExpired)
+	})
+
+	t.Run("short key", func(t *testing.T) {
+		_, _, err := oauth2.NewFlowSession(oauth2.FlowSessionOptions{Key: []byte("short")})
+		assert.ErrorIs(t, err, oauth2.ErrInvalidFlowSession)
+	})
+}
+
+func TestCallbackHandler_FlowSession(t *testing.T) {
+	provider := &mockProvider{typ: "google", returnToken: dummyToken{}, returnUserInfo: dummyUser{}}
+	client := oauth2.NewClient(provider)
+
+	token, session, err := oauth2.NewFlowSession(oauth2.FlowSessionOptions{
+		Key:       flowKey,
+		PKCE:      true,
+		Nonce:     true,
+		ReturnURL: "/settings",
+	})
+	assert.NoError(t, err)
+
+	t.Run("state, verifier and return URL come from the session", func(t *testing.T) {
+		var got oauth2.FlowSession
+		handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+			FlowSessionKey:  flowKey,
+			SuccessRedirect: "/dashboard",
+			OnSuccess: func(w http.ResponseWriter, r *http.Request, result *oauth2.AuthResult) error {
+				got, _ = oauth2.FlowSessionFromRequest(r)
+				return nil
+			},
+		})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newCallbackRequest("/callback?provider=google&code=abc&state="+session.State, token))
+
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/settings", rec.Header().Get("Location"))
+		assert.Equal(t, session.CodeVerifier, provider.gotTokenOpts.CodeVerifier)
+		assert.Equal(t, session.Nonce, got.Nonce)
+	})
+
+	t.Run("state mismatch", func(t *testing.T) {
+		handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{FlowSessionKey: flowKey})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newCallbackRequest("/callback?provider=google&code=abc&state=other", token))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("tampered cookie", func(t *testing.T) {
+		var gotErr error
+		handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+			FlowSessionKey: flowKey,
+			OnError: func(w http.ResponseWriter, r *http.Request, err error) {
+				gotErr = err
+			},
+		})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newCallbackRequest("/callback?provider=google&code=abc&state="+session.State, session.State))
+		assert.ErrorIs(t, gotErr, oauth2.ErrInvalidFlowSession)
+	})
+}
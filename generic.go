@@ -0,0 +1,640 @@
+package oauth2
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GenericProviderType is the name of a generic provider whose GenericConfig.Name is empty
+const GenericProviderType ProviderType = "generic"
+
+// GenericDefaultScopes are requested by generic providers whose ProviderSetting.Scopes is empty.
+// Replace it during initialization, before any provider is used
+var GenericDefaultScopes = []string{"openid", "email", "profile"}
+
+type (
+	// GenericConfig describes a standards-compliant OpenID Connect provider (Okta, Auth0, Keycloak,
+	// self-hosted GitLab, ...) that does not warrant a dedicated package
+	GenericConfig struct {
+		// ProviderSetting holds the credentials, HTTP client and Scopes (default openid email profile).
+		// Its AuthURL, TokenURL and UserInfoURL override the matching Endpoints
+		ProviderSetting
+
+		// Name identifies the provider in NewClient routing, errors and GetProvider,
+		// defaults to GenericProviderType
+		Name ProviderType
+
+		// Endpoints must hold at least the authorization and token endpoints,
+		// use DiscoverEndpoints to read them from the provider
+		Endpoints Endpoints
+
+		// MapUserInfo builds the user from the raw userinfo response or id_token claims,
+		// nil reads the standard claims (sub, email, name, preferred_username, picture, gender)
+		MapUserInfo func(raw map[string]any) UserInfo
+	}
+
+	// genericProvider talks to the endpoints of a GenericConfig
+	genericProvider struct {
+		requester    *Requester
+		providerType ProviderType
+		clientID     string
+		clientSecret string
+		redirectURL  string
+		endpoints    Endpoints
+		mapUserInfo  func(raw map[string]any) UserInfo
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    AuthURLLimits
+		nameStrategy     NameStrategy
+		scopes           []string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
+	}
+
+	// genericUserInfo represents the standard claims returned by the userinfo endpoint or the id_token
+	genericUserInfo struct {
+		Subject           string    `json:"sub"`
+		Email             string    `json:"email"`
+		Name              string    `json:"name"`
+		PreferredUsername string    `json:"preferred_username"`
+		Picture           string    `json:"picture"`
+		Gender            string    `json:"gender"`
+		PhoneNumber       string    `json:"phone_number"`
+		Birthdate         string    `json:"birthdate"`
+		Locale            string    `json:"locale"`
+		EmailVerified     BoolClaim `json:"email_verified"`
+
+		raw          map[string]any
+		nameStrategy NameStrategy
+	}
+
+	// genericTokenInfo represents the token response of RFC 6749 section 5.1
+	genericTokenInfo struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+		TokenType    string `json:"token_type"`
+		IDToken      string `json:"id_token"`
+
+		// RefreshTokenExpiresIn is a common extension, zero when the provider does not send it
+		RefreshTokenExpiresIn int `json:"refresh_token_expires_in"`
+
+		issuedAt time.Time
+	}
+
+	// genericIDTokenClaims represents the id_token claims used to identify the user
+	genericIDTokenClaims struct {
+		Issuer            string          `json:"iss"`
+		Audience          json.RawMessage `json:"aud"`
+		Subject           string          `json:"sub"`
+		ExpiresAt         int64           `json:"exp"`
+		Email             string          `json:"email"`
+		Name              string          `json:"name"`
+		PreferredUsername string          `json:"preferred_username"`
+		Picture           string          `json:"picture"`
+		EmailVerified     BoolClaim       `json:"email_verified"`
+	}
+)
+
+// genericProvider must keep implementing Provider and the id_token login
+var (
+	_ Provider        = (*genericProvider)(nil)
+	_ IDTokenProvider = (*genericProvider)(nil)
+)
+
+// NewGenericProvider initializes a provider talking to the endpoints of config.
+// It is not registered with RegisterConstructor since it needs endpoints, pass it to NewClient instead
+//   - fails with ErrEndpointNotSet when the authorization or token endpoint is missing
+//   - fails with ErrInvalidRedirectURL when ProviderSetting.Validate rejects the redirect URL
+//
+// The generic package wraps it for providers configured from their discovery document
+//
+//	example:
+//	keycloak, err := oauth2.NewGenericProvider(oauth2.GenericConfig{
+//	    ProviderSetting: setting,
+//	    Name:            "keycloak",
+//	    Endpoints: oauth2.Endpoints{
+//	        AuthURL:     "https://sso.example.com/realms/main/protocol/openid-connect/auth",
+//	        TokenURL:    "https://sso.example.com/realms/main/protocol/openid-connect/token",
+//	        UserInfoURL: "https://sso.example.com/realms/main/protocol/openid-connect/userinfo",
+//	    },
+//	})
+//	if err != nil { ... }
+//	client := oauth2.NewClient(google.NewProvider(googleSetting), keycloak)
+func NewGenericProvider(config GenericConfig) (Provider, error) {
+	providerType := cmp.Or(config.Name, GenericProviderType)
+	config.Endpoints.AuthURL = cmp.Or(config.AuthURL, config.Endpoints.AuthURL)
+	config.Endpoints.TokenURL = cmp.Or(config.TokenURL, config.Endpoints.TokenURL)
+	if config.Endpoints.AuthURL == "" || config.Endpoints.TokenURL == "" {
+		return nil, WrapProviderError(
+			providerType,
+			ErrEndpointNotSet,
+			"authorization and token endpoints are required",
+		)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, WrapProviderError(providerType, err, "")
+	}
+
+	return &genericProvider{
+		requester:    NewRequester(config.ProviderSetting),
+		providerType: providerType,
+		clientID:     config.ClientID,
+		clientSecret: config.ClientSecret,
+		redirectURL:  config.RedirectURL,
+		endpoints:    config.Endpoints,
+		mapUserInfo:  config.MapUserInfo,
+
+		strictTokenType:  config.StrictTokenType,
+		authURLLimits:    config.AuthURLLimits,
+		nameStrategy:     config.NameStrategy,
+		scopes:           config.Scopes,
+		revocationMethod: cmp.Or(config.RevocationMethod, http.MethodPost),
+
+		userInfoURL:          cmp.Or(config.UserInfoURL, config.Endpoints.UserInfoURL),
+		userInfoFallbackURLs: config.UserInfoFallbackURLs,
+	}, nil
+}
+
+// GetUserInfo retrieves the user from the userinfo endpoint,
+// failing with ErrUnsupportedOperation when the provider has none
+func (p *genericProvider) GetUserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	if accessToken == "" {
+		return nil, WrapProviderError(p.providerType, ErrEmptyAccessToken, "")
+	}
+	if p.userInfoURL == "" {
+		return nil, WrapProviderError(
+			p.providerType,
+			ErrUnsupportedOperation,
+			"no userinfo endpoint",
+		)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.userInfoURL, nil)
+	if err != nil {
+		return nil, WrapProviderError(
+			p.providerType,
+			ErrUserInfoRequestFailed,
+			err.Error(),
+		)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	SetAcceptLanguage(req)
+
+	end := p.requester.Observe(ctx, p.providerType, OpGetUserInfo)
+	resp, err := p.requester.DoWithFallback(req, p.userInfoFallbackURLs)
+	end(resp, err)
+	if err != nil {
+		return nil, WrapProviderCause(p.providerType, ErrUserInfoRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, WrapResponseError(
+			p.providerType,
+			OpGetUserInfo,
+			ErrUserInfoRequestFailed,
+			resp,
+		)
+	}
+
+	if err := CheckJSONResponse(resp); err != nil {
+		return nil, WrapProviderCause(p.providerType, ErrUserInfoRequestFailed, err)
+	}
+	return p.decodeUserInfo(resp.Body)
+}
+
+// UserInfoFromIDToken builds the user from the id_token returned with the token.
+// It must come straight from the token endpoint, so the claims are checked but not the signature,
+// use oidc.VerifyIDToken with this provider as the key source for tokens from elsewhere
+func (p *genericProvider) UserInfoFromIDToken(token TokenInfo) (UserInfo, error) {
+	if token == nil || token.GetIDToken() == "" {
+		return nil, WrapProviderError(
+			p.providerType,
+			ErrInvalidIDToken,
+			"id_token is missing",
+		)
+	}
+
+	var claims genericIDTokenClaims
+	if err := DecodeJWTClaims(token.GetIDToken(), &claims); err != nil {
+		return nil, WrapProviderCause(p.providerType, ErrUserInfoRequestFailed, err)
+	}
+
+	var reason string
+	switch {
+	case p.endpoints.Issuer != "" && claims.Issuer != p.endpoints.Issuer:
+		reason = "unexpected issuer"
+	case !slices.Contains(decodeAudience(claims.Audience), p.clientID):
+		reason = "unexpected audience"
+	case time.Now().Unix() >= claims.ExpiresAt:
+		reason = "token expired"
+	case claims.Subject == "":
+		reason = "subject is missing"
+	}
+	if reason != "" {
+		return nil, WrapProviderError(p.providerType, ErrInvalidIDToken, reason)
+	}
+
+	var raw map[string]any
+	_ = DecodeJWTClaims(token.GetIDToken(), &raw)
+	if p.mapUserInfo != nil {
+		return p.mapRaw(raw)
+	}
+
+	return &genericUserInfo{
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+		Picture:           claims.Picture,
+		EmailVerified:     claims.EmailVerified,
+
+		raw:          raw,
+		nameStrategy: p.nameStrategy,
+	}, nil
+}
+
+// decodeUserInfo reads a userinfo response with MapUserInfo, or as standard claims without one
+func (p *genericProvider) decodeUserInfo(body []byte) (UserInfo, error) {
+	if p.mapUserInfo != nil {
+		var raw map[string]any
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, WrapProviderError(p.providerType, ErrUserInfoRequestFailed, err.Error())
+		}
+		return p.mapRaw(raw)
+	}
+
+	var userInfo genericUserInfo
+	if err := json.Unmarshal(body, &userInfo); err != nil {
+		return nil, WrapProviderError(p.providerType, ErrUserInfoRequestFailed, err.Error())
+	}
+	userInfo.raw = DecodeRawUserInfo(body)
+	userInfo.nameStrategy = p.nameStrategy
+
+	return &userInfo, nil
+}
+
+// mapRaw applies MapUserInfo, a nil result fails with ErrUserInfoRequestFailed
+func (p *genericProvider) mapRaw(raw map[string]any) (UserInfo, error) {
+	user := p.mapUserInfo(raw)
+	if user == nil {
+		return nil, WrapProviderError(p.providerType, ErrUserInfoRequestFailed, "MapUserInfo returned no user")
+	}
+	return user, nil
+}
+
+// GetAuthURL constructs the authorization URL at the discovered authorization endpoint
+//   - scopes from WithScopes are merged into ProviderSetting.Scopes (default openid email profile)
+//   - WithOfflineAccess(true) adds the offline_access scope
+//   - WithPrompt accepts none, login, consent, select_account and create
+//   - WithPKCE adds the S256 code_challenge, WithResource and WithClaimsRequest are sent as is
+func (p *genericProvider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...AuthOption,
+) (string, error) {
+	if p.redirectURL == "" {
+		return "", WrapProviderError(p.providerType, ErrRedirectURLNotSet, "")
+	}
+	if p.clientID == "" {
+		return "", WrapProviderError(p.providerType, ErrClientIDNotSet, "")
+	}
+
+	options := NewAuthOptions(opts...)
+	if err := ValidatePrompts(options.Prompts); err != nil {
+		return "", WrapProviderError(p.providerType, err, strings.Join(options.Prompts, " "))
+	}
+
+	scopes := p.scopes
+	if len(scopes) == 0 {
+		scopes = GenericDefaultScopes
+	}
+	extraScopes := options.Scopes
+	if options.OfflineAccess != nil && *options.OfflineAccess {
+		extraScopes = append(slices.Clone(extraScopes), "offline_access")
+	}
+
+	query := url.Values{}
+	query.Set("client_id", p.clientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("response_type", "code")
+	query.Set("scope", strings.Join(NormalizeScopes(scopes, extraScopes), " "))
+	query.Set("state", state)
+	if prompts := SupportedPrompts(
+		options.Prompts,
+		PromptNone,
+		PromptLogin,
+		PromptConsent,
+		PromptSelectAccount,
+		PromptCreate,
+	); len(prompts) > 0 {
+		query.Set("prompt", strings.Join(prompts, " "))
+	}
+	SetCodeChallenge(query, options.CodeVerifier)
+	SetNonce(query, options.Nonce)
+	SetAuthParams(query, options.AuthParams)
+	if err := SetResources(query, options.Resources); err != nil {
+		return "", WrapProviderError(p.providerType, err, strings.Join(options.Resources, " "))
+	}
+	if err := SetClaimsRequest(query, options.ClaimsRequest); err != nil {
+		return "", WrapProviderError(p.providerType, err, "")
+	}
+
+	return BuildAuthURL(p.providerType, p.endpoints.AuthURL, query, p.authURLLimits)
+}
+
+// GetToken exchanges the authorization code for tokens at the discovered token endpoint.
+// With WithResource, a JWT access token must be audience-restricted to the requested resources
+func (p *genericProvider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...AuthOption,
+) (TokenInfo, error) {
+	var tokenInfo genericTokenInfo
+	if code == "" {
+		return tokenInfo, WrapProviderError(p.providerType, ErrEmptyAuthCode, "")
+	}
+	ctx = WithoutRetry(ctx)
+
+	options := NewAuthOptions(opts...)
+	form := url.Values{}
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("grant_type", "authorization_code")
+	SetCodeVerifier(form, options.CodeVerifier)
+	if err := SetResources(form, options.Resources); err != nil {
+		return tokenInfo, WrapProviderError(
+			p.providerType,
+			err,
+			strings.Join(options.Resources, " "),
+		)
+	}
+
+	if err := p.requestToken(ctx, OpGetToken, form, &tokenInfo); err != nil {
+		return tokenInfo, err
+	}
+
+	if err := CheckResourceAudience(tokenInfo.AccessToken, options.Resources); err != nil {
+		return tokenInfo, WrapProviderError(p.providerType, err, "")
+	}
+
+	return tokenInfo, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access token at the discovered token endpoint
+func (p *genericProvider) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (TokenInfo, error) {
+	var tokenInfo genericTokenInfo
+
+	if refreshToken == "" {
+		return tokenInfo, WrapProviderError(p.providerType, ErrEmptyRefreshToken, "")
+	}
+
+	form := url.Values{}
+	form.Set("refresh_token", refreshToken)
+	form.Set("grant_type", "refresh_token")
+
+	if err := p.requestToken(ctx, OpRefreshToken, form, &tokenInfo); err != nil {
+		return tokenInfo, err
+	}
+
+	return tokenInfo, nil
+}
+
+// GetClientCredentialsToken requests an app-level token with the client credentials grant,
+// scopes are normalized and sent space-separated when given
+func (p *genericProvider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (TokenInfo, error) {
+	var tokenInfo genericTokenInfo
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if scopes := NormalizeScopes(scopes); len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+
+	if err := p.requestToken(ctx, OpClientCredentials, form, &tokenInfo); err != nil {
+		return tokenInfo, err
+	}
+
+	return tokenInfo, nil
+}
+
+// requestToken posts form with the client credentials (client_secret_post) to the token endpoint
+// and decodes the response into tokenInfo
+func (p *genericProvider) requestToken(ctx context.Context, op string, form url.Values, tokenInfo *genericTokenInfo) error {
+	form.Set("client_id", p.clientID)
+	form.Set("client_secret", p.clientSecret)
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		p.endpoints.TokenURL,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return WrapProviderError(
+			p.providerType,
+			ErrTokenRequestFailed,
+			err.Error(),
+		)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	end := p.requester.Observe(ctx, p.providerType, op)
+	resp, err := p.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return WrapProviderCause(p.providerType, ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return WrapResponseError(
+			p.providerType,
+			op,
+			ErrTokenRequestFailed,
+			resp,
+		)
+	}
+
+	if err := DecodeJSONResponse(resp, tokenInfo); err != nil {
+		return WrapProviderCause(p.providerType, ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	// a refresh keeps the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = form.Get("refresh_token")
+	}
+
+	if err := ValidateTokenType(tokenInfo.TokenType, p.strictTokenType); err != nil {
+		return WrapProviderError(p.providerType, err, tokenInfo.TokenType)
+	}
+
+	return nil
+}
+
+// RevokeToken revokes an access or refresh token at the discovered revocation endpoint (RFC 7009),
+// failing with ErrUnsupportedOperation when the provider has none
+func (p *genericProvider) RevokeToken(ctx context.Context, token string) error {
+	if token == "" {
+		return WrapProviderError(p.providerType, ErrTokenRevocationFailed, "token is empty")
+	}
+	if p.endpoints.RevocationURL == "" {
+		return WrapProviderError(
+			p.providerType,
+			ErrUnsupportedOperation,
+			"no revocation endpoint",
+		)
+	}
+
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("client_id", p.clientID)
+	form.Set("client_secret", p.clientSecret)
+
+	req, err := NewFormRequest(ctx, p.revocationMethod, p.endpoints.RevocationURL, form)
+	if err != nil {
+		return WrapProviderError(
+			p.providerType,
+			ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	resp, err := p.requester.Do(req)
+	if err != nil {
+		return WrapProviderCause(p.providerType, ErrTokenRevocationFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return WrapResponseError(
+			p.providerType,
+			OpRevokeToken,
+			ErrTokenRevocationFailed,
+			resp,
+		)
+	}
+
+	return nil
+}
+
+// CanRefresh reports whether token still holds a refresh token that has not expired
+func (p genericProvider) CanRefresh(token TokenInfo) bool { return CanRefresh(token) }
+
+// SigningKeys fetches the id_token signing keys at the discovered jwks_uri,
+// failing with ErrUnsupportedOperation when the provider has none
+func (p *genericProvider) SigningKeys(ctx context.Context) ([]PublicKeyInfo, error) {
+	if p.endpoints.JWKSURL == "" {
+		return nil, WrapProviderError(
+			p.providerType,
+			ErrUnsupportedOperation,
+			"no JWKS endpoint",
+		)
+	}
+
+	keys, err := p.requester.FetchJWKS(ctx, p.endpoints.JWKSURL)
+	if err != nil {
+		return nil, WrapProviderCause(p.providerType, ErrSigningKeysFailed, err)
+	}
+	return keys, nil
+}
+
+// Unlink is not supported, OpenID Connect has no standard endpoint to disconnect a user
+func (p *genericProvider) Unlink(ctx context.Context, accessToken string) error {
+	return WrapProviderError(p.providerType, ErrUnsupportedOperation, "unlink")
+}
+
+// GetProvider returns GenericConfig.Name, "generic" by default
+func (p genericProvider) GetProvider() ProviderType { return p.providerType }
+
+// GetRedirectURL returns the configured redirect URL
+func (p genericProvider) GetRedirectURL() string { return p.redirectURL }
+
+// GetID returns the subject identifier of the user
+func (u genericUserInfo) GetID() string { return u.Subject }
+
+// GetEmail returns the user's email address
+func (u genericUserInfo) GetEmail() string { return u.Email }
+
+// GetName returns the user's full name, preferred username or email depending on the NameStrategy
+func (u genericUserInfo) GetName() string {
+	return SelectName(u.nameStrategy, u.Name, u.PreferredUsername, u.Email)
+}
+
+// GetGender returns the user's gender
+func (u genericUserInfo) GetGender() string { return u.Gender }
+
+// GetGenderNormalized maps the gender claim to a Gender
+func (u genericUserInfo) GetGenderNormalized() Gender { return NormalizeGender(u.Gender) }
+
+// GetProfileImage returns the user's profile image URL
+func (u genericUserInfo) GetProfileImage() string { return u.Picture }
+
+// GetPhoneNumber returns the phone_number claim, empty without the phone scope
+func (u genericUserInfo) GetPhoneNumber() string { return u.PhoneNumber }
+
+// GetBirthday returns the YYYY-MM-DD birthdate claim, as MM-DD when the year is withheld as 0000
+func (u genericUserInfo) GetBirthday() string {
+	if monthDay, ok := strings.CutPrefix(u.Birthdate, "0000-"); ok {
+		return monthDay
+	}
+	return u.Birthdate
+}
+
+// GetLocale returns the locale claim, a BCP47 language tag (e.g. "en-US")
+func (u genericUserInfo) GetLocale() string { return u.Locale }
+
+// IsEmailVerified reports the email_verified claim
+func (u genericUserInfo) IsEmailVerified() bool { return bool(u.EmailVerified) }
+
+// GetRaw returns the decoded userinfo response or id_token claims
+func (u genericUserInfo) GetRaw() map[string]any { return u.raw }
+
+// GetAccessToken returns the OAuth2 access token
+func (t genericTokenInfo) GetAccessToken() string { return t.AccessToken }
+
+// GetRefreshToken returns the OAuth2 refresh token
+func (t genericTokenInfo) GetRefreshToken() string { return t.RefreshToken }
+
+// GetExpiry returns the token expiration time in seconds
+func (t genericTokenInfo) GetExpiry() int { return t.ExpiresIn }
+
+// HasRefreshToken reports whether a refresh token was issued
+func (t genericTokenInfo) HasRefreshToken() bool { return t.RefreshToken != "" }
+
+// GetRefreshTokenExpiresAt returns when the refresh token expires, zero when the provider sets no limit
+func (t genericTokenInfo) GetRefreshTokenExpiresAt() time.Time {
+	return ExpiryTime(t.issuedAt, t.RefreshTokenExpiresIn)
+}
+
+// HasExpiry reports whether the access token expires, false when expires_in was not returned
+func (t genericTokenInfo) HasExpiry() bool { return t.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (t genericTokenInfo) GetExpiresAt() time.Time { return ExpiryTime(t.issuedAt, t.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (t genericTokenInfo) IsExpired() bool { return Expired(t.GetExpiresAt()) }
+
+// GetScope returns the space-delimited scopes granted by the user
+func (t genericTokenInfo) GetScope() string { return t.Scope }
+
+// GetTokenType returns the token type (e.g. "Bearer")
+func (t genericTokenInfo) GetTokenType() string { return t.TokenType }
+
+// GetIDToken returns the OpenID Connect id_token
+func (t genericTokenInfo) GetIDToken() string { return t.IDToken }
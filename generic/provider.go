@@ -0,0 +1,40 @@
+package generic
+
+import (
+	"github.com/dings-things/oauth2"
+)
+
+// ProviderType is the default identifier of a generic provider, override it with Setting.Type
+const ProviderType = oauth2.GenericProviderType
+
+// Setting extends oauth2.ProviderSetting with the endpoints of any standards-compliant
+// OpenID Connect provider (Okta, Auth0, Keycloak, ...), usually from oauth2.DiscoverEndpoints
+type Setting struct {
+	oauth2.ProviderSetting
+
+	// Type identifies the provider in errors and GetProvider, defaults to ProviderType
+	Type oauth2.ProviderType
+
+	// Endpoints must hold at least the authorization and token endpoints
+	Endpoints oauth2.Endpoints
+
+	// MapUserInfo builds the user from the raw userinfo response or id_token claims,
+	// nil reads the standard claims
+	MapUserInfo func(raw map[string]any) oauth2.UserInfo
+}
+
+// NewProvider initializes a provider talking to the given endpoints, see oauth2.NewGenericProvider.
+// ProviderSetting.AuthURL, TokenURL and UserInfoURL override the discovered endpoints
+//
+//	example:
+//	endpoints, err := oauth2.DiscoverEndpoints(ctx, "https://dev-123.okta.com", nil)
+//	if err != nil { ... }
+//	provider, err := generic.NewProvider(generic.Setting{ProviderSetting: setting, Type: "okta", Endpoints: endpoints})
+func NewProvider(setting Setting) (oauth2.Provider, error) {
+	return oauth2.NewGenericProvider(oauth2.GenericConfig{
+		ProviderSetting: setting.ProviderSetting,
+		Name:            setting.Type,
+		Endpoints:       setting.Endpoints,
+		MapUserInfo:     setting.MapUserInfo,
+	})
+}
@@ -0,0 +1,302 @@
+package generic_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/generic"
+	"github.com/stretchr/testify/assert"
+)
+
+const issuer = "https://idp.example.com"
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// routes answers each request with the handler registered for its URL without the query
+type routes map[string]func(req *http.Request) (int, string)
+
+func (r routes) client(t *testing.T) *http.Client {
+	return &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		endpoint := *req.URL
+		endpoint.RawQuery = ""
+		handler, ok := r[endpoint.String()]
+		if !assert.True(t, ok, "unexpected request to %s", endpoint.String()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		status, body := handler(req)
+		return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+	})}
+}
+
+// discovered returns the endpoints a discovery document for issuer would list
+func discovered(t *testing.T) oauth2.Endpoints {
+	document, _ := json.Marshal(map[string]string{
+		"issuer":                 issuer,
+		"authorization_endpoint": issuer + "/authorize",
+		"token_endpoint":         issuer + "/token",
+		"userinfo_endpoint":      issuer + "/userinfo",
+		"jwks_uri":               issuer + "/jwks",
+		"revocation_endpoint":    issuer + "/revoke",
+	})
+	client := routes{
+		issuer + "/.well-known/openid-configuration": func(*http.Request) (int, string) {
+			return http.StatusOK, string(document)
+		},
+	}.client(t)
+
+	endpoints, err := oauth2.DiscoverEndpoints(context.Background(), issuer, client)
+	assert.NoError(t, err)
+	return endpoints
+}
+
+func newProvider(t *testing.T, r routes) oauth2.Provider {
+	provider, err := generic.NewProvider(generic.Setting{
+		ProviderSetting: oauth2.ProviderSetting{
+			Client:       r.client(t),
+			ClientID:     "client-id",
+			ClientSecret: "client-secret",
+			RedirectURL:  "https://app.example.com/callback",
+		},
+		Type:      "okta",
+		Endpoints: discovered(t),
+	})
+	assert.NoError(t, err)
+	return provider
+}
+
+// unsignedJWT builds a JWT carrying claims, the signature is never checked by the provider
+func unsignedJWT(claims map[string]any) string {
+	payload, _ := json.Marshal(claims)
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Run("missing endpoints", func(t *testing.T) {
+		_, err := generic.NewProvider(generic.Setting{
+			ProviderSetting: oauth2.ProviderSetting{RedirectURL: "https://app.example.com/callback"},
+			Endpoints:       oauth2.Endpoints{AuthURL: issuer + "/authorize"},
+		})
+		assert.ErrorIs(t, err, oauth2.ErrEndpointNotSet)
+		assert.ErrorContains(t, err, "generic provider")
+	})
+
+	t.Run("invalid redirect URL", func(t *testing.T) {
+		_, err := generic.NewProvider(generic.Setting{
+			ProviderSetting: oauth2.ProviderSetting{RedirectURL: "/callback"},
+			Endpoints:       discovered(t),
+		})
+		assert.ErrorIs(t, err, oauth2.ErrInvalidRedirectURL)
+	})
+
+	t.Run("type defaults to generic", func(t *testing.T) {
+		provider, err := generic.NewProvider(generic.Setting{Endpoints: discovered(t)})
+		assert.NoError(t, err)
+		assert.Equal(t, generic.ProviderType, provider.GetProvider())
+	})
+}
+
+func TestGenericProvider_GetAuthURL(t *testing.T) {
+	provider := newProvider(t, routes{})
+	assert.Equal(t, oauth2.ProviderType("okta"), provider.GetProvider())
+
+	authURL, err := provider.GetAuthURL(
+		context.Background(),
+		"state-123",
+		oauth2.WithOfflineAccess(true),
+		oauth2.WithPrompt(oauth2.PromptLogin),
+		oauth2.WithResource("https://api.example.com"),
+		oauth2.WithClaimsRequest(json.RawMessage(`{"id_token": {"email": null}}`)),
+		oauth2.WithNonce("nonce-123"),
+	)
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, issuer+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+
+	query := parsed.Query()
+	assert.Equal(t, "client-id", query.Get("client_id"))
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "state-123", query.Get("state"))
+	assert.Equal(t, "openid email profile offline_access", query.Get("scope"))
+	assert.Equal(t, "login", query.Get("prompt"))
+	assert.Equal(t, "https://api.example.com", query.Get("resource"))
+	assert.Equal(t, `{"id_token":{"email":null}}`, query.Get("claims"))
+	assert.Equal(t, "nonce-123", query.Get("nonce"))
+
+	t.Run("invalid resource", func(t *testing.T) {
+		_, err := provider.GetAuthURL(context.Background(), "state", oauth2.WithResource("api"))
+		assert.ErrorIs(t, err, oauth2.ErrInvalidResource)
+	})
+}
+
+func TestGenericProvider_GetToken(t *testing.T) {
+	accessToken := unsignedJWT(map[string]any{"aud": "https://api.example.com"})
+	provider := newProvider(t, routes{
+		issuer + "/token": func(req *http.Request) (int, string) {
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "authorization_code", req.PostForm.Get("grant_type"))
+			assert.Equal(t, "client-secret", req.PostForm.Get("client_secret"))
+			assert.Equal(t, "https://api.example.com", req.PostForm.Get("resource"))
+			if req.PostForm.Get("code") == "bad-code" {
+				return http.StatusBadRequest, `{"error":"invalid_grant"}`
+			}
+			return http.StatusOK, `{"access_token":"` + accessToken + `","token_type":"Bearer","expires_in":3600,"refresh_token":"refresh"}`
+		},
+	})
+
+	token, err := provider.GetToken(context.Background(), "code", oauth2.WithResource("https://api.example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, accessToken, token.GetAccessToken())
+	assert.True(t, token.HasRefreshToken())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), token.GetExpiresAt(), time.Minute)
+
+	t.Run("audience does not match the resource", func(t *testing.T) {
+		_, err := provider.GetToken(context.Background(), "code", oauth2.WithResource("https://api.example.com"),
+			oauth2.WithResource("https://other.example.com"))
+		assert.ErrorIs(t, err, oauth2.ErrInvalidResource)
+	})
+
+	t.Run("token endpoint error", func(t *testing.T) {
+		_, err := provider.GetToken(context.Background(), "bad-code", oauth2.WithResource("https://api.example.com"))
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.ErrorContains(t, err, "okta provider")
+	})
+}
+
+func TestGenericProvider_RefreshToken(t *testing.T) {
+	provider := newProvider(t, routes{
+		issuer + "/token": func(req *http.Request) (int, string) {
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "refresh_token", req.PostForm.Get("grant_type"))
+			if req.PostForm.Get("refresh_token") == "rotating" {
+				return http.StatusOK, `{"access_token":"access","token_type":"Bearer","refresh_token":"rotated"}`
+			}
+			return http.StatusOK, `{"access_token":"access","token_type":"Bearer"}`
+		},
+	})
+
+	t.Run("new refresh token returned", func(t *testing.T) {
+		token, err := provider.RefreshToken(context.Background(), "rotating")
+		assert.NoError(t, err)
+		assert.Equal(t, "rotated", token.GetRefreshToken())
+	})
+
+	t.Run("no refresh token returned, reuse old", func(t *testing.T) {
+		token, err := provider.RefreshToken(context.Background(), "refresh")
+		assert.NoError(t, err)
+		assert.Equal(t, "refresh", token.GetRefreshToken())
+	})
+}
+
+func TestGenericProvider_GetClientCredentialsToken(t *testing.T) {
+	provider := newProvider(t, routes{
+		issuer + "/token": func(req *http.Request) (int, string) {
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "client_credentials", req.PostForm.Get("grant_type"))
+			assert.Equal(t, "client-secret", req.PostForm.Get("client_secret"))
+			assert.Equal(t, "api.read api.write", req.PostForm.Get("scope"))
+			return http.StatusOK, `{"access_token":"app-token","token_type":"Bearer","expires_in":300}`
+		},
+	})
+
+	token, err := provider.GetClientCredentialsToken(context.Background(), "api.read", "api.write api.read")
+	assert.NoError(t, err)
+	assert.Equal(t, "app-token", token.GetAccessToken())
+	assert.False(t, token.HasRefreshToken())
+}
+
+func TestGenericProvider_GetUserInfo(t *testing.T) {
+	provider := newProvider(t, routes{
+		issuer + "/userinfo": func(req *http.Request) (int, string) {
+			assert.Equal(t, "Bearer access", req.Header.Get("Authorization"))
+			return http.StatusOK, `{"sub":"user-1","email":"test@example.com","email_verified":true,"name":"Test User","picture":"https://img"}`
+		},
+	})
+
+	user, err := provider.GetUserInfo(context.Background(), "access")
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", user.GetID())
+	assert.Equal(t, "test@example.com", user.GetEmail())
+	assert.Equal(t, "Test User", user.GetName())
+	assert.Equal(t, "https://img", user.GetProfileImage())
+	assert.True(t, user.IsEmailVerified())
+
+	_, err = provider.GetUserInfo(context.Background(), "")
+	assert.ErrorIs(t, err, oauth2.ErrEmptyAccessToken)
+}
+
+func TestGenericProvider_UserInfoFromIDToken(t *testing.T) {
+	provider := newProvider(t, routes{
+		issuer + "/token": func(*http.Request) (int, string) {
+			idToken := unsignedJWT(map[string]any{
+				"iss":                issuer,
+				"aud":                []string{"client-id", "other"},
+				"sub":                "user-1",
+				"exp":                time.Now().Add(time.Hour).Unix(),
+				"preferred_username": "tester",
+			})
+			return http.StatusOK, `{"access_token":"access","token_type":"Bearer","id_token":"` + idToken + `"}`
+		},
+	})
+	idTokenProvider, ok := provider.(oauth2.IDTokenProvider)
+	assert.True(t, ok)
+
+	token, err := provider.GetToken(context.Background(), "code")
+	assert.NoError(t, err)
+
+	user, err := idTokenProvider.UserInfoFromIDToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", user.GetID())
+	assert.Equal(t, "tester", user.GetName())
+	assert.False(t, user.IsEmailVerified(), "the id_token has no email_verified claim")
+
+	_, err = idTokenProvider.UserInfoFromIDToken(nil)
+	assert.ErrorIs(t, err, oauth2.ErrInvalidIDToken)
+}
+
+func TestGenericProvider_RevokeToken(t *testing.T) {
+	provider := newProvider(t, routes{
+		issuer + "/revoke": func(req *http.Request) (int, string) {
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "refresh", req.PostForm.Get("token"))
+			return http.StatusOK, ""
+		},
+	})
+	assert.NoError(t, provider.RevokeToken(context.Background(), "refresh"))
+
+	t.Run("no revocation endpoint", func(t *testing.T) {
+		endpoints := discovered(t)
+		endpoints.RevocationURL = ""
+		provider, err := generic.NewProvider(generic.Setting{Endpoints: endpoints})
+		assert.NoError(t, err)
+
+		err = provider.RevokeToken(context.Background(), "refresh")
+		assert.ErrorIs(t, err, oauth2.ErrUnsupportedOperation)
+	})
+}
+
+func TestGenericProvider_SigningKeys(t *testing.T) {
+	provider := newProvider(t, routes{
+		issuer + "/jwks": func(*http.Request) (int, string) {
+			return http.StatusOK, `{"keys":[{"kty":"RSA","kid":"kid-1","alg":"RS256","n":"AQAB","e":"AQAB"}]}`
+		},
+	})
+
+	keys, err := provider.SigningKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, "kid-1", keys[0].KeyID)
+}
@@ -0,0 +1,161 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+// gitlabUser is a caller-defined UserInfo built by a MapUserInfo function
+type gitlabUser struct {
+	id       string
+	username string
+	email    string
+	avatar   string
+}
+
+func (u gitlabUser) GetID() string                      { return u.id }
+func (u gitlabUser) GetEmail() string                   { return u.email }
+func (u gitlabUser) GetName() string                    { return u.username }
+func (u gitlabUser) GetGender() string                  { return "" }
+func (u gitlabUser) GetGenderNormalized() oauth2.Gender { return oauth2.GenderUnknown }
+func (u gitlabUser) GetProfileImage() string            { return u.avatar }
+func (u gitlabUser) GetPhoneNumber() string             { return "" }
+func (u gitlabUser) GetBirthday() string                { return "" }
+func (u gitlabUser) GetLocale() string                  { return "" }
+func (u gitlabUser) IsEmailVerified() bool              { return false }
+func (u gitlabUser) GetRaw() map[string]any             { return nil }
+
+// mapGitLabUser reads the non-standard fields of a self-hosted GitLab userinfo response
+func mapGitLabUser(raw map[string]any) oauth2.UserInfo {
+	id, _ := raw["sub"].(string)
+	username, _ := raw["nickname"].(string)
+	email, _ := raw["email"].(string)
+	avatar, _ := raw["picture"].(string)
+	return gitlabUser{id: id, username: username, email: email, avatar: avatar}
+}
+
+func gitlabEndpoints() oauth2.Endpoints {
+	return oauth2.Endpoints{
+		Issuer:      "https://gitlab.example.com",
+		AuthURL:     "https://gitlab.example.com/oauth/authorize",
+		TokenURL:    "https://gitlab.example.com/oauth/token",
+		UserInfoURL: "https://gitlab.example.com/oauth/userinfo",
+	}
+}
+
+func TestNewGenericProvider(t *testing.T) {
+	ctx := context.Background()
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "https://gitlab.example.com/oauth/userinfo", req.URL.String())
+		assert.Equal(t, "Bearer access", req.Header.Get("Authorization"))
+		body := `{"sub":"42","nickname":"octo","email":"octo@example.com","picture":"https://img",` +
+			`"phone_number":"+1 555 0100","birthdate":"0000-01-31","locale":"en-US","groups":["dev"]}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+	})}
+
+	t.Run("custom mapper routed through NewClient", func(t *testing.T) {
+		provider, err := oauth2.NewGenericProvider(oauth2.GenericConfig{
+			ProviderSetting: oauth2.ProviderSetting{
+				Client:      client,
+				ClientID:    "client-id",
+				RedirectURL: "https://app.example.com/callback",
+				Scopes:      []string{"openid", "read_user"},
+			},
+			Name:        "gitlab",
+			Endpoints:   gitlabEndpoints(),
+			MapUserInfo: mapGitLabUser,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, oauth2.ProviderType("gitlab"), provider.GetProvider())
+
+		oauthClient := oauth2.NewClient(provider)
+		user, err := oauthClient.RequestUserInfo(ctx, "gitlab", "access")
+		assert.NoError(t, err)
+		assert.Equal(t, gitlabUser{id: "42", username: "octo", email: "octo@example.com", avatar: "https://img"}, user)
+
+		authURL := oauthClient.RequestAuthURL(ctx, "gitlab", "state")
+		assert.True(t, strings.HasPrefix(authURL, "https://gitlab.example.com/oauth/authorize?"))
+		assert.Contains(t, authURL, "scope=openid+read_user")
+	})
+
+	t.Run("standard claims without a mapper", func(t *testing.T) {
+		provider, err := oauth2.NewGenericProvider(oauth2.GenericConfig{
+			ProviderSetting: oauth2.ProviderSetting{
+				Client:       client,
+				NameStrategy: oauth2.PreferNickname,
+			},
+			Endpoints: gitlabEndpoints(),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, oauth2.GenericProviderType, provider.GetProvider())
+
+		user, err := provider.GetUserInfo(ctx, "access")
+		assert.NoError(t, err)
+		assert.Equal(t, "42", user.GetID())
+		assert.Equal(t, "octo@example.com", user.GetEmail())
+		assert.Equal(t, "https://img", user.GetProfileImage())
+		assert.Equal(t, "+1 555 0100", user.GetPhoneNumber())
+		assert.Equal(t, "01-31", user.GetBirthday(), "a withheld year is dropped")
+		assert.Equal(t, "en-US", user.GetLocale())
+	})
+
+	t.Run("mapper applied to id_token claims", func(t *testing.T) {
+		payload, _ := json.Marshal(map[string]any{
+			"iss":      "https://gitlab.example.com",
+			"aud":      "client-id",
+			"sub":      "42",
+			"exp":      time.Now().Add(time.Hour).Unix(),
+			"nickname": "octo",
+		})
+		idToken := "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+		tokenClient := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"access_token":"access","token_type":"Bearer","id_token":"` + idToken + `"}`
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+		})}
+
+		provider, err := oauth2.NewGenericProvider(oauth2.GenericConfig{
+			ProviderSetting: oauth2.ProviderSetting{Client: tokenClient, ClientID: "client-id"},
+			Name:            "gitlab",
+			Endpoints:       gitlabEndpoints(),
+			MapUserInfo:     mapGitLabUser,
+		})
+		assert.NoError(t, err)
+
+		token, err := provider.GetToken(ctx, "code")
+		assert.NoError(t, err)
+		user, err := provider.(oauth2.IDTokenProvider).UserInfoFromIDToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "octo", user.GetName())
+	})
+
+	t.Run("mapper returning nil", func(t *testing.T) {
+		provider, err := oauth2.NewGenericProvider(oauth2.GenericConfig{
+			ProviderSetting: oauth2.ProviderSetting{Client: client},
+			Endpoints:       gitlabEndpoints(),
+			MapUserInfo:     func(map[string]any) oauth2.UserInfo { return nil },
+		})
+		assert.NoError(t, err)
+
+		_, err = provider.GetUserInfo(ctx, "access")
+		assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+	})
+
+	t.Run("missing token endpoint", func(t *testing.T) {
+		_, err := oauth2.NewGenericProvider(oauth2.GenericConfig{
+			Name:      "gitlab",
+			Endpoints: oauth2.Endpoints{AuthURL: "https://gitlab.example.com/oauth/authorize"},
+		})
+		assert.ErrorIs(t, err, oauth2.ErrEndpointNotSet)
+		assert.ErrorContains(t, err, "gitlab provider")
+	})
+}
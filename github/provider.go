@@ -0,0 +1,535 @@
+package github
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// ProviderType is the identifier for the GitHub OAuth2 provider
+	//   - REFS : https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps
+	ProviderType oauth2.ProviderType = "github"
+
+	// UserInfoURL is the endpoint to retrieve the authenticated user's profile
+	UserInfoURL = "https://api.github.com/user"
+
+	// EmailsURL is the endpoint listing the user's email addresses (requires user:email).
+	// With ProviderSetting.UserInfoURL set, the emails are read from its /emails sub-path instead
+	EmailsURL = "https://api.github.com/user/emails"
+
+	// AuthURL is the endpoint to start the OAuth2 authorization flow
+	AuthURL = "https://github.com/login/oauth/authorize"
+
+	// TokenURL is the endpoint to exchange the authorization code for an access token
+	TokenURL = "https://github.com/login/oauth/access_token"
+
+	// RevokeURL is the endpoint deleting an OAuth app token, the client ID is appended as a path segment
+	RevokeURL = "https://api.github.com/applications"
+
+	// apiAcceptHeader is the media type recommended for REST API calls
+	apiAcceptHeader = "application/vnd.github+json"
+)
+
+// DefaultScopes are requested when ProviderSetting.Scopes is empty, WithScopes adds to them.
+// Replace it during initialization, before any provider is used
+var DefaultScopes = []string{"read:user", "user:email"}
+
+type (
+	// provider holds the configuration for GitHub's OAuth2 implementation
+	provider struct {
+		requester    *oauth2.Requester
+		clientID     string
+		clientSecret string
+		redirectURL  string
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
+		emailsURL            string
+	}
+
+	// userInfo represents the user information returned from GitHub
+	userInfo struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		AvatarURL string `json:"avatar_url"`
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
+	}
+
+	// email represents an entry of the /user/emails response
+	email struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+
+	// tokenInfo represents the token information returned from GitHub.
+	// GitHub answers token errors with 200 and the error fields set
+	tokenInfo struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+		TokenType    string `json:"token_type"`
+
+		// RefreshTokenExpiresIn is only sent for GitHub Apps with expiring user tokens
+		RefreshTokenExpiresIn int `json:"refresh_token_expires_in"`
+
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+
+		issuedAt time.Time
+	}
+)
+
+// provider must keep implementing oauth2.Provider
+var _ oauth2.Provider = (*provider)(nil)
+
+func init() {
+	oauth2.RegisterConstructor(ProviderType, NewProvider)
+}
+
+// NewProvider initializes and returns a new GitHub OAuth2 provider
+func NewProvider(setting oauth2.ProviderSetting) oauth2.Provider {
+	return &provider{
+		requester:    oauth2.NewRequester(setting),
+		clientID:     setting.ClientID,
+		clientSecret: setting.ClientSecret,
+		redirectURL:  setting.RedirectURL,
+
+		strictTokenType:  setting.StrictTokenType,
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodDelete),
+
+		authURL:  cmp.Or(setting.AuthURL, AuthURL),
+		tokenURL: cmp.Or(setting.TokenURL, TokenURL),
+
+		userInfoURL:          cmp.Or(setting.UserInfoURL, UserInfoURL),
+		userInfoFallbackURLs: setting.UserInfoFallbackURLs,
+		emailsURL:            emailsURL(setting.UserInfoURL),
+	}
+}
+
+// emailsURL resolves the /user/emails endpoint next to a configured user info endpoint,
+// so a GitHub Enterprise base (https://github.example.com/api/v3/user) is kept
+func emailsURL(userInfoURL string) string {
+	if userInfoURL == "" {
+		return EmailsURL
+	}
+	return strings.TrimSuffix(userInfoURL, "/") + "/emails"
+}
+
+// NewProviderWithError is NewProvider failing with ErrClientIDNotSet, ErrClientSecretNotSet
+// or ErrInvalidRedirectURL instead of building a provider whose calls cannot succeed
+func NewProviderWithError(setting oauth2.ProviderSetting) (oauth2.Provider, error) {
+	if err := setting.ValidateCredentials(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+	return NewProvider(setting), nil
+}
+
+// GetUserInfo retrieves the GitHub user's profile using the access token.
+// When the public profile has no email, the primary verified address from /user/emails is used
+func (g *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
+	if accessToken == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAccessToken, "")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.userInfoURL, nil)
+	if err != nil {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrUserInfoRequestFailed,
+			err.Error(),
+		)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", apiAcceptHeader)
+	oauth2.SetAcceptLanguage(req)
+
+	end := g.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+	resp, err := g.requester.DoWithFallback(req, g.userInfoFallbackURLs)
+	end(resp, err)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpGetUserInfo,
+			oauth2.ErrUserInfoRequestFailed,
+			resp,
+		)
+	}
+
+	var userInfo userInfo
+	if err := oauth2.DecodeJSONResponse(resp, &userInfo); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	if userInfo.Email == "" {
+		primaryEmail, err := g.primaryEmail(ctx, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		userInfo.Email = primaryEmail
+	}
+
+	userInfo.raw = oauth2.DecodeRawUserInfo(resp.Body)
+	userInfo.nameStrategy = g.nameStrategy
+
+	return &userInfo, nil
+}
+
+// primaryEmail returns the primary verified address across all /user/emails pages,
+// or an empty string when there is none
+func (g *provider) primaryEmail(ctx context.Context, accessToken string) (string, error) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+accessToken)
+	header.Set("Accept", apiAcceptHeader)
+
+	var primary string
+	err := g.requester.FetchAllPages(ctx, g.emailsURL, header, func(body []byte) error {
+		var emails []email
+		if err := json.Unmarshal(body, &emails); err != nil {
+			return err
+		}
+		for _, email := range emails {
+			if email.Primary && email.Verified {
+				primary = email.Email
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	return primary, nil
+}
+
+// GetAuthURL constructs the GitHub OAuth2 authorization URL
+//   - ProviderSetting.Scopes replaces the read:user user:email defaults, WithScopes adds to them
+//   - WithPrompt forwards select_account
+//   - WithPKCE adds the S256 code_challenge
+//   - WithOfflineAccess is ignored since refresh tokens depend on the app's token expiration setting
+func (g *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
+	if g.redirectURL == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
+	}
+	if g.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+
+	options := oauth2.NewAuthOptions(opts...)
+	if err := oauth2.ValidatePrompts(options.Prompts); err != nil {
+		return "", oauth2.WrapProviderError(ProviderType, err, strings.Join(options.Prompts, " "))
+	}
+
+	scopes := g.scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	query := url.Values{}
+	query.Set("client_id", g.clientID)
+	query.Set("redirect_uri", g.redirectURL)
+	query.Set("scope", strings.Join(oauth2.NormalizeScopes(scopes, options.Scopes), " "))
+	query.Set("state", state)
+	if prompts := oauth2.SupportedPrompts(
+		options.Prompts,
+		oauth2.PromptSelectAccount,
+	); len(prompts) > 0 {
+		query.Set("prompt", strings.Join(prompts, " "))
+	}
+	oauth2.SetCodeChallenge(query, options.CodeVerifier)
+	oauth2.SetAuthParams(query, options.AuthParams)
+
+	return oauth2.BuildAuthURL(ProviderType, g.authURL, query, g.authURLLimits)
+}
+
+// GetToken exchanges the authorization code for an access token from GitHub
+func (g *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	var tokenInfo tokenInfo
+	if code == "" {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
+	}
+	ctx = oauth2.WithoutRetry(ctx)
+
+	form := url.Values{}
+	form.Set("code", code)
+	form.Set("client_id", g.clientID)
+	form.Set("client_secret", g.clientSecret)
+	form.Set("redirect_uri", g.redirectURL)
+	oauth2.SetCodeVerifier(form, oauth2.NewAuthOptions(opts...).CodeVerifier)
+
+	return g.requestToken(ctx, oauth2.OpGetToken, form)
+}
+
+// RefreshToken exchanges a refresh token for a new access token from GitHub.
+// Only GitHub Apps with expiring user tokens issue refresh tokens
+func (g *provider) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (oauth2.TokenInfo, error) {
+	if refreshToken == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyRefreshToken, "")
+	}
+
+	form := url.Values{}
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", g.clientID)
+	form.Set("client_secret", g.clientSecret)
+	form.Set("grant_type", "refresh_token")
+
+	return g.requestToken(ctx, oauth2.OpRefreshToken, form)
+}
+
+// requestToken posts form to the token endpoint, asking for JSON instead of the default
+// form-encoded body, and reports errors GitHub returns with a 200 status
+func (g *provider) requestToken(ctx context.Context, op string, form url.Values) (oauth2.TokenInfo, error) {
+	var tokenInfo tokenInfo
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, g.tokenURL, form)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRequestFailed,
+			err.Error(),
+		)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	end := g.requester.Observe(ctx, ProviderType, op)
+	resp, err := g.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
+			ProviderType,
+			op,
+			oauth2.ErrTokenRequestFailed,
+			resp,
+		)
+	}
+
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	// a refresh keeps the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = form.Get("refresh_token")
+	}
+
+	if tokenInfo.Error != "" {
+		return tokenInfo, oauth2.WrapResponseError(
+			ProviderType,
+			op,
+			oauth2.ErrTokenRequestFailed,
+			resp,
+		)
+	}
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, g.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
+	return tokenInfo, nil
+}
+
+// RevokeToken deletes the access token through the OAuth app API,
+// authenticating with the client ID and secret
+func (g *provider) RevokeToken(ctx context.Context, accessToken string) error {
+	if accessToken == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRevocationFailed, "token is empty")
+	}
+
+	body, err := json.Marshal(map[string]string{"access_token": accessToken})
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	endpoint, err := url.JoinPath(RevokeURL, g.clientID, "token")
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, g.revocationMethod, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+	req.SetBasicAuth(g.clientID, g.clientSecret)
+	req.Header.Set("Accept", apiAcceptHeader)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRevocationFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusNoContent {
+		return oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpRevokeToken,
+			oauth2.ErrTokenRevocationFailed,
+			resp,
+		)
+	}
+
+	return nil
+}
+
+// CanRefresh reports whether token still holds a refresh token that has not expired
+func (g provider) CanRefresh(token oauth2.TokenInfo) bool { return oauth2.CanRefresh(token) }
+
+// SigningKeys is not supported since GitHub does not publish a JWKS
+func (g provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	return nil, oauth2.WrapProviderError(
+		ProviderType,
+		oauth2.ErrUnsupportedOperation,
+		"no JWKS endpoint",
+	)
+}
+
+// GetClientCredentialsToken is not supported, GitHub Apps authenticate with an installation
+// token obtained from a signed JWT instead
+func (g provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrGrantNotSupported, "client_credentials")
+}
+
+// Unlink is not supported, GitHub has no endpoint to disconnect a user from the app
+func (g provider) Unlink(ctx context.Context, accessToken string) error {
+	return oauth2.WrapProviderError(ProviderType, oauth2.ErrUnsupportedOperation, "unlink")
+}
+
+// GetProvider returns the provider type ("github")
+func (g provider) GetProvider() oauth2.ProviderType { return ProviderType }
+
+// GetRedirectURL returns the configured redirect URL
+func (g provider) GetRedirectURL() string { return g.redirectURL }
+
+// GetID returns the user's GitHub ID
+func (g userInfo) GetID() string { return strconv.FormatInt(g.ID, 10) }
+
+// GetNumericID returns the user's GitHub ID in its native numeric form
+func (g userInfo) GetNumericID() (int64, bool) { return g.ID, true }
+
+// GetEmail returns the user's public or primary verified email address
+func (g userInfo) GetEmail() string { return g.Email }
+
+// GetName returns the user's name, login or email depending on the NameStrategy.
+// The login is treated as the nickname
+func (g userInfo) GetName() string {
+	return oauth2.SelectName(g.nameStrategy, g.Name, g.Login, g.Email)
+}
+
+// GetGender returns an empty string since GitHub has no gender field
+func (g userInfo) GetGender() string { return "" }
+
+// GetGenderNormalized returns oauth2.GenderUnknown since there is no gender
+func (g userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.GenderUnknown }
+
+// GetProfileImage returns the user's avatar URL
+func (g userInfo) GetProfileImage() string { return g.AvatarURL }
+
+// GetPhoneNumber returns an empty string since GitHub has no phone number field
+func (g userInfo) GetPhoneNumber() string { return "" }
+
+// GetBirthday returns an empty string since GitHub has no birthday field
+func (g userInfo) GetBirthday() string { return "" }
+
+// GetLocale returns an empty string since GitHub does not share a locale
+func (g userInfo) GetLocale() string { return "" }
+
+// IsEmailVerified reports whether an email is set: GitHub only lets users make a verified
+// address public, and a fallback from /user/emails is the primary verified one
+func (g userInfo) IsEmailVerified() bool { return g.Email != "" }
+
+// GetRaw returns the decoded /user response (e.g. "company", "blog"),
+// it lacks the email when it was read from /user/emails
+func (g userInfo) GetRaw() map[string]any { return g.raw }
+
+// GetAccessToken returns the OAuth2 access token
+func (g tokenInfo) GetAccessToken() string { return g.AccessToken }
+
+// GetRefreshToken returns the OAuth2 refresh token
+func (g tokenInfo) GetRefreshToken() string { return g.RefreshToken }
+
+// GetExpiry returns the token expiration time in seconds, 0 for non-expiring OAuth app tokens
+func (g tokenInfo) GetExpiry() int { return g.ExpiresIn }
+
+// HasRefreshToken reports whether a refresh token was issued
+func (g tokenInfo) HasRefreshToken() bool { return g.RefreshToken != "" }
+
+// GetRefreshTokenExpiresAt returns when the refresh token expires, zero when unknown
+func (g tokenInfo) GetRefreshTokenExpiresAt() time.Time {
+	return oauth2.ExpiryTime(g.issuedAt, g.RefreshTokenExpiresIn)
+}
+
+// HasExpiry reports whether the access token expires, classic OAuth app tokens do not
+func (g tokenInfo) HasExpiry() bool { return g.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (g tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(g.issuedAt, g.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (g tokenInfo) IsExpired() bool { return oauth2.Expired(g.GetExpiresAt()) }
+
+// GetScope returns the comma-delimited scopes granted by the user
+func (g tokenInfo) GetScope() string { return g.Scope }
+
+// GetTokenType returns the token type (e.g. "bearer")
+func (g tokenInfo) GetTokenType() string { return g.TokenType }
+
+// GetIDToken is always empty since GitHub does not support OpenID Connect
+func (g tokenInfo) GetIDToken() string { return "" }
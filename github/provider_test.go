@@ -0,0 +1,361 @@
+package github_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/github"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newMockClient(fn roundTripperFunc) *http.Client {
+	return &http.Client{Transport: fn}
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestGitHubProvider_GetUserInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		emails     []string
+		wantEmail  string
+		wantVerify bool
+		wantCalls  int
+		wantErr    error
+		wantName   string
+		userStatus int
+	}{
+		{
+			name:       "public email",
+			user:       `{"id":42,"login":"octocat","name":"The Octocat","email":"octo@example.com"}`,
+			wantEmail:  "octo@example.com",
+			wantVerify: true,
+			wantName:   "The Octocat",
+			wantCalls:  1,
+			userStatus: http.StatusOK,
+		},
+		{
+			name: "primary verified email fallback",
+			user: `{"id":42,"login":"octocat","email":null}`,
+			emails: []string{
+				`[{"email":"old@example.com","primary":false,"verified":true}]`,
+				`[{"email":"unverified@example.com","primary":true,"verified":false},` +
+					`{"email":"primary@example.com","primary":true,"verified":true}]`,
+			},
+			wantEmail:  "primary@example.com",
+			wantVerify: true,
+			wantName:   "octocat",
+			wantCalls:  3,
+			userStatus: http.StatusOK,
+		},
+		{
+			name:       "no primary verified email",
+			user:       `{"id":42,"login":"octocat"}`,
+			emails:     []string{`[{"email":"unverified@example.com","primary":true,"verified":false}]`},
+			wantEmail:  "",
+			wantName:   "octocat",
+			wantCalls:  2,
+			userStatus: http.StatusOK,
+		},
+		{
+			name:       "unauthorized",
+			user:       `{"message":"Bad credentials"}`,
+			wantErr:    oauth2.ErrUserInfoRequestFailed,
+			wantCalls:  1,
+			userStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				calls++
+				assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+				if req.URL.String() == github.UserInfoURL {
+					return jsonResponse(tt.userStatus, tt.user), nil
+				}
+
+				page := 0
+				if p := req.URL.Query().Get("page"); p != "" {
+					page = int(p[0] - '0')
+				}
+				resp := jsonResponse(http.StatusOK, tt.emails[page])
+				if page+1 < len(tt.emails) {
+					resp.Header.Set("Link", `<`+github.EmailsURL+`?page=1>; rel="next"`)
+				}
+				return resp, nil
+			})
+
+			provider := github.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			info, err := provider.GetUserInfo(context.Background(), "token")
+			assert.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "42", info.GetID())
+			assert.Equal(t, tt.wantEmail, info.GetEmail())
+			assert.Equal(t, tt.wantVerify, info.IsEmailVerified())
+			assert.Equal(t, tt.wantName, info.GetName())
+
+			numericID, ok := oauth2.NumericID(info)
+			assert.True(t, ok)
+			assert.Equal(t, int64(42), numericID)
+		})
+	}
+}
+
+func TestGitHubProvider_GetUserInfo_EnterpriseBase(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v3/user":
+			_, _ = w.Write([]byte(`{"id":42,"login":"octocat"}`))
+		case "/api/v3/user/emails":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `<`+server.URL+`/api/v3/user/emails?page=2>; rel="next"`)
+				_, _ = w.Write([]byte(`[{"email":"old@example.com","verified":true}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"email":"octocat@example.com","primary":true,"verified":true}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := github.NewProvider(oauth2.ProviderSetting{
+		Client:      server.Client(),
+		UserInfoURL: server.URL + "/api/v3/user",
+	})
+
+	info, err := provider.GetUserInfo(context.Background(), "token")
+	assert.NoError(t, err)
+	assert.Equal(t, "octocat@example.com", info.GetEmail())
+	assert.True(t, info.IsEmailVerified())
+}
+
+func TestGitHubProvider_GetToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantErr  error
+		wantCode string
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body:   `{"access_token":"gho_token","token_type":"bearer","scope":"read:user,user:email"}`,
+		},
+		{
+			name:     "error with 200 status",
+			status:   http.StatusOK,
+			body:     `{"error":"bad_verification_code","error_description":"The code passed is incorrect or expired."}`,
+			wantErr:  oauth2.ErrTokenRequestFailed,
+			wantCode: "bad_verification_code",
+		},
+		{
+			name:    "server error",
+			status:  http.StatusInternalServerError,
+			body:    `{}`,
+			wantErr: oauth2.ErrTokenRequestFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, http.MethodPost, req.Method)
+				assert.Equal(t, github.TokenURL, req.URL.String())
+				assert.Equal(t, "application/json", req.Header.Get("Accept"))
+				return jsonResponse(tt.status, tt.body), nil
+			})
+			provider := github.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			token, err := provider.GetToken(context.Background(), "code")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				var providerErr *oauth2.ProviderError
+				if assert.ErrorAs(t, err, &providerErr) {
+					assert.Equal(t, oauth2.OpGetToken, providerErr.Op)
+					assert.Equal(t, tt.status, providerErr.StatusCode)
+					assert.Equal(t, tt.wantCode, providerErr.Code)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "gho_token", token.GetAccessToken())
+			assert.False(t, token.HasRefreshToken())
+
+			expiring, ok := token.(oauth2.ExpiringToken)
+			assert.True(t, ok)
+			assert.False(t, expiring.HasExpiry())
+			assert.False(t, expiring.IsExpired())
+		})
+	}
+
+	t.Run("empty code", func(t *testing.T) {
+		provider := github.NewProvider(oauth2.ProviderSetting{})
+
+		_, err := provider.GetToken(context.Background(), "")
+		assert.ErrorIs(t, err, oauth2.ErrEmptyAuthCode)
+	})
+
+	t.Run("code exchange is not retried", func(t *testing.T) {
+		calls := 0
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, github.TokenURL, req.URL.String())
+			calls++
+			return jsonResponse(http.StatusServiceUnavailable, `{}`), nil
+		})
+		provider := github.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+			RedirectURL:  "https://app.example.com/callback",
+			MaxRetries:   3,
+			RetryBackoff: time.Millisecond,
+		})
+
+		_, err := provider.GetToken(context.Background(), "code")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.Equal(t, 1, calls, "the single use code must not be replayed")
+	})
+}
+
+func TestGitHubProvider_RefreshToken(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		wantRefreshToken string
+	}{
+		{
+			name:             "new refresh token returned",
+			body:             `{"access_token":"ghu_new","token_type":"bearer","refresh_token":"ghr_rotated"}`,
+			wantRefreshToken: "ghr_rotated",
+		},
+		{
+			name:             "no refresh token returned, reuse old",
+			body:             `{"access_token":"ghu_new","token_type":"bearer"}`,
+			wantRefreshToken: "ghr_old",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				assert.NoError(t, req.ParseForm())
+				assert.Equal(t, "refresh_token", req.PostForm.Get("grant_type"))
+				return jsonResponse(http.StatusOK, tt.body), nil
+			})
+			provider := github.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			token, err := provider.RefreshToken(context.Background(), "ghr_old")
+			assert.NoError(t, err)
+			assert.Equal(t, "ghu_new", token.GetAccessToken())
+			assert.Equal(t, tt.wantRefreshToken, token.GetRefreshToken())
+		})
+	}
+}
+
+func TestGitHubProvider_GetAuthURL(t *testing.T) {
+	provider := github.NewProvider(oauth2.ProviderSetting{
+		ClientID:    "github-client",
+		RedirectURL: "http://localhost/callback",
+	})
+
+	authURL, err := provider.GetAuthURL(
+		context.Background(),
+		"xyz",
+		oauth2.WithScopes("repo"),
+		oauth2.WithPrompt(oauth2.PromptSelectAccount),
+	)
+	assert.NoError(t, err)
+
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	q := u.Query()
+
+	assert.Equal(t, "github-client", q.Get("client_id"))
+	assert.Equal(t, "http://localhost/callback", q.Get("redirect_uri"))
+	assert.Equal(t, "read:user user:email repo", q.Get("scope"))
+	assert.Equal(t, "xyz", q.Get("state"))
+	assert.Equal(t, "select_account", q.Get("prompt"))
+
+	_, err = github.NewProvider(oauth2.ProviderSetting{ClientID: "github-client"}).
+		GetAuthURL(context.Background(), "xyz")
+	assert.ErrorIs(t, err, oauth2.ErrRedirectURLNotSet)
+
+	configured := github.NewProvider(oauth2.ProviderSetting{
+		ClientID:    "github-client",
+		RedirectURL: "http://localhost/callback",
+		Scopes:      []string{"read:user"},
+	})
+	authURL, err = configured.GetAuthURL(context.Background(), "xyz", oauth2.WithScopes("read:org"))
+	assert.NoError(t, err)
+	u, err = url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "read:user read:org", u.Query().Get("scope"))
+}
+
+func TestGitHubProvider_RevokeToken(t *testing.T) {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, http.MethodDelete, req.Method)
+		assert.Equal(t, "https://api.github.com/applications/github-client/token", req.URL.String())
+
+		username, password, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "github-client", username)
+		assert.Equal(t, "secret", password)
+
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		assert.Equal(t, "gho_token", body["access_token"])
+		return jsonResponse(http.StatusNoContent, ""), nil
+	})
+	provider := github.NewProvider(oauth2.ProviderSetting{
+		Client:       client,
+		ClientID:     "github-client",
+		ClientSecret: "secret",
+	})
+
+	assert.NoError(t, provider.RevokeToken(context.Background(), "gho_token"))
+	assert.ErrorIs(
+		t,
+		provider.RevokeToken(context.Background(), ""),
+		oauth2.ErrTokenRevocationFailed,
+	)
+}
+
+func TestGitHubProvider_Unlink(t *testing.T) {
+	provider := github.NewProvider(oauth2.ProviderSetting{})
+	err := provider.Unlink(context.Background(), "access-token")
+	assert.ErrorIs(t, err, oauth2.ErrUnsupportedOperation)
+}
@@ -0,0 +1,457 @@
+package gitlab
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// ProviderType is the identifier for the GitLab OAuth2 provider
+	//   - REFS : https://docs.gitlab.com/ee/api/oauth2.html
+	ProviderType oauth2.ProviderType = "gitlab"
+
+	// DefaultBaseURL is the GitLab instance used unless WithBaseURL overrides it
+	DefaultBaseURL = "https://gitlab.com"
+
+	// endpoint paths, relative to the instance base URL
+	authPath     = "/oauth/authorize"
+	tokenPath    = "/oauth/token"
+	revokePath   = "/oauth/revoke"
+	keysPath     = "/oauth/discovery/keys"
+	userInfoPath = "/api/v4/user"
+)
+
+// DefaultScopes are requested when ProviderSetting.Scopes is empty, WithScopes adds to them.
+// Replace it during initialization, before any provider is used
+var DefaultScopes = []string{"read_user"}
+
+type (
+	// Option customizes the GitLab provider
+	Option func(*provider)
+
+	// provider holds the configuration for GitLab's OAuth2 implementation
+	provider struct {
+		requester    *oauth2.Requester
+		clientID     string
+		clientSecret string
+		redirectURL  string
+		baseURL      string
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
+	}
+
+	// userInfo represents the /api/v4/user response
+	userInfo struct {
+		ID          int64  `json:"id"`
+		Username    string `json:"username"`
+		Name        string `json:"name"`
+		Email       string `json:"email"`
+		AvatarURL   string `json:"avatar_url"`
+		ConfirmedAt string `json:"confirmed_at"`
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
+	}
+
+	// tokenInfo represents the token information returned from GitLab
+	tokenInfo struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+		TokenType    string `json:"token_type"`
+		IDToken      string `json:"id_token"`
+
+		issuedAt time.Time
+	}
+)
+
+// provider and userInfo must keep implementing the oauth2 interfaces
+var (
+	_ oauth2.Provider      = (*provider)(nil)
+	_ oauth2.NumericIDUser = userInfo{}
+)
+
+func init() {
+	oauth2.RegisterConstructor(ProviderType, func(setting oauth2.ProviderSetting) oauth2.Provider {
+		return NewProvider(setting)
+	})
+}
+
+// WithBaseURL points every endpoint at a self-managed instance (e.g. "https://gitlab.example.com"),
+// an empty baseURL keeps DefaultBaseURL
+func WithBaseURL(baseURL string) Option {
+	return func(p *provider) {
+		p.baseURL = cmp.Or(strings.TrimSuffix(baseURL, "/"), DefaultBaseURL)
+	}
+}
+
+// NewProvider initializes and returns a new GitLab OAuth2 provider for gitlab.com,
+// or for a self-managed instance with WithBaseURL.
+// ProviderSetting.AuthURL, TokenURL and UserInfoURL still override the derived endpoints
+//
+//	example:
+//	provider := gitlab.NewProvider(setting, gitlab.WithBaseURL("https://gitlab.example.com"))
+func NewProvider(setting oauth2.ProviderSetting, opts ...Option) oauth2.Provider {
+	p := &provider{
+		requester:    oauth2.NewRequester(setting),
+		clientID:     setting.ClientID,
+		clientSecret: setting.ClientSecret,
+		redirectURL:  setting.RedirectURL,
+		baseURL:      DefaultBaseURL,
+
+		strictTokenType:  setting.StrictTokenType,
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodPost),
+
+		userInfoFallbackURLs: setting.UserInfoFallbackURLs,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.authURL = cmp.Or(setting.AuthURL, AuthURL(p.baseURL))
+	p.tokenURL = cmp.Or(setting.TokenURL, TokenURL(p.baseURL))
+	p.userInfoURL = cmp.Or(setting.UserInfoURL, UserInfoURL(p.baseURL))
+
+	return p
+}
+
+// NewProviderWithError is NewProvider failing with ErrClientIDNotSet, ErrClientSecretNotSet
+// or ErrInvalidRedirectURL instead of building a provider whose calls cannot succeed
+func NewProviderWithError(setting oauth2.ProviderSetting, opts ...Option) (oauth2.Provider, error) {
+	if err := setting.ValidateCredentials(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+	return NewProvider(setting, opts...), nil
+}
+
+// AuthURL returns the authorization endpoint of the instance at baseURL
+func AuthURL(baseURL string) string { return baseURL + authPath }
+
+// TokenURL returns the token endpoint of the instance at baseURL
+func TokenURL(baseURL string) string { return baseURL + tokenPath }
+
+// UserInfoURL returns the current user endpoint of the instance at baseURL
+func UserInfoURL(baseURL string) string { return baseURL + userInfoPath }
+
+// GetUserInfo retrieves the GitLab user's profile using the access token (requires read_user)
+func (g *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
+	if accessToken == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAccessToken, "")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.userInfoURL, nil)
+	if err != nil {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrUserInfoRequestFailed,
+			err.Error(),
+		)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	oauth2.SetAcceptLanguage(req)
+
+	end := g.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+	resp, err := g.requester.DoWithFallback(req, g.userInfoFallbackURLs)
+	end(resp, err)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpGetUserInfo,
+			oauth2.ErrUserInfoRequestFailed,
+			resp,
+		)
+	}
+
+	var userInfo userInfo
+	if err := oauth2.DecodeJSONResponse(resp, &userInfo); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+	userInfo.raw = oauth2.DecodeRawUserInfo(resp.Body)
+	userInfo.nameStrategy = g.nameStrategy
+
+	return &userInfo, nil
+}
+
+// GetAuthURL constructs the GitLab OAuth2 authorization URL
+//   - ProviderSetting.Scopes replaces the read_user default, WithScopes adds to them
+//   - WithPKCE adds the S256 code_challenge
+//   - WithPrompt and WithOfflineAccess are ignored, GitLab always issues a refresh token
+func (g *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
+	if g.redirectURL == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
+	}
+	if g.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+
+	options := oauth2.NewAuthOptions(opts...)
+	if err := oauth2.ValidatePrompts(options.Prompts); err != nil {
+		return "", oauth2.WrapProviderError(ProviderType, err, strings.Join(options.Prompts, " "))
+	}
+
+	scopes := g.scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	query := url.Values{}
+	query.Set("client_id", g.clientID)
+	query.Set("redirect_uri", g.redirectURL)
+	query.Set("response_type", "code")
+	query.Set("scope", strings.Join(oauth2.NormalizeScopes(scopes, options.Scopes), " "))
+	query.Set("state", state)
+	oauth2.SetCodeChallenge(query, options.CodeVerifier)
+	oauth2.SetAuthParams(query, options.AuthParams)
+
+	return oauth2.BuildAuthURL(ProviderType, g.authURL, query, g.authURLLimits)
+}
+
+// GetToken exchanges the authorization code for an access token from GitLab
+func (g *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	if code == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
+	}
+	ctx = oauth2.WithoutRetry(ctx)
+
+	form := url.Values{}
+	form.Set("code", code)
+	form.Set("client_id", g.clientID)
+	form.Set("client_secret", g.clientSecret)
+	form.Set("redirect_uri", g.redirectURL)
+	form.Set("grant_type", "authorization_code")
+	oauth2.SetCodeVerifier(form, oauth2.NewAuthOptions(opts...).CodeVerifier)
+
+	return g.requestToken(ctx, oauth2.OpGetToken, form)
+}
+
+// RefreshToken exchanges a refresh token for a new access token, GitLab rotates the refresh token
+func (g *provider) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (oauth2.TokenInfo, error) {
+	if refreshToken == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyRefreshToken, "")
+	}
+
+	form := url.Values{}
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", g.clientID)
+	form.Set("client_secret", g.clientSecret)
+	form.Set("redirect_uri", g.redirectURL)
+	form.Set("grant_type", "refresh_token")
+
+	return g.requestToken(ctx, oauth2.OpRefreshToken, form)
+}
+
+// requestToken posts form to the token endpoint and decodes the token response
+func (g *provider) requestToken(ctx context.Context, op string, form url.Values) (oauth2.TokenInfo, error) {
+	var tokenInfo tokenInfo
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, g.tokenURL, form)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRequestFailed,
+			err.Error(),
+		)
+	}
+
+	end := g.requester.Observe(ctx, ProviderType, op)
+	resp, err := g.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
+			ProviderType,
+			op,
+			oauth2.ErrTokenRequestFailed,
+			resp,
+		)
+	}
+
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	// a refresh keeps the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = form.Get("refresh_token")
+	}
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, g.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
+	return tokenInfo, nil
+}
+
+// RevokeToken revokes an access or refresh token (RFC 7009), authenticating with the client credentials
+func (g *provider) RevokeToken(ctx context.Context, token string) error {
+	if token == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRevocationFailed, "token is empty")
+	}
+
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("client_id", g.clientID)
+	form.Set("client_secret", g.clientSecret)
+
+	req, err := oauth2.NewFormRequest(ctx, g.revocationMethod, g.baseURL+revokePath, form)
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	resp, err := g.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRevocationFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpRevokeToken,
+			oauth2.ErrTokenRevocationFailed,
+			resp,
+		)
+	}
+
+	return nil
+}
+
+// CanRefresh reports whether token still holds a refresh token
+func (g provider) CanRefresh(token oauth2.TokenInfo) bool { return oauth2.CanRefresh(token) }
+
+// SigningKeys fetches the id_token signing keys of the instance, issued with the openid scope
+func (g *provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	keys, err := g.requester.FetchJWKS(ctx, g.baseURL+keysPath)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrSigningKeysFailed, err)
+	}
+	return keys, nil
+}
+
+// GetClientCredentialsToken is not supported, GitLab OAuth applications act on behalf of users
+func (g provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrGrantNotSupported, "client_credentials")
+}
+
+// Unlink is not supported, users revoke applications from their GitLab settings
+func (g provider) Unlink(ctx context.Context, accessToken string) error {
+	return oauth2.WrapProviderError(ProviderType, oauth2.ErrUnsupportedOperation, "unlink")
+}
+
+// GetProvider returns the provider type ("gitlab")
+func (g provider) GetProvider() oauth2.ProviderType { return ProviderType }
+
+// GetRedirectURL returns the configured redirect URL
+func (g provider) GetRedirectURL() string { return g.redirectURL }
+
+// GetID returns the user's GitLab ID, unique within the instance
+func (g userInfo) GetID() string { return strconv.FormatInt(g.ID, 10) }
+
+// GetNumericID returns the user's GitLab ID in its native numeric form
+func (g userInfo) GetNumericID() (int64, bool) { return g.ID, true }
+
+// GetEmail returns the user's primary email address
+func (g userInfo) GetEmail() string { return g.Email }
+
+// GetName returns the user's name, username or email depending on the NameStrategy.
+// The username is treated as the nickname
+func (g userInfo) GetName() string {
+	return oauth2.SelectName(g.nameStrategy, g.Name, g.Username, g.Email)
+}
+
+// GetGender returns an empty string since GitLab has no gender field
+func (g userInfo) GetGender() string { return "" }
+
+// GetGenderNormalized returns oauth2.GenderUnknown since there is no gender
+func (g userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.GenderUnknown }
+
+// GetProfileImage returns the user's avatar URL
+func (g userInfo) GetProfileImage() string { return g.AvatarURL }
+
+// GetPhoneNumber returns an empty string since GitLab has no phone number field
+func (g userInfo) GetPhoneNumber() string { return "" }
+
+// GetBirthday returns an empty string since GitLab has no birthday field
+func (g userInfo) GetBirthday() string { return "" }
+
+// GetLocale returns an empty string since the user API does not share a locale
+func (g userInfo) GetLocale() string { return "" }
+
+// IsEmailVerified reports whether the account, and with it the primary email, was confirmed
+func (g userInfo) IsEmailVerified() bool { return g.ConfirmedAt != "" }
+
+// GetRaw returns the decoded /api/v4/user response (e.g. "web_url", "state")
+func (g userInfo) GetRaw() map[string]any { return g.raw }
+
+// GetAccessToken returns the OAuth2 access token
+func (g tokenInfo) GetAccessToken() string { return g.AccessToken }
+
+// GetRefreshToken returns the OAuth2 refresh token
+func (g tokenInfo) GetRefreshToken() string { return g.RefreshToken }
+
+// GetExpiry returns the token expiration time in seconds
+func (g tokenInfo) GetExpiry() int { return g.ExpiresIn }
+
+// HasRefreshToken reports whether a refresh token was issued
+func (g tokenInfo) HasRefreshToken() bool { return g.RefreshToken != "" }
+
+// HasExpiry reports whether the access token expires, false when expires_in was not returned
+func (g tokenInfo) HasExpiry() bool { return g.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (g tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(g.issuedAt, g.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (g tokenInfo) IsExpired() bool { return oauth2.Expired(g.GetExpiresAt()) }
+
+// GetScope returns the space-delimited scopes granted by the user
+func (g tokenInfo) GetScope() string { return g.Scope }
+
+// GetTokenType returns the token type (e.g. "Bearer")
+func (g tokenInfo) GetTokenType() string { return g.TokenType }
+
+// GetIDToken returns the OpenID Connect id_token, issued with the openid scope
+func (g tokenInfo) GetIDToken() string { return g.IDToken }
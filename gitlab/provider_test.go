@@ -0,0 +1,179 @@
+package gitlab_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/gitlab"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newMockClient(fn roundTripperFunc) *http.Client {
+	return &http.Client{Transport: fn}
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// newRecordingProvider answers token and user requests, recording the URLs they were sent to
+func newRecordingProvider(urls *[]string, opts ...gitlab.Option) oauth2.Provider {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		*urls = append(*urls, req.URL.String())
+		if strings.HasSuffix(req.URL.Path, "/oauth/token") {
+			return jsonResponse(http.StatusOK, `{"access_token":"glpat","refresh_token":"glrt",`+
+				`"token_type":"Bearer","expires_in":7200,"scope":"read_user"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"id":42,"username":"octo","name":"Octo Cat",`+
+			`"email":"octo@example.com","avatar_url":"https://img","confirmed_at":"2024-01-01T00:00:00Z"}`), nil
+	})
+	return gitlab.NewProvider(oauth2.ProviderSetting{
+		Client:      client,
+		ClientID:    "client-id",
+		RedirectURL: "https://app.example.com/callback",
+	}, opts...)
+}
+
+func TestGitLabProvider_BaseURL(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		opts    []gitlab.Option
+		baseURL string
+	}{
+		{name: "default", baseURL: gitlab.DefaultBaseURL},
+		{name: "empty keeps the default", opts: []gitlab.Option{gitlab.WithBaseURL("")}, baseURL: gitlab.DefaultBaseURL},
+		{
+			name:    "self-managed",
+			opts:    []gitlab.Option{gitlab.WithBaseURL("https://gitlab.example.com/")},
+			baseURL: "https://gitlab.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var urls []string
+			provider := newRecordingProvider(&urls, tt.opts...)
+
+			authURL, err := provider.GetAuthURL(ctx, "state")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(authURL, tt.baseURL+"/oauth/authorize?"), authURL)
+
+			_, err = provider.GetToken(ctx, "code")
+			assert.NoError(t, err)
+			_, err = provider.GetUserInfo(ctx, "glpat")
+			assert.NoError(t, err)
+
+			assert.Equal(t, []string{tt.baseURL + "/oauth/token", tt.baseURL + "/api/v4/user"}, urls)
+		})
+	}
+}
+
+func TestGitLabProvider_GetUserInfo(t *testing.T) {
+	var urls []string
+	provider := newRecordingProvider(&urls)
+
+	user, err := provider.GetUserInfo(context.Background(), "glpat")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", user.GetID())
+
+	numericID, ok := oauth2.NumericID(user)
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), numericID)
+	assert.Equal(t, "Octo Cat", user.GetName())
+	assert.Equal(t, "octo@example.com", user.GetEmail())
+	assert.Equal(t, "https://img", user.GetProfileImage())
+	assert.True(t, user.IsEmailVerified())
+
+	nickname := gitlab.NewProvider(oauth2.ProviderSetting{
+		Client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{"id":42,"username":"octo"}`), nil
+		}),
+		NameStrategy: oauth2.PreferNickname,
+	})
+	user, err = nickname.GetUserInfo(context.Background(), "glpat")
+	assert.NoError(t, err)
+	assert.Equal(t, "octo", user.GetName())
+	assert.False(t, user.IsEmailVerified())
+}
+
+func TestGitLabProvider_GetToken(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("exchanges the code with PKCE", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "authorization_code", req.PostForm.Get("grant_type"))
+			assert.Equal(t, "verifier", req.PostForm.Get("code_verifier"))
+			return jsonResponse(http.StatusOK, `{"access_token":"glpat","refresh_token":"glrt","token_type":"Bearer","expires_in":7200}`), nil
+		})
+		provider := gitlab.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		token, err := provider.GetToken(ctx, "code", oauth2.WithPKCE("verifier"))
+		assert.NoError(t, err)
+		assert.Equal(t, "glpat", token.GetAccessToken())
+		assert.Equal(t, "glrt", token.GetRefreshToken())
+		assert.True(t, provider.CanRefresh(token))
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusBadRequest, `{"error":"invalid_grant"}`), nil
+		})
+		provider := gitlab.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetToken(ctx, "code")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+
+		var providerErr *oauth2.ProviderError
+		assert.ErrorAs(t, err, &providerErr)
+		assert.Equal(t, "invalid_grant", providerErr.Code)
+	})
+}
+
+func TestGitLabProvider_RevokeToken(t *testing.T) {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "https://gitlab.example.com/oauth/revoke", req.URL.String())
+		assert.NoError(t, req.ParseForm())
+		assert.Equal(t, "glpat", req.PostForm.Get("token"))
+		return jsonResponse(http.StatusOK, `{}`), nil
+	})
+	provider := gitlab.NewProvider(
+		oauth2.ProviderSetting{Client: client},
+		gitlab.WithBaseURL("https://gitlab.example.com"),
+	)
+
+	assert.NoError(t, provider.RevokeToken(context.Background(), "glpat"))
+	assert.ErrorIs(t, provider.RevokeToken(context.Background(), ""), oauth2.ErrTokenRevocationFailed)
+}
+
+func TestGitLabProvider_GetAuthURL(t *testing.T) {
+	provider := gitlab.NewProvider(oauth2.ProviderSetting{
+		ClientID:    "client-id",
+		RedirectURL: "https://app.example.com/callback",
+	})
+
+	authURL, err := provider.GetAuthURL(context.Background(), "state", oauth2.WithScopes("openid"))
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "read_user openid", parsed.Query().Get("scope"))
+	assert.Equal(t, "code", parsed.Query().Get("response_type"))
+}
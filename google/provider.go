@@ -1,12 +1,15 @@
 package google
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
-	"io"
+	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dings-things/oauth2"
 )
@@ -24,15 +27,58 @@ const (
 
 	// TokenURL is the endpoint to exchange the authorization code for an access token
 	TokenURL = "https://oauth2.googleapis.com/token"
+
+	// RevokeURL is the endpoint to revoke an access or refresh token
+	RevokeURL = "https://oauth2.googleapis.com/revoke"
+
+	// KeysURL is the JWKS endpoint serving the id_token signing keys
+	KeysURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+	// DeviceAuthURL is the endpoint to start the device authorization flow
+	DeviceAuthURL = "https://oauth2.googleapis.com/device/code"
 )
 
+// DefaultScopes are requested when ProviderSetting.Scopes is empty, WithScopes adds to them.
+// Replace it during initialization, before any provider is used
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// issuers lists the accepted id_token "iss" values
+var issuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
 type (
+	// Option customizes the Google provider
+	Option func(*provider)
+
 	// provider holds the configuration for Google's OAuth2 implementation
 	provider struct {
-		client       *http.Client
+		requester    *oauth2.Requester
 		clientID     string
 		clientSecret string
 		redirectURL  string
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
+
+		// deviceFlows keeps the polling interval of each started device flow by device code
+		deviceFlows *sync.Map
+
+		// hostedDomain restricts logins to a Google Workspace domain, empty when unrestricted
+		hostedDomain string
+	}
+
+	// deviceFlow is the polling state of a device code until it expires
+	deviceFlow struct {
+		interval  time.Duration
+		expiresAt time.Time
 	}
 
 	// userInfo represents the user information returned from Google
@@ -42,6 +88,17 @@ type (
 		Name    string `json:"name"`
 		Picture string `json:"picture"`
 		Locale  string `json:"locale"`
+
+		// HostedDomain is the Google Workspace domain of the account, empty for consumer accounts
+		HostedDomain string `json:"hd"`
+
+		// Gender is only returned when the user.gender.read scope is granted
+		Gender string `json:"gender"`
+
+		VerifiedEmail bool `json:"verified_email"`
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
 	}
 
 	// tokenInfo represents the token information returned from Google
@@ -49,22 +106,103 @@ type (
 		AccessToken  string `json:"access_token"`
 		ExpiresIn    int    `json:"expires_in"`
 		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+		TokenType    string `json:"token_type"`
+		IDToken      string `json:"id_token"`
+
+		// RefreshTokenExpiresIn is only sent for time-limited access (e.g. test-mode apps)
+		RefreshTokenExpiresIn int `json:"refresh_token_expires_in"`
+
+		issuedAt time.Time
 	}
+
+	// idTokenClaims represents the id_token claims used to identify the user
+	idTokenClaims struct {
+		Issuer    string `json:"iss"`
+		Audience  string `json:"aud"`
+		Subject   string `json:"sub"`
+		ExpiresAt int64  `json:"exp"`
+		Email     string `json:"email"`
+		Name      string `json:"name"`
+		Picture   string `json:"picture"`
+
+		HostedDomain  string           `json:"hd"`
+		EmailVerified oauth2.BoolClaim `json:"email_verified"`
+	}
+)
+
+// provider and userInfo must keep implementing the oauth2 interfaces
+var (
+	_ oauth2.Provider           = (*provider)(nil)
+	_ oauth2.IDTokenProvider    = (*provider)(nil)
+	_ oauth2.DeviceFlowProvider = (*provider)(nil)
+	_ oauth2.UserInfo           = (*userInfo)(nil)
 )
 
+func init() {
+	oauth2.RegisterConstructor(ProviderType, func(setting oauth2.ProviderSetting) oauth2.Provider {
+		return NewProvider(setting)
+	})
+}
+
+// WithHostedDomain restricts logins to the Google Workspace domain (e.g. "example.com"):
+// the authorization URL carries hd so Google only offers accounts of that domain, and since
+// the parameter can be stripped from the URL, users whose hd claim is not domain fail with
+// ErrHostedDomainMismatch. The email domain is not trusted since consumer accounts may use any
+// address. Empty keeps logins unrestricted
+func WithHostedDomain(domain string) Option {
+	return func(p *provider) {
+		p.hostedDomain = domain
+	}
+}
+
 // NewProvider initializes and returns a new Google OAuth2 provider
-func NewProvider(setting oauth2.ProviderSetting) oauth2.Provider {
-	return &provider{
-		client:       setting.Client,
+//
+//	example:
+//	provider := google.NewProvider(setting, google.WithHostedDomain("example.com"))
+func NewProvider(setting oauth2.ProviderSetting, opts ...Option) oauth2.Provider {
+	p := &provider{
+		requester:    oauth2.NewRequester(setting),
 		clientID:     setting.ClientID,
 		clientSecret: setting.ClientSecret,
 		redirectURL:  setting.RedirectURL,
+
+		strictTokenType:  setting.StrictTokenType,
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodPost),
+
+		authURL:  cmp.Or(setting.AuthURL, AuthURL),
+		tokenURL: cmp.Or(setting.TokenURL, TokenURL),
+
+		userInfoURL:          cmp.Or(setting.UserInfoURL, UserInfoURL),
+		userInfoFallbackURLs: setting.UserInfoFallbackURLs,
+
+		deviceFlows: &sync.Map{},
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// NewProviderWithError is NewProvider failing with ErrClientIDNotSet, ErrClientSecretNotSet
+// or ErrInvalidRedirectURL instead of building a provider whose calls cannot succeed
+func NewProviderWithError(setting oauth2.ProviderSetting, opts ...Option) (oauth2.Provider, error) {
+	if err := setting.ValidateCredentials(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+	return NewProvider(setting, opts...), nil
 }
 
 // GetUserInfo retrieves the user profile information from Google using the access token
 func (g *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, UserInfoURL, nil)
+	if accessToken == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAccessToken, "")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.userInfoURL, nil)
 	if err != nil {
 		return nil, oauth2.WrapProviderError(
 			ProviderType,
@@ -74,68 +212,173 @@ func (g *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
+	oauth2.SetAcceptLanguage(req)
 
-	response, err := g.client.Do(req)
+	end := g.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+	resp, err := g.requester.DoWithFallback(req, g.userInfoFallbackURLs)
+	end(resp, err)
 	if err != nil {
-		return nil, oauth2.WrapProviderError(
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, oauth2.WrapResponseError(
 			ProviderType,
+			oauth2.OpGetUserInfo,
 			oauth2.ErrUserInfoRequestFailed,
-			err.Error(),
+			resp,
 		)
 	}
-	defer response.Body.Close()
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrUserInfoRequestFailed,
-			err.Error(),
-		)
+	var userInfo userInfo
+	if unmarshalErr := oauth2.DecodeJSONResponse(resp, &userInfo); unmarshalErr != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, unmarshalErr)
+	}
+
+	if err := g.checkHostedDomain(userInfo.HostedDomain); err != nil {
+		return nil, err
 	}
 
-	var userInfo *userInfo
-	if unmarshalErr := json.Unmarshal(body, &userInfo); unmarshalErr != nil {
+	userInfo.raw = oauth2.DecodeRawUserInfo(resp.Body)
+	userInfo.nameStrategy = g.nameStrategy
+
+	return &userInfo, nil
+}
+
+// checkHostedDomain fails with ErrHostedDomainMismatch when WithHostedDomain is set
+// and hostedDomain, the user's hd claim, is a different domain
+func (g *provider) checkHostedDomain(hostedDomain string) error {
+	if g.hostedDomain == "" || strings.EqualFold(hostedDomain, g.hostedDomain) {
+		return nil
+	}
+	return oauth2.WrapProviderError(
+		ProviderType,
+		oauth2.ErrHostedDomainMismatch,
+		fmt.Sprintf("hd %q, want %q", hostedDomain, g.hostedDomain),
+	)
+}
+
+// UserInfoFromIDToken builds the user from the id_token returned with the token.
+// It must come straight from the token endpoint, so the claims are checked but not the signature
+func (g *provider) UserInfoFromIDToken(token oauth2.TokenInfo) (oauth2.UserInfo, error) {
+	if token == nil || token.GetIDToken() == "" {
 		return nil, oauth2.WrapProviderError(
 			ProviderType,
-			oauth2.ErrUserInfoRequestFailed,
-			unmarshalErr.Error(),
+			oauth2.ErrInvalidIDToken,
+			"id_token is missing",
 		)
 	}
 
-	return userInfo, nil
+	var claims idTokenClaims
+	if err := oauth2.DecodeJWTClaims(token.GetIDToken(), &claims); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	var reason string
+	switch {
+	case !slices.Contains(issuers, claims.Issuer):
+		reason = "unexpected issuer"
+	case claims.Audience != g.clientID:
+		reason = "unexpected audience"
+	case time.Now().Unix() >= claims.ExpiresAt:
+		reason = "token expired"
+	case claims.Subject == "":
+		reason = "subject is missing"
+	}
+	if reason != "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrInvalidIDToken, reason)
+	}
+	if err := g.checkHostedDomain(claims.HostedDomain); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	_ = oauth2.DecodeJWTClaims(token.GetIDToken(), &raw)
+
+	return &userInfo{
+		ID:      claims.Subject,
+		Email:   claims.Email,
+		Name:    claims.Name,
+		Picture: claims.Picture,
+
+		HostedDomain:  claims.HostedDomain,
+		VerifiedEmail: bool(claims.EmailVerified),
+
+		raw:          raw,
+		nameStrategy: g.nameStrategy,
+	}, nil
 }
 
 // GetAuthURL constructs the Google OAuth2 authorization URL
-func (g *provider) GetAuthURL(ctx context.Context, state string) (string, error) {
+//   - offline access (refresh token) is requested unless WithOfflineAccess(false) is given
+//   - scopes from WithScopes are merged into ProviderSetting.Scopes (default openid email profile)
+//   - prompt defaults to consent, WithPrompt overrides it with none, consent or select_account
+//   - WithPKCE adds the S256 code_challenge
+//   - WithHostedDomain adds hd
+func (g *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
 	if g.redirectURL == "" {
 		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
 	}
+	if g.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
 
-	scopes := []string{
-		"openid",
-		"email",
-		"profile",
+	options := oauth2.NewAuthOptions(opts...)
+	if err := oauth2.ValidatePrompts(options.Prompts); err != nil {
+		return "", oauth2.WrapProviderError(ProviderType, err, strings.Join(options.Prompts, " "))
 	}
 
 	query := url.Values{}
 	query.Set("client_id", g.clientID)
 	query.Set("redirect_uri", g.redirectURL)
 	query.Set("response_type", "code")
-	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("scope", strings.Join(oauth2.NormalizeScopes(g.defaultScopes(), options.Scopes), " "))
 	query.Set("state", state)
 	query.Set("access_type", "offline")
-	query.Set("prompt", "consent")
+	if options.OfflineAccess != nil && !*options.OfflineAccess {
+		query.Set("access_type", "online")
+	}
+	query.Set("prompt", oauth2.PromptConsent)
+	if prompts := oauth2.SupportedPrompts(
+		options.Prompts,
+		oauth2.PromptNone,
+		oauth2.PromptConsent,
+		oauth2.PromptSelectAccount,
+	); len(prompts) > 0 {
+		query.Set("prompt", strings.Join(prompts, " "))
+	}
+	if g.hostedDomain != "" {
+		query.Set("hd", g.hostedDomain)
+	}
+	oauth2.SetCodeChallenge(query, options.CodeVerifier)
+	oauth2.SetNonce(query, options.Nonce)
+	oauth2.SetAuthParams(query, options.AuthParams)
 
-	return AuthURL + "?" + query.Encode(), nil
+	return oauth2.BuildAuthURL(ProviderType, g.authURL, query, g.authURLLimits)
+}
+
+// defaultScopes returns the configured scopes, or DefaultScopes
+func (g *provider) defaultScopes() []string {
+	if len(g.scopes) > 0 {
+		return g.scopes
+	}
+	return DefaultScopes
 }
 
 // GetToken exchanges the authorization code for an access token from Google
-func (g *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+func (g *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
 	var tokenInfo tokenInfo
 	if code == "" {
 		return tokenInfo, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
 	}
+	ctx = oauth2.WithoutRetry(ctx)
 
 	form := url.Values{}
 	form.Set("code", code)
@@ -143,11 +386,12 @@ func (g *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo,
 	form.Set("client_secret", g.clientSecret)
 	form.Set("redirect_uri", g.redirectURL)
 	form.Set("grant_type", "authorization_code")
+	oauth2.SetCodeVerifier(form, oauth2.NewAuthOptions(opts...).CodeVerifier)
 
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		TokenURL,
+		g.tokenURL,
 		strings.NewReader(form.Encode()),
 	)
 	if err != nil {
@@ -159,39 +403,29 @@ func (g *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo,
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := g.client.Do(req)
+	end := g.requester.Observe(ctx, ProviderType, oauth2.OpGetToken)
+	resp, err := g.requester.Do(req)
+	end(resp, err)
 	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			err.Error(),
-		)
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
 			ProviderType,
+			oauth2.OpGetToken,
 			oauth2.ErrTokenRequestFailed,
-			err.Error(),
+			resp,
 		)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return tokenInfo, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			string(body),
-		)
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
 	}
+	tokenInfo.issuedAt = time.Now()
 
-	if err := json.Unmarshal(body, &tokenInfo); err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			err.Error(),
-		)
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, g.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
 	}
 
 	return tokenInfo, nil
@@ -217,7 +451,7 @@ func (g *provider) RefreshToken(
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		TokenURL,
+		g.tokenURL,
 		strings.NewReader(form.Encode()),
 	)
 	if err != nil {
@@ -229,62 +463,250 @@ func (g *provider) RefreshToken(
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := g.client.Do(req)
+	end := g.requester.Observe(ctx, ProviderType, oauth2.OpRefreshToken)
+	resp, err := g.requester.Do(req)
+	end(resp, err)
 	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
 			ProviderType,
+			oauth2.OpRefreshToken,
 			oauth2.ErrTokenRequestFailed,
-			err.Error(),
+			resp,
 		)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	// keep the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = refreshToken
+	}
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, g.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
+	return tokenInfo, nil
+}
+
+// GetClientCredentialsToken is not supported, Google issues app tokens to service accounts only
+func (g *provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrGrantNotSupported, "client_credentials")
+}
+
+// StartDeviceFlow requests a device and user code for the configured scopes.
+// The OAuth client must be of the "TVs and Limited Input devices" type
+func (g *provider) StartDeviceFlow(ctx context.Context) (oauth2.DeviceAuth, error) {
+	form := url.Values{}
+	form.Set("client_id", g.clientID)
+	form.Set("scope", strings.Join(oauth2.NormalizeScopes(g.defaultScopes()), " "))
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, DeviceAuthURL, form)
 	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+		return oauth2.DeviceAuth{}, oauth2.WrapProviderError(
 			ProviderType,
-			oauth2.ErrTokenRequestFailed,
+			oauth2.ErrDeviceFlowFailed,
 			err.Error(),
 		)
 	}
 
+	end := g.requester.Observe(ctx, ProviderType, oauth2.OpStartDeviceFlow)
+	resp, err := g.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return oauth2.DeviceAuth{}, oauth2.WrapProviderCause(ProviderType, oauth2.ErrDeviceFlowFailed, err)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return tokenInfo, oauth2.WrapProviderError(
+		return oauth2.DeviceAuth{}, oauth2.WrapResponseError(
 			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			string(body),
+			oauth2.OpStartDeviceFlow,
+			oauth2.ErrDeviceFlowFailed,
+			resp,
 		)
 	}
 
-	if err := json.Unmarshal(body, &tokenInfo); err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+	auth, err := oauth2.DecodeDeviceAuth(resp.Body)
+	if err != nil {
+		return oauth2.DeviceAuth{}, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrDeviceFlowFailed,
+			err.Error(),
+		)
+	}
+
+	now := time.Now()
+	g.deviceFlows.Range(func(deviceCode, flow any) bool {
+		if now.After(flow.(deviceFlow).expiresAt) {
+			g.deviceFlows.Delete(deviceCode)
+		}
+		return true
+	})
+	g.deviceFlows.Store(auth.DeviceCode, deviceFlow{interval: auth.Interval, expiresAt: now.Add(auth.ExpiresIn)})
+
+	return auth, nil
+}
+
+// PollDeviceToken polls the token endpoint at the interval of StartDeviceFlow until the user
+// approves the request, slowing down when asked to
+//   - a denied request fails with ErrAccessDenied and an expired one with ErrDeviceCodeExpired
+//   - when ctx is done first, the error wraps ErrAuthorizationPending and ctx.Err()
+func (g *provider) PollDeviceToken(ctx context.Context, deviceCode string) (oauth2.TokenInfo, error) {
+	if deviceCode == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRequestFailed, "device code is empty")
+	}
+
+	interval := oauth2.DefaultDeviceInterval
+	if flow, ok := g.deviceFlows.Load(deviceCode); ok {
+		interval = flow.(deviceFlow).interval
+	}
+	defer g.deviceFlows.Delete(deviceCode)
+
+	return oauth2.PollDevice(ctx, interval, func(ctx context.Context) (oauth2.TokenInfo, error) {
+		return g.requestDeviceToken(ctx, deviceCode)
+	})
+}
+
+// requestDeviceToken polls the token endpoint once for deviceCode
+func (g *provider) requestDeviceToken(ctx context.Context, deviceCode string) (oauth2.TokenInfo, error) {
+	form := url.Values{}
+	form.Set("client_id", g.clientID)
+	form.Set("client_secret", g.clientSecret)
+	form.Set("device_code", deviceCode)
+	form.Set("grant_type", oauth2.DeviceCodeGrantType)
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, g.tokenURL, form)
+	if err != nil {
+		return nil, oauth2.WrapProviderError(
 			ProviderType,
 			oauth2.ErrTokenRequestFailed,
 			err.Error(),
 		)
 	}
 
+	end := g.requester.Observe(ctx, ProviderType, oauth2.OpPollDeviceToken)
+	resp, err := g.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, oauth2.WrapDeviceTokenError(ProviderType, resp)
+	}
+
+	var tokenInfo tokenInfo
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, g.strictTokenType); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
 	return tokenInfo, nil
 }
 
+// RevokeToken revokes an access or refresh token by posting it to Google's revocation endpoint
+func (g *provider) RevokeToken(ctx context.Context, token string) error {
+	if token == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRevocationFailed, "token is empty")
+	}
+
+	form := url.Values{}
+	form.Set("token", token)
+
+	req, err := oauth2.NewFormRequest(ctx, g.revocationMethod, RevokeURL, form)
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	resp, err := g.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRevocationFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpRevokeToken,
+			oauth2.ErrTokenRevocationFailed,
+			resp,
+		)
+	}
+
+	return nil
+}
+
+// CanRefresh reports whether token still holds a refresh token that has not expired
+func (g provider) CanRefresh(token oauth2.TokenInfo) bool { return oauth2.CanRefresh(token) }
+
+// SigningKeys fetches the id_token signing keys served at KeysURL
+func (g *provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	keys, err := g.requester.FetchJWKS(ctx, KeysURL)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrSigningKeysFailed, err)
+	}
+	return keys, nil
+}
+
+// Unlink is not supported, RevokeToken already removes the app's access for Google
+func (g *provider) Unlink(ctx context.Context, accessToken string) error {
+	return oauth2.WrapProviderError(ProviderType, oauth2.ErrUnsupportedOperation, "unlink")
+}
+
 // GetProvider returns the provider type ("google")
 func (g provider) GetProvider() oauth2.ProviderType { return ProviderType }
 
+// GetRedirectURL returns the configured redirect URL
+func (g provider) GetRedirectURL() string { return g.redirectURL }
+
 // GetID returns the user's Google ID
 func (g userInfo) GetID() string { return g.ID }
 
 // GetEmail returns the user's email address
 func (g userInfo) GetEmail() string { return g.Email }
 
-// GetName returns the user's full name
-func (g userInfo) GetName() string { return g.Name }
+// GetName returns the user's full name, or the email depending on the NameStrategy.
+// Google has no nickname, so PreferNickname behaves like PreferRealName
+func (g userInfo) GetName() string { return oauth2.SelectName(g.nameStrategy, g.Name, "", g.Email) }
 
-// GetGender returns the user's gender
-func (g userInfo) GetGender() string { return "" }
+// GetGender returns the user's gender, empty unless the user.gender.read scope was granted
+func (g userInfo) GetGender() string { return g.Gender }
+
+// GetGenderNormalized maps the raw gender to an oauth2.Gender
+func (g userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.NormalizeGender(g.GetGender()) }
 
 // GetProfileImage returns the user's profile image URL
 func (g userInfo) GetProfileImage() string { return g.Picture }
 
+// GetPhoneNumber returns an empty string since the userinfo endpoint has no phone number
+func (g userInfo) GetPhoneNumber() string { return "" }
+
+// GetBirthday returns an empty string since the userinfo endpoint has no birthday
+func (g userInfo) GetBirthday() string { return "" }
+
+// GetLocale returns the user's locale (e.g. "en" or "ko-KR"), empty when Google omits it
+func (g userInfo) GetLocale() string { return g.Locale }
+
+// IsEmailVerified reports Google's verified_email (email_verified in the id_token)
+func (g userInfo) IsEmailVerified() bool { return g.VerifiedEmail }
+
+// GetRaw returns the decoded userinfo response or id_token claims (e.g. "locale", "verified_email")
+func (g userInfo) GetRaw() map[string]any { return g.raw }
+
 // GetAccessToken returns the OAuth2 access token
 func (g tokenInfo) GetAccessToken() string { return g.AccessToken }
 
@@ -293,3 +715,29 @@ func (g tokenInfo) GetRefreshToken() string { return g.RefreshToken }
 
 // GetExpiry returns the token expiration time in seconds
 func (g tokenInfo) GetExpiry() int { return g.ExpiresIn }
+
+// HasRefreshToken reports whether a refresh token was issued
+func (g tokenInfo) HasRefreshToken() bool { return g.RefreshToken != "" }
+
+// GetRefreshTokenExpiresAt returns when the refresh token expires, zero when Google sets no limit
+func (g tokenInfo) GetRefreshTokenExpiresAt() time.Time {
+	return oauth2.ExpiryTime(g.issuedAt, g.RefreshTokenExpiresIn)
+}
+
+// HasExpiry reports whether the access token expires, false when expires_in was not returned
+func (g tokenInfo) HasExpiry() bool { return g.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (g tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(g.issuedAt, g.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (g tokenInfo) IsExpired() bool { return oauth2.Expired(g.GetExpiresAt()) }
+
+// GetScope returns the space-delimited scopes granted by the user
+func (g tokenInfo) GetScope() string { return g.Scope }
+
+// GetTokenType returns the token type (e.g. "Bearer")
+func (g tokenInfo) GetTokenType() string { return g.TokenType }
+
+// GetIDToken returns the OpenID Connect id_token
+func (g tokenInfo) GetIDToken() string { return g.IDToken }
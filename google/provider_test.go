@@ -3,12 +3,17 @@ package google_test
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/dings-things/oauth2"
 	"github.com/dings-things/oauth2/google"
@@ -51,8 +56,13 @@ func TestGoogleProvider_GetUserInfo(t *testing.T) {
 		user, err := provider.GetUserInfo(context.Background(), "test-token")
 		assert.NoError(t, err)
 		assert.Equal(t, "123", user.GetID())
+
+		_, ok := oauth2.NumericID(user)
+		assert.False(t, ok, "google IDs are strings")
 		assert.Equal(t, "test@example.com", user.GetEmail())
 		assert.Equal(t, "Test User", user.GetName())
+		assert.Empty(t, user.GetPhoneNumber())
+		assert.Equal(t, oauth2.GenderUnknown, user.GetGenderNormalized(), "gender was not granted")
 	})
 
 	t.Run("error on user info request", func(t *testing.T) {
@@ -70,6 +80,110 @@ func TestGoogleProvider_GetUserInfo(t *testing.T) {
 		_, err := provider.GetUserInfo(context.Background(), "test-token")
 		assert.Error(t, err)
 	})
+
+	t.Run("empty access token fails before the request", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatal("no request expected")
+			return nil, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetUserInfo(context.Background(), "")
+		assert.ErrorIs(t, err, oauth2.ErrEmptyAccessToken)
+	})
+
+	t.Run("profile image and gender", func(t *testing.T) {
+		mockBody, _ := json.Marshal(googleUserInfoResponse{
+			ID:      "123",
+			Picture: "https://lh3.googleusercontent.com/a/photo.jpg",
+			Gender:  "female",
+		})
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(mockBody)),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		user, err := provider.GetUserInfo(context.Background(), "test-token")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://lh3.googleusercontent.com/a/photo.jpg", user.GetProfileImage())
+		assert.Equal(t, "female", user.GetGender())
+	})
+
+	t.Run("email verification", func(t *testing.T) {
+		for body, want := range map[string]bool{
+			`{"id":"123","email":"a@example.com","verified_email":true}`:  true,
+			`{"id":"123","email":"a@example.com","verified_email":false}`: false,
+			`{"id":"123","email":"a@example.com"}`:                        false,
+		} {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+				}, nil
+			})
+			provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			user, err := provider.GetUserInfo(context.Background(), "test-token")
+			assert.NoError(t, err)
+			assert.Equal(t, want, user.IsEmailVerified(), body)
+		}
+	})
+
+	t.Run("raw response keeps provider-specific fields", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"id":"123","locale":"ko","verified_email":true}`))),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		user, err := provider.GetUserInfo(context.Background(), "test-token")
+		assert.NoError(t, err)
+		assert.Equal(t, "ko", user.GetRaw()["locale"])
+		assert.Equal(t, "ko", user.GetLocale())
+		assert.Equal(t, true, user.GetRaw()["verified_email"])
+	})
+}
+
+func TestGoogleProvider_NilClient(t *testing.T) {
+	defaultTransport := http.DefaultTransport
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+	http.DefaultTransport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"id":"123"}`))),
+		}, nil
+	})
+
+	provider := google.NewProvider(oauth2.ProviderSetting{})
+	user, err := provider.GetUserInfo(context.Background(), "test-token")
+	assert.NoError(t, err)
+	assert.Equal(t, "123", user.GetID())
+}
+
+func TestGoogleProvider_AcceptLanguage(t *testing.T) {
+	var got []string
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		got = append(got, req.Header.Get("Accept-Language"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"id":"123"}`))),
+		}, nil
+	})
+	provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+	_, err := provider.GetUserInfo(context.Background(), "token")
+	assert.NoError(t, err)
+
+	ctx := oauth2.WithAcceptLanguage(context.Background(), "ja-JP")
+	_, err = provider.GetUserInfo(ctx, "token")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"", "ja-JP"}, got)
 }
 
 func TestGoogleProvider_GetAccessToken(t *testing.T) {
@@ -78,6 +192,8 @@ func TestGoogleProvider_GetAccessToken(t *testing.T) {
 			AccessToken:  "access-token",
 			RefreshToken: "refresh-token",
 			ExpiresIn:    3600,
+			Scope:        "openid email",
+			TokenType:    "Bearer",
 		}
 		mockBody, _ := json.Marshal(mockResp)
 		client := newMockClient(func(req *http.Request) (*http.Response, error) {
@@ -99,6 +215,24 @@ func TestGoogleProvider_GetAccessToken(t *testing.T) {
 		assert.Equal(t, "access-token", token.GetAccessToken())
 		assert.Equal(t, "refresh-token", token.GetRefreshToken())
 		assert.Equal(t, 3600, token.GetExpiry())
+		assert.Equal(t, []string{"openid", "email"}, oauth2.GrantedScopes(token))
+		assert.Equal(t, "openid email", token.GetScope())
+		assert.Equal(t, "Bearer", token.GetTokenType())
+	})
+
+	t.Run("sends PKCE verifier", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "verifier", req.PostForm.Get("code_verifier"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"access_token":"access-token"}`))),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetToken(context.Background(), "valid-code", oauth2.WithPKCE("verifier"))
+		assert.NoError(t, err)
 	})
 
 	t.Run("empty code returns error", func(t *testing.T) {
@@ -124,7 +258,196 @@ func TestGoogleProvider_GetAccessToken(t *testing.T) {
 	})
 }
 
+func TestGoogleProvider_RefreshToken(t *testing.T) {
+	t.Run("successful refresh", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, google.TokenURL, req.URL.String())
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "refresh_token", req.PostForm.Get("grant_type"))
+			assert.Equal(t, "refresh-token", req.PostForm.Get("refresh_token"))
+			assert.Equal(t, "id", req.PostForm.Get("client_id"))
+			assert.Equal(t, "secret", req.PostForm.Get("client_secret"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"access_token":"new-access-token","expires_in":3600}`))),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+		})
+
+		token, err := provider.RefreshToken(context.Background(), "refresh-token")
+		assert.NoError(t, err)
+		assert.Equal(t, "new-access-token", token.GetAccessToken())
+		assert.Equal(t, "refresh-token", token.GetRefreshToken(), "the omitted refresh token is reused")
+	})
+
+	t.Run("new refresh token returned", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewReader(
+					[]byte(`{"access_token":"new-access-token","refresh_token":"rotated","expires_in":3600}`),
+				)),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		token, err := provider.RefreshToken(context.Background(), "refresh-token")
+		assert.NoError(t, err)
+		assert.Equal(t, "rotated", token.GetRefreshToken())
+	})
+
+	t.Run("empty refresh token returns error", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: &http.Client{}})
+
+		_, err := provider.RefreshToken(context.Background(), "")
+		assert.ErrorIs(t, err, oauth2.ErrEmptyRefreshToken)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return nil, req.Context().Err()
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := provider.RefreshToken(ctx, "refresh-token")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestGoogleProvider_GetClientCredentialsToken(t *testing.T) {
+	provider := google.NewProvider(oauth2.ProviderSetting{})
+	_, err := provider.GetClientCredentialsToken(context.Background())
+	assert.ErrorIs(t, err, oauth2.ErrGrantNotSupported)
+}
+
+func TestGoogleProvider_TokenExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantExpiry bool
+	}{
+		{name: "expiring token", body: `{"access_token":"a","expires_in":3600}`, wantExpiry: true},
+		{name: "no expires_in", body: `{"access_token":"a"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader([]byte(tt.body))),
+				}, nil
+			})
+			provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			token, err := provider.GetToken(context.Background(), "code")
+			assert.NoError(t, err)
+
+			expiring, ok := token.(oauth2.ExpiringToken)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantExpiry, expiring.HasExpiry())
+			assert.False(t, expiring.IsExpired())
+			if tt.wantExpiry {
+				assert.WithinDuration(t, time.Now().Add(time.Hour), expiring.GetExpiresAt(), time.Second)
+			} else {
+				assert.True(t, expiring.GetExpiresAt().IsZero())
+			}
+		})
+	}
+}
+
+func TestGoogleProvider_UnexpectedRedirect(t *testing.T) {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusFound,
+			Header:     http.Header{"Location": {"https://accounts.google.com/ServiceLogin"}},
+			Body:       io.NopCloser(bytes.NewReader(nil)),
+		}, nil
+	})
+	provider := google.NewProvider(oauth2.ProviderSetting{
+		Client:       client,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost",
+	})
+
+	_, err := provider.GetToken(context.Background(), "code")
+	assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+	assert.ErrorIs(t, err, oauth2.ErrUnexpectedRedirect)
+	assert.ErrorContains(t, err, "https://accounts.google.com/ServiceLogin")
+
+	_, err = provider.GetUserInfo(context.Background(), "token")
+	assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+	assert.ErrorIs(t, err, oauth2.ErrUnexpectedRedirect)
+}
+
 func TestGoogleProvider_GetAuthURL(t *testing.T) {
+	t.Run("default scopes", func(t *testing.T) {
+		scopeOf := func(setting oauth2.ProviderSetting, opts ...oauth2.AuthOption) string {
+			setting.ClientID = "client-id"
+			setting.RedirectURL = "http://localhost/callback"
+			authURL, err := google.NewProvider(setting).GetAuthURL(context.Background(), "state", opts...)
+			assert.NoError(t, err)
+
+			parsedURL, err := url.Parse(authURL)
+			assert.NoError(t, err)
+			return parsedURL.Query().Get("scope")
+		}
+
+		assert.Equal(t, []string{"openid", "email", "profile"}, google.DefaultScopes)
+		assert.Equal(t, "openid email profile", scopeOf(oauth2.ProviderSetting{}))
+
+		extended := append(slices.Clone(google.DefaultScopes), "https://www.googleapis.com/auth/calendar.readonly")
+		assert.Equal(
+			t,
+			"openid email profile https://www.googleapis.com/auth/calendar.readonly",
+			scopeOf(oauth2.ProviderSetting{Scopes: extended}),
+		)
+		assert.Equal(t, "openid", scopeOf(oauth2.ProviderSetting{Scopes: []string{"openid"}}))
+
+		defaults := google.DefaultScopes
+		t.Cleanup(func() { google.DefaultScopes = defaults })
+		google.DefaultScopes = []string{"openid", "email"}
+		assert.Equal(t, "openid email", scopeOf(oauth2.ProviderSetting{}))
+	})
+
+	t.Run("extra parameters", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		authURL, err := provider.GetAuthURL(
+			context.Background(),
+			"test-state",
+			oauth2.WithAuthParams(url.Values{
+				"login_hint":  {"user@example.com"},
+				"hd":          {"example.com"},
+				"access_type": {"online"},
+				"prompt":      {"select_account"},
+			}),
+		)
+		assert.NoError(t, err)
+
+		parsedURL, err := url.Parse(authURL)
+		assert.NoError(t, err)
+
+		params := parsedURL.Query()
+		assert.Equal(t, "user@example.com", params.Get("login_hint"))
+		assert.Equal(t, "example.com", params.Get("hd"))
+		assert.Equal(t, "online", params.Get("access_type"), "the offline default is overridden")
+		assert.Equal(t, "select_account", params.Get("prompt"))
+		assert.Equal(t, "openid email profile", params.Get("scope"), "other defaults are kept")
+		assert.Equal(t, "test-state", params.Get("state"))
+	})
+
 	t.Run("successful auth URL generation", func(t *testing.T) {
 		provider := google.NewProvider(oauth2.ProviderSetting{
 			Client:      &http.Client{},
@@ -148,25 +471,678 @@ func TestGoogleProvider_GetAuthURL(t *testing.T) {
 		assert.Equal(t, "consent", params.Get("prompt"))
 	})
 
+	t.Run("offline access translation", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		tests := []struct {
+			opts []oauth2.AuthOption
+			want string
+		}{
+			{opts: nil, want: "offline"},
+			{opts: []oauth2.AuthOption{oauth2.WithOfflineAccess(true)}, want: "offline"},
+			{opts: []oauth2.AuthOption{oauth2.WithOfflineAccess(false)}, want: "online"},
+		}
+		for _, tt := range tests {
+			authURL, err := provider.GetAuthURL(context.Background(), "state", tt.opts...)
+			assert.NoError(t, err)
+
+			parsedURL, err := url.Parse(authURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, parsedURL.Query().Get("access_type"))
+		}
+	})
+
+	t.Run("prompt translation", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		tests := []struct {
+			prompts []string
+			want    string
+		}{
+			{prompts: nil, want: "consent"},
+			{prompts: []string{oauth2.PromptSelectAccount}, want: "select_account"},
+			{
+				prompts: []string{oauth2.PromptSelectAccount, oauth2.PromptConsent},
+				want:    "select_account consent",
+			},
+			{prompts: []string{oauth2.PromptCreate}, want: "consent"},
+		}
+		for _, tt := range tests {
+			authURL, err := provider.GetAuthURL(
+				context.Background(),
+				"state",
+				oauth2.WithPrompt(tt.prompts...),
+			)
+			assert.NoError(t, err)
+
+			parsedURL, err := url.Parse(authURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, parsedURL.Query().Get("prompt"))
+		}
+	})
+
+	t.Run("conflicting prompts", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		_, err := provider.GetAuthURL(
+			context.Background(),
+			"state",
+			oauth2.WithPrompt(oauth2.PromptNone, oauth2.PromptConsent),
+		)
+		assert.ErrorIs(t, err, oauth2.ErrConflictingPrompts)
+		assert.Contains(t, err.Error(), "none consent")
+	})
+
+	t.Run("additional scopes", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		authURL, err := provider.GetAuthURL(
+			context.Background(),
+			"state",
+			oauth2.WithScopes("email", "https://www.googleapis.com/auth/drive.readonly"),
+		)
+		assert.NoError(t, err)
+
+		parsedURL, err := url.Parse(authURL)
+		assert.NoError(t, err)
+		assert.Equal(
+			t,
+			"openid email profile https://www.googleapis.com/auth/drive.readonly",
+			parsedURL.Query().Get("scope"),
+		)
+	})
+
+	t.Run("configured scopes replace the defaults", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+			Scopes:      []string{"openid", "https://www.googleapis.com/auth/drive.readonly"},
+		})
+
+		authURL, err := provider.GetAuthURL(context.Background(), "state", oauth2.WithScopes("email"))
+		assert.NoError(t, err)
+
+		parsedURL, err := url.Parse(authURL)
+		assert.NoError(t, err)
+		assert.Equal(
+			t,
+			"openid https://www.googleapis.com/auth/drive.readonly email",
+			parsedURL.Query().Get("scope"),
+		)
+	})
+
+	t.Run("oversized scope list", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		scopes := make([]string, 100)
+		for i := range scopes {
+			scopes[i] = fmt.Sprintf("https://www.googleapis.com/auth/scope-%d", i)
+		}
+
+		authURL, err := provider.GetAuthURL(context.Background(), "state", oauth2.WithScopes(scopes...))
+		assert.ErrorIs(t, err, oauth2.ErrAuthURLTooLong)
+		assert.Empty(t, authURL)
+	})
+
 	t.Run("missing redirect URL", func(t *testing.T) {
 		provider := google.NewProvider(oauth2.ProviderSetting{
 			Client:   &http.Client{},
 			ClientID: "client-id",
 		})
 		url, err := provider.GetAuthURL(context.Background(), "state")
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, oauth2.ErrRedirectURLNotSet)
+		assert.Empty(t, url)
+	})
+
+	t.Run("missing client ID", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			Client:      &http.Client{},
+			RedirectURL: "http://localhost/callback",
+		})
+		url, err := provider.GetAuthURL(context.Background(), "state")
+		assert.ErrorIs(t, err, oauth2.ErrClientIDNotSet)
 		assert.Empty(t, url)
 	})
 }
 
+func TestGoogleProvider_RevokeToken(t *testing.T) {
+	t.Run("posts the token as a form", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodPost, req.Method)
+			assert.Equal(t, google.RevokeURL, req.URL.String())
+			assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "access-token", req.PostForm.Get("token"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(nil)),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		assert.NoError(t, provider.RevokeToken(context.Background(), "access-token"))
+	})
+
+	t.Run("provider rejects token", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"error":"invalid_token"}`))),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		err := provider.RevokeToken(context.Background(), "access-token")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+		assert.Contains(t, err.Error(), "invalid_token")
+	})
+
+	t.Run("network error", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("network down")
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		err := provider.RevokeToken(context.Background(), "access-token")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+	})
+
+	t.Run("empty token", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{})
+		err := provider.RevokeToken(context.Background(), "")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+	})
+}
+
+func TestGoogleProvider_SigningKeys(t *testing.T) {
+	t.Run("fetches the JWKS", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, google.KeysURL, req.URL.String())
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewReader([]byte(
+					`{"keys":[{"kty":"RSA","kid":"key-1","alg":"RS256","use":"sig","n":"sXchDaQebHnPiGvyDOAT4saGEUetSyo9MKLOoWFsueri23bOdgWp4Dy1WlUzewbgBHod5pcM9H95GQRV3JDXboIRROSBigeC5yjU1hGzHHyXss8UDprecbAYxknTcQkhslANGRUZmdTOQ5qTRsLAt6BTYuyvVRdhS8exSZEy_c4gs_7svlJJQ4H9_NxsiIoLwAEk7-Q3UXERGYw_75IDrGA84-lA_-Ct4eTlXHBIY2EaV7t7LjJaynVJCpkv4LKjTTAumiGUIuQhrNhZLuF_RJLqHpM2kgWFLU7-VTdL1VbC2tejvcI2BlMkEpk1BzBZI0KQB0GaDWFLN-aEAw3vRw","e":"AQAB"}]}`,
+				))),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		keys, err := provider.SigningKeys(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, keys, 1)
+		assert.Equal(t, "key-1", keys[0].KeyID)
+		assert.Equal(t, "RS256", keys[0].Algorithm)
+	})
+
+	t.Run("network error", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("network down")
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.SigningKeys(context.Background())
+		assert.ErrorIs(t, err, oauth2.ErrSigningKeysFailed)
+	})
+}
+
+func TestGoogleProvider_StrictTokenType(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenType string
+		wantErr   bool
+	}{
+		{name: "bearer", tokenType: "bearer"},
+		{name: "missing", tokenType: ""},
+		{name: "unsupported", tokenType: "mac", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBody, _ := json.Marshal(map[string]any{
+				"access_token": "access-token",
+				"token_type":   tt.tokenType,
+			})
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader(mockBody)),
+				}, nil
+			})
+			provider := google.NewProvider(oauth2.ProviderSetting{
+				Client:          client,
+				ClientID:        "id",
+				ClientSecret:    "secret",
+				RedirectURL:     "http://localhost",
+				StrictTokenType: true,
+			})
+
+			_, err := provider.GetToken(context.Background(), "code")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oauth2.ErrUnsupportedTokenType)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("lenient by default", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewReader(
+					[]byte(`{"access_token":"access-token","token_type":"mac"}`),
+				)),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetToken(context.Background(), "code")
+		assert.NoError(t, err)
+	})
+}
+
+func FuzzGoogleProvider_GetUserInfo(f *testing.F) {
+	f.Add([]byte(`{"id":"1","email":"a@b.c","name":"n","picture":"p","locale":"ko"}`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`{"id":1}`))
+	f.Add([]byte(`{"id":"1","extra":{"a":[null]}}`))
+	f.Add([]byte(`not json`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		info, err := provider.GetUserInfo(context.Background(), "token")
+		if err != nil {
+			return
+		}
+		_ = info.GetID()
+		_ = info.GetEmail()
+		_ = info.GetName()
+		_ = info.GetGender()
+		_ = info.GetProfileImage()
+	})
+}
+
+func FuzzGoogleProvider_GetToken(f *testing.F) {
+	f.Add([]byte(`{"access_token":"a","refresh_token":"r","expires_in":3600}`))
+	f.Add([]byte(`{"access_token":null,"expires_in":"x"}`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`{`))
+	f.Add([]byte(`{"unknown":{"nested":[1,2,3]}}`))
+	f.Add([]byte(``))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+			RedirectURL:  "http://localhost",
+		})
+
+		token, err := provider.GetToken(context.Background(), "code")
+		if err != nil {
+			return
+		}
+		_ = token.GetAccessToken()
+		_ = token.GetRefreshToken()
+		_ = token.GetExpiry()
+	})
+}
+
 type googleUserInfoResponse struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
+	ID      string `json:"id"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	Picture string `json:"picture,omitempty"`
+	Gender  string `json:"gender,omitempty"`
 }
 
 type tokenInfoResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+}
+
+func TestGoogleProvider_AuthenticateIDTokenOnly(t *testing.T) {
+	newIDToken := func(claims map[string]any) string {
+		payload, _ := json.Marshal(claims)
+		return "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+	}
+	validClaims := map[string]any{
+		"iss":   "https://accounts.google.com",
+		"aud":   "client-id",
+		"sub":   "123",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": "test@example.com",
+		"name":  "Test User",
+	}
+
+	newClient := func(idToken string) oauth2.Client {
+		httpClient := newMockClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.String() != google.TokenURL {
+				t.Fatalf("unexpected request to %s", req.URL)
+			}
+			body, _ := json.Marshal(map[string]any{
+				"access_token": "access-token",
+				"id_token":     idToken,
+			})
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		})
+
+		return oauth2.NewClient(google.NewProvider(oauth2.ProviderSetting{
+			Client:   httpClient,
+			ClientID: "client-id",
+		}))
+	}
+
+	t.Run("user derived without userinfo request", func(t *testing.T) {
+		client := newClient(newIDToken(validClaims))
+
+		result, err := client.Authenticate(
+			context.Background(),
+			google.ProviderType,
+			"code",
+			oauth2.WithIDTokenOnly(),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, "123", result.User.GetID())
+		assert.Equal(t, "test@example.com", result.User.GetEmail())
+		assert.Equal(t, "Test User", result.User.GetName())
+		assert.Equal(t, "https://accounts.google.com", result.User.GetRaw()["iss"])
+	})
+
+	invalid := map[string]map[string]any{
+		"issuer":   {"iss": "https://evil.example.com"},
+		"audience": {"aud": "other-client"},
+		"expired":  {"exp": time.Now().Add(-time.Minute).Unix()},
+		"subject":  {"sub": ""},
+	}
+	for name, override := range invalid {
+		t.Run("invalid "+name, func(t *testing.T) {
+			claims := maps.Clone(validClaims)
+			maps.Copy(claims, override)
+			client := newClient(newIDToken(claims))
+
+			_, err := client.Authenticate(
+				context.Background(),
+				google.ProviderType,
+				"code",
+				oauth2.WithIDTokenOnly(),
+			)
+			assert.ErrorIs(t, err, oauth2.ErrInvalidIDToken)
+		})
+	}
+
+	t.Run("missing id_token", func(t *testing.T) {
+		client := newClient("")
+
+		_, err := client.Authenticate(
+			context.Background(),
+			google.ProviderType,
+			"code",
+			oauth2.WithIDTokenOnly(),
+		)
+		assert.ErrorIs(t, err, oauth2.ErrInvalidIDToken)
+	})
+}
+
+func TestGoogleProvider_HostedDomain(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("auth URL carries hd", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		}, google.WithHostedDomain("example.com"))
+
+		authURL, err := provider.GetAuthURL(ctx, "state")
+		assert.NoError(t, err)
+
+		parsedURL, err := url.Parse(authURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com", parsedURL.Query().Get("hd"))
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "matching domain", body: `{"id":"1","email":"user@example.com","hd":"example.com"}`},
+		{name: "matching domain in another case", body: `{"id":"1","email":"user@example.com","hd":"Example.com"}`},
+		{name: "other domain", body: `{"id":"1","email":"user@other.com","hd":"other.com"}`, wantErr: true},
+		{name: "consumer account with a domain email", body: `{"id":"1","email":"user@example.com"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := google.NewProvider(
+				oauth2.ProviderSetting{Client: newStaticClient([]byte(tt.body))},
+				google.WithHostedDomain("example.com"),
+			)
+
+			user, err := provider.GetUserInfo(ctx, "token")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oauth2.ErrHostedDomainMismatch)
+				assert.Nil(t, user)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "1", user.GetID())
+		})
+	}
+
+	t.Run("id_token hd claim", func(t *testing.T) {
+		tokenWithHostedDomain := func(hd string) oauth2.TokenInfo {
+			payload, _ := json.Marshal(map[string]any{
+				"iss": "https://accounts.google.com",
+				"aud": "client-id",
+				"sub": "123",
+				"exp": time.Now().Add(time.Hour).Unix(),
+				"hd":  hd,
+			})
+			idToken := "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+			body := `{"access_token":"access","token_type":"Bearer","id_token":"` + idToken + `"}`
+			provider := google.NewProvider(oauth2.ProviderSetting{Client: newStaticClient([]byte(body))})
+
+			token, err := provider.GetToken(ctx, "code")
+			assert.NoError(t, err)
+			return token
+		}
+		provider := google.NewProvider(
+			oauth2.ProviderSetting{ClientID: "client-id"},
+			google.WithHostedDomain("example.com"),
+		).(oauth2.IDTokenProvider)
+
+		user, err := provider.UserInfoFromIDToken(tokenWithHostedDomain("example.com"))
+		assert.NoError(t, err)
+		assert.Equal(t, "123", user.GetID())
+
+		_, err = provider.UserInfoFromIDToken(tokenWithHostedDomain("other.com"))
+		assert.ErrorIs(t, err, oauth2.ErrHostedDomainMismatch)
+	})
+
+	t.Run("unrestricted without the option", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			Client: newStaticClient([]byte(`{"id":"1","email":"user@gmail.com"}`)),
+		})
+
+		_, err := provider.GetUserInfo(ctx, "token")
+		assert.NoError(t, err)
+	})
+}
+
+// newStaticClient answers every request with body, as a fresh reader per call
+func newStaticClient(body []byte) *http.Client {
+	return newMockClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+		}, nil
+	})
+}
+
+// BenchmarkGoogleProvider_GetToken measures the token exchange decode path, run with -benchmem
+func BenchmarkGoogleProvider_GetToken(b *testing.B) {
+	body := []byte(`{"access_token":"access-token","expires_in":3599,"refresh_token":"refresh-token",` +
+		`"scope":"openid email profile","token_type":"Bearer","id_token":"header.payload.signature"}`)
+	provider := google.NewProvider(oauth2.ProviderSetting{Client: newStaticClient(body)})
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := provider.GetToken(ctx, "code"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGoogleProvider_GetUserInfo measures the userinfo decode path, run with -benchmem
+func BenchmarkGoogleProvider_GetUserInfo(b *testing.B) {
+	body := []byte(`{"id":"1234567890","email":"user@example.com","name":"Example User",` +
+		`"picture":"https://lh3.googleusercontent.com/a/photo","locale":"en"}`)
+	provider := google.NewProvider(oauth2.ProviderSetting{Client: newStaticClient(body)})
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := provider.GetUserInfo(ctx, "token"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGoogleProvider_DeviceFlow(t *testing.T) {
+	deviceBody := `{"device_code":"device-code","user_code":"ABCD-EFGH","verification_url":"https://www.google.com/device","expires_in":1800,"interval":1}`
+
+	t.Run("pending then success", func(t *testing.T) {
+		polls := 0
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.NoError(t, req.ParseForm())
+			if req.URL.String() == google.DeviceAuthURL {
+				assert.Equal(t, "client-id", req.PostForm.Get("client_id"))
+				assert.Equal(t, "openid email profile", req.PostForm.Get("scope"))
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(deviceBody))}, nil
+			}
+
+			assert.Equal(t, google.TokenURL, req.URL.String())
+			assert.Equal(t, oauth2.DeviceCodeGrantType, req.PostForm.Get("grant_type"))
+			assert.Equal(t, "device-code", req.PostForm.Get("device_code"))
+			polls++
+			if polls == 1 {
+				return &http.Response{
+					StatusCode: http.StatusPreconditionRequired,
+					Body:       io.NopCloser(bytes.NewBufferString(`{"error":"authorization_pending"}`)),
+				}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"access_token":"access-token","token_type":"Bearer","expires_in":3600}`)),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client, ClientID: "client-id", ClientSecret: "secret"})
+		deviceProvider, ok := provider.(oauth2.DeviceFlowProvider)
+		assert.True(t, ok)
+
+		auth, err := deviceProvider.StartDeviceFlow(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "ABCD-EFGH", auth.UserCode)
+		assert.Equal(t, "https://www.google.com/device", auth.VerificationURL)
+		assert.Equal(t, time.Second, auth.Interval)
+		assert.Equal(t, 30*time.Minute, auth.ExpiresIn)
+
+		token, err := deviceProvider.PollDeviceToken(context.Background(), auth.DeviceCode)
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token", token.GetAccessToken())
+		assert.Equal(t, 2, polls)
+	})
+
+	t.Run("slow_down backs off until ctx is done", func(t *testing.T) {
+		polls := 0
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			polls++
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"error":"slow_down"}`)),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client}).(oauth2.DeviceFlowProvider)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err := provider.PollDeviceToken(ctx, "device-code")
+		assert.ErrorIs(t, err, oauth2.ErrSlowDown)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, polls)
+	})
+
+	t.Run("denied and expired requests stop polling", func(t *testing.T) {
+		tests := map[string]error{
+			"access_denied": oauth2.ErrAccessDenied,
+			"expired_token": oauth2.ErrDeviceCodeExpired,
+		}
+		for code, wantErr := range tests {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusForbidden,
+					Body:       io.NopCloser(bytes.NewBufferString(`{"error":"` + code + `"}`)),
+				}, nil
+			})
+			provider := google.NewProvider(oauth2.ProviderSetting{Client: client}).(oauth2.DeviceFlowProvider)
+
+			_, err := provider.PollDeviceToken(context.Background(), "device-code")
+			assert.ErrorIs(t, err, wantErr)
+
+			var providerErr *oauth2.ProviderError
+			assert.ErrorAs(t, err, &providerErr)
+			assert.Equal(t, oauth2.OpPollDeviceToken, providerErr.Op)
+			assert.Equal(t, http.StatusForbidden, providerErr.StatusCode)
+		}
+	})
+
+	t.Run("device authorization failure", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"error":"invalid_client"}`)),
+			}, nil
+		})
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client}).(oauth2.DeviceFlowProvider)
+
+		_, err := provider.StartDeviceFlow(context.Background())
+		assert.ErrorIs(t, err, oauth2.ErrDeviceFlowFailed)
+	})
 }
@@ -0,0 +1,267 @@
+package oauth2
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// StateCookieName is the default cookie used to carry the OAuth2 state between login and callback
+	StateCookieName = "oauth_state"
+
+	// ProviderQueryParam is the default callback query parameter carrying the provider type
+	ProviderQueryParam = "provider"
+)
+
+type (
+	// CallbackConfig configures the handler returned by NewCallbackHandler
+	CallbackConfig struct {
+		// OnSuccess is invoked after the token and user info are fetched (e.g. to create a session).
+		// Without SuccessRedirect it is responsible for writing the response
+		OnSuccess func(w http.ResponseWriter, r *http.Request, result *AuthResult) error
+
+		// OnError writes the failure response, defaults to http.Error with a status derived from err
+		// and its generic status text. err may carry provider response bodies, so log it here rather
+		// than showing it to the user
+		OnError func(w http.ResponseWriter, r *http.Request, err error)
+
+		// SuccessRedirect switches the handler to redirect mode: after OnSuccess it responds with
+		// a 302 to this URL instead of leaving the response to OnSuccess
+		SuccessRedirect string
+
+		// ReturnURL optionally resolves a per-request return URL (e.g. carried in signed state).
+		// It is validated with ValidateReturnURL and falls back to SuccessRedirect when unsafe
+		ReturnURL func(r *http.Request) string
+
+		// AllowedRedirectHosts lists the hosts absolute return URLs may point to,
+		// relative paths on the same origin are always allowed
+		AllowedRedirectHosts []string
+
+		// StateCookie overrides the state cookie name (default StateCookieName)
+		StateCookie string
+
+		// StateTTL rejects states issued by GenerateState longer ago than this with ErrStateExpired,
+		// independently of the cookie MaxAge. Zero disables the check
+		StateTTL time.Duration
+
+		// ProviderParam overrides the provider query parameter name (default ProviderQueryParam)
+		ProviderParam string
+
+		// CodeVerifier optionally returns the PKCE verifier stored alongside the state at login
+		// (e.g. in a cookie), sent with the code exchange when not empty
+		CodeVerifier func(r *http.Request) string
+
+		// FlowSessionKey makes the state cookie carry a NewFlowSession token signed with this key.
+		// Its state, PKCE verifier and return URL are used, and FlowSessionFromRequest exposes it to OnSuccess
+		FlowSessionKey []byte
+	}
+)
+
+// NewCallbackHandler returns an http.Handler for the provider redirect back to the application.
+// It validates the state cookie, exchanges the code, fetches the user info and calls OnSuccess.
+// Callbacks posted with response_mode=form_post (e.g. Apple) are read from the form body,
+// r.PostForm stays available to OnSuccess (e.g. for apple.ParseFormUser)
+//
+//	example:
+//	mux.Handle("/callback", oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+//	    OnSuccess: func(w http.ResponseWriter, r *http.Request, result *oauth2.AuthResult) error {
+//	        return sessions.Create(w, result.Provider, result.User.GetID())
+//	    },
+//	    SuccessRedirect: "/dashboard",
+//	}))
+func NewCallbackHandler(client Client, config CallbackConfig) http.Handler {
+	if config.OnError == nil {
+		config.OnError = defaultCallbackError
+	}
+	if config.StateCookie == "" {
+		config.StateCookie = StateCookieName
+	}
+	if config.ProviderParam == "" {
+		config.ProviderParam = ProviderQueryParam
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Method == http.MethodPost {
+			if err := r.ParseForm(); err != nil {
+				config.OnError(w, r, fmt.Errorf("%w: %w", ErrEmptyAuthCode, err))
+				return
+			}
+			query = r.Form
+		}
+
+		provider := ProviderType(query.Get(config.ProviderParam))
+		if provider == "" {
+			config.OnError(w, r, ErrProviderNotSet)
+			return
+		}
+
+		var expected string
+		if cookie, err := r.Cookie(config.StateCookie); err == nil {
+			expected = cookie.Value
+		}
+		var session FlowSession
+		if config.FlowSessionKey != nil {
+			var err error
+			if session, err = VerifyFlowSession(config.FlowSessionKey, expected, config.StateTTL); err != nil {
+				config.OnError(w, r, WrapProviderError(provider, err, ""))
+				return
+			}
+			expected = session.State
+			r = withFlowSession(r, session)
+		}
+		if err := ValidateState(expected, query.Get("state"), config.StateTTL); err != nil {
+			config.OnError(w, r, WrapProviderError(provider, err, ""))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     config.StateCookie,
+			Path:     "/",
+			HttpOnly: true,
+			MaxAge:   -1,
+		})
+
+		if err := ParseCallbackError(query); err != nil {
+			config.OnError(w, r, WrapProviderError(provider, err, ""))
+			return
+		}
+
+		code := query.Get("code")
+		if code == "" {
+			config.OnError(w, r, WrapProviderError(provider, ErrEmptyAuthCode, ""))
+			return
+		}
+
+		var opts []AuthenticateOption
+		if config.CodeVerifier != nil {
+			opts = append(opts, WithPKCEVerifier(config.CodeVerifier(r)))
+		} else if session.CodeVerifier != "" {
+			opts = append(opts, WithPKCEVerifier(session.CodeVerifier))
+		}
+
+		result, err := client.Authenticate(r.Context(), provider, code, opts...)
+		if err != nil {
+			config.OnError(w, r, err)
+			return
+		}
+
+		if config.OnSuccess != nil {
+			if err := config.OnSuccess(w, r, result); err != nil {
+				config.OnError(w, r, err)
+				return
+			}
+		}
+
+		if config.SuccessRedirect != "" {
+			http.Redirect(w, r, config.successRedirect(r), http.StatusFound)
+		}
+	})
+}
+
+// successRedirect resolves the redirect target, preferring a safe per-request return URL
+// from ReturnURL or else the flow session
+func (c CallbackConfig) successRedirect(r *http.Request) string {
+	var returnURL string
+	if c.ReturnURL != nil {
+		returnURL = c.ReturnURL(r)
+	} else if session, ok := FlowSessionFromRequest(r); ok {
+		returnURL = session.ReturnURL
+	}
+
+	if returnURL == "" || ValidateReturnURL(returnURL, c.AllowedRedirectHosts...) != nil {
+		return c.SuccessRedirect
+	}
+	return returnURL
+}
+
+// CallbackURL appends the provider segment to a base callback URL for path-based routing
+//
+//	example:
+//	oauth2.CallbackURL("https://app.example.com/callback", google.ProviderType)
+//	// => https://app.example.com/callback/google
+func CallbackURL(base string, provider ProviderType) (string, error) {
+	if provider == "" {
+		return "", ErrProviderNotSet
+	}
+
+	return url.JoinPath(base, string(provider))
+}
+
+// ValidateReturnURL reports whether target is safe to redirect to after login
+//   - same-origin absolute paths ("/dashboard") are always allowed
+//   - absolute http(s) URLs are allowed only when their host is in allowedHosts
+//   - protocol-relative ("//evil.com") and backslash tricks ("/\evil.com") are rejected
+func ValidateReturnURL(target string, allowedHosts ...string) error {
+	if target == "" || strings.ContainsAny(target, "\\\r\n\t") {
+		return ErrUnsafeRedirect
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return ErrUnsafeRedirect
+	}
+
+	if parsed.Scheme == "" && parsed.Host == "" {
+		if strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") {
+			return nil
+		}
+		return ErrUnsafeRedirect
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ErrUnsafeRedirect
+	}
+	for _, host := range allowedHosts {
+		if strings.EqualFold(parsed.Host, host) {
+			return nil
+		}
+	}
+	return ErrUnsafeRedirect
+}
+
+// ParseCallbackError returns the error reported on the provider redirect, or nil when there is none
+//   - access_denied: ErrAccessDenied (the user declined consent)
+//   - interaction_required, login_required, consent_required, account_selection_required:
+//     ErrInteractionRequired (prompt=none could not complete silently)
+//   - anything else: ErrAuthorizationFailed
+//
+// The error_description is kept in the message so it can be shown or logged
+func ParseCallbackError(values url.Values) error {
+	code := values.Get("error")
+	if code == "" {
+		return nil
+	}
+
+	base := ErrAuthorizationFailed
+	switch code {
+	case "access_denied":
+		base = ErrAccessDenied
+	case "interaction_required", "login_required", "consent_required", "account_selection_required":
+		base = ErrInteractionRequired
+	}
+
+	if description := values.Get("error_description"); description != "" {
+		return fmt.Errorf("%w: %s: %s", base, code, description)
+	}
+	return fmt.Errorf("%w: %s", base, code)
+}
+
+// defaultCallbackError writes the status text of a status matching the cause of err,
+// never err itself since it may carry provider response bodies and internal causes
+func defaultCallbackError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrStateMismatch), errors.Is(err, ErrStateExpired),
+		errors.Is(err, ErrAccessDenied):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrInteractionRequired):
+		status = http.StatusUnauthorized
+	case errors.Is(err, ErrProviderNotSet), errors.Is(err, ErrEmptyAuthCode):
+		status = http.StatusBadRequest
+	}
+	http.Error(w, http.StatusText(status), status)
+}
@@ -0,0 +1,285 @@
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCallbackRequest(target, state string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.AddCookie(&http.Cookie{Name: oauth2.StateCookieName, Value: state})
+	return req
+}
+
+func TestCallbackHandler_SuccessRedirect(t *testing.T) {
+	client := oauth2.NewClient(&mockProvider{
+		typ:            "google",
+		returnToken:    dummyToken{},
+		returnUserInfo: dummyUser{},
+	})
+
+	t.Run("redirects to configured URL after success hook", func(t *testing.T) {
+		var got *oauth2.AuthResult
+		handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+			OnSuccess: func(w http.ResponseWriter, r *http.Request, result *oauth2.AuthResult) error {
+				got = result
+				return nil
+			},
+			SuccessRedirect: "/dashboard",
+		})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newCallbackRequest("/callback?provider=google&state=xyz&code=abc", "xyz"))
+
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/dashboard", rec.Header().Get("Location"))
+		assert.Equal(t, oauth2.ProviderType("google"), got.Provider)
+		assert.Equal(t, "id", got.User.GetID())
+		assert.Equal(t, "access-token", got.Token.GetAccessToken())
+	})
+
+	t.Run("uses safe return URL", func(t *testing.T) {
+		handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+			SuccessRedirect:      "/dashboard",
+			AllowedRedirectHosts: []string{"app.example.com"},
+			ReturnURL: func(r *http.Request) string {
+				return r.URL.Query().Get("return_to")
+			},
+		})
+
+		for _, returnTo := range []string{"/settings", "https://app.example.com/home"} {
+			rec := httptest.NewRecorder()
+			req := newCallbackRequest("/callback?provider=google&state=xyz&code=abc", "xyz")
+			q := req.URL.Query()
+			q.Set("return_to", returnTo)
+			req.URL.RawQuery = q.Encode()
+
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusFound, rec.Code)
+			assert.Equal(t, returnTo, rec.Header().Get("Location"))
+		}
+	})
+
+	t.Run("falls back on open redirect attempts", func(t *testing.T) {
+		handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+			SuccessRedirect: "/dashboard",
+			ReturnURL: func(r *http.Request) string {
+				return r.URL.Query().Get("return_to")
+			},
+		})
+
+		for _, returnTo := range []string{
+			"https://evil.com",
+			"//evil.com",
+			"/\\evil.com",
+			"javascript:alert(1)",
+		} {
+			rec := httptest.NewRecorder()
+			req := newCallbackRequest("/callback?provider=google&state=xyz&code=abc", "xyz")
+			q := req.URL.Query()
+			q.Set("return_to", returnTo)
+			req.URL.RawQuery = q.Encode()
+
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, "/dashboard", rec.Header().Get("Location"), returnTo)
+		}
+	})
+
+	t.Run("state mismatch", func(t *testing.T) {
+		handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+			SuccessRedirect: "/dashboard",
+		})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newCallbackRequest("/callback?provider=google&state=xyz&code=abc", "other"))
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, rec.Header().Get("Location"))
+	})
+}
+
+// formPostProvider is a FormPostProvider recording the code exchanged by the callback handler
+type formPostProvider struct {
+	*mockProvider
+	gotCode string
+}
+
+func (p *formPostProvider) UsesFormPost() bool { return true }
+
+func (p *formPostProvider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	p.gotCode = code
+	return p.mockProvider.GetToken(ctx, code, opts...)
+}
+
+func TestCallbackHandler_FormPost(t *testing.T) {
+	provider := &formPostProvider{mockProvider: &mockProvider{
+		typ:            "apple",
+		redirectURL:    "https://app.example.com/callback",
+		returnToken:    dummyToken{},
+		returnUserInfo: dummyUser{},
+	}}
+	client := oauth2.NewClient(provider)
+
+	_, cookie, err := client.BeginLogin(context.Background(), "apple")
+	assert.NoError(t, err)
+	assert.Equal(t, http.SameSiteNoneMode, cookie.SameSite)
+	assert.True(t, cookie.Secure, "browsers drop SameSite=None cookies without Secure")
+
+	_, wrapped, err := oauth2.NewClient(oauth2.WithRateLimit(provider, 10, 1)).BeginLogin(context.Background(), "apple")
+	assert.NoError(t, err)
+	assert.Equal(t, http.SameSiteNoneMode, wrapped.SameSite, "wrappers keep the form_post cookie")
+
+	var formUser string
+	handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+		OnSuccess: func(w http.ResponseWriter, r *http.Request, result *oauth2.AuthResult) error {
+			formUser = r.PostForm.Get("user")
+			return nil
+		},
+		SuccessRedirect: "/dashboard",
+	})
+
+	form := url.Values{}
+	form.Set("code", "abc")
+	form.Set("state", cookie.Value)
+	form.Set("user", `{"email":"jane@example.com"}`)
+	req := httptest.NewRequest(http.MethodPost, "/callback?provider=apple", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/dashboard", rec.Header().Get("Location"))
+	assert.Equal(t, "abc", provider.gotCode)
+	assert.Equal(t, `{"email":"jane@example.com"}`, formUser)
+
+	t.Run("posted state must match the cookie", func(t *testing.T) {
+		form.Set("state", "other")
+		req := httptest.NewRequest(http.MethodPost, "/callback?provider=apple", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(cookie)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestValidateReturnURL(t *testing.T) {
+	tests := []struct {
+		target  string
+		allowed []string
+		wantErr bool
+	}{
+		{target: "/dashboard"},
+		{target: "/path?x=1#frag"},
+		{target: "https://app.example.com/x", allowed: []string{"app.example.com"}},
+		{target: "", wantErr: true},
+		{target: "dashboard", wantErr: true},
+		{target: "//evil.com", wantErr: true},
+		{target: "/\\evil.com", wantErr: true},
+		{target: "https://evil.com", wantErr: true},
+		{target: "https://evil.com", allowed: []string{"app.example.com"}, wantErr: true},
+		{target: "javascript:alert(1)", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := oauth2.ValidateReturnURL(tt.target, tt.allowed...)
+		if tt.wantErr {
+			assert.ErrorIs(t, err, oauth2.ErrUnsafeRedirect, tt.target)
+		} else {
+			assert.NoError(t, err, tt.target)
+		}
+	}
+}
+
+func TestCallbackURL(t *testing.T) {
+	callbackURL, err := oauth2.CallbackURL("https://app.example.com/callback", "google")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.example.com/callback/google", callbackURL)
+
+	callbackURL, err = oauth2.CallbackURL("https://app.example.com/auth/callback/", "kakao")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.example.com/auth/callback/kakao", callbackURL)
+
+	_, err = oauth2.CallbackURL("https://app.example.com/callback", "")
+	assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+}
+
+func TestParseCallbackError(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantErr  error
+		contains string
+	}{
+		{name: "no error", query: "code=abc&state=xyz"},
+		{
+			name:     "access denied",
+			query:    "error=access_denied&error_description=User+denied+access",
+			wantErr:  oauth2.ErrAccessDenied,
+			contains: "User denied access",
+		},
+		{name: "login required", query: "error=login_required", wantErr: oauth2.ErrInteractionRequired},
+		{name: "consent required", query: "error=consent_required", wantErr: oauth2.ErrInteractionRequired},
+		{
+			name:     "other error",
+			query:    "error=server_error&error_description=try+later",
+			wantErr:  oauth2.ErrAuthorizationFailed,
+			contains: "server_error: try later",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+
+			err := oauth2.ParseCallbackError(values)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Contains(t, err.Error(), tt.contains)
+		})
+	}
+}
+
+func TestCallbackHandler_ProviderError(t *testing.T) {
+	client := oauth2.NewClient(&mockProvider{typ: "google"})
+	handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{SuccessRedirect: "/dashboard"})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newCallbackRequest(
+		"/callback?provider=google&state=xyz&error=access_denied&error_description=Canceled+By+User",
+		"xyz",
+	))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, http.StatusText(http.StatusForbidden)+"\n", rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "Canceled By User")
+
+	// the detail still reaches a custom OnError, e.g. to log it
+	var got error
+	handler = oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+		SuccessRedirect: "/dashboard",
+		OnError:         func(w http.ResponseWriter, r *http.Request, err error) { got = err },
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), newCallbackRequest(
+		"/callback?provider=google&state=xyz&error=access_denied&error_description=Canceled+By+User",
+		"xyz",
+	))
+	assert.ErrorIs(t, got, oauth2.ErrAccessDenied)
+	assert.Contains(t, got.Error(), "Canceled By User")
+}
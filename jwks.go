@@ -0,0 +1,274 @@
+package oauth2
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+type (
+	// PublicKeyInfo is a provider signing key from its JWKS, ready for offline id_token verification
+	PublicKeyInfo struct {
+		// KeyID is the "kid" matched against the JWT header
+		KeyID string
+
+		// Algorithm is the "alg" the key is used with (e.g. RS256), empty when the JWKS omits it
+		Algorithm string
+
+		// Key is an *rsa.PublicKey or *ecdsa.PublicKey
+		Key crypto.PublicKey
+	}
+
+	// jsonWebKey is a single key of a JWKS, only the RSA and EC members are read
+	jsonWebKey struct {
+		KeyType   string `json:"kty"`
+		KeyID     string `json:"kid"`
+		Algorithm string `json:"alg"`
+		Use       string `json:"use"`
+		N         string `json:"n"`
+		E         string `json:"e"`
+		Curve     string `json:"crv"`
+		X         string `json:"x"`
+		Y         string `json:"y"`
+	}
+)
+
+// FetchJWKS GETs the key set at jwksURL and parses it with ParseJWKS
+func (r *Requester) FetchJWKS(ctx context.Context, jwksURL string) ([]PublicKeyInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	return ParseJWKS(resp.Body)
+}
+
+// ParseJWKS parses a JSON Web Key Set (RFC 7517) into its signing keys
+//   - RSA and EC (P-256, P-384, P-521) keys are returned, other key types are skipped
+//   - keys marked for encryption ("use": "enc") or with invalid parameters are skipped
+func ParseJWKS(body []byte) ([]PublicKeyInfo, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make([]PublicKeyInfo, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use == "enc" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		switch jwk.KeyType {
+		case "RSA":
+			key = jwk.rsaKey()
+		case "EC":
+			key = jwk.ecKey()
+		}
+		if key == nil {
+			continue
+		}
+
+		keys = append(keys, PublicKeyInfo{KeyID: jwk.KeyID, Algorithm: jwk.Algorithm, Key: key})
+	}
+
+	return keys, nil
+}
+
+// rsaKey decodes the modulus and exponent, nil when they are invalid
+func (k jsonWebKey) rsaKey() crypto.PublicKey {
+	n, nErr := base64.RawURLEncoding.DecodeString(k.N)
+	e, eErr := base64.RawURLEncoding.DecodeString(k.E)
+	if nErr != nil || eErr != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+		return nil
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}
+}
+
+// ecKey decodes the curve point, nil when it is invalid or not on the curve
+func (k jsonWebKey) ecKey() crypto.PublicKey {
+	var (
+		curve     elliptic.Curve
+		ecdhCurve ecdh.Curve
+	)
+	switch k.Curve {
+	case "P-256":
+		curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+	default:
+		return nil
+	}
+
+	x, xErr := base64.RawURLEncoding.DecodeString(k.X)
+	y, yErr := base64.RawURLEncoding.DecodeString(k.Y)
+	size := (curve.Params().BitSize + 7) / 8
+	if xErr != nil || yErr != nil || len(x) != size || len(y) != size {
+		return nil
+	}
+
+	// ecdh rejects points that are not on the curve
+	uncompressed := append(append([]byte{4}, x...), y...)
+	if _, err := ecdhCurve.NewPublicKey(uncompressed); err != nil {
+		return nil
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+}
+
+// DefaultJWKSMinRefreshInterval is the default minimum time between two JWKSCache fetches
+const DefaultJWKSMinRefreshInterval = 10 * time.Second
+
+type (
+	// JWKSCache serves the keys of a JWKS endpoint by kid, refetching them after a TTL
+	// or when an unknown kid shows up after a key rotation. It is safe for concurrent use
+	JWKSCache struct {
+		requester          *Requester
+		url                string
+		ttl                time.Duration
+		minRefreshInterval time.Duration
+
+		mu          sync.Mutex
+		keys        []PublicKeyInfo
+		fetchedAt   time.Time
+		lastAttempt time.Time
+	}
+
+	// JWKSCacheOption customizes a JWKSCache
+	JWKSCacheOption func(*JWKSCache)
+)
+
+// WithMinRefreshInterval overrides DefaultJWKSMinRefreshInterval, the minimum time between
+// two fetches. It keeps tokens with random kids from hammering the endpoint
+func WithMinRefreshInterval(interval time.Duration) JWKSCacheOption {
+	return func(c *JWKSCache) {
+		c.minRefreshInterval = interval
+	}
+}
+
+// NewJWKSCache creates a cache for the key set at jwksURL fetched with client (nil uses the default client).
+// Keys are fetched lazily on first use and kept for ttl
+//
+//	example:
+//	keys := oauth2.NewJWKSCache(google.KeysURL, httpClient, time.Hour)
+//	err := oidc.VerifyIDToken(ctx, token.GetIDToken(), "https://accounts.google.com", clientID, keys)
+func NewJWKSCache(jwksURL string, client *http.Client, ttl time.Duration, opts ...JWKSCacheOption) *JWKSCache {
+	cache := &JWKSCache{
+		requester:          NewRequester(ProviderSetting{Client: client}),
+		url:                jwksURL,
+		ttl:                ttl,
+		minRefreshInterval: DefaultJWKSMinRefreshInterval,
+	}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	return cache
+}
+
+// Key returns the public key for kid
+//   - the key set is refetched once it is older than the TTL, stale keys are served if that fails
+//   - an unknown kid triggers one refetch (the provider may have rotated its keys),
+//     at most once per minimum refresh interval, then fails with ErrSigningKeyNotFound
+func (c *JWKSCache) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	if c.expired() {
+		_, err = c.refresh(ctx)
+	}
+	if key, ok := c.find(kid); ok {
+		return key, nil
+	}
+
+	if err == nil {
+		var refreshed bool
+		if refreshed, err = c.refresh(ctx); refreshed {
+			if key, ok := c.find(kid); ok {
+				return key, nil
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: kid %s", ErrSigningKeyNotFound, kid)
+}
+
+// SigningKeys returns every cached key, refetching them once they are older than the TTL.
+// It lets a JWKSCache stand in for a provider as the key source of an id_token verifier
+func (c *JWKSCache) SigningKeys(ctx context.Context) ([]PublicKeyInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.expired() {
+		if _, err := c.refresh(ctx); err != nil && c.keys == nil {
+			return nil, err
+		}
+	}
+	return slices.Clone(c.keys), nil
+}
+
+// expired reports whether the key set was never fetched or is older than the TTL.
+// It must be called with c.mu held
+func (c *JWKSCache) expired() bool {
+	return c.fetchedAt.IsZero() || time.Since(c.fetchedAt) >= c.ttl
+}
+
+// find returns the cached key for kid. It must be called with c.mu held
+func (c *JWKSCache) find(kid string) (crypto.PublicKey, bool) {
+	for _, key := range c.keys {
+		if key.KeyID == kid {
+			return key.Key, true
+		}
+	}
+	return nil, false
+}
+
+// refresh fetches the key set unless the last attempt is more recent than the minimum
+// refresh interval, reporting whether it did. It must be called with c.mu held
+func (c *JWKSCache) refresh(ctx context.Context) (bool, error) {
+	if !c.lastAttempt.IsZero() && time.Since(c.lastAttempt) < c.minRefreshInterval {
+		return false, nil
+	}
+	c.lastAttempt = time.Now()
+
+	keys, err := c.requester.FetchJWKS(ctx, c.url)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrSigningKeysFailed, err)
+	}
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return true, nil
+}
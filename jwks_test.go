@@ -0,0 +1,256 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// mockJWKS returns a key set with one RSA key, one EC key and entries that must be skipped
+func mockJWKS(t *testing.T) ([]byte, *rsa.PublicKey, *ecdsa.PublicKey) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	body, _ := json.Marshal(map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   b64(rsaKey.N.Bytes()),
+				"e":   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-1",
+				"alg": "ES256",
+				"crv": "P-256",
+				"x":   b64(ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   b64(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+			{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": b64(rsaKey.N.Bytes()), "e": "AQAB"},
+			{"kty": "EC", "kid": "off-curve", "crv": "P-256", "x": b64(make([]byte, 32)), "y": b64(make([]byte, 32))},
+			{"kty": "oct", "kid": "secret", "k": "c2VjcmV0"},
+		},
+	})
+	return body, &rsaKey.PublicKey, &ecKey.PublicKey
+}
+
+func TestParseJWKS(t *testing.T) {
+	t.Run("RSA and EC keys are returned", func(t *testing.T) {
+		body, rsaKey, ecKey := mockJWKS(t)
+
+		keys, err := oauth2.ParseJWKS(body)
+		assert.NoError(t, err)
+		assert.Len(t, keys, 2)
+
+		assert.Equal(t, "rsa-1", keys[0].KeyID)
+		assert.Equal(t, "RS256", keys[0].Algorithm)
+		assert.True(t, rsaKey.Equal(keys[0].Key))
+
+		assert.Equal(t, "ec-1", keys[1].KeyID)
+		assert.Equal(t, "ES256", keys[1].Algorithm)
+		assert.True(t, ecKey.Equal(keys[1].Key))
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		_, err := oauth2.ParseJWKS([]byte("not json"))
+		assert.Error(t, err)
+	})
+}
+
+func TestRequester_FetchJWKS(t *testing.T) {
+	body, _, _ := mockJWKS(t)
+
+	t.Run("fetches the key set", func(t *testing.T) {
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, http.MethodGet, req.Method)
+				assert.Equal(t, "https://provider.test/jwks", req.URL.String())
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+			})},
+		})
+
+		keys, err := requester.FetchJWKS(context.Background(), "https://provider.test/jwks")
+		assert.NoError(t, err)
+		assert.Len(t, keys, 2)
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader("down"))}, nil
+			})},
+		})
+
+		_, err := requester.FetchJWKS(context.Background(), "https://provider.test/jwks")
+		assert.ErrorContains(t, err, "down")
+	})
+}
+
+// jwksServer serves an RSA key under each of its kids and counts the fetches
+type jwksServer struct {
+	mu      sync.Mutex
+	kids    []string
+	key     *rsa.PublicKey
+	fail    bool
+	fetches int
+}
+
+func (s *jwksServer) setKids(kids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kids = kids
+}
+
+func (s *jwksServer) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func (s *jwksServer) client() *http.Client {
+	return &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		if s.fail {
+			return nil, errors.New("connection refused")
+		}
+
+		keys := make([]map[string]string, 0, len(s.kids))
+		for _, kid := range s.kids {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"n":   b64(s.key.N.Bytes()),
+				"e":   b64(big.NewInt(int64(s.key.E)).Bytes()),
+			})
+		}
+		body, _ := json.Marshal(map[string]any{"keys": keys})
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body))}, nil
+	})}
+}
+
+func TestJWKSCache(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	newServer := func(kids ...string) *jwksServer {
+		return &jwksServer{kids: kids, key: &rsaKey.PublicKey}
+	}
+
+	t.Run("cache hit", func(t *testing.T) {
+		server := newServer("a")
+		cache := oauth2.NewJWKSCache("https://provider.test/jwks", server.client(), time.Hour)
+
+		for range 3 {
+			key, err := cache.Key(ctx, "a")
+			assert.NoError(t, err)
+			assert.True(t, rsaKey.PublicKey.Equal(key))
+		}
+		assert.Equal(t, 1, server.fetches)
+	})
+
+	t.Run("expiry refresh", func(t *testing.T) {
+		server := newServer("a")
+		cache := oauth2.NewJWKSCache(
+			"https://provider.test/jwks",
+			server.client(),
+			10*time.Millisecond,
+			oauth2.WithMinRefreshInterval(0),
+		)
+
+		_, err := cache.Key(ctx, "a")
+		assert.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		_, err = cache.Key(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, server.fetches)
+	})
+
+	t.Run("unknown kid refetch", func(t *testing.T) {
+		server := newServer("a")
+		cache := oauth2.NewJWKSCache(
+			"https://provider.test/jwks",
+			server.client(),
+			time.Hour,
+			oauth2.WithMinRefreshInterval(0),
+		)
+
+		_, err := cache.Key(ctx, "a")
+		assert.NoError(t, err)
+
+		server.setKids("a", "b")
+		key, err := cache.Key(ctx, "b")
+		assert.NoError(t, err)
+		assert.NotNil(t, key)
+		assert.Equal(t, 2, server.fetches)
+	})
+
+	t.Run("unknown kids are throttled", func(t *testing.T) {
+		server := newServer("a")
+		cache := oauth2.NewJWKSCache("https://provider.test/jwks", server.client(), time.Hour)
+
+		for _, kid := range []string{"x", "y", "z"} {
+			_, err := cache.Key(ctx, kid)
+			assert.ErrorIs(t, err, oauth2.ErrSigningKeyNotFound)
+		}
+		assert.Equal(t, 1, server.fetches)
+	})
+
+	t.Run("stale keys are served when refresh fails", func(t *testing.T) {
+		server := newServer("a")
+		cache := oauth2.NewJWKSCache(
+			"https://provider.test/jwks",
+			server.client(),
+			10*time.Millisecond,
+			oauth2.WithMinRefreshInterval(0),
+		)
+
+		_, err := cache.Key(ctx, "a")
+		assert.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		server.setFail(true)
+
+		key, err := cache.Key(ctx, "a")
+		assert.NoError(t, err)
+		assert.NotNil(t, key)
+
+		_, err = cache.Key(ctx, "b")
+		assert.ErrorIs(t, err, oauth2.ErrSigningKeysFailed)
+	})
+
+	t.Run("signing keys", func(t *testing.T) {
+		server := newServer("a", "b")
+		cache := oauth2.NewJWKSCache("https://provider.test/jwks", server.client(), time.Hour)
+
+		keys, err := cache.SigningKeys(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, keys, 2)
+		_, err = cache.Key(ctx, "b")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, server.fetches)
+	})
+}
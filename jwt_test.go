@@ -0,0 +1,46 @@
+package oauth2_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeJWTClaims(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"123","email":"a@b.c"}`))
+
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+	}
+	err := oauth2.DecodeJWTClaims("header."+payload+".signature", &claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "123", claims.Subject)
+	assert.Equal(t, "a@b.c", claims.Email)
+
+	invalidJSON := base64.RawURLEncoding.EncodeToString([]byte("["))
+	for _, token := range []string{"", "a.b", "header.!!!.signature", "header." + invalidJSON + ".sig"} {
+		assert.ErrorIs(t, oauth2.DecodeJWTClaims(token, &claims), oauth2.ErrInvalidIDToken, token)
+	}
+}
+
+func TestBoolClaim(t *testing.T) {
+	for body, want := range map[string]bool{
+		`{"v":true}`:    true,
+		`{"v":"true"}`:  true,
+		`{"v":false}`:   false,
+		`{"v":"false"}`: false,
+		`{"v":"yes"}`:   false,
+		`{"v":null}`:    false,
+		`{}`:            false,
+	} {
+		var claims struct {
+			V oauth2.BoolClaim `json:"v"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(body), &claims), body)
+		assert.Equal(t, want, bool(claims.V), body)
+	}
+}
@@ -1,13 +1,13 @@
 package kakao
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dings-things/oauth2"
 )
@@ -25,29 +25,59 @@ const (
 
 	// TokenURL is the endpoint to exchange authorization code for access token
 	TokenURL = "https://kauth.kakao.com/oauth/token"
+
+	// LogoutURL is the endpoint to expire the user's access and refresh tokens
+	LogoutURL = "https://kapi.kakao.com/v1/user/logout"
+
+	// UnlinkURL is the endpoint to disconnect the user from the app
+	UnlinkURL = "https://kapi.kakao.com/v1/user/unlink"
+
+	// KeysURL is the JWKS endpoint serving the OpenID Connect id_token signing keys
+	KeysURL = "https://kauth.kakao.com/.well-known/jwks.json"
 )
 
 type (
 	// provider stores Kakao-specific OAuth2 credentials and config
 	provider struct {
-		client       *http.Client
+		requester    *oauth2.Requester
 		clientID     string
 		clientSecret string
 		redirectURL  string
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
 	}
 
 	// userInfo holds the response structure returned from Kakao user info API
 	userInfo struct {
-		ID          int `json:"id"`
+		ID          int64 `json:"id"`
 		AccountInfo struct {
 			Email   string `json:"email"`
 			Profile struct {
 				NickName        string `json:"nickname"`
 				ProfileImageURL string `json:"profile_image_url"`
 			} `json:"profile"`
-			Gender string `json:"gender"`
-			Name   string `json:"name"`
+			Gender      string `json:"gender"`
+			Name        string `json:"name"`
+			PhoneNumber string `json:"phone_number"`
+			Birthday    string `json:"birthday"`
+			BirthYear   string `json:"birthyear"`
+
+			IsEmailValid    bool `json:"is_email_valid"`
+			IsEmailVerified bool `json:"is_email_verified"`
 		} `json:"kakao_account"`
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
 	}
 
 	// tokenInfo holds token response returned from Kakao token endpoint
@@ -55,41 +85,116 @@ type (
 		AccessToken  string `json:"access_token"`
 		RefreshToken string `json:"refresh_token"`
 		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+		TokenType    string `json:"token_type"`
+		IDToken      string `json:"id_token"`
+
+		RefreshTokenExpiresIn int `json:"refresh_token_expires_in"`
+
+		issuedAt time.Time
 	}
 )
 
+// provider must keep implementing oauth2.Provider
+var _ oauth2.Provider = (*provider)(nil)
+
+func init() {
+	oauth2.RegisterConstructor(ProviderType, NewProvider)
+}
+
 // NewProvider initializes the Kakao OAuth2 provider with given settings
 func NewProvider(setting oauth2.ProviderSetting) oauth2.Provider {
 	return &provider{
-		client:       setting.Client,
+		requester:    oauth2.NewRequester(setting),
 		clientID:     setting.ClientID,
 		clientSecret: setting.ClientSecret,
 		redirectURL:  setting.RedirectURL,
+
+		strictTokenType:  setting.StrictTokenType,
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodPost),
+
+		authURL:  cmp.Or(setting.AuthURL, AuthURL),
+		tokenURL: cmp.Or(setting.TokenURL, TokenURL),
+
+		userInfoURL:          cmp.Or(setting.UserInfoURL, UserInfoURL),
+		userInfoFallbackURLs: setting.UserInfoFallbackURLs,
 	}
 }
 
+// NewProviderWithError is NewProvider failing with ErrClientIDNotSet or ErrInvalidRedirectURL
+// instead of building a provider whose calls cannot succeed.
+// ClientSecret may stay empty since Kakao's client secret is optional, enabled in the console
+func NewProviderWithError(setting oauth2.ProviderSetting) (oauth2.Provider, error) {
+	if err := setting.Validate(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+	if setting.ClientID == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+	return NewProvider(setting), nil
+}
+
 // GetAuthURL generates the URL to redirect the user for Kakao OAuth2 login
-func (k *provider) GetAuthURL(ctx context.Context, state string) (string, error) {
+//   - WithOfflineAccess is ignored since Kakao always issues a refresh token
+//   - ProviderSetting.Scopes and WithScopes ask for consent items via the comma-delimited scope parameter
+//   - WithPrompt forwards none, login, create and select_account
+//   - WithPKCE adds the S256 code_challenge
+func (k *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
 	if k.redirectURL == "" {
 		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
 	}
+	if k.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+
+	options := oauth2.NewAuthOptions(opts...)
+	if err := oauth2.ValidatePrompts(options.Prompts); err != nil {
+		return "", oauth2.WrapProviderError(ProviderType, err, strings.Join(options.Prompts, " "))
+	}
 
 	query := url.Values{}
 	query.Set("client_id", k.clientID)
 	query.Set("redirect_uri", k.redirectURL)
 	query.Set("response_type", "code")
 	query.Set("state", state)
+	if scopes := oauth2.NormalizeScopes(k.scopes, options.Scopes); len(scopes) > 0 {
+		query.Set("scope", strings.Join(scopes, ","))
+	}
+	if prompts := oauth2.SupportedPrompts(
+		options.Prompts,
+		oauth2.PromptNone,
+		oauth2.PromptLogin,
+		oauth2.PromptCreate,
+		oauth2.PromptSelectAccount,
+	); len(prompts) > 0 {
+		query.Set("prompt", strings.Join(prompts, ","))
+	}
+	oauth2.SetCodeChallenge(query, options.CodeVerifier)
+	oauth2.SetNonce(query, options.Nonce)
+	oauth2.SetAuthParams(query, options.AuthParams)
 
-	return AuthURL + "?" + query.Encode(), nil
+	return oauth2.BuildAuthURL(ProviderType, k.authURL, query, k.authURLLimits)
 }
 
 // GetToken exchanges the authorization code for an access token from Kakao
-func (k *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+func (k *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
 	var tokenInfo tokenInfo
 
 	if code == "" {
 		return tokenInfo, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
 	}
+	ctx = oauth2.WithoutRetry(ctx)
 
 	form := url.Values{}
 	form.Set("grant_type", "authorization_code")
@@ -97,11 +202,12 @@ func (k *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo,
 	form.Set("redirect_uri", k.redirectURL)
 	form.Set("code", code)
 	form.Set("client_secret", k.clientSecret)
+	oauth2.SetCodeVerifier(form, oauth2.NewAuthOptions(opts...).CodeVerifier)
 
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		TokenURL,
+		k.tokenURL,
 		strings.NewReader(form.Encode()),
 	)
 	if err != nil {
@@ -113,39 +219,29 @@ func (k *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo,
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := k.client.Do(req)
+	end := k.requester.Observe(ctx, ProviderType, oauth2.OpGetToken)
+	resp, err := k.requester.Do(req)
+	end(resp, err)
 	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			err.Error(),
-		)
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
 			ProviderType,
+			oauth2.OpGetToken,
 			oauth2.ErrTokenRequestFailed,
-			err.Error(),
+			resp,
 		)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return tokenInfo, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			string(body),
-		)
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
 	}
+	tokenInfo.issuedAt = time.Now()
 
-	if err := json.Unmarshal(body, &tokenInfo); err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			err.Error(),
-		)
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, k.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
 	}
 
 	return tokenInfo, nil
@@ -153,7 +249,11 @@ func (k *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo,
 
 // GetUserInfo retrieves the Kakao user's profile using the access token
 func (k *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, UserInfoURL, nil)
+	if accessToken == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAccessToken, "")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.userInfoURL, nil)
 	if err != nil {
 		return nil, oauth2.WrapProviderError(
 			ProviderType,
@@ -163,35 +263,31 @@ func (k *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
+	oauth2.SetAcceptLanguage(req)
 
-	resp, err := k.client.Do(req)
+	end := k.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+	resp, err := k.requester.DoWithFallback(req, k.userInfoFallbackURLs)
+	end(resp, err)
 	if err != nil {
-		return nil, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrUserInfoRequestFailed,
-			err.Error(),
-		)
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, oauth2.WrapProviderError(
+	if resp.StatusCode != http.StatusOK {
+		return nil, oauth2.WrapResponseError(
 			ProviderType,
+			oauth2.OpGetUserInfo,
 			oauth2.ErrUserInfoRequestFailed,
-			err.Error(),
+			resp,
 		)
 	}
 
 	var userInfo userInfo
-	if err := json.Unmarshal(body, &userInfo); err != nil {
-		return nil, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrUserInfoRequestFailed,
-			err.Error(),
-		)
+	if err := oauth2.DecodeJSONResponse(resp, &userInfo); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
 	}
 
+	userInfo.raw = oauth2.DecodeRawUserInfo(resp.Body)
+	userInfo.nameStrategy = k.nameStrategy
+
 	return &userInfo, nil
 }
 
@@ -215,7 +311,7 @@ func (k *provider) RefreshToken(
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		TokenURL,
+		k.tokenURL,
 		strings.NewReader(form.Encode()),
 	)
 	if err != nil {
@@ -227,67 +323,180 @@ func (k *provider) RefreshToken(
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := k.client.Do(req)
+	end := k.requester.Observe(ctx, ProviderType, oauth2.OpRefreshToken)
+	resp, err := k.requester.Do(req)
+	end(resp, err)
 	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
 			ProviderType,
+			oauth2.OpRefreshToken,
 			oauth2.ErrTokenRequestFailed,
-			err.Error(),
+			resp,
 		)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	// keep the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = refreshToken
+	}
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, k.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
+	return tokenInfo, nil
+}
+
+// GetClientCredentialsToken is not supported since Kakao only issues user tokens
+func (k *provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrGrantNotSupported, "client_credentials")
+}
+
+// RevokeToken logs the user out of Kakao, expiring the given access token and its refresh token
+func (k *provider) RevokeToken(ctx context.Context, accessToken string) error {
+	if accessToken == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRevocationFailed, "token is empty")
+	}
+
+	req, err := oauth2.NewFormRequest(ctx, k.revocationMethod, LogoutURL, url.Values{})
 	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+		return oauth2.WrapProviderError(
 			ProviderType,
-			oauth2.ErrTokenRequestFailed,
+			oauth2.ErrTokenRevocationFailed,
 			err.Error(),
 		)
 	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := k.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRevocationFailed, err)
+	}
 
 	if resp.StatusCode != http.StatusOK {
-		return tokenInfo, oauth2.WrapProviderError(
+		return oauth2.WrapResponseError(
 			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			string(body),
+			oauth2.OpRevokeToken,
+			oauth2.ErrTokenRevocationFailed,
+			resp,
 		)
 	}
 
-	if err := json.Unmarshal(body, &tokenInfo); err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+	return nil
+}
+
+// GetProvider returns the provider type ("kakao")
+func (k provider) GetProvider() oauth2.ProviderType { return ProviderType }
+
+// Unlink disconnects the user of the access token from the app, which also expires their tokens.
+// The user has to consent again on the next login
+func (k *provider) Unlink(ctx context.Context, accessToken string) error {
+	if accessToken == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrUnlinkFailed, "token is empty")
+	}
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, UnlinkURL, url.Values{})
+	if err != nil {
+		return oauth2.WrapProviderError(
 			ProviderType,
-			oauth2.ErrTokenRequestFailed,
+			oauth2.ErrUnlinkFailed,
 			err.Error(),
 		)
 	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	return tokenInfo, nil
+	resp, err := k.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrUnlinkFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpUnlink,
+			oauth2.ErrUnlinkFailed,
+			resp,
+		)
+	}
+
+	return nil
 }
 
-// GetProvider returns the provider type ("kakao")
-func (k provider) GetProvider() oauth2.ProviderType { return ProviderType }
+// CanRefresh reports whether token still holds a refresh token that has not expired.
+// Kakao omits refresh_token on refresh unless it is rotated, so keep the previous one in that case
+func (k provider) CanRefresh(token oauth2.TokenInfo) bool { return oauth2.CanRefresh(token) }
+
+// SigningKeys fetches the id_token signing keys served at KeysURL
+func (k *provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	keys, err := k.requester.FetchJWKS(ctx, KeysURL)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrSigningKeysFailed, err)
+	}
+	return keys, nil
+}
+
+// GetRedirectURL returns the configured redirect URL
+func (k provider) GetRedirectURL() string { return k.redirectURL }
 
 // GetID returns the user ID as string
-func (k userInfo) GetID() string { return strconv.Itoa(k.ID) }
+func (k userInfo) GetID() string { return strconv.FormatInt(k.ID, 10) }
+
+// GetNumericID returns the user's Kakao ID in its native numeric form
+func (k userInfo) GetNumericID() (int64, bool) { return k.ID, true }
 
 // GetEmail returns the user's email address
 func (k userInfo) GetEmail() string { return k.AccountInfo.Email }
 
-// GetName returns the user's nickname
+// GetName returns the user's name, nickname or email depending on the NameStrategy
 func (k userInfo) GetName() string {
-	if k.AccountInfo.Name == "" {
-		return k.AccountInfo.Profile.NickName
-	}
-	return k.AccountInfo.Name
+	return oauth2.SelectName(
+		k.nameStrategy,
+		k.AccountInfo.Name,
+		k.AccountInfo.Profile.NickName,
+		k.AccountInfo.Email,
+	)
 }
 
 // GetGender returns the user's gender
 func (k userInfo) GetGender() string { return k.AccountInfo.Gender }
 
+// GetGenderNormalized maps the raw gender to an oauth2.Gender
+func (k userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.NormalizeGender(k.GetGender()) }
+
 // GetProfileImage returns the user's profile image URL
 func (k userInfo) GetProfileImage() string { return k.AccountInfo.Profile.ProfileImageURL }
 
+// GetPhoneNumber returns the phone number as Kakao formats it (e.g. "+82 10-1234-5678"),
+// it is not converted to E.164. Empty without the phone_number consent item
+func (k userInfo) GetPhoneNumber() string { return k.AccountInfo.PhoneNumber }
+
+// GetBirthday combines birthyear and the MMDD birthday into YYYY-MM-DD (MM-DD without the
+// birthyear consent item), empty without the birthday consent item
+func (k userInfo) GetBirthday() string {
+	return oauth2.FormatBirthday(k.AccountInfo.BirthYear, k.AccountInfo.Birthday)
+}
+
+// GetLocale returns an empty string since Kakao has no locale field
+func (k userInfo) GetLocale() string { return "" }
+
+// IsEmailVerified reports whether Kakao verified the email and it is still valid,
+// an expired address may since have been reassigned to someone else
+func (k userInfo) IsEmailVerified() bool {
+	return k.AccountInfo.IsEmailVerified && k.AccountInfo.IsEmailValid
+}
+
+// GetRaw returns the decoded /v2/user/me response (e.g. "kakao_account" with birthday and phone_number)
+func (k userInfo) GetRaw() map[string]any { return k.raw }
+
 // GetAccessToken returns the OAuth2 access token
 func (k tokenInfo) GetAccessToken() string { return k.AccessToken }
 
@@ -296,3 +505,29 @@ func (k tokenInfo) GetRefreshToken() string { return k.RefreshToken }
 
 // GetExpiry returns the access token's expiration time in seconds
 func (k tokenInfo) GetExpiry() int { return k.ExpiresIn }
+
+// HasRefreshToken reports whether a refresh token was issued
+func (k tokenInfo) HasRefreshToken() bool { return k.RefreshToken != "" }
+
+// GetRefreshTokenExpiresAt returns when the refresh token expires, computed from refresh_token_expires_in
+func (k tokenInfo) GetRefreshTokenExpiresAt() time.Time {
+	return oauth2.ExpiryTime(k.issuedAt, k.RefreshTokenExpiresIn)
+}
+
+// HasExpiry reports whether the access token expires, false when expires_in was not returned
+func (k tokenInfo) HasExpiry() bool { return k.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (k tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(k.issuedAt, k.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (k tokenInfo) IsExpired() bool { return oauth2.Expired(k.GetExpiresAt()) }
+
+// GetScope returns the space-delimited scopes the user agreed to
+func (k tokenInfo) GetScope() string { return k.Scope }
+
+// GetTokenType returns the token type (e.g. "Bearer")
+func (k tokenInfo) GetTokenType() string { return k.TokenType }
+
+// GetIDToken returns the id_token, only sent when OpenID Connect is enabled for the app
+func (k tokenInfo) GetIDToken() string { return k.IDToken }
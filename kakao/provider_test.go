@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"testing"
@@ -53,8 +54,143 @@ func TestKakaoProvider_GetUserInfo(t *testing.T) {
 		info, err := provider.GetUserInfo(context.Background(), "token")
 		assert.NoError(t, err)
 		assert.Equal(t, "1001", info.GetID())
+
+		numericID, ok := oauth2.NumericID(info)
+		assert.True(t, ok)
+		assert.Equal(t, int64(1001), numericID)
 		assert.Equal(t, "kakao@example.com", info.GetEmail())
 		assert.Equal(t, "kakao-user", info.GetName())
+		assert.Empty(t, info.GetPhoneNumber())
+		assert.Empty(t, info.GetLocale(), "kakao has no locale")
+	})
+
+	t.Run("64-bit id", func(t *testing.T) {
+		mockBody := []byte(`{"id":9223372036854775807,"kakao_account":{"email":"kakao@example.com"}}`)
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(mockBody)),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		info, err := provider.GetUserInfo(context.Background(), "token")
+		assert.NoError(t, err)
+		assert.Equal(t, "9223372036854775807", info.GetID())
+
+		numericID, ok := oauth2.NumericID(info)
+		assert.True(t, ok)
+		assert.Equal(t, int64(math.MaxInt64), numericID)
+	})
+
+	t.Run("phone number", func(t *testing.T) {
+		mockBody := []byte(`{"id":1001,"kakao_account":{"phone_number":"+82 10-1234-5678"}}`)
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(mockBody)),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		info, err := provider.GetUserInfo(context.Background(), "token")
+		assert.NoError(t, err)
+		assert.Equal(t, "+82 10-1234-5678", info.GetPhoneNumber())
+	})
+
+	t.Run("gender", func(t *testing.T) {
+		mockBody := []byte(`{"id":1001,"kakao_account":{"gender":"female"}}`)
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(mockBody)),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		info, err := provider.GetUserInfo(context.Background(), "token")
+		assert.NoError(t, err)
+		assert.Equal(t, "female", info.GetGender())
+		assert.Equal(t, oauth2.GenderFemale, info.GetGenderNormalized())
+	})
+
+	t.Run("birthday", func(t *testing.T) {
+		tests := map[string]string{
+			`{"birthyear":"1990","birthday":"0131"}`: "1990-01-31",
+			`{"birthday":"0131"}`:                    "01-31",
+			`{"birthyear":"1990"}`:                   "",
+		}
+		for account, want := range tests {
+			mockBody := []byte(`{"id":1001,"kakao_account":` + account + `}`)
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader(mockBody)),
+				}, nil
+			})
+			provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			info, err := provider.GetUserInfo(context.Background(), "token")
+			assert.NoError(t, err)
+			assert.Equal(t, want, info.GetBirthday(), account)
+		}
+	})
+
+	t.Run("name strategy", func(t *testing.T) {
+		mockBody := []byte(`{"id":1001,"kakao_account":{"email":"kakao@example.com",` +
+			`"name":"Real Name","profile":{"nickname":"kakao-user"}}}`)
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(mockBody)),
+			}, nil
+		})
+
+		tests := map[oauth2.NameStrategy]string{
+			"":                    "Real Name",
+			oauth2.PreferRealName: "Real Name",
+			oauth2.PreferNickname: "kakao-user",
+			oauth2.PreferEmail:    "kakao@example.com",
+		}
+		for strategy, want := range tests {
+			provider := kakao.NewProvider(oauth2.ProviderSetting{
+				Client:       client,
+				NameStrategy: strategy,
+			})
+
+			info, err := provider.GetUserInfo(context.Background(), "token")
+			assert.NoError(t, err)
+			assert.Equal(t, want, info.GetName(), strategy)
+		}
+	})
+
+	t.Run("email verification", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			account string
+			want    bool
+		}{
+			{name: "verified and valid", account: `"is_email_verified":true,"is_email_valid":true`, want: true},
+			{name: "verified but expired", account: `"is_email_verified":true,"is_email_valid":false`},
+			{name: "unverified", account: `"is_email_verified":false,"is_email_valid":true`},
+			{name: "not consented", account: `"email_needs_agreement":true`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				body := `{"id":1001,"kakao_account":{"email":"kakao@example.com",` + tt.account + `}}`
+				client := newMockClient(func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+					}, nil
+				})
+				provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+				info, err := provider.GetUserInfo(context.Background(), "token")
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, info.IsEmailVerified())
+			})
+		}
 	})
 
 	t.Run("network error", func(t *testing.T) {
@@ -76,6 +212,8 @@ func TestKakaoProvider_GetAccessToken(t *testing.T) {
 			AccessToken:  "access-token",
 			RefreshToken: "refresh-token",
 			ExpiresIn:    7200,
+			Scope:        "account_email profile_nickname",
+			TokenType:    "bearer",
 		}
 		mockBody, _ := json.Marshal(mockResp)
 		client := newMockClient(func(req *http.Request) (*http.Response, error) {
@@ -97,6 +235,9 @@ func TestKakaoProvider_GetAccessToken(t *testing.T) {
 		assert.Equal(t, "access-token", token.GetAccessToken())
 		assert.Equal(t, "refresh-token", token.GetRefreshToken())
 		assert.Equal(t, 7200, token.GetExpiry())
+		assert.Equal(t, []string{"account_email", "profile_nickname"}, oauth2.GrantedScopes(token))
+		assert.Equal(t, "account_email profile_nickname", token.GetScope())
+		assert.Equal(t, "bearer", token.GetTokenType())
 	})
 
 	t.Run("empty code", func(t *testing.T) {
@@ -140,17 +281,427 @@ func TestKakaoProvider_GetAuthURL(t *testing.T) {
 		assert.Equal(t, "http://localhost/callback", q.Get("redirect_uri"))
 		assert.Equal(t, "code", q.Get("response_type"))
 		assert.Equal(t, "xyz", q.Get("state"))
+		assert.False(t, q.Has("scope"))
+	})
+
+	t.Run("configured scopes are comma-delimited", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "kakao-client",
+			RedirectURL: "http://localhost/callback",
+			Scopes:      []string{"account_email", "profile_nickname"},
+		})
+
+		authURL, err := provider.GetAuthURL(context.Background(), "xyz", oauth2.WithScopes("openid"))
+		assert.NoError(t, err)
+
+		u, err := url.Parse(authURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "account_email,profile_nickname,openid", u.Query().Get("scope"))
+	})
+
+	t.Run("prompt create is passed through", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "kakao-client",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		authURL, err := provider.GetAuthURL(
+			context.Background(),
+			"xyz",
+			oauth2.WithPrompt(oauth2.PromptCreate),
+		)
+		assert.NoError(t, err)
+
+		u, err := url.Parse(authURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "create", u.Query().Get("prompt"))
+	})
+
+	t.Run("unknown prompt", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "kakao-client",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		_, err := provider.GetAuthURL(context.Background(), "xyz", oauth2.WithPrompt("signup"))
+		assert.ErrorIs(t, err, oauth2.ErrInvalidPrompt)
+	})
+
+	t.Run("additional scopes", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "kakao-client",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		authURL, err := provider.GetAuthURL(
+			context.Background(),
+			"xyz",
+			oauth2.WithScopes("account_email", "talk_message"),
+		)
+		assert.NoError(t, err)
+
+		u, err := url.Parse(authURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "account_email,talk_message", u.Query().Get("scope"))
+	})
+
+	t.Run("offline access is implicit", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		for _, offline := range []bool{true, false} {
+			authURL, err := provider.GetAuthURL(
+				context.Background(),
+				"state",
+				oauth2.WithOfflineAccess(offline),
+			)
+			assert.NoError(t, err)
+
+			u, err := url.Parse(authURL)
+			assert.NoError(t, err)
+			assert.False(t, u.Query().Has("access_type"))
+			assert.False(t, u.Query().Has("scope"))
+		}
 	})
 
 	t.Run("missing redirect URL", func(t *testing.T) {
 		provider := kakao.NewProvider(oauth2.ProviderSetting{})
 		_, err := provider.GetAuthURL(context.Background(), "test")
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, oauth2.ErrRedirectURLNotSet)
+	})
+
+	t.Run("missing client ID", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{
+			RedirectURL: "http://localhost/callback",
+		})
+		authURL, err := provider.GetAuthURL(context.Background(), "test")
+		assert.ErrorIs(t, err, oauth2.ErrClientIDNotSet)
+		assert.Empty(t, authURL)
+	})
+}
+
+func TestKakaoProvider_GetClientCredentialsToken(t *testing.T) {
+	provider := kakao.NewProvider(oauth2.ProviderSetting{})
+	_, err := provider.GetClientCredentialsToken(context.Background())
+	assert.ErrorIs(t, err, oauth2.ErrGrantNotSupported)
+}
+
+func TestKakaoProvider_RevokeToken(t *testing.T) {
+	t.Run("logs out with bearer token", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodPost, req.Method)
+			assert.Equal(t, kakao.LogoutURL, req.URL.String())
+			assert.Equal(t, "Bearer access-token", req.Header.Get("Authorization"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"id":1001}`))),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		assert.NoError(t, provider.RevokeToken(context.Background(), "access-token"))
+	})
+
+	t.Run("provider rejects token", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"code":-401}`))),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		err := provider.RevokeToken(context.Background(), "access-token")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+
+		var providerErr *oauth2.ProviderError
+		if assert.ErrorAs(t, err, &providerErr) {
+			assert.Equal(t, oauth2.OpRevokeToken, providerErr.Op)
+			assert.Equal(t, http.StatusUnauthorized, providerErr.StatusCode)
+		}
+	})
+
+	t.Run("name strategy", func(t *testing.T) {
+		mockBody := []byte(`{"id":1001,"kakao_account":{"email":"kakao@example.com",` +
+			`"name":"Real Name","profile":{"nickname":"kakao-user"}}}`)
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(mockBody)),
+			}, nil
+		})
+
+		tests := map[oauth2.NameStrategy]string{
+			"":                    "Real Name",
+			oauth2.PreferRealName: "Real Name",
+			oauth2.PreferNickname: "kakao-user",
+			oauth2.PreferEmail:    "kakao@example.com",
+		}
+		for strategy, want := range tests {
+			provider := kakao.NewProvider(oauth2.ProviderSetting{
+				Client:       client,
+				NameStrategy: strategy,
+			})
+
+			info, err := provider.GetUserInfo(context.Background(), "token")
+			assert.NoError(t, err)
+			assert.Equal(t, want, info.GetName(), strategy)
+		}
+	})
+
+	t.Run("network error", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("network down")
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		err := provider.RevokeToken(context.Background(), "access-token")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+	})
+}
+
+func TestKakaoProvider_Unlink(t *testing.T) {
+	t.Run("posts to the unlink endpoint with a bearer token", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodPost, req.Method)
+			assert.Equal(t, kakao.UnlinkURL, req.URL.String())
+			assert.Equal(t, "Bearer access-token", req.Header.Get("Authorization"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"id":1001}`))),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		assert.NoError(t, provider.Unlink(context.Background(), "access-token"))
+	})
+
+	t.Run("provider rejects token", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"code":-401}`))),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		err := provider.Unlink(context.Background(), "access-token")
+		assert.ErrorIs(t, err, oauth2.ErrUnlinkFailed)
+
+		var providerErr *oauth2.ProviderError
+		if assert.ErrorAs(t, err, &providerErr) {
+			assert.Equal(t, oauth2.OpUnlink, providerErr.Op)
+			assert.Equal(t, http.StatusUnauthorized, providerErr.StatusCode)
+		}
+	})
+
+	t.Run("empty access token", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{})
+		assert.ErrorIs(t, provider.Unlink(context.Background(), ""), oauth2.ErrUnlinkFailed)
+	})
+}
+
+func TestKakaoProvider_StrictTokenType(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenType string
+		wantErr   bool
+	}{
+		{name: "bearer", tokenType: "bearer"},
+		{name: "missing", tokenType: ""},
+		{name: "unsupported", tokenType: "mac", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBody, _ := json.Marshal(map[string]any{
+				"access_token": "access-token",
+				"token_type":   tt.tokenType,
+			})
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader(mockBody)),
+				}, nil
+			})
+			provider := kakao.NewProvider(oauth2.ProviderSetting{
+				Client:          client,
+				ClientID:        "id",
+				ClientSecret:    "secret",
+				RedirectURL:     "http://localhost",
+				StrictTokenType: true,
+			})
+
+			_, err := provider.GetToken(context.Background(), "code")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oauth2.ErrUnsupportedTokenType)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("lenient by default", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewReader(
+					[]byte(`{"access_token":"access-token","token_type":"mac"}`),
+				)),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetToken(context.Background(), "code")
+		assert.NoError(t, err)
+	})
+}
+
+func TestKakaoProvider_RefreshToken(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		wantRefreshToken string
+	}{
+		{
+			name:             "new refresh token returned",
+			body:             `{"access_token":"a","refresh_token":"rotated","refresh_token_expires_in":5184000}`,
+			wantRefreshToken: "rotated",
+		},
+		{
+			name:             "no refresh token returned, reuse old",
+			body:             `{"access_token":"a","expires_in":21599}`,
+			wantRefreshToken: "refresh-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				assert.NoError(t, req.ParseForm())
+				assert.Equal(t, "refresh_token", req.PostForm.Get("grant_type"))
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader([]byte(tt.body))),
+				}, nil
+			})
+			provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			token, err := provider.RefreshToken(context.Background(), "refresh-token")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRefreshToken, token.GetRefreshToken())
+			assert.True(t, provider.CanRefresh(token))
+		})
+	}
+}
+
+func TestKakaoProvider_CanRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{
+			name: "refresh token with expiry",
+			body: `{"access_token":"a","refresh_token":"r","refresh_token_expires_in":5184000}`,
+			want: true,
+		},
+		{
+			name: "refresh token without expiry",
+			body: `{"access_token":"a","refresh_token":"r"}`,
+			want: true,
+		},
+		{
+			name: "no refresh token",
+			body: `{"access_token":"a"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader([]byte(tt.body))),
+				}, nil
+			})
+			provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			token, err := provider.GetToken(context.Background(), "code")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, token.HasRefreshToken())
+			assert.Equal(t, tt.want, provider.CanRefresh(token))
+		})
+	}
+}
+
+func FuzzKakaoProvider_GetUserInfo(f *testing.F) {
+	f.Add([]byte(`{"id":1,"kakao_account":{"email":"a@b.c","profile":{"nickname":"n"}}}`))
+	f.Add([]byte(`{"id":1,"kakao_account":null}`))
+	f.Add([]byte(`{"id":1,"kakao_account":{"profile":null}}`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`{"id":"1"}`))
+	f.Add([]byte(`{"id":1e40}`))
+	f.Add([]byte(`{"id":1,"new_field":true}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		info, err := provider.GetUserInfo(context.Background(), "token")
+		if err != nil {
+			return
+		}
+		_ = info.GetID()
+		_ = info.GetEmail()
+		_ = info.GetName()
+		_ = info.GetGender()
+		_ = info.GetProfileImage()
+	})
+}
+
+func FuzzKakaoProvider_GetToken(f *testing.F) {
+	f.Add([]byte(`{"access_token":"a","refresh_token":"r","expires_in":3600}`))
+	f.Add([]byte(`{"access_token":null,"expires_in":"x"}`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`{`))
+	f.Add([]byte(`{"unknown":{"nested":[1,2,3]}}`))
+	f.Add([]byte(``))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		})
+		provider := kakao.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+			RedirectURL:  "http://localhost",
+		})
+
+		token, err := provider.GetToken(context.Background(), "code")
+		if err != nil {
+			return
+		}
+		_ = token.GetAccessToken()
+		_ = token.GetRefreshToken()
+		_ = token.GetExpiry()
 	})
 }
 
 type userInfoResponse struct {
-	ID          int         `json:"id"`
+	ID          int64       `json:"id"`
 	AccountInfo accountInfo `json:"kakao_account"`
 }
 
@@ -168,4 +719,6 @@ type tokenInfoResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
 }
@@ -0,0 +1,149 @@
+package keycloak
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// ProviderType is the identifier for the Keycloak OpenID Connect provider
+	//   - REFS : https://www.keycloak.org/docs/latest/securing_apps/#endpoints
+	ProviderType oauth2.ProviderType = "keycloak"
+
+	// endpoint paths, relative to the realm URL
+	authPath     = "/protocol/openid-connect/auth"
+	tokenPath    = "/protocol/openid-connect/token"
+	userInfoPath = "/protocol/openid-connect/userinfo"
+	logoutPath   = "/protocol/openid-connect/logout"
+	keysPath     = "/protocol/openid-connect/certs"
+)
+
+type (
+	// provider is a generic OpenID Connect provider bound to a realm, ending sessions on RevokeToken
+	provider struct {
+		oauth2.Provider
+		oauth2.IDTokenProvider
+
+		requester    *oauth2.Requester
+		clientID     string
+		clientSecret string
+		logoutURL    string
+	}
+)
+
+// provider must keep implementing oauth2.Provider and the id_token login
+var (
+	_ oauth2.Provider        = (*provider)(nil)
+	_ oauth2.IDTokenProvider = (*provider)(nil)
+)
+
+// RealmURL returns the issuer of realm on the Keycloak server at baseURL
+//
+//	example:
+//	keycloak.RealmURL("https://sso.example.com/", "main")
+//	// => https://sso.example.com/realms/main
+func RealmURL(baseURL string, realm string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/realms/" + url.PathEscape(realm)
+}
+
+// AuthURL returns the authorization endpoint of realm
+func AuthURL(baseURL string, realm string) string { return RealmURL(baseURL, realm) + authPath }
+
+// TokenURL returns the token endpoint of realm
+func TokenURL(baseURL string, realm string) string { return RealmURL(baseURL, realm) + tokenPath }
+
+// UserInfoURL returns the userinfo endpoint of realm
+func UserInfoURL(baseURL string, realm string) string {
+	return RealmURL(baseURL, realm) + userInfoPath
+}
+
+// LogoutURL returns the endpoint ending the session of a refresh token in realm
+func LogoutURL(baseURL string, realm string) string { return RealmURL(baseURL, realm) + logoutPath }
+
+// NewProvider initializes a Keycloak provider for realm on the server at baseURL
+// (e.g. "https://sso.example.com", without the legacy /auth prefix of Keycloak 16 and older).
+// It is not registered with RegisterConstructor since it needs the server, pass it to NewClient instead
+//   - fails with ErrEndpointNotSet when baseURL or realm is empty
+//   - fails with ErrInvalidRedirectURL when ProviderSetting.Validate rejects the redirect URL
+//
+// Users are read from the standard claims: sub, name or preferred_username (see NameStrategy),
+// email and picture. ProviderSetting.AuthURL, TokenURL and UserInfoURL override the realm endpoints
+//
+//	example:
+//	provider, err := keycloak.NewProvider(setting, "https://sso.example.com", "main")
+func NewProvider(setting oauth2.ProviderSetting, baseURL string, realm string) (oauth2.Provider, error) {
+	if baseURL == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEndpointNotSet, "base URL is empty")
+	}
+	if realm == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEndpointNotSet, "realm is empty")
+	}
+
+	realmURL := RealmURL(baseURL, realm)
+	generic, err := oauth2.NewGenericProvider(oauth2.GenericConfig{
+		ProviderSetting: setting,
+		Name:            ProviderType,
+		Endpoints: oauth2.Endpoints{
+			Issuer:      realmURL,
+			AuthURL:     realmURL + authPath,
+			TokenURL:    realmURL + tokenPath,
+			UserInfoURL: realmURL + userInfoPath,
+			JWKSURL:     realmURL + keysPath,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider{
+		Provider:        generic,
+		IDTokenProvider: generic.(oauth2.IDTokenProvider),
+		requester:       oauth2.NewRequester(setting),
+		clientID:        setting.ClientID,
+		clientSecret:    setting.ClientSecret,
+		logoutURL:       realmURL + logoutPath,
+	}, nil
+}
+
+// RevokeToken ends the Keycloak session of refreshToken at the logout endpoint,
+// which invalidates every token issued in that session. Keycloak needs the refresh token here,
+// an access token is rejected
+func (k *provider) RevokeToken(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRevocationFailed, "token is empty")
+	}
+
+	form := url.Values{}
+	form.Set("client_id", k.clientID)
+	form.Set("client_secret", k.clientSecret)
+	form.Set("refresh_token", refreshToken)
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, k.logoutURL, form)
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	resp, err := k.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRevocationFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpRevokeToken,
+			oauth2.ErrTokenRevocationFailed,
+			resp,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package keycloak_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/keycloak"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newMockClient(fn roundTripperFunc) *http.Client {
+	return &http.Client{Transport: fn}
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func newSetting(client *http.Client) oauth2.ProviderSetting {
+	return oauth2.ProviderSetting{
+		Client:       client,
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectURL:  "https://app.example.com/callback",
+	}
+}
+
+func TestKeycloakProvider_RealmEndpoints(t *testing.T) {
+	ctx := context.Background()
+	realmURL := "https://sso.example.com/realms/main"
+
+	var urls []string
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		urls = append(urls, req.URL.String())
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/token"):
+			return jsonResponse(http.StatusOK, `{"access_token":"at","refresh_token":"rt",`+
+				`"token_type":"Bearer","expires_in":300}`), nil
+		case strings.HasSuffix(req.URL.Path, "/logout"):
+			return jsonResponse(http.StatusNoContent, ``), nil
+		}
+		return jsonResponse(http.StatusOK, `{"sub":"f1c2","preferred_username":"octo"}`), nil
+	})
+
+	// the trailing slash of the base URL must not double up
+	provider, err := keycloak.NewProvider(newSetting(client), "https://sso.example.com/", "main")
+	assert.NoError(t, err)
+
+	authURL, err := provider.GetAuthURL(ctx, "state")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(authURL, realmURL+"/protocol/openid-connect/auth?"), authURL)
+
+	_, err = provider.GetToken(ctx, "code")
+	assert.NoError(t, err)
+	_, err = provider.GetUserInfo(ctx, "at")
+	assert.NoError(t, err)
+	assert.NoError(t, provider.RevokeToken(ctx, "rt"))
+
+	assert.Equal(t, []string{
+		realmURL + "/protocol/openid-connect/token",
+		realmURL + "/protocol/openid-connect/userinfo",
+		realmURL + "/protocol/openid-connect/logout",
+	}, urls)
+
+	assert.Equal(t, realmURL+"/protocol/openid-connect/logout", keycloak.LogoutURL("https://sso.example.com", "main"))
+}
+
+func TestKeycloakProvider_GetUserInfo(t *testing.T) {
+	ctx := context.Background()
+
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "Bearer at", req.Header.Get("Authorization"))
+		return jsonResponse(http.StatusOK, `{"sub":"f1c2","preferred_username":"octo","name":"Octo Cat",`+
+			`"email":"octo@example.com","email_verified":true,"picture":"https://img"}`), nil
+	})
+
+	tests := []struct {
+		name     string
+		strategy oauth2.NameStrategy
+		wantName string
+	}{
+		{name: "real name", wantName: "Octo Cat"},
+		{name: "preferred username", strategy: oauth2.PreferNickname, wantName: "octo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := newSetting(client)
+			setting.NameStrategy = tt.strategy
+			provider, err := keycloak.NewProvider(setting, "https://sso.example.com", "main")
+			assert.NoError(t, err)
+
+			user, err := provider.GetUserInfo(ctx, "at")
+			assert.NoError(t, err)
+			assert.Equal(t, "f1c2", user.GetID())
+			assert.Equal(t, tt.wantName, user.GetName())
+			assert.Equal(t, "octo@example.com", user.GetEmail())
+			assert.True(t, user.IsEmailVerified())
+			assert.Equal(t, "https://img", user.GetProfileImage())
+		})
+	}
+}
+
+func TestKeycloakProvider_RevokeToken(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		status  int
+		token   string
+		wantErr bool
+	}{
+		{name: "no content", status: http.StatusNoContent, token: "rt"},
+		{name: "ok", status: http.StatusOK, token: "rt"},
+		{name: "rejected", status: http.StatusBadRequest, token: "rt", wantErr: true},
+		{name: "empty token", token: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, http.MethodPost, req.Method)
+				body, _ := io.ReadAll(req.Body)
+				form, _ := url.ParseQuery(string(body))
+				assert.Equal(t, "client-id", form.Get("client_id"))
+				assert.Equal(t, "client-secret", form.Get("client_secret"))
+				assert.Equal(t, tt.token, form.Get("refresh_token"))
+				return jsonResponse(tt.status, `{"error":"invalid_grant"}`), nil
+			})
+			provider, err := keycloak.NewProvider(newSetting(client), "https://sso.example.com", "main")
+			assert.NoError(t, err)
+
+			err = provider.RevokeToken(ctx, tt.token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNewProvider_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		realm   string
+		wantErr error
+	}{
+		{name: "empty base URL", realm: "main", wantErr: oauth2.ErrEndpointNotSet},
+		{name: "empty realm", baseURL: "https://sso.example.com", wantErr: oauth2.ErrEndpointNotSet},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := keycloak.NewProvider(newSetting(nil), tt.baseURL, tt.realm)
+			assert.Nil(t, provider)
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+		})
+	}
+}
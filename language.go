@@ -0,0 +1,33 @@
+package oauth2
+
+import (
+	"context"
+	"net/http"
+)
+
+// acceptLanguageKey is the context key carrying the caller's preferred language
+type acceptLanguageKey struct{}
+
+// WithAcceptLanguage returns a context whose userinfo requests send language as Accept-Language,
+// so providers that localize profile fields return names in the caller's language
+//
+//	example:
+//	ctx := oauth2.WithAcceptLanguage(r.Context(), r.Header.Get("Accept-Language"))
+//	user, err := client.RequestUserInfo(ctx, google.ProviderType, accessToken)
+func WithAcceptLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, acceptLanguageKey{}, language)
+}
+
+// AcceptLanguage returns the language set by WithAcceptLanguage, or an empty string
+func AcceptLanguage(ctx context.Context) string {
+	language, _ := ctx.Value(acceptLanguageKey{}).(string)
+	return language
+}
+
+// SetAcceptLanguage copies the language of the request context into the Accept-Language header.
+// The header is omitted when no language was set
+func SetAcceptLanguage(req *http.Request) {
+	if language := AcceptLanguage(req.Context()); language != "" {
+		req.Header.Set("Accept-Language", language)
+	}
+}
@@ -0,0 +1,27 @@
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAcceptLanguage(t *testing.T) {
+	t.Run("omitted by default", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "http://provider.test", nil)
+		oauth2.SetAcceptLanguage(req)
+		assert.Empty(t, req.Header.Get("Accept-Language"))
+	})
+
+	t.Run("set from context", func(t *testing.T) {
+		ctx := oauth2.WithAcceptLanguage(context.Background(), "ko-KR")
+		assert.Equal(t, "ko-KR", oauth2.AcceptLanguage(ctx))
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://provider.test", nil)
+		oauth2.SetAcceptLanguage(req)
+		assert.Equal(t, "ko-KR", req.Header.Get("Accept-Language"))
+	})
+}
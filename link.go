@@ -0,0 +1,82 @@
+package oauth2
+
+import (
+	"context"
+	"strings"
+)
+
+// linkStatePrefix marks a state issued by GenerateLinkState
+const linkStatePrefix = "link."
+
+type (
+	// AccountKey identifies an external account independently of the provider's user info shape,
+	// suitable as the lookup key of an account-link table
+	AccountKey struct {
+		Provider ProviderType
+		ID       string
+	}
+
+	// LinkResult is the secondary identity obtained by LinkAccount
+	LinkResult struct {
+		Key   AccountKey
+		User  UserInfo
+		Token TokenInfo
+	}
+)
+
+// NewAccountKey returns the key of user as authenticated by provider
+func NewAccountKey(provider ProviderType, user UserInfo) AccountKey {
+	return AccountKey{Provider: provider, ID: user.GetID()}
+}
+
+// String returns the key as "<provider>:<id>"
+func (k AccountKey) String() string { return string(k.Provider) + ":" + k.ID }
+
+// GenerateLinkState returns a state like GenerateState carrying the link intent,
+// detected on the callback with IsLinkState
+func GenerateLinkState() (string, error) {
+	state, err := GenerateState()
+	if err != nil {
+		return "", err
+	}
+	return linkStatePrefix + state, nil
+}
+
+// IsLinkState reports whether state was issued by GenerateLinkState
+func IsLinkState(state string) bool { return strings.HasPrefix(state, linkStatePrefix) }
+
+// LinkAccount completes a flow started with BeginLink and returns the new identity
+// without creating a session, so the caller can attach it to the signed-in user.
+// state must already be validated against the cookie (e.g. with ValidateState),
+// a state without the link intent fails with ErrNotLinkState
+//
+//	example:
+//	state := r.URL.Query().Get("state")
+//	if oauth2.IsLinkState(state) {
+//	    linked, err := oauth2.LinkAccount(ctx, client, provider, state, code)
+//	    if err != nil { ... }
+//	    accounts.Link(session.UserID, linked.Key.String())
+//	}
+func LinkAccount(
+	ctx context.Context,
+	client Client,
+	provider ProviderType,
+	state string,
+	code string,
+	opts ...AuthenticateOption,
+) (*LinkResult, error) {
+	if !IsLinkState(state) {
+		return nil, WrapProviderError(provider, ErrNotLinkState, "")
+	}
+
+	result, err := client.Authenticate(ctx, provider, code, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LinkResult{
+		Key:   NewAccountKey(result.Provider, result.User),
+		User:  result.User,
+		Token: result.Token,
+	}, nil
+}
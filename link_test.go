@@ -0,0 +1,75 @@
+package oauth2_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateLinkState(t *testing.T) {
+	state, err := oauth2.GenerateLinkState()
+	assert.NoError(t, err)
+	assert.True(t, oauth2.IsLinkState(state))
+	assert.NoError(t, oauth2.ValidateState(state, state, time.Minute))
+
+	login, err := oauth2.GenerateState()
+	assert.NoError(t, err)
+	assert.False(t, oauth2.IsLinkState(login))
+}
+
+func TestOAuth2Client_BeginLink(t *testing.T) {
+	client := oauth2.NewClient(google.NewProvider(oauth2.ProviderSetting{
+		ClientID:    "client-id",
+		RedirectURL: "https://app.example.com/callback",
+	}))
+
+	authURL, cookie, err := client.BeginLink(context.Background(), google.ProviderType)
+	assert.NoError(t, err)
+
+	parsedURL, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, cookie.Value, parsedURL.Query().Get("state"))
+	assert.True(t, oauth2.IsLinkState(cookie.Value))
+}
+
+func TestLinkAccount(t *testing.T) {
+	ctx := context.Background()
+	client := oauth2.NewClient(&mockProvider{
+		typ:            "kakao",
+		returnToken:    dummyToken{},
+		returnUserInfo: dummyUser{},
+	})
+
+	t.Run("returns the secondary account key", func(t *testing.T) {
+		state, err := oauth2.GenerateLinkState()
+		assert.NoError(t, err)
+
+		linked, err := oauth2.LinkAccount(ctx, client, "kakao", state, "code")
+		assert.NoError(t, err)
+		assert.Equal(t, oauth2.AccountKey{Provider: "kakao", ID: "id"}, linked.Key)
+		assert.Equal(t, "kakao:id", linked.Key.String())
+		assert.Equal(t, "email", linked.User.GetEmail())
+		assert.Equal(t, "access-token", linked.Token.GetAccessToken())
+	})
+
+	t.Run("login state is rejected", func(t *testing.T) {
+		state, err := oauth2.GenerateState()
+		assert.NoError(t, err)
+
+		_, err = oauth2.LinkAccount(ctx, client, "kakao", state, "code")
+		assert.ErrorIs(t, err, oauth2.ErrNotLinkState)
+	})
+
+	t.Run("provider not set", func(t *testing.T) {
+		state, err := oauth2.GenerateLinkState()
+		assert.NoError(t, err)
+
+		_, err = oauth2.LinkAccount(ctx, client, "naver", state, "code")
+		assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+	})
+}
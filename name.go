@@ -0,0 +1,30 @@
+package oauth2
+
+import "cmp"
+
+// NameStrategy selects which profile field UserInfo.GetName returns when several are present
+type NameStrategy string
+
+const (
+	// PreferRealName returns the real name, then the nickname, then the email (the default)
+	PreferRealName NameStrategy = "real_name"
+
+	// PreferNickname returns the nickname, then the real name, then the email,
+	// for apps where real names are PII-restricted
+	PreferNickname NameStrategy = "nickname"
+
+	// PreferEmail returns the email, then the real name, then the nickname
+	PreferEmail NameStrategy = "email"
+)
+
+// SelectName picks the display name according to strategy, skipping empty fields
+func SelectName(strategy NameStrategy, realName string, nickname string, email string) string {
+	switch strategy {
+	case PreferNickname:
+		return cmp.Or(nickname, realName, email)
+	case PreferEmail:
+		return cmp.Or(email, realName, nickname)
+	default:
+		return cmp.Or(realName, nickname, email)
+	}
+}
@@ -0,0 +1,50 @@
+package oauth2_test
+
+import (
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectName(t *testing.T) {
+	all := [3]string{"Real", "Nick", "a@b.c"}
+
+	tests := []struct {
+		name     string
+		strategy oauth2.NameStrategy
+		fields   [3]string
+		want     string
+	}{
+		{name: "default prefers real name", fields: all, want: "Real"},
+		{name: "real name", strategy: oauth2.PreferRealName, fields: all, want: "Real"},
+		{name: "nickname", strategy: oauth2.PreferNickname, fields: all, want: "Nick"},
+		{name: "email", strategy: oauth2.PreferEmail, fields: all, want: "a@b.c"},
+		{
+			name:     "real name missing",
+			strategy: oauth2.PreferRealName,
+			fields:   [3]string{"", "Nick", "a@b.c"},
+			want:     "Nick",
+		},
+		{
+			name:     "nickname missing",
+			strategy: oauth2.PreferNickname,
+			fields:   [3]string{"Real", "", "a@b.c"},
+			want:     "Real",
+		},
+		{
+			name:     "email missing",
+			strategy: oauth2.PreferEmail,
+			fields:   [3]string{"", "Nick", ""},
+			want:     "Nick",
+		},
+		{name: "all missing", strategy: oauth2.PreferNickname},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := oauth2.SelectName(tt.strategy, tt.fields[0], tt.fields[1], tt.fields[2])
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
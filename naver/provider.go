@@ -1,13 +1,13 @@
 package naver
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dings-things/oauth2"
 )
@@ -25,27 +25,57 @@ const (
 
 	// TokenURL is the endpoint to exchange an authorization code for an access token
 	TokenURL = "https://nid.naver.com/oauth2.0/token"
+
+	// resultCodeSuccess is the user info "resultcode" of a successful response
+	resultCodeSuccess = "00"
 )
 
 type (
 	// provider defines the Naver OAuth2 provider settings
 	provider struct {
-		client       *http.Client
+		requester    *oauth2.Requester
 		clientID     string
 		clientSecret string
 		redirectURL  string
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
 	}
 
 	// userInfo represents the response structure from Naver's user info API
 	userInfo struct {
 		Resultcode string `json:"resultcode"`
+		Message    string `json:"message"`
 		Response   struct {
 			ID           string `json:"id"`
 			Email        string `json:"email"`
 			Name         string `json:"name"`
+			Nickname     string `json:"nickname"`
 			ProfileImage string `json:"profile_image"`
 			Gender       string `json:"gender"`
+			Mobile       string `json:"mobile"`
+			Birthday     string `json:"birthday"`
+			BirthYear    string `json:"birthyear"`
 		} `json:"response"`
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
+	}
+
+	// revokeResult represents the response structure for token deletion requests
+	revokeResult struct {
+		Result           string `json:"result"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
 	}
 
 	// tokenInfo represents the response structure for access token requests
@@ -53,41 +83,95 @@ type (
 		AccessToken  string `json:"access_token"`
 		RefreshToken string `json:"refresh_token"`
 		ExpiresIn    string `json:"expires_in"`
+		TokenType    string `json:"token_type"`
+
+		issuedAt time.Time
 	}
 )
 
+// provider must keep implementing oauth2.Provider
+var _ oauth2.Provider = (*provider)(nil)
+
+func init() {
+	oauth2.RegisterConstructor(ProviderType, NewProvider)
+}
+
 // NewProvider initializes and returns a new Naver OAuth2 provider
 func NewProvider(setting oauth2.ProviderSetting) oauth2.Provider {
 	return &provider{
-		client:       setting.Client,
+		requester:    oauth2.NewRequester(setting),
 		clientID:     setting.ClientID,
 		clientSecret: setting.ClientSecret,
 		redirectURL:  setting.RedirectURL,
+
+		strictTokenType:  setting.StrictTokenType,
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodGet),
+
+		authURL:  cmp.Or(setting.AuthURL, AuthURL),
+		tokenURL: cmp.Or(setting.TokenURL, TokenURL),
+
+		userInfoURL:          cmp.Or(setting.UserInfoURL, UserInfoURL),
+		userInfoFallbackURLs: setting.UserInfoFallbackURLs,
 	}
 }
 
+// NewProviderWithError is NewProvider failing with ErrClientIDNotSet, ErrClientSecretNotSet
+// or ErrInvalidRedirectURL instead of building a provider whose calls cannot succeed
+func NewProviderWithError(setting oauth2.ProviderSetting) (oauth2.Provider, error) {
+	if err := setting.ValidateCredentials(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+	return NewProvider(setting), nil
+}
+
 // GetAuthURL generates the authorization URL to redirect the user to Naver's login screen
-func (n *provider) GetAuthURL(ctx context.Context, state string) (string, error) {
+//   - WithOfflineAccess is ignored since Naver always issues a refresh token
+//   - ProviderSetting.Scopes, WithScopes and WithPrompt are ignored since Naver supports none of them,
+//     prompts are still validated like for every other provider
+//   - WithAuthParams adds e.g. auth_type=reauthenticate to force a new login
+func (n *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
 	if n.redirectURL == "" {
 		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
 	}
+	if n.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+
+	options := oauth2.NewAuthOptions(opts...)
+	if err := oauth2.ValidatePrompts(options.Prompts); err != nil {
+		return "", oauth2.WrapProviderError(ProviderType, err, strings.Join(options.Prompts, " "))
+	}
 
 	query := url.Values{}
 	query.Set("response_type", "code")
 	query.Set("client_id", n.clientID)
 	query.Set("redirect_uri", n.redirectURL)
 	query.Set("state", state)
+	oauth2.SetAuthParams(query, options.AuthParams)
 
-	return AuthURL + "?" + query.Encode(), nil
+	return oauth2.BuildAuthURL(ProviderType, n.authURL, query, n.authURLLimits)
 }
 
-// GetToken exchanges the authorization code for an access token from Naver
-func (n *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+// GetToken exchanges the authorization code for an access token from Naver.
+// PKCE is not supported, so WithPKCE is ignored
+func (n *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
 	var tokenInfo tokenInfo
 
 	if code == "" {
 		return tokenInfo, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
 	}
+	ctx = oauth2.WithoutRetry(ctx)
 
 	form := url.Values{}
 	form.Set("grant_type", "authorization_code")
@@ -99,7 +183,7 @@ func (n *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo,
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		TokenURL,
+		n.tokenURL,
 		strings.NewReader(form.Encode()),
 	)
 	if err != nil {
@@ -111,39 +195,29 @@ func (n *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo,
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := n.client.Do(req)
+	end := n.requester.Observe(ctx, ProviderType, oauth2.OpGetToken)
+	resp, err := n.requester.Do(req)
+	end(resp, err)
 	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			err.Error(),
-		)
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
 			ProviderType,
+			oauth2.OpGetToken,
 			oauth2.ErrTokenRequestFailed,
-			err.Error(),
+			resp,
 		)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return tokenInfo, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			string(body),
-		)
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
 	}
+	tokenInfo.issuedAt = time.Now()
 
-	if err := json.Unmarshal(body, &tokenInfo); err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			err.Error(),
-		)
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, n.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
 	}
 
 	return tokenInfo, nil
@@ -151,7 +225,11 @@ func (n *provider) GetToken(ctx context.Context, code string) (oauth2.TokenInfo,
 
 // GetUserInfo retrieves user information from Naver using the access token
 func (n *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, UserInfoURL, nil)
+	if accessToken == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAccessToken, "")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.userInfoURL, nil)
 	if err != nil {
 		return nil, oauth2.WrapProviderError(
 			ProviderType,
@@ -161,35 +239,40 @@ func (n *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
+	oauth2.SetAcceptLanguage(req)
 
-	resp, err := n.client.Do(req)
+	end := n.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+	resp, err := n.requester.DoWithFallback(req, n.userInfoFallbackURLs)
+	end(resp, err)
 	if err != nil {
-		return nil, oauth2.WrapProviderError(
-			ProviderType,
-			oauth2.ErrUserInfoRequestFailed,
-			err.Error(),
-		)
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, oauth2.WrapProviderError(
+	if resp.StatusCode != http.StatusOK {
+		return nil, oauth2.WrapResponseError(
 			ProviderType,
+			oauth2.OpGetUserInfo,
 			oauth2.ErrUserInfoRequestFailed,
-			err.Error(),
+			resp,
 		)
 	}
 
 	var userInfo userInfo
-	if err := json.Unmarshal(body, &userInfo); err != nil {
+	if err := oauth2.DecodeJSONResponse(resp, &userInfo); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	// Naver reports failures such as an invalid token with 200 and a non-"00" resultcode
+	if userInfo.Resultcode != resultCodeSuccess {
 		return nil, oauth2.WrapProviderError(
 			ProviderType,
 			oauth2.ErrUserInfoRequestFailed,
-			err.Error(),
+			userInfo.Resultcode+": "+userInfo.Message,
 		)
 	}
 
+	userInfo.raw = oauth2.DecodeRawUserInfo(resp.Body)
+	userInfo.nameStrategy = n.nameStrategy
+
 	return &userInfo, nil
 }
 
@@ -213,7 +296,7 @@ func (n *provider) RefreshToken(
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		TokenURL,
+		n.tokenURL,
 		strings.NewReader(form.Encode()),
 	)
 	if err != nil {
@@ -225,62 +308,164 @@ func (n *provider) RefreshToken(
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := n.client.Do(req)
+	end := n.requester.Observe(ctx, ProviderType, oauth2.OpRefreshToken)
+	resp, err := n.requester.Do(req)
+	end(resp, err)
 	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
 			ProviderType,
+			oauth2.OpRefreshToken,
 			oauth2.ErrTokenRequestFailed,
-			err.Error(),
+			resp,
 		)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	// keep the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = refreshToken
+	}
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, n.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
+	return tokenInfo, nil
+}
+
+// RevokeToken deletes the access token through Naver's token endpoint (grant_type=delete)
+func (n *provider) RevokeToken(ctx context.Context, accessToken string) error {
+	return n.deleteToken(ctx, accessToken, oauth2.OpRevokeToken, oauth2.ErrTokenRevocationFailed)
+}
+
+// Unlink disconnects the user from the app. Naver has no separate endpoint, deleting the
+// access token (grant_type=delete) also removes the app connection
+func (n *provider) Unlink(ctx context.Context, accessToken string) error {
+	return n.deleteToken(ctx, accessToken, oauth2.OpUnlink, oauth2.ErrUnlinkFailed)
+}
+
+// deleteToken posts grant_type=delete for the access token, failing with base
+//   - Naver reports failures with an "error" field even when the status is 200
+func (n *provider) deleteToken(ctx context.Context, accessToken string, op string, base error) error {
+	if accessToken == "" {
+		return oauth2.WrapProviderError(ProviderType, base, "token is empty")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "delete")
+	form.Set("client_id", n.clientID)
+	form.Set("client_secret", n.clientSecret)
+	form.Set("access_token", accessToken)
+	form.Set("service_provider", "NAVER")
+
+	req, err := oauth2.NewFormRequest(ctx, n.revocationMethod, n.tokenURL, form)
 	if err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+		return oauth2.WrapProviderError(
 			ProviderType,
-			oauth2.ErrTokenRequestFailed,
+			base,
 			err.Error(),
 		)
 	}
 
+	resp, err := n.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, base, err)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return tokenInfo, oauth2.WrapProviderError(
+		return oauth2.WrapResponseError(
 			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			string(body),
+			op,
+			base,
+			resp,
 		)
 	}
 
-	if err := json.Unmarshal(body, &tokenInfo); err != nil {
-		return tokenInfo, oauth2.WrapProviderError(
+	var result revokeResult
+	if err := oauth2.DecodeJSONResponse(resp, &result); err != nil {
+		return oauth2.WrapProviderCause(ProviderType, base, err)
+	}
+	if result.Error != "" {
+		return oauth2.WrapProviderError(
 			ProviderType,
-			oauth2.ErrTokenRequestFailed,
-			err.Error(),
+			base,
+			result.Error+": "+result.ErrorDescription,
 		)
 	}
 
-	return tokenInfo, nil
+	return nil
+}
+
+// GetClientCredentialsToken is not supported since Naver only issues user tokens
+func (n provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrGrantNotSupported, "client_credentials")
 }
 
 // GetProvider returns the provider type ("naver")
 func (n provider) GetProvider() oauth2.ProviderType { return ProviderType }
 
+// CanRefresh reports whether token holds a refresh token, Naver does not report its expiry
+func (n provider) CanRefresh(token oauth2.TokenInfo) bool { return oauth2.CanRefresh(token) }
+
+// SigningKeys is not supported since Naver does not publish a JWKS
+func (n provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	return nil, oauth2.WrapProviderError(
+		ProviderType,
+		oauth2.ErrUnsupportedOperation,
+		"no JWKS endpoint",
+	)
+}
+
+// GetRedirectURL returns the configured redirect URL
+func (n provider) GetRedirectURL() string { return n.redirectURL }
+
 // GetID returns the user's ID
 func (n userInfo) GetID() string { return n.Response.ID }
 
 // GetEmail returns the user's email
 func (n userInfo) GetEmail() string { return n.Response.Email }
 
-// GetName returns the user's name
-func (n userInfo) GetName() string { return n.Response.Name }
+// GetName returns the user's name, nickname or email depending on the NameStrategy
+func (n userInfo) GetName() string {
+	return oauth2.SelectName(n.nameStrategy, n.Response.Name, n.Response.Nickname, n.Response.Email)
+}
 
 // GetGender returns the user's gender
 func (n userInfo) GetGender() string { return n.Response.Gender }
 
+// GetGenderNormalized maps the raw gender to an oauth2.Gender
+func (n userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.NormalizeGender(n.GetGender()) }
+
 // GetProfileImage returns the user's profile image URL
 func (n userInfo) GetProfileImage() string { return n.Response.ProfileImage }
 
+// GetPhoneNumber returns the mobile number as Naver formats it (e.g. "010-1234-5678"),
+// empty unless the user agreed to share it
+func (n userInfo) GetPhoneNumber() string { return n.Response.Mobile }
+
+// GetBirthday combines birthyear and the MM-DD birthday into YYYY-MM-DD (MM-DD when the
+// birth year was not shared), empty unless the user agreed to share the birthday
+func (n userInfo) GetBirthday() string {
+	return oauth2.FormatBirthday(n.Response.BirthYear, n.Response.Birthday)
+}
+
+// GetLocale returns an empty string since Naver has no locale field
+func (n userInfo) GetLocale() string { return "" }
+
+// IsEmailVerified is always false, Naver does not say whether the email was verified
+func (n userInfo) IsEmailVerified() bool { return false }
+
+// GetRaw returns the decoded profile response, the fields are nested under "response"
+func (n userInfo) GetRaw() map[string]any { return n.raw }
+
 // GetAccessToken returns the access token string
 func (n tokenInfo) GetAccessToken() string { return n.AccessToken }
 
@@ -292,3 +477,24 @@ func (n tokenInfo) GetExpiry() int {
 	sec, _ := strconv.Atoi(n.ExpiresIn)
 	return sec
 }
+
+// GetScope returns an empty string since Naver does not report the granted scope
+func (n tokenInfo) GetScope() string { return "" }
+
+// GetTokenType returns the token type (e.g. "Bearer")
+func (n tokenInfo) GetTokenType() string { return n.TokenType }
+
+// GetIDToken is always empty since Naver does not support OpenID Connect
+func (n tokenInfo) GetIDToken() string { return "" }
+
+// HasRefreshToken reports whether a refresh token was issued
+func (n tokenInfo) HasRefreshToken() bool { return n.RefreshToken != "" }
+
+// HasExpiry reports whether the access token expires, false when expires_in was not returned
+func (n tokenInfo) HasExpiry() bool { return n.GetExpiry() > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (n tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(n.issuedAt, n.GetExpiry()) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (n tokenInfo) IsExpired() bool { return oauth2.Expired(n.GetExpiresAt()) }
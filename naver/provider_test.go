@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/dings-things/oauth2"
 	"github.com/dings-things/oauth2/naver"
@@ -58,6 +59,69 @@ func TestNaverProvider_GetUserInfo(t *testing.T) {
 		assert.Equal(t, "naver-id", info.GetID())
 		assert.Equal(t, "naver@example.com", info.GetEmail())
 		assert.Equal(t, "naver-user", info.GetName())
+		assert.False(t, info.IsEmailVerified(), "naver does not report email verification")
+		assert.Empty(t, info.GetPhoneNumber(), "mobile was not shared")
+		assert.Empty(t, info.GetLocale(), "naver has no locale")
+	})
+
+	t.Run("mobile number", func(t *testing.T) {
+		mockBody := []byte(`{"resultcode":"00","response":{"id":"naver-id","mobile":"010-1234-5678"}}`)
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(mockBody)),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		info, err := provider.GetUserInfo(context.Background(), "token")
+		assert.NoError(t, err)
+		assert.Equal(t, "010-1234-5678", info.GetPhoneNumber())
+	})
+
+	t.Run("gender", func(t *testing.T) {
+		tests := map[string]oauth2.Gender{
+			"M": oauth2.GenderMale,
+			"F": oauth2.GenderFemale,
+			"U": oauth2.GenderUnknown,
+		}
+		for gender, want := range tests {
+			mockBody := []byte(`{"resultcode":"00","response":{"id":"naver-id","gender":"` + gender + `"}}`)
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader(mockBody)),
+				}, nil
+			})
+			provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			info, err := provider.GetUserInfo(context.Background(), "token")
+			assert.NoError(t, err)
+			assert.Equal(t, gender, info.GetGender())
+			assert.Equal(t, want, info.GetGenderNormalized(), gender)
+		}
+	})
+
+	t.Run("birthday", func(t *testing.T) {
+		tests := map[string]string{
+			`{"id":"naver-id","birthyear":"1990","birthday":"01-31"}`: "1990-01-31",
+			`{"id":"naver-id","birthday":"01-31"}`:                    "01-31",
+			`{"id":"naver-id"}`:                                       "",
+		}
+		for response, want := range tests {
+			mockBody := []byte(`{"resultcode":"00","response":` + response + `}`)
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader(mockBody)),
+				}, nil
+			})
+			provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			info, err := provider.GetUserInfo(context.Background(), "token")
+			assert.NoError(t, err)
+			assert.Equal(t, want, info.GetBirthday(), response)
+		}
 	})
 
 	t.Run("network error", func(t *testing.T) {
@@ -68,6 +132,50 @@ func TestNaverProvider_GetUserInfo(t *testing.T) {
 		_, err := provider.GetUserInfo(context.Background(), "token")
 		assert.Error(t, err)
 	})
+
+	t.Run("failure resultcode", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewReader(
+					[]byte(`{"resultcode":"024","message":"Authentication failed"}`),
+				)),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetUserInfo(context.Background(), "token")
+		assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+		assert.ErrorContains(t, err, "024: Authentication failed")
+	})
+}
+
+func TestNaverProvider_UserInfoFallback(t *testing.T) {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		if req.URL.String() == naver.UserInfoURL {
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(bytes.NewReader(nil)),
+			}, nil
+		}
+
+		assert.Equal(t, "https://fallback.example.com/v1/nid/me", req.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: io.NopCloser(bytes.NewReader(
+				[]byte(`{"resultcode":"00","response":{"id":"naver-id"}}`),
+			)),
+		}, nil
+	})
+	provider := naver.NewProvider(oauth2.ProviderSetting{
+		Client:               client,
+		UserInfoFallbackURLs: []string{"https://fallback.example.com/v1/nid/me"},
+	})
+
+	user, err := provider.GetUserInfo(context.Background(), "token")
+	assert.NoError(t, err)
+	assert.Equal(t, "naver-id", user.GetID())
 }
 
 func TestNaverProvider_GetAccessToken(t *testing.T) {
@@ -119,6 +227,44 @@ func TestNaverProvider_GetAccessToken(t *testing.T) {
 	})
 }
 
+func TestNaverProvider_RefreshToken(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		wantRefreshToken string
+	}{
+		{
+			name:             "new refresh token returned",
+			body:             `{"access_token":"new-access-token","refresh_token":"rotated","expires_in":"3600"}`,
+			wantRefreshToken: "rotated",
+		},
+		{
+			name:             "no refresh token returned, reuse old",
+			body:             `{"access_token":"new-access-token","token_type":"bearer","expires_in":"3600"}`,
+			wantRefreshToken: "refresh-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				assert.NoError(t, req.ParseForm())
+				assert.Equal(t, "refresh_token", req.PostForm.Get("grant_type"))
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader([]byte(tt.body))),
+				}, nil
+			})
+			provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			token, err := provider.RefreshToken(context.Background(), "refresh-token")
+			assert.NoError(t, err)
+			assert.Equal(t, "new-access-token", token.GetAccessToken())
+			assert.Equal(t, tt.wantRefreshToken, token.GetRefreshToken())
+		})
+	}
+}
+
 func TestNaverProvider_GetAuthURL(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		provider := naver.NewProvider(oauth2.ProviderSetting{
@@ -139,10 +285,354 @@ func TestNaverProvider_GetAuthURL(t *testing.T) {
 		assert.Equal(t, "xyz", query.Get("state"))
 	})
 
+	t.Run("scopes are ignored", func(t *testing.T) {
+		provider := naver.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "test-client",
+			RedirectURL: "http://localhost/callback",
+			Scopes:      []string{"email"},
+		})
+
+		urlStr, err := provider.GetAuthURL(context.Background(), "xyz", oauth2.WithScopes("name"))
+		assert.NoError(t, err)
+
+		u, err := url.Parse(urlStr)
+		assert.NoError(t, err)
+		assert.False(t, u.Query().Has("scope"))
+	})
+
+	t.Run("prompts are validated then ignored", func(t *testing.T) {
+		provider := naver.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "test-client",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		urlStr, err := provider.GetAuthURL(context.Background(), "xyz", oauth2.WithPrompt("login"))
+		assert.NoError(t, err)
+		u, err := url.Parse(urlStr)
+		assert.NoError(t, err)
+		assert.False(t, u.Query().Has("prompt"))
+
+		_, err = provider.GetAuthURL(context.Background(), "xyz", oauth2.WithPrompt("bogus"))
+		assert.ErrorIs(t, err, oauth2.ErrInvalidPrompt)
+
+		_, err = provider.GetAuthURL(context.Background(), "xyz", oauth2.WithPrompt("none", "login"))
+		assert.ErrorIs(t, err, oauth2.ErrConflictingPrompts)
+	})
+
+	t.Run("offline access is implicit", func(t *testing.T) {
+		provider := naver.NewProvider(oauth2.ProviderSetting{
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost/callback",
+		})
+
+		for _, offline := range []bool{true, false} {
+			authURL, err := provider.GetAuthURL(
+				context.Background(),
+				"state",
+				oauth2.WithOfflineAccess(offline),
+			)
+			assert.NoError(t, err)
+
+			u, err := url.Parse(authURL)
+			assert.NoError(t, err)
+			assert.False(t, u.Query().Has("access_type"))
+			assert.False(t, u.Query().Has("scope"))
+		}
+	})
+
 	t.Run("missing redirect URL", func(t *testing.T) {
 		provider := naver.NewProvider(oauth2.ProviderSetting{})
 		_, err := provider.GetAuthURL(context.Background(), "abc")
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, oauth2.ErrRedirectURLNotSet)
+	})
+
+	t.Run("missing client ID", func(t *testing.T) {
+		provider := naver.NewProvider(oauth2.ProviderSetting{
+			RedirectURL: "http://localhost/callback",
+		})
+		urlStr, err := provider.GetAuthURL(context.Background(), "abc")
+		assert.ErrorIs(t, err, oauth2.ErrClientIDNotSet)
+		assert.Empty(t, urlStr)
+	})
+}
+
+func TestNaverProvider_RevokeToken(t *testing.T) {
+	t.Run("deletes token with GET by default", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodGet, req.Method)
+			assert.Equal(t, "nid.naver.com", req.URL.Host)
+			assert.Equal(t, "/oauth2.0/token", req.URL.Path)
+
+			query := req.URL.Query()
+			assert.Equal(t, "delete", query.Get("grant_type"))
+			assert.Equal(t, "id", query.Get("client_id"))
+			assert.Equal(t, "secret", query.Get("client_secret"))
+			assert.Equal(t, "access-token", query.Get("access_token"))
+			assert.Equal(t, "NAVER", query.Get("service_provider"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewReader(
+					[]byte(`{"access_token":"access-token","result":"success"}`),
+				)),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+		})
+
+		assert.NoError(t, provider.RevokeToken(context.Background(), "access-token"))
+	})
+
+	t.Run("method override sends a form body", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodPost, req.Method)
+			assert.Empty(t, req.URL.RawQuery)
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "delete", req.PostForm.Get("grant_type"))
+			assert.Equal(t, "access-token", req.PostForm.Get("access_token"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"result":"success"}`))),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{
+			Client:           client,
+			ClientID:         "id",
+			ClientSecret:     "secret",
+			RevocationMethod: http.MethodPost,
+		})
+
+		assert.NoError(t, provider.RevokeToken(context.Background(), "access-token"))
+	})
+
+	t.Run("error reported with status 200", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewReader(
+					[]byte(`{"error":"invalid_request","error_description":"no valid data in session"}`),
+				)),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		err := provider.RevokeToken(context.Background(), "access-token")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+		assert.Contains(t, err.Error(), "no valid data in session")
+	})
+
+	t.Run("network error", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("network down")
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		err := provider.RevokeToken(context.Background(), "access-token")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+	})
+}
+
+func TestNaverProvider_Unlink(t *testing.T) {
+	t.Run("deletes the token", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "/oauth2.0/token", req.URL.Path)
+
+			query := req.URL.Query()
+			assert.Equal(t, "delete", query.Get("grant_type"))
+			assert.Equal(t, "id", query.Get("client_id"))
+			assert.Equal(t, "secret", query.Get("client_secret"))
+			assert.Equal(t, "access-token", query.Get("access_token"))
+			assert.Equal(t, "NAVER", query.Get("service_provider"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"result":"success"}`))),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+		})
+
+		assert.NoError(t, provider.Unlink(context.Background(), "access-token"))
+	})
+
+	t.Run("error with 200 status", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewReader(
+					[]byte(`{"error":"invalid_request","error_description":"no valid data in session"}`),
+				)),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		err := provider.Unlink(context.Background(), "access-token")
+		assert.ErrorIs(t, err, oauth2.ErrUnlinkFailed)
+		assert.NotErrorIs(t, err, oauth2.ErrTokenRevocationFailed)
+	})
+}
+
+func TestNaverProvider_TokenExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantExpiry time.Duration
+	}{
+		{name: "string expires_in", body: `{"access_token":"a","expires_in":"3600"}`, wantExpiry: time.Hour},
+		{name: "missing expires_in", body: `{"access_token":"a"}`},
+		{name: "invalid expires_in", body: `{"access_token":"a","expires_in":"soon"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader([]byte(tt.body))),
+				}, nil
+			})
+			provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+			token, err := provider.GetToken(context.Background(), "code")
+			assert.NoError(t, err)
+			assert.False(t, token.IsExpired())
+			if tt.wantExpiry > 0 {
+				assert.WithinDuration(t, time.Now().Add(tt.wantExpiry), token.GetExpiresAt(), time.Second)
+			} else {
+				assert.True(t, token.GetExpiresAt().IsZero())
+			}
+		})
+	}
+}
+
+func TestNaverProvider_SigningKeys(t *testing.T) {
+	provider := naver.NewProvider(oauth2.ProviderSetting{})
+	_, err := provider.SigningKeys(context.Background())
+	assert.ErrorIs(t, err, oauth2.ErrUnsupportedOperation)
+}
+
+func TestNaverProvider_GetClientCredentialsToken(t *testing.T) {
+	provider := naver.NewProvider(oauth2.ProviderSetting{})
+	_, err := provider.GetClientCredentialsToken(context.Background())
+	assert.ErrorIs(t, err, oauth2.ErrGrantNotSupported)
+}
+
+func TestNaverProvider_StrictTokenType(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenType string
+		wantErr   bool
+	}{
+		{name: "bearer", tokenType: "bearer"},
+		{name: "missing", tokenType: ""},
+		{name: "unsupported", tokenType: "mac", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBody, _ := json.Marshal(map[string]any{
+				"access_token": "access-token",
+				"token_type":   tt.tokenType,
+			})
+			client := newMockClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader(mockBody)),
+				}, nil
+			})
+			provider := naver.NewProvider(oauth2.ProviderSetting{
+				Client:          client,
+				ClientID:        "id",
+				ClientSecret:    "secret",
+				RedirectURL:     "http://localhost",
+				StrictTokenType: true,
+			})
+
+			_, err := provider.GetToken(context.Background(), "code")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oauth2.ErrUnsupportedTokenType)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("lenient by default", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewReader(
+					[]byte(`{"access_token":"access-token","token_type":"mac"}`),
+				)),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetToken(context.Background(), "code")
+		assert.NoError(t, err)
+	})
+}
+
+func FuzzNaverProvider_GetUserInfo(f *testing.F) {
+	f.Add([]byte(`{"resultcode":"00","message":"success","response":{"id":"1","email":"a@b.c"}}`))
+	f.Add([]byte(`{"resultcode":"00","response":null}`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`{"response":[]}`))
+	f.Add([]byte(`{"resultcode":"024","message":"Authentication failed"}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		info, err := provider.GetUserInfo(context.Background(), "token")
+		if err != nil {
+			return
+		}
+		_ = info.GetID()
+		_ = info.GetEmail()
+		_ = info.GetName()
+		_ = info.GetGender()
+		_ = info.GetProfileImage()
+	})
+}
+
+func FuzzNaverProvider_GetToken(f *testing.F) {
+	f.Add([]byte(`{"access_token":"a","refresh_token":"r","expires_in":"3600"}`))
+	f.Add([]byte(`{"access_token":"a","expires_in":3600}`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`{"expires_in":"not-a-number"}`))
+	f.Add([]byte(``))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		})
+		provider := naver.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+			RedirectURL:  "http://localhost",
+		})
+
+		token, err := provider.GetToken(context.Background(), "code")
+		if err != nil {
+			return
+		}
+		_ = token.GetAccessToken()
+		_ = token.GetRefreshToken()
+		_ = token.GetExpiry()
 	})
 }
 
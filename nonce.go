@@ -0,0 +1,41 @@
+package oauth2
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+)
+
+// GenerateNonce returns a random, URL-safe OpenID Connect nonce with 128 bits of entropy
+func GenerateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SetNonce adds nonce to an authorization query, leaving it untouched when nonce is empty
+func SetNonce(query url.Values, nonce string) {
+	if nonce != "" {
+		query.Set("nonce", nonce)
+	}
+}
+
+// VerifyNonce checks the nonce claim of an id_token against the nonce sent with WithNonce,
+// failing with ErrInvalidIDToken when it is missing or different. The signature is not checked,
+// use oidc.VerifyIDToken with oidc.WithNonce for an id_token that did not come from the token endpoint
+func VerifyNonce(idToken string, nonce string) error {
+	var claims struct {
+		Nonce string `json:"nonce"`
+	}
+	if err := DecodeJWTClaims(idToken, &claims); err != nil {
+		return err
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package oauth2_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/dings-things/oauth2/kakao"
+	"github.com/dings-things/oauth2/naver"
+	"github.com/stretchr/testify/assert"
+)
+
+// idTokenWithClaims returns an unsigned id_token carrying the given JSON claims
+func idTokenWithClaims(claims string) string {
+	return "header." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+}
+
+func TestGenerateNonce(t *testing.T) {
+	nonce, err := oauth2.GenerateNonce()
+	assert.NoError(t, err)
+	assert.Len(t, nonce, 22)
+
+	other, err := oauth2.GenerateNonce()
+	assert.NoError(t, err)
+	assert.NotEqual(t, nonce, other)
+}
+
+func TestWithNonce(t *testing.T) {
+	ctx := context.Background()
+	setting := oauth2.ProviderSetting{ClientID: "client-id", RedirectURL: "https://app.example.com/callback"}
+	nonce, err := oauth2.GenerateNonce()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		provider  oauth2.Provider
+		wantNonce string
+	}{
+		{name: "google", provider: google.NewProvider(setting), wantNonce: nonce},
+		{name: "kakao", provider: kakao.NewProvider(setting), wantNonce: nonce},
+		{name: "naver ignores it", provider: naver.NewProvider(setting)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authURL, err := tt.provider.GetAuthURL(ctx, "state", oauth2.WithNonce(nonce))
+			assert.NoError(t, err)
+
+			parsed, err := url.Parse(authURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNonce, parsed.Query().Get("nonce"))
+
+			authURL, err = tt.provider.GetAuthURL(ctx, "state")
+			assert.NoError(t, err)
+			assert.NotContains(t, authURL, "nonce=")
+		})
+	}
+}
+
+func TestVerifyNonce(t *testing.T) {
+	idToken := idTokenWithClaims(`{"sub":"123","nonce":"n-0S6_WzA2Mj"}`)
+
+	assert.NoError(t, oauth2.VerifyNonce(idToken, "n-0S6_WzA2Mj"))
+	assert.ErrorIs(t, oauth2.VerifyNonce(idToken, "replayed"), oauth2.ErrInvalidIDToken)
+	assert.ErrorIs(t, oauth2.VerifyNonce(idToken, ""), oauth2.ErrInvalidIDToken)
+	assert.ErrorIs(t, oauth2.VerifyNonce(idTokenWithClaims(`{"sub":"123"}`), "n-0S6_WzA2Mj"), oauth2.ErrInvalidIDToken)
+	assert.ErrorIs(t, oauth2.VerifyNonce("not-a-jwt", "n-0S6_WzA2Mj"), oauth2.ErrInvalidIDToken)
+}
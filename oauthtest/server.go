@@ -0,0 +1,225 @@
+// Package oauthtest provides a fake OAuth2 provider server for testing login flows end to end
+package oauthtest
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// ClientID is the client ID of the ProviderSetting returned by Server.Setting
+	ClientID = "test-client-id"
+
+	// ClientSecret is the client secret of the ProviderSetting returned by Server.Setting
+	ClientSecret = "test-client-secret"
+
+	// DefaultCode is the authorization code issued by the authorize endpoint
+	DefaultCode = "test-code"
+
+	// DefaultAccessToken is the access token of the default token fixture
+	DefaultAccessToken = "test-access-token"
+
+	// Endpoint paths served by Server
+	AuthorizePath = "/authorize"
+	TokenPath     = "/token"
+	UserInfoPath  = "/userinfo"
+)
+
+type (
+	// Option customizes the fixtures of NewServer
+	Option func(*Server)
+
+	// Request is a request received by Server, Form holds the query or form parameters
+	Request struct {
+		Header http.Header
+		Form   url.Values
+	}
+
+	// Server is a fake provider backed by an httptest.Server:
+	//   - AuthorizePath approves every request, redirecting to redirect_uri with the code and state
+	//   - TokenPath exchanges the code (or any refresh token) for the token fixture
+	//   - UserInfoPath returns the userinfo fixture to the access token of the token fixture
+	Server struct {
+		*httptest.Server
+
+		code     string
+		token    map[string]any
+		userInfo map[string]any
+
+		mu       sync.Mutex
+		requests map[string][]Request
+	}
+)
+
+// WithCode issues code from the authorize endpoint instead of DefaultCode
+func WithCode(code string) Option {
+	return func(s *Server) {
+		s.code = code
+	}
+}
+
+// WithToken replaces the token endpoint response, the userinfo endpoint then
+// expects its access_token
+func WithToken(token map[string]any) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithUserInfo replaces the userinfo endpoint response
+func WithUserInfo(userInfo map[string]any) Option {
+	return func(s *Server) {
+		s.userInfo = userInfo
+	}
+}
+
+// NewServer starts a fake provider, Close it when the test ends.
+// The default userinfo fixture carries both Google's id and the OpenID Connect sub
+//
+//	example:
+//	server := oauthtest.NewServer()
+//	defer server.Close()
+//	client := oauth2.NewClient(google.NewProvider(server.Setting("https://app.example.com/callback")))
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		code: DefaultCode,
+		token: map[string]any{
+			"access_token":  DefaultAccessToken,
+			"refresh_token": "test-refresh-token",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+		},
+		userInfo: map[string]any{
+			"id":             "test-user",
+			"sub":            "test-user",
+			"email":          "user@example.com",
+			"email_verified": true,
+			"verified_email": true,
+			"name":           "Test User",
+		},
+		requests: make(map[string][]Request),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(AuthorizePath, s.authorize)
+	mux.HandleFunc(TokenPath, s.exchange)
+	mux.HandleFunc(UserInfoPath, s.serveUserInfo)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Setting returns a ProviderSetting pointing a provider at the server, using its HTTP client
+func (s *Server) Setting(redirectURL string) oauth2.ProviderSetting {
+	return oauth2.ProviderSetting{
+		Client:       s.Client(),
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		RedirectURL:  redirectURL,
+		AuthURL:      s.URL + AuthorizePath,
+		TokenURL:     s.URL + TokenPath,
+		UserInfoURL:  s.URL + UserInfoPath,
+	}
+}
+
+// Requests returns the requests received at path (e.g. TokenPath), oldest first
+func (s *Server) Requests(path string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests[path]...)
+}
+
+// LastRequest returns the most recent request received at path
+func (s *Server) LastRequest(path string) (Request, bool) {
+	requests := s.Requests(path)
+	if len(requests) == 0 {
+		return Request{}, false
+	}
+	return requests[len(requests)-1], true
+}
+
+// record keeps r, its form included, for Requests
+func (s *Server) record(r *http.Request) {
+	_ = r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[r.URL.Path] = append(s.requests[r.URL.Path], Request{
+		Header: r.Header.Clone(),
+		Form:   maps.Clone(r.Form),
+	})
+}
+
+// authorize approves the request like a user would, redirecting back with the code and state
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	s.record(r)
+
+	redirectURL, err := url.Parse(r.Form.Get("redirect_uri"))
+	if err != nil || !redirectURL.IsAbs() {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_request"})
+		return
+	}
+
+	query := redirectURL.Query()
+	query.Set("code", s.code)
+	query.Set("state", r.Form.Get("state"))
+	redirectURL.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+// exchange answers the token endpoint, failing with invalid_grant for an unknown code
+func (s *Server) exchange(w http.ResponseWriter, r *http.Request) {
+	s.record(r)
+
+	switch r.Form.Get("grant_type") {
+	case "authorization_code":
+		if r.Form.Get("code") != s.code {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+			return
+		}
+	case "refresh_token":
+		if r.Form.Get("refresh_token") == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+			return
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "unsupported_grant_type"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.token)
+}
+
+// serveUserInfo answers the userinfo endpoint, failing with 401 for another access token
+func (s *Server) serveUserInfo(w http.ResponseWriter, r *http.Request) {
+	s.record(r)
+
+	if r.Header.Get("Authorization") != "Bearer "+s.accessToken() {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.userInfo)
+}
+
+// accessToken returns the access token of the token fixture
+func (s *Server) accessToken() string {
+	accessToken, _ := s.token["access_token"].(string)
+	return accessToken
+}
+
+// writeJSON writes body as a JSON response with status
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,103 @@
+package oauthtest_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/dings-things/oauth2/oauthtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const redirectURL = "https://app.example.com/callback"
+
+// authorize follows authURL like a browser and returns the callback query
+func authorize(t *testing.T, server *oauthtest.Server, authURL string) url.Values {
+	t.Helper()
+
+	browser := server.Client()
+	browser.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := browser.Get(authURL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, redirectURL, location.Scheme+"://"+location.Host+location.Path)
+	return location.Query()
+}
+
+func TestServer_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	server := oauthtest.NewServer()
+	defer server.Close()
+
+	client := oauth2.NewClient(google.NewProvider(server.Setting(redirectURL)))
+
+	authURL, cookie, err := client.BeginLogin(ctx, google.ProviderType)
+	assert.NoError(t, err)
+
+	callback := authorize(t, server, authURL)
+	assert.Equal(t, oauthtest.DefaultCode, callback.Get("code"))
+	assert.Equal(t, cookie.Value, callback.Get("state"))
+
+	authorizeRequest, ok := server.LastRequest(oauthtest.AuthorizePath)
+	assert.True(t, ok)
+	assert.Equal(t, oauthtest.ClientID, authorizeRequest.Form.Get("client_id"))
+	assert.Equal(t, cookie.Value, authorizeRequest.Form.Get("state"))
+
+	result, err := client.Authenticate(ctx, google.ProviderType, callback.Get("code"))
+	assert.NoError(t, err)
+	assert.Equal(t, oauthtest.DefaultAccessToken, result.Token.GetAccessToken())
+	assert.Equal(t, "test-user", result.User.GetID())
+	assert.Equal(t, "user@example.com", result.User.GetEmail())
+
+	tokenRequest, ok := server.LastRequest(oauthtest.TokenPath)
+	assert.True(t, ok)
+	assert.Equal(t, oauthtest.DefaultCode, tokenRequest.Form.Get("code"))
+	assert.Equal(t, redirectURL, tokenRequest.Form.Get("redirect_uri"))
+
+	userInfoRequest, ok := server.LastRequest(oauthtest.UserInfoPath)
+	assert.True(t, ok)
+	assert.Equal(t, "Bearer "+oauthtest.DefaultAccessToken, userInfoRequest.Header.Get("Authorization"))
+}
+
+func TestServer_Fixtures(t *testing.T) {
+	ctx := context.Background()
+	server := oauthtest.NewServer(
+		oauthtest.WithCode("custom-code"),
+		oauthtest.WithToken(map[string]any{"access_token": "custom-token", "token_type": "Bearer"}),
+		oauthtest.WithUserInfo(map[string]any{"id": "42", "name": "Custom User"}),
+	)
+	defer server.Close()
+
+	provider := google.NewProvider(server.Setting(redirectURL))
+
+	_, err := provider.GetToken(ctx, oauthtest.DefaultCode)
+	var providerErr *oauth2.ProviderError
+	assert.ErrorAs(t, err, &providerErr)
+	assert.Equal(t, "invalid_grant", providerErr.Code)
+
+	token, err := provider.GetToken(ctx, "custom-code")
+	assert.NoError(t, err)
+	assert.Equal(t, "custom-token", token.GetAccessToken())
+	assert.False(t, token.HasRefreshToken())
+
+	user, err := provider.GetUserInfo(ctx, "custom-token")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", user.GetID())
+	assert.Equal(t, "Custom User", user.GetName())
+
+	_, err = provider.GetUserInfo(ctx, oauthtest.DefaultAccessToken)
+	assert.ErrorAs(t, err, &providerErr)
+	assert.Equal(t, http.StatusUnauthorized, providerErr.StatusCode)
+
+	assert.Len(t, server.Requests(oauthtest.TokenPath), 2)
+	assert.Len(t, server.Requests(oauthtest.UserInfoPath), 2)
+}
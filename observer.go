@@ -0,0 +1,62 @@
+package oauth2
+
+import (
+	"context"
+	"time"
+)
+
+// Observer is notified around the HTTP calls of a provider, e.g. to record Prometheus
+// metrics or OpenTelemetry spans. Set it with ProviderSetting.Observer.
+// Implementations must be safe for concurrent use
+//
+//	example:
+//	type metrics struct{ latency *prometheus.HistogramVec }
+//
+//	func (m metrics) OnRequestStart(ctx context.Context, provider oauth2.ProviderType, op string) {}
+//
+//	func (m metrics) OnRequestEnd(
+//	    ctx context.Context, provider oauth2.ProviderType, op string,
+//	    statusCode int, err error, dur time.Duration,
+//	) {
+//	    m.latency.WithLabelValues(string(provider), op, strconv.Itoa(statusCode)).Observe(dur.Seconds())
+//	}
+type Observer interface {
+	// OnRequestStart is called before the call of op (e.g. OpGetToken) is sent
+	OnRequestStart(ctx context.Context, provider ProviderType, op string)
+
+	// OnRequestEnd is called once the call of op completed, retries and fallback hosts included.
+	// statusCode is 0 and err is set when no response was received, a non-2xx response is
+	// reported through statusCode alone
+	OnRequestEnd(
+		ctx context.Context,
+		provider ProviderType,
+		op string,
+		statusCode int,
+		err error,
+		dur time.Duration,
+	)
+}
+
+// Observe notifies the ProviderSetting.Observer that the call of op starts and returns the
+// function reporting its result, a no-op when no observer is set
+//
+//	example:
+//	end := p.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+//	resp, err := p.requester.Do(req)
+//	end(resp, err)
+func (r *Requester) Observe(ctx context.Context, provider ProviderType, op string) func(*Response, error) {
+	if r.observer == nil {
+		return func(*Response, error) {}
+	}
+
+	start := time.Now()
+	r.observer.OnRequestStart(ctx, provider, op)
+
+	return func(resp *Response, err error) {
+		var statusCode int
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		r.observer.OnRequestEnd(ctx, provider, op, statusCode, err, time.Since(start))
+	}
+}
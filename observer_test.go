@@ -0,0 +1,129 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	// observedCall is a call reported to recordingObserver
+	observedCall struct {
+		provider   oauth2.ProviderType
+		op         string
+		statusCode int
+		err        error
+		dur        time.Duration
+	}
+
+	// recordingObserver records the calls it is notified of
+	recordingObserver struct {
+		mu     sync.Mutex
+		starts []string
+		ends   []observedCall
+	}
+)
+
+func (o *recordingObserver) OnRequestStart(ctx context.Context, provider oauth2.ProviderType, op string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.starts = append(o.starts, op)
+}
+
+func (o *recordingObserver) OnRequestEnd(
+	ctx context.Context,
+	provider oauth2.ProviderType,
+	op string,
+	statusCode int,
+	err error,
+	dur time.Duration,
+) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.ends = append(o.ends, observedCall{provider: provider, op: op, statusCode: statusCode, err: err, dur: dur})
+}
+
+func TestProviderSetting_Observer(t *testing.T) {
+	ctx := context.Background()
+	const delay = 5 * time.Millisecond
+
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		time.Sleep(delay)
+		body := `{"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":3600}`
+		if strings.Contains(req.URL.Path, "userinfo") {
+			body = `{"sub":"42","email":"user@example.com"}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+	})}
+
+	observer := &recordingObserver{}
+	provider := google.NewProvider(oauth2.ProviderSetting{Client: client, Observer: observer})
+
+	_, err := provider.GetToken(ctx, "code")
+	assert.NoError(t, err)
+	_, err = provider.RefreshToken(ctx, "refresh")
+	assert.NoError(t, err)
+	_, err = provider.GetUserInfo(ctx, "access")
+	assert.NoError(t, err)
+
+	ops := []string{oauth2.OpGetToken, oauth2.OpRefreshToken, oauth2.OpGetUserInfo}
+	assert.Equal(t, ops, observer.starts)
+	assert.Len(t, observer.ends, len(ops))
+	for i, call := range observer.ends {
+		assert.Equal(t, google.ProviderType, call.provider)
+		assert.Equal(t, ops[i], call.op)
+		assert.Equal(t, http.StatusOK, call.statusCode)
+		assert.NoError(t, call.err)
+		assert.GreaterOrEqual(t, call.dur, delay)
+	}
+
+	t.Run("error response", func(t *testing.T) {
+		client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"error":"invalid_grant"}`
+			return &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+		})}
+		observer := &recordingObserver{}
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client, Observer: observer})
+
+		_, err := provider.GetToken(ctx, "code")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.Len(t, observer.ends, 1)
+		assert.Equal(t, http.StatusBadRequest, observer.ends[0].statusCode)
+		assert.NoError(t, observer.ends[0].err)
+	})
+
+	t.Run("no response", func(t *testing.T) {
+		errNetwork := errors.New("connection refused")
+		client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errNetwork
+		})}
+		observer := &recordingObserver{}
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client, Observer: observer})
+
+		_, err := provider.GetUserInfo(ctx, "access")
+		assert.ErrorIs(t, err, errNetwork)
+		assert.Equal(t, []string{oauth2.OpGetUserInfo}, observer.starts)
+		assert.Len(t, observer.ends, 1)
+		assert.Zero(t, observer.ends[0].statusCode)
+		assert.ErrorIs(t, observer.ends[0].err, errNetwork)
+	})
+
+	t.Run("requests that fail before sending are not observed", func(t *testing.T) {
+		observer := &recordingObserver{}
+		provider := google.NewProvider(oauth2.ProviderSetting{Observer: observer})
+
+		_, err := provider.GetToken(ctx, "")
+		assert.ErrorIs(t, err, oauth2.ErrEmptyAuthCode)
+		assert.Empty(t, observer.starts)
+	})
+}
@@ -0,0 +1,233 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for the RS256/ES256 digests
+	_ "crypto/sha512" // registers SHA-384 and SHA-512
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dings-things/oauth2"
+)
+
+// clockSkew is the leeway allowed when comparing exp and iat with the local clock
+const clockSkew = time.Minute
+
+type (
+	// JWKSProvider serves the signing keys of an identity provider.
+	// Providers with a JWKS endpoint (e.g. google.NewProvider) implement it through oauth2.Provider
+	JWKSProvider interface {
+		SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error)
+	}
+
+	// Claims are the standard claims of an id_token
+	Claims struct {
+		Issuer    string   `json:"iss"`
+		Subject   string   `json:"sub"`
+		Audience  Audience `json:"aud"`
+		ExpiresAt int64    `json:"exp"`
+		IssuedAt  int64    `json:"iat"`
+		Nonce     string   `json:"nonce"`
+		Email     string   `json:"email"`
+		Name      string   `json:"name"`
+		Picture   string   `json:"picture"`
+	}
+
+	// Audience is the aud claim, sent either as a single string or as a list
+	Audience []string
+
+	// VerifyOption adds a check to VerifyIDToken
+	VerifyOption func(*verifyOptions)
+
+	// verifyOptions holds the resolved VerifyOption values
+	verifyOptions struct {
+		nonce string
+	}
+
+	// header is the protected header of a compact JWT
+	header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+)
+
+// UnmarshalJSON accepts both the string and the list form of aud
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// WithNonce requires the nonce claim to equal nonce, the value sent with the authorization request
+func WithNonce(nonce string) VerifyOption {
+	return func(o *verifyOptions) {
+		o.nonce = nonce
+	}
+}
+
+// ParseIDToken decodes the claims of an id_token without verifying it,
+// use VerifyIDToken before trusting them
+func ParseIDToken(token string) (Claims, error) {
+	var claims Claims
+	if err := oauth2.DecodeJWTClaims(token, &claims); err != nil {
+		return Claims{}, err
+	}
+	return claims, nil
+}
+
+// VerifyIDToken verifies an id_token for offline use, failing with oauth2.ErrInvalidIDToken
+//   - the signature must match a key of jwks, selected by kid (RS256/384/512, ES256/384/512)
+//   - iss must equal issuer and aud must contain audience (your client ID)
+//   - exp must not have passed, allowing a minute of clock skew
+//   - with WithNonce, the nonce claim must match
+//
+// Provider keys are fetched on every call, use an oauth2.NewJWKSCache when verifying often
+//
+//	example:
+//	provider := google.NewProvider(setting)
+//	err := oidc.VerifyIDToken(ctx, token.GetIDToken(), "https://accounts.google.com", setting.ClientID, provider,
+//	    oidc.WithNonce(nonce))
+func VerifyIDToken(
+	ctx context.Context,
+	token string,
+	issuer string,
+	audience string,
+	jwks JWKSProvider,
+	opts ...VerifyOption,
+) error {
+	var options verifyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return invalid("malformed token")
+	}
+
+	var hdr header
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return invalid("malformed header")
+	}
+	hash, ok := hashes[hdr.Algorithm]
+	if !ok {
+		return invalid("unsupported alg " + hdr.Algorithm)
+	}
+
+	keys, err := jwks.SigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", oauth2.ErrInvalidIDToken, err)
+	}
+	key, ok := selectKey(keys, hdr)
+	if !ok {
+		return invalid("no signing key for kid " + hdr.KeyID)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return invalid("malformed signature")
+	}
+	if !verifySignature(key, hash, hdr.Algorithm, parts[0]+"."+parts[1], signature) {
+		return invalid("signature mismatch")
+	}
+
+	claims, err := ParseIDToken(token)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Issuer != issuer:
+		return invalid("unexpected issuer " + claims.Issuer)
+	case !slices.Contains(claims.Audience, audience):
+		return invalid("audience mismatch")
+	case claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)):
+		return invalid("token expired")
+	case claims.IssuedAt != 0 && time.Unix(claims.IssuedAt, 0).After(now.Add(clockSkew)):
+		return invalid("token issued in the future")
+	case options.nonce != "" && claims.Nonce != options.nonce:
+		return invalid("nonce mismatch")
+	}
+	return nil
+}
+
+// hashes maps the supported JWS algorithms to their digest
+var hashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// selectKey finds the key named by the header kid, or the only key when the token has no kid.
+// A key whose JWKS alg differs from the header alg is never used
+func selectKey(keys []oauth2.PublicKeyInfo, hdr header) (crypto.PublicKey, bool) {
+	var candidates []oauth2.PublicKeyInfo
+	for _, key := range keys {
+		if key.Algorithm != "" && key.Algorithm != hdr.Algorithm {
+			continue
+		}
+		if hdr.KeyID == "" || key.KeyID == hdr.KeyID {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) != 1 {
+		return nil, false
+	}
+	return candidates[0].Key, true
+}
+
+// verifySignature checks signature over signed with key, whose type must match the alg family
+func verifySignature(key crypto.PublicKey, hash crypto.Hash, alg string, signed string, signature []byte) bool {
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") && rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(key, digest, r, s)
+	}
+	return false
+}
+
+// decodeSegment decodes a base64url JWT segment into v
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// invalid wraps reason as an oauth2.ErrInvalidIDToken
+func invalid(reason string) error {
+	return fmt.Errorf("%w: %s", oauth2.ErrInvalidIDToken, reason)
+}
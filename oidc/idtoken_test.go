@@ -0,0 +1,222 @@
+package oidc_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/dings-things/oauth2/oidc"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	issuer   = "https://accounts.google.com"
+	clientID = "client-id"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// staticKeys is a JWKSProvider serving fixed keys
+type staticKeys []oauth2.PublicKeyInfo
+
+func (k staticKeys) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	return k, nil
+}
+
+// signRS256 builds a compact JWT signed with key
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// validClaims returns claims that pass every check
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":   issuer,
+		"sub":   "123",
+		"aud":   clientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": "n-0S6_WzA2Mj",
+		"email": "test@example.com",
+	}
+}
+
+// newFakeJWKS returns a google provider whose KeysURL serves key under kid
+func newFakeJWKS(t *testing.T, kid string, key *rsa.PublicKey) oauth2.Provider {
+	body, _ := json.Marshal(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, google.KeysURL, req.URL.String())
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body))}, nil
+	})}
+	return google.NewProvider(oauth2.ProviderSetting{Client: client})
+}
+
+func TestParseIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	claims := validClaims()
+	claims["aud"] = []string{clientID, "other"}
+	parsed, err := oidc.ParseIDToken(signRS256(t, key, "kid-1", claims))
+	assert.NoError(t, err)
+	assert.Equal(t, issuer, parsed.Issuer)
+	assert.Equal(t, "123", parsed.Subject)
+	assert.Equal(t, oidc.Audience{clientID, "other"}, parsed.Audience)
+	assert.Equal(t, "test@example.com", parsed.Email)
+
+	_, err = oidc.ParseIDToken("not-a-jwt")
+	assert.ErrorIs(t, err, oauth2.ErrInvalidIDToken)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	jwks := newFakeJWKS(t, "kid-1", &key.PublicKey)
+
+	t.Run("valid token", func(t *testing.T) {
+		token := signRS256(t, key, "kid-1", validClaims())
+		err := oidc.VerifyIDToken(ctx, token, issuer, clientID, jwks, oidc.WithNonce("n-0S6_WzA2Mj"))
+		assert.NoError(t, err)
+	})
+
+	tests := []struct {
+		name   string
+		token  func() string
+		opts   []oidc.VerifyOption
+		reason string
+	}{
+		{
+			name:   "signed by another key",
+			token:  func() string { return signRS256(t, otherKey, "kid-1", validClaims()) },
+			reason: "signature mismatch",
+		},
+		{
+			name:   "unknown kid",
+			token:  func() string { return signRS256(t, key, "kid-2", validClaims()) },
+			reason: "no signing key",
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://evil.example.com"
+				return signRS256(t, key, "kid-1", claims)
+			},
+			reason: "unexpected issuer",
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = "another-client"
+				return signRS256(t, key, "kid-1", claims)
+			},
+			reason: "audience mismatch",
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return signRS256(t, key, "kid-1", claims)
+			},
+			reason: "token expired",
+		},
+		{
+			name:   "nonce mismatch",
+			token:  func() string { return signRS256(t, key, "kid-1", validClaims()) },
+			opts:   []oidc.VerifyOption{oidc.WithNonce("another-nonce")},
+			reason: "nonce mismatch",
+		},
+		{
+			name: "alg none",
+			token: func() string {
+				header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"kid-1"}`))
+				payload, _ := json.Marshal(validClaims())
+				return header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+			},
+			reason: "unsupported alg",
+		},
+		{
+			name:   "malformed",
+			token:  func() string { return "a.b" },
+			reason: "malformed token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := oidc.VerifyIDToken(ctx, tt.token(), issuer, clientID, jwks, tt.opts...)
+			assert.ErrorIs(t, err, oauth2.ErrInvalidIDToken)
+			assert.ErrorContains(t, err, tt.reason)
+		})
+	}
+
+	t.Run("ES256 key", func(t *testing.T) {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"ec-1"}`))
+		payload, _ := json.Marshal(validClaims())
+		signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		assert.NoError(t, err)
+		signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		token := signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+		keys := staticKeys{{KeyID: "ec-1", Algorithm: "ES256", Key: &ecKey.PublicKey}}
+		assert.NoError(t, oidc.VerifyIDToken(ctx, token, issuer, clientID, keys))
+	})
+
+	t.Run("JWKS fetch failure", func(t *testing.T) {
+		client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("network down")
+		})}
+		provider := google.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		token := signRS256(t, key, "kid-1", validClaims())
+		err := oidc.VerifyIDToken(ctx, token, issuer, clientID, provider)
+		assert.ErrorIs(t, err, oauth2.ErrInvalidIDToken)
+		assert.ErrorIs(t, err, oauth2.ErrSigningKeysFailed)
+	})
+}
@@ -0,0 +1,297 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+)
+
+// Prompt values understood by WithPrompt, each provider forwards only the ones it supports
+const (
+	PromptNone          = "none"
+	PromptLogin         = "login"
+	PromptConsent       = "consent"
+	PromptSelectAccount = "select_account"
+	PromptCreate        = "create"
+)
+
+// knownPrompts lists the values accepted by ValidatePrompts
+var knownPrompts = []string{PromptNone, PromptLogin, PromptConsent, PromptSelectAccount, PromptCreate}
+
+// reservedAuthParams are the authorization parameters WithAuthParams cannot replace
+var reservedAuthParams = []string{
+	"client_id",
+	"redirect_uri",
+	"response_type",
+	"state",
+	"code_challenge",
+	"code_challenge_method",
+}
+
+type (
+	// AuthenticateOption customizes a single Client.Authenticate call
+	AuthenticateOption func(*AuthenticateOptions)
+
+	// AuthenticateOptions holds the resolved options read by Client.Authenticate
+	AuthenticateOptions struct {
+		// IDTokenOnly derives the user from the id_token instead of calling the userinfo endpoint
+		IDTokenOnly bool
+
+		// CodeVerifier is the PKCE verifier sent with the code exchange
+		CodeVerifier string
+
+		// Requirements are the UserInfo fields the fetched user must have
+		Requirements UserInfoRequirements
+	}
+
+	// AuthOption customizes a single authorization request
+	AuthOption func(*AuthOptions)
+
+	// AuthOptions holds the resolved per-request options read by providers when building requests
+	AuthOptions struct {
+		// OfflineAccess requests (or suppresses) a refresh token, nil keeps the provider default
+		OfflineAccess *bool
+
+		// Scopes are requested in addition to the provider defaults
+		Scopes []string
+
+		// Prompts overrides the provider's default prompt behavior
+		Prompts []string
+
+		// CodeVerifier enables PKCE: its S256 challenge is sent with the authorization request
+		// and the verifier itself with the code exchange
+		CodeVerifier string
+
+		// Resources are RFC 8707 resource indicators sent with both the authorization
+		// request and the code exchange
+		Resources []string
+
+		// ClaimsRequest is the OpenID Connect claims request parameter, a JSON object
+		// asking for individual id_token and userinfo claims
+		ClaimsRequest json.RawMessage
+
+		// Nonce is the OpenID Connect nonce sent with the authorization request,
+		// echoed back in the id_token nonce claim
+		Nonce string
+
+		// AuthParams are extra authorization query parameters, replacing the provider's values
+		AuthParams url.Values
+	}
+)
+
+// NewAuthOptions resolves opts into AuthOptions
+func NewAuthOptions(opts ...AuthOption) AuthOptions {
+	var options AuthOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// NewAuthenticateOptions resolves opts into AuthenticateOptions
+func NewAuthenticateOptions(opts ...AuthenticateOption) AuthenticateOptions {
+	var options AuthenticateOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithIDTokenOnly authenticates from the id_token claims alone, skipping the userinfo request.
+// Providers that cannot derive a user from the id_token fail with ErrUnsupportedOperation
+func WithIDTokenOnly() AuthenticateOption {
+	return func(o *AuthenticateOptions) {
+		o.IDTokenOnly = true
+	}
+}
+
+// WithPKCEVerifier sends the PKCE verifier given to WithPKCE at login with the code exchange
+func WithPKCEVerifier(verifier string) AuthenticateOption {
+	return func(o *AuthenticateOptions) {
+		o.CodeVerifier = verifier
+	}
+}
+
+// WithUserInfoRequirements fails authentication with ErrIncompleteProfile, listing the missing
+// fields, when the fetched user lacks a required field
+//
+//	example:
+//	result, err := client.Authenticate(ctx, provider, code, oauth2.WithUserInfoRequirements(
+//	    oauth2.UserInfoRequirements{ID: true, Email: true},
+//	))
+//	if errors.Is(err, oauth2.ErrIncompleteProfile) {
+//	    // ask the user to grant email access
+//	}
+func WithUserInfoRequirements(requirements UserInfoRequirements) AuthenticateOption {
+	return func(o *AuthenticateOptions) {
+		o.Requirements = requirements
+	}
+}
+
+// WithOfflineAccess is the portable "I want a refresh token" knob, translated by each provider
+//   - google: access_type=offline (the default) or access_type=online
+//   - kakao, naver: refresh tokens are always issued, so the option is ignored
+func WithOfflineAccess(offline bool) AuthOption {
+	return func(o *AuthOptions) {
+		o.OfflineAccess = &offline
+	}
+}
+
+// WithScopes requests additional scopes on top of the provider defaults
+//   - google: merged into the space-delimited scope parameter
+//   - kakao: sent as the comma-delimited scope parameter for additional consent
+//   - naver: scopes are managed in the developer console, so the option is ignored
+func WithScopes(scopes ...string) AuthOption {
+	return func(o *AuthOptions) {
+		o.Scopes = append(o.Scopes, scopes...)
+	}
+}
+
+// WithPrompt controls the login/consent screens shown by the provider
+//   - google: none, consent and select_account (the default is consent)
+//   - kakao: none, login, create and select_account
+//   - naver: prompts are not supported, so the option is ignored
+//   - twitch: login and consent are both sent as force_verify=true
+//
+// Values a provider does not support are dropped, unknown values fail with ErrInvalidPrompt
+// and none combined with any other value fails with ErrConflictingPrompts.
+// PromptSelectAccount alone gives a "switch account" UX without re-consent, but Google then
+// only returns a refresh token on the first consent, even with WithOfflineAccess(true)
+//
+//	example:
+//	// "Sign up" button
+//	client.BeginLogin(ctx, kakao.ProviderType, oauth2.WithPrompt(oauth2.PromptCreate))
+//	// "Switch account" button
+//	client.BeginLogin(ctx, google.ProviderType, oauth2.WithPrompt(oauth2.PromptSelectAccount))
+func WithPrompt(prompts ...string) AuthOption {
+	return func(o *AuthOptions) {
+		o.Prompts = append(o.Prompts, prompts...)
+	}
+}
+
+// WithPKCE enables PKCE (RFC 7636) with a verifier from GenerateCodeVerifier
+//   - google, kakao, github, gitlab: S256 challenge
+//   - x: S256 challenge, required since X rejects authorization requests without it
+//   - naver, apple, slack, twitch: PKCE is not supported, so the option is ignored
+//
+// Pass it to BeginLogin (code_challenge) and RequestToken (code_verifier),
+// storing the verifier alongside the state in between
+//
+//	example:
+//	verifier, err := oauth2.GenerateCodeVerifier()
+//	if err != nil { ... }
+//	authURL, cookie, err := client.BeginLogin(ctx, google.ProviderType, oauth2.WithPKCE(verifier))
+//	// on the callback
+//	token, err := client.RequestToken(ctx, google.ProviderType, code, oauth2.WithPKCE(verifier))
+func WithPKCE(verifier string) AuthOption {
+	return func(o *AuthOptions) {
+		o.CodeVerifier = verifier
+	}
+}
+
+// WithResource asks for a token audience-restricted to the API at uri (RFC 8707 resource indicator),
+// repeat it to request several resources. uri must be absolute and without a fragment
+//   - generic: sent to the authorization and token endpoints, a JWT access token must carry the resources in aud
+//   - google, kakao, naver, github, gitlab, apple, facebook, slack, twitch, x: resource indicators are not supported, so the option is ignored
+//
+// Like WithPKCE, pass it to both BeginLogin and RequestToken
+//
+//	example:
+//	authURL, cookie, err := client.BeginLogin(ctx, provider, oauth2.WithResource("https://api.example.com"))
+//	// on the callback
+//	token, err := client.RequestToken(ctx, provider, code, oauth2.WithResource("https://api.example.com"))
+func WithResource(uri string) AuthOption {
+	return func(o *AuthOptions) {
+		o.Resources = append(o.Resources, uri)
+	}
+}
+
+// WithClaimsRequest asks for specific id_token or userinfo claims with the OpenID Connect
+// claims parameter (OIDC Core 5.5), e.g. verified claims from a compliant identity provider.
+// The generic provider sends it, google, kakao, naver, github, gitlab, apple, facebook, slack, twitch and x do not support it and ignore it
+//
+//	example:
+//	oauth2.WithClaimsRequest(json.RawMessage(`{"id_token":{"email_verified":{"essential":true}}}`))
+func WithClaimsRequest(claims json.RawMessage) AuthOption {
+	return func(o *AuthOptions) {
+		o.ClaimsRequest = claims
+	}
+}
+
+// WithNonce sends an OpenID Connect nonce (see GenerateNonce) with the authorization request,
+// which the provider copies into the id_token so a replayed id_token can be detected
+//   - google, kakao, apple, twitch, generic: sent, check it with VerifyNonce or oidc.WithNonce
+//   - naver, github, gitlab, facebook, slack, x: nonce is not supported, so the option is ignored
+//
+// Store the nonce alongside the state (FlowSessionOptions.Nonce does both) and check it on the callback
+//
+//	example:
+//	nonce, err := oauth2.GenerateNonce()
+//	if err != nil { ... }
+//	authURL, cookie, err := client.BeginLogin(ctx, google.ProviderType, oauth2.WithNonce(nonce))
+//	// on the callback
+//	err = oauth2.VerifyNonce(token.GetIDToken(), nonce)
+func WithNonce(nonce string) AuthOption {
+	return func(o *AuthOptions) {
+		o.Nonce = nonce
+	}
+}
+
+// WithAuthParams adds provider-specific query parameters to the authorization URL
+// (e.g. login_hint, Google's hd or Naver's auth_type), repeat it to add more.
+// A parameter replaces the value the provider would send, such as Google's access_type or prompt,
+// except for client_id, redirect_uri, response_type, state and the PKCE challenge which are never replaced
+//
+//	example:
+//	client.BeginLogin(ctx, google.ProviderType, oauth2.WithAuthParams(url.Values{
+//	    "login_hint": {"user@example.com"},
+//	    "hd":         {"example.com"},
+//	}))
+func WithAuthParams(params url.Values) AuthOption {
+	return func(o *AuthOptions) {
+		if o.AuthParams == nil {
+			o.AuthParams = url.Values{}
+		}
+		for key, values := range params {
+			o.AuthParams[key] = append(o.AuthParams[key], values...)
+		}
+	}
+}
+
+// SetAuthParams copies the WithAuthParams parameters into an authorization query,
+// skipping the ones identifying the client and the flow
+func SetAuthParams(query url.Values, params url.Values) {
+	for key, values := range params {
+		if slices.Contains(reservedAuthParams, key) {
+			continue
+		}
+		query[key] = slices.Clone(values)
+	}
+}
+
+// ValidatePrompts checks every prompt is one of the known Prompt values
+// and that none, which forbids any interaction, is not combined with another prompt
+func ValidatePrompts(prompts []string) error {
+	for _, prompt := range prompts {
+		if !slices.Contains(knownPrompts, prompt) {
+			return ErrInvalidPrompt
+		}
+		if prompt == PromptNone && slices.ContainsFunc(prompts, func(p string) bool {
+			return p != PromptNone
+		}) {
+			return ErrConflictingPrompts
+		}
+	}
+	return nil
+}
+
+// SupportedPrompts returns the prompts contained in supported, keeping their order
+func SupportedPrompts(prompts []string, supported ...string) []string {
+	var filtered []string
+	for _, prompt := range prompts {
+		if slices.Contains(supported, prompt) && !slices.Contains(filtered, prompt) {
+			filtered = append(filtered, prompt)
+		}
+	}
+	return filtered
+}
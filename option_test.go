@@ -0,0 +1,87 @@
+package oauth2_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuthOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		options := oauth2.NewAuthOptions()
+		assert.Nil(t, options.OfflineAccess)
+	})
+
+	t.Run("offline access", func(t *testing.T) {
+		options := oauth2.NewAuthOptions(oauth2.WithOfflineAccess(true))
+		assert.True(t, *options.OfflineAccess)
+
+		options = oauth2.NewAuthOptions(oauth2.WithOfflineAccess(true), oauth2.WithOfflineAccess(false))
+		assert.False(t, *options.OfflineAccess, "last option wins")
+	})
+
+	t.Run("prompts", func(t *testing.T) {
+		options := oauth2.NewAuthOptions(oauth2.WithPrompt(oauth2.PromptCreate))
+		assert.Equal(t, []string{"create"}, options.Prompts)
+	})
+
+	t.Run("scopes accumulate", func(t *testing.T) {
+		options := oauth2.NewAuthOptions(oauth2.WithScopes("a", "b"), oauth2.WithScopes("c"))
+		assert.Equal(t, []string{"a", "b", "c"}, options.Scopes)
+	})
+}
+
+func TestValidatePrompts(t *testing.T) {
+	tests := []struct {
+		prompts []string
+		wantErr error
+	}{
+		{prompts: nil},
+		{prompts: []string{"none"}},
+		{prompts: []string{"select_account"}},
+		{prompts: []string{"select_account", "consent"}},
+		{prompts: []string{"login", "consent", "select_account", "create"}},
+		{prompts: []string{"none", "none"}},
+		{prompts: []string{"consent", "signup"}, wantErr: oauth2.ErrInvalidPrompt},
+		{prompts: []string{"none", "consent"}, wantErr: oauth2.ErrConflictingPrompts},
+		{prompts: []string{"select_account", "none"}, wantErr: oauth2.ErrConflictingPrompts},
+	}
+
+	for _, tt := range tests {
+		err := oauth2.ValidatePrompts(tt.prompts)
+		if tt.wantErr == nil {
+			assert.NoError(t, err, tt.prompts)
+		} else {
+			assert.ErrorIs(t, err, tt.wantErr, tt.prompts)
+		}
+	}
+}
+
+func TestSupportedPrompts(t *testing.T) {
+	prompts := oauth2.SupportedPrompts(
+		[]string{"create", "consent", "login", "consent"},
+		"consent",
+		"login",
+	)
+	assert.Equal(t, []string{"consent", "login"}, prompts)
+	assert.Empty(t, oauth2.SupportedPrompts([]string{"create"}, "consent"))
+}
+
+func TestSetAuthParams(t *testing.T) {
+	options := oauth2.NewAuthOptions(
+		oauth2.WithAuthParams(url.Values{"login_hint": {"user@example.com"}}),
+		oauth2.WithAuthParams(url.Values{"hd": {"example.com"}, "state": {"forged"}}),
+	)
+
+	query := url.Values{"state": {"state"}, "prompt": {"consent"}}
+	oauth2.SetAuthParams(query, options.AuthParams)
+	assert.Equal(t, "user@example.com", query.Get("login_hint"))
+	assert.Equal(t, "example.com", query.Get("hd"))
+	assert.Equal(t, "state", query.Get("state"), "reserved parameters are never replaced")
+	assert.Equal(t, "consent", query.Get("prompt"))
+
+	oauth2.SetAuthParams(query, url.Values{"prompt": {"select_account"}})
+	assert.Equal(t, "select_account", query.Get("prompt"))
+}
@@ -0,0 +1,99 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxPages bounds pagination so a misbehaving Link header cannot loop forever
+const maxPages = 50
+
+// FetchAllPages GETs pageURL and every page linked by `Link: <...>; rel="next"`,
+// passing each page body to decode in order (e.g. to aggregate GitHub's /user/emails)
+//   - header is sent on every page request (e.g. Authorization, Accept)
+//   - a next link to another scheme or host aborts the walk, so header never leaves the first origin
+//   - a non-200 page aborts the walk with the status and body in the error
+func (r *Requester) FetchAllPages(
+	ctx context.Context,
+	pageURL string,
+	header http.Header,
+	decode func(body []byte) error,
+) error {
+	visited := make(map[string]struct{})
+	var origin *url.URL
+
+	for page := 0; pageURL != ""; page++ {
+		if _, ok := visited[pageURL]; ok || page >= maxPages {
+			return fmt.Errorf("pagination did not terminate at %s", pageURL)
+		}
+		visited[pageURL] = struct{}{}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return err
+		}
+		if origin == nil {
+			origin = req.URL
+		} else if !sameOrigin(origin, req.URL) {
+			return fmt.Errorf("next page %s is not on the origin of %s", pageURL, origin)
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		resp, err := r.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, resp.Body)
+		}
+		if err := decode(resp.Body); err != nil {
+			return err
+		}
+
+		pageURL = nextPageURL(req.URL, resp.Header)
+	}
+
+	return nil
+}
+
+// sameOrigin reports whether a and b share their scheme and host, port included
+func sameOrigin(a *url.URL, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host)
+}
+
+// nextPageURL extracts the rel="next" target of an RFC 8288 Link header, resolved against current
+func nextPageURL(current *url.URL, header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range parts[1:] {
+				key, rel, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(key, "rel") {
+					continue
+				}
+				for _, relType := range strings.Fields(strings.Trim(rel, `"`)) {
+					if !strings.EqualFold(relType, "next") {
+						continue
+					}
+					next, err := current.Parse(strings.Trim(target, "<>"))
+					if err != nil {
+						return ""
+					}
+					return next.String()
+				}
+			}
+		}
+	}
+
+	return ""
+}
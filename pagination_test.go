@@ -0,0 +1,140 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequester_FetchAllPages(t *testing.T) {
+	type email struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+
+	newPagedRequester := func(pages map[string]string, links map[string]string) *oauth2.Requester {
+		return oauth2.NewRequester(oauth2.ProviderSetting{
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+
+				body, ok := pages[req.URL.String()]
+				if !ok {
+					return &http.Response{
+						StatusCode: http.StatusNotFound,
+						Body:       io.NopCloser(bytes.NewReader([]byte("not found"))),
+					}, nil
+				}
+				header := http.Header{}
+				if link, ok := links[req.URL.String()]; ok {
+					header.Set("Link", link)
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     header,
+					Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+				}, nil
+			})},
+		})
+	}
+	header := http.Header{"Authorization": {"Bearer token"}}
+
+	t.Run("aggregates two pages", func(t *testing.T) {
+		requester := newPagedRequester(
+			map[string]string{
+				"https://api.test/emails":        `[{"email":"old@example.com","verified":true}]`,
+				"https://api.test/emails?page=2": `[{"email":"me@example.com","primary":true,"verified":true}]`,
+			},
+			map[string]string{
+				"https://api.test/emails": `<https://api.test/emails?page=2>; rel="next", ` +
+					`<https://api.test/emails?page=2>; rel="last"`,
+			},
+		)
+
+		var emails []email
+		err := requester.FetchAllPages(context.Background(), "https://api.test/emails", header,
+			func(body []byte) error {
+				var page []email
+				if err := json.Unmarshal(body, &page); err != nil {
+					return err
+				}
+				emails = append(emails, page...)
+				return nil
+			},
+		)
+		assert.NoError(t, err)
+		assert.Len(t, emails, 2)
+		assert.Equal(t, "old@example.com", emails[0].Email)
+		assert.Equal(t, "me@example.com", emails[1].Email)
+		assert.True(t, emails[1].Primary)
+	})
+
+	t.Run("relative next link", func(t *testing.T) {
+		requester := newPagedRequester(
+			map[string]string{
+				"https://api.test/emails":        `[]`,
+				"https://api.test/emails?page=2": `[]`,
+			},
+			map[string]string{"https://api.test/emails": `</emails?page=2>; rel="next"`},
+		)
+
+		pages := 0
+		err := requester.FetchAllPages(context.Background(), "https://api.test/emails", header,
+			func(body []byte) error {
+				pages++
+				return nil
+			},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, pages)
+	})
+
+	t.Run("self referencing link terminates", func(t *testing.T) {
+		requester := newPagedRequester(
+			map[string]string{"https://api.test/emails": `[]`},
+			map[string]string{"https://api.test/emails": `<https://api.test/emails>; rel="next"`},
+		)
+
+		err := requester.FetchAllPages(context.Background(), "https://api.test/emails", header,
+			func(body []byte) error { return nil },
+		)
+		assert.Error(t, err)
+	})
+
+	t.Run("cross origin next link is not followed", func(t *testing.T) {
+		for _, next := range []string{"https://evil.test/emails?page=2", "http://api.test/emails?page=2"} {
+			requester := newPagedRequester(
+				map[string]string{"https://api.test/emails": `[]`, next: `[]`},
+				map[string]string{"https://api.test/emails": `<` + next + `>; rel="next"`},
+			)
+
+			pages := 0
+			err := requester.FetchAllPages(context.Background(), "https://api.test/emails", header,
+				func(body []byte) error {
+					pages++
+					return nil
+				},
+			)
+			assert.ErrorContains(t, err, "origin")
+			assert.Equal(t, 1, pages, "the token must not be sent to %s", next)
+		}
+	})
+
+	t.Run("failed page", func(t *testing.T) {
+		requester := newPagedRequester(
+			map[string]string{"https://api.test/emails": `[]`},
+			map[string]string{"https://api.test/emails": `<https://api.test/missing>; rel="next"`},
+		)
+
+		err := requester.FetchAllPages(context.Background(), "https://api.test/emails", header,
+			func(body []byte) error { return nil },
+		)
+		assert.ErrorContains(t, err, "404")
+	})
+}
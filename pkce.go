@@ -0,0 +1,44 @@
+package oauth2
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+)
+
+// CodeChallengeMethodS256 is the only PKCE challenge method sent by the providers
+const CodeChallengeMethodS256 = "S256"
+
+// GenerateCodeVerifier returns a random PKCE code verifier: 32 bytes of entropy
+// encoded as 43 base64url characters, within the 43-128 range of RFC 7636
+func GenerateCodeVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallengeS256 returns the S256 challenge of verifier: BASE64URL(SHA256(verifier))
+func CodeChallengeS256(verifier string) string {
+	digest := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// SetCodeChallenge adds the S256 code_challenge of verifier to an authorization query,
+// leaving it untouched when verifier is empty
+func SetCodeChallenge(query url.Values, verifier string) {
+	if verifier == "" {
+		return
+	}
+	query.Set("code_challenge", CodeChallengeS256(verifier))
+	query.Set("code_challenge_method", CodeChallengeMethodS256)
+}
+
+// SetCodeVerifier adds verifier to a token request form, leaving it untouched when empty
+func SetCodeVerifier(form url.Values, verifier string) {
+	if verifier != "" {
+		form.Set("code_verifier", verifier)
+	}
+}
@@ -0,0 +1,57 @@
+package oauth2_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateCodeVerifier(t *testing.T) {
+	verifier, err := oauth2.GenerateCodeVerifier()
+	assert.NoError(t, err)
+	assert.Len(t, verifier, 43)
+
+	other, err := oauth2.GenerateCodeVerifier()
+	assert.NoError(t, err)
+	assert.NotEqual(t, verifier, other)
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	verifier, err := oauth2.GenerateCodeVerifier()
+	assert.NoError(t, err)
+
+	digest := sha256.Sum256([]byte(verifier))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(digest[:]), oauth2.CodeChallengeS256(verifier))
+}
+
+func TestOAuth2Client_BeginLoginWithPKCE(t *testing.T) {
+	client := oauth2.NewClient(google.NewProvider(oauth2.ProviderSetting{
+		ClientID:    "client-id",
+		RedirectURL: "https://app.example.com/callback",
+	}))
+	verifier, err := oauth2.GenerateCodeVerifier()
+	assert.NoError(t, err)
+
+	authURL, _, err := client.BeginLogin(
+		context.Background(),
+		google.ProviderType,
+		oauth2.WithPKCE(verifier),
+	)
+	assert.NoError(t, err)
+
+	parsedURL, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	query := parsedURL.Query()
+	assert.Equal(t, oauth2.CodeChallengeS256(verifier), query.Get("code_challenge"))
+	assert.Equal(t, oauth2.CodeChallengeMethodS256, query.Get("code_challenge_method"))
+
+	authURL, _, err = client.BeginLogin(context.Background(), google.ProviderType)
+	assert.NoError(t, err)
+	assert.NotContains(t, authURL, "code_challenge")
+}
@@ -0,0 +1,170 @@
+package oauth2
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// RateLimitConfig configures the RateLimiter of ProviderSetting.RateLimit
+	RateLimitConfig struct {
+		// RequestsPerSecond is the sustained rate, a non-positive value disables limiting
+		RequestsPerSecond float64
+
+		// Burst is how many requests may go out at once, at least 1
+		Burst int
+	}
+
+	// RateLimiter is a thread-safe token bucket pacing outgoing provider calls
+	RateLimiter struct {
+		mu     sync.Mutex
+		rate   float64
+		burst  float64
+		tokens float64
+		last   time.Time
+	}
+
+	// rateLimitedProvider throttles the HTTP calls of the wrapped provider
+	rateLimitedProvider struct {
+		Provider
+		limiter *RateLimiter
+	}
+
+	// rateLimitedIDTokenProvider keeps IDTokenProvider available when the wrapped provider has it
+	rateLimitedIDTokenProvider struct {
+		*rateLimitedProvider
+		IDTokenProvider
+	}
+)
+
+// NewRateLimiter creates a limiter allowing rps calls per second with bursts of up to burst calls.
+// A non-positive rps disables limiting
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	burst = max(burst, 1)
+	return &RateLimiter{
+		rate:   rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a call is allowed or ctx is done, in which case ctx.Err() is returned
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// hand the reserved token back so cancelled calls do not slow down the others
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// WithRateLimit wraps provider so its token, userinfo, refresh, revoke, unlink and client
+// credentials calls are paced to rps calls per second (with bursts of burst calls), blocking
+// until allowed or ctx is done.
+// This keeps a busy service under provider quotas instead of self-inflicting 429s
+//
+//	example:
+//	client := oauth2.NewClient(
+//	    oauth2.WithRateLimit(kakao.NewProvider(setting), 10, 5),
+//	)
+func WithRateLimit(provider Provider, rps float64, burst int) Provider {
+	limited := &rateLimitedProvider{
+		Provider: provider,
+		limiter:  NewRateLimiter(rps, burst),
+	}
+	if idTokenProvider, ok := provider.(IDTokenProvider); ok {
+		return &rateLimitedIDTokenProvider{
+			rateLimitedProvider: limited,
+			IDTokenProvider:     idTokenProvider,
+		}
+	}
+	return limited
+}
+
+// GetUserInfo waits for the limiter before fetching the user info
+func (p *rateLimitedProvider) GetUserInfo(
+	ctx context.Context,
+	accessToken string,
+) (UserInfo, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, WrapProviderCause(p.GetProvider(), ErrUserInfoRequestFailed, err)
+	}
+	return p.Provider.GetUserInfo(ctx, accessToken)
+}
+
+// GetToken waits for the limiter before exchanging the code
+func (p *rateLimitedProvider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...AuthOption,
+) (TokenInfo, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, WrapProviderCause(p.GetProvider(), ErrTokenRequestFailed, err)
+	}
+	return p.Provider.GetToken(ctx, code, opts...)
+}
+
+// RefreshToken waits for the limiter before refreshing the token
+func (p *rateLimitedProvider) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (TokenInfo, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, WrapProviderCause(p.GetProvider(), ErrTokenRequestFailed, err)
+	}
+	return p.Provider.RefreshToken(ctx, refreshToken)
+}
+
+// RevokeToken waits for the limiter before revoking the token
+func (p *rateLimitedProvider) RevokeToken(ctx context.Context, token string) error {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return WrapProviderCause(p.GetProvider(), ErrTokenRevocationFailed, err)
+	}
+	return p.Provider.RevokeToken(ctx, token)
+}
+
+// GetClientCredentialsToken waits for the limiter before requesting the app-level token
+func (p *rateLimitedProvider) GetClientCredentialsToken(
+	ctx context.Context,
+	scopes ...string,
+) (TokenInfo, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, WrapProviderCause(p.GetProvider(), ErrTokenRequestFailed, err)
+	}
+	return p.Provider.GetClientCredentialsToken(ctx, scopes...)
+}
+
+// UsesFormPost reports whether the wrapped provider is a FormPostProvider posting its callback
+func (p *rateLimitedProvider) UsesFormPost() bool { return usesFormPost(p.Provider) }
+
+// Unlink waits for the limiter before unlinking the user
+func (p *rateLimitedProvider) Unlink(ctx context.Context, accessToken string) error {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return WrapProviderCause(p.GetProvider(), ErrUnlinkFailed, err)
+	}
+	return p.Provider.Unlink(ctx, accessToken)
+}
@@ -0,0 +1,152 @@
+package oauth2_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/dings-things/oauth2/kakao"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	t.Run("calls are paced to the rate after the burst", func(t *testing.T) {
+		limiter := oauth2.NewRateLimiter(20, 2)
+
+		start := time.Now()
+		for range 6 {
+			assert.NoError(t, limiter.Wait(context.Background()))
+		}
+		// 2 calls pass with the burst, the remaining 4 wait 50ms each
+		assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+	})
+
+	t.Run("non-positive rate disables limiting", func(t *testing.T) {
+		limiter := oauth2.NewRateLimiter(0, 1)
+
+		start := time.Now()
+		for range 100 {
+			assert.NoError(t, limiter.Wait(context.Background()))
+		}
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("context cancellation unblocks a waiting call", func(t *testing.T) {
+		limiter := oauth2.NewRateLimiter(0.1, 1)
+		assert.NoError(t, limiter.Wait(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+	})
+}
+
+func TestWithRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("provider calls are paced", func(t *testing.T) {
+		provider := oauth2.WithRateLimit(&mockProvider{
+			typ:            "google",
+			returnToken:    dummyToken{},
+			returnUserInfo: dummyUser{},
+		}, 20, 1)
+
+		start := time.Now()
+		_, err := provider.GetToken(ctx, "code")
+		assert.NoError(t, err)
+		_, err = provider.GetUserInfo(ctx, "access-token")
+		assert.NoError(t, err)
+		_, err = provider.GetUserInfo(ctx, "access-token")
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	})
+
+	t.Run("waiting call fails with the context error", func(t *testing.T) {
+		provider := oauth2.WithRateLimit(&mockProvider{typ: "google", returnToken: dummyToken{}}, 0.1, 1)
+		_, err := provider.GetToken(ctx, "code")
+		assert.NoError(t, err)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = provider.GetToken(cancelled, "code")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = provider.GetClientCredentialsToken(cancelled)
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("id_token support is kept", func(t *testing.T) {
+		provider := oauth2.WithRateLimit(google.NewProvider(oauth2.ProviderSetting{}), 10, 1)
+		_, ok := provider.(oauth2.IDTokenProvider)
+		assert.True(t, ok)
+
+		provider = oauth2.WithRateLimit(&mockProvider{typ: "kakao"}, 10, 1)
+		_, ok = provider.(oauth2.IDTokenProvider)
+		assert.False(t, ok)
+	})
+}
+
+func TestProviderSetting_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int32
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		body := `{"id":1001,"access_token":"access","token_type":"bearer"}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+
+	t.Run("token, userinfo and refresh requests are paced", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{
+			Client:    client,
+			RateLimit: &oauth2.RateLimitConfig{RequestsPerSecond: 20, Burst: 1},
+		})
+
+		start := time.Now()
+		_, err := provider.GetToken(ctx, "code")
+		assert.NoError(t, err)
+		_, err = provider.GetUserInfo(ctx, "access")
+		assert.NoError(t, err)
+		_, err = provider.RefreshToken(ctx, "refresh")
+		assert.NoError(t, err)
+		// the first call passes with the burst, the other two wait 50ms each
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	})
+
+	t.Run("context cancellation unblocks a waiting call", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{
+			Client:    client,
+			RateLimit: &oauth2.RateLimitConfig{RequestsPerSecond: 0.1, Burst: 1},
+		})
+		_, err := provider.GetUserInfo(ctx, "access")
+		assert.NoError(t, err)
+
+		before := calls.Load()
+		timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = provider.GetUserInfo(timeout, "access")
+		assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, before, calls.Load(), "the request must not be sent")
+	})
+
+	t.Run("no config leaves requests unpaced", func(t *testing.T) {
+		provider := kakao.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		start := time.Now()
+		for range 20 {
+			_, err := provider.GetUserInfo(ctx, "access")
+			assert.NoError(t, err)
+		}
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+}
@@ -0,0 +1,48 @@
+package oauth2
+
+import "sync"
+
+// ProviderConstructor builds a Provider from its settings (e.g. google.NewProvider)
+type ProviderConstructor func(setting ProviderSetting) Provider
+
+var (
+	constructorsMu sync.RWMutex
+	constructors   = make(map[ProviderType]ProviderConstructor)
+)
+
+// RegisterConstructor registers the constructor used by NewProviderByType for the given type
+//   - provider subpackages call it from init(), so importing them is enough to register
+//   - registering the same type twice replaces the previous constructor
+func RegisterConstructor(providerType ProviderType, constructor ProviderConstructor) {
+	constructorsMu.Lock()
+	defer constructorsMu.Unlock()
+
+	constructors[providerType] = constructor
+}
+
+// NewProviderByType constructs a registered provider from its type, e.g. a string loaded from config.
+// The setting is checked with ProviderSetting.Validate first
+//
+//	example:
+//	import _ "github.com/dings-things/oauth2/google"
+//
+//	provider, err := oauth2.NewProviderByType(oauth2.ProviderType(cfg.Name), oauth2.ProviderSetting{
+//	    ClientID:     cfg.ClientID,
+//	    ClientSecret: cfg.ClientSecret,
+//	    RedirectURL:  cfg.RedirectURL,
+//	})
+func NewProviderByType(providerType ProviderType, setting ProviderSetting) (Provider, error) {
+	constructorsMu.RLock()
+	constructor, ok := constructors[providerType]
+	constructorsMu.RUnlock()
+
+	if !ok {
+		return nil, WrapProviderError(providerType, ErrProviderNotRegistered, "")
+	}
+
+	if err := setting.Validate(); err != nil {
+		return nil, WrapProviderError(providerType, err, "")
+	}
+
+	return constructor(setting), nil
+}
@@ -0,0 +1,44 @@
+package oauth2_test
+
+import (
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	_ "github.com/dings-things/oauth2/google"
+	_ "github.com/dings-things/oauth2/kakao"
+	_ "github.com/dings-things/oauth2/naver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProviderByType(t *testing.T) {
+	for _, name := range []string{"google", "kakao", "naver"} {
+		t.Run(name, func(t *testing.T) {
+			provider, err := oauth2.NewProviderByType(
+				oauth2.ProviderType(name),
+				oauth2.ProviderSetting{ClientID: "client-id"},
+			)
+			assert.NoError(t, err)
+			assert.Equal(t, oauth2.ProviderType(name), provider.GetProvider())
+		})
+	}
+
+	t.Run("unregistered provider", func(t *testing.T) {
+		_, err := oauth2.NewProviderByType("unknown", oauth2.ProviderSetting{})
+		assert.ErrorIs(t, err, oauth2.ErrProviderNotRegistered)
+	})
+
+	t.Run("invalid redirect URL", func(t *testing.T) {
+		_, err := oauth2.NewProviderByType("google", oauth2.ProviderSetting{RedirectURL: "/callback"})
+		assert.ErrorIs(t, err, oauth2.ErrInvalidRedirectURL)
+	})
+
+	t.Run("custom constructor", func(t *testing.T) {
+		oauth2.RegisterConstructor("custom", func(setting oauth2.ProviderSetting) oauth2.Provider {
+			return &mockProvider{typ: "custom"}
+		})
+
+		provider, err := oauth2.NewProviderByType("custom", oauth2.ProviderSetting{})
+		assert.NoError(t, err)
+		assert.Equal(t, oauth2.ProviderType("custom"), provider.GetProvider())
+	})
+}
@@ -0,0 +1,281 @@
+package oauth2
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultClientTimeout bounds every provider request when ProviderSetting.Client is nil
+	DefaultClientTimeout = 10 * time.Second
+
+	// maxDrainBytes bounds how much of an unread body is discarded so the connection can be reused
+	maxDrainBytes = 4 << 10
+
+	// DefaultMaxResponseBytes caps every provider response body when ProviderSetting.MaxResponseBytes is 0
+	DefaultMaxResponseBytes = 1 << 20
+
+	// maxPooledBufferBytes keeps buffers grown by unusually large responses out of the pool
+	maxPooledBufferBytes = 64 << 10
+)
+
+// bufferPool holds the buffers response bodies are read into before being copied out
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+type (
+	// Requester sends provider HTTP requests and owns the response body lifecycle,
+	// so providers never leak a connection on an early return
+	Requester struct {
+		client          *http.Client
+		followRedirects bool
+		maxRetries      int
+		retryBackoff    time.Duration
+		timeout         time.Duration
+
+		// maxResponseBytes caps the read response body, unlimited when negative
+		maxResponseBytes int64
+
+		// limiter paces the HTTP calls, nil when unlimited
+		limiter *RateLimiter
+
+		// slots bounds the in-flight requests, nil when unlimited
+		slots chan struct{}
+
+		// observer is notified around the calls reported with Observe, nil when unset
+		observer Observer
+	}
+
+	// Response is a provider HTTP response whose body has been fully read and closed
+	Response struct {
+		StatusCode int
+		Header     http.Header
+		Body       []byte
+	}
+)
+
+// NewRequester creates the Requester used by a provider built from the given setting.
+// A nil setting.Client is replaced by a client over http.DefaultTransport with DefaultClientTimeout
+func NewRequester(setting ProviderSetting) *Requester {
+	client := setting.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultClientTimeout}
+	}
+	if !setting.FollowRedirects {
+		noRedirectClient := *client
+		noRedirectClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		client = &noRedirectClient
+	}
+
+	var slots chan struct{}
+	if setting.MaxConcurrentRequests > 0 {
+		slots = make(chan struct{}, setting.MaxConcurrentRequests)
+	}
+
+	var limiter *RateLimiter
+	if setting.RateLimit != nil && setting.RateLimit.RequestsPerSecond > 0 {
+		limiter = NewRateLimiter(setting.RateLimit.RequestsPerSecond, setting.RateLimit.Burst)
+	}
+
+	return &Requester{
+		client:          client,
+		followRedirects: setting.FollowRedirects,
+		maxRetries:      max(setting.MaxRetries, 0),
+		retryBackoff:    cmp.Or(setting.RetryBackoff, DefaultRetryBackoff),
+		timeout:         max(setting.RequestTimeout, 0),
+		slots:           slots,
+		limiter:         limiter,
+		observer:        setting.Observer,
+
+		maxResponseBytes: cmp.Or(setting.MaxResponseBytes, DefaultMaxResponseBytes),
+	}
+}
+
+// Do sends req and reads the whole response body
+//   - the body is closed on every path, and drained on read errors so the connection is reusable
+//   - a body longer than ProviderSetting.MaxResponseBytes fails with ErrResponseTooLarge
+//   - cancelling the request context closes the body, unblocking a read stuck on a slow server
+//   - unless redirects are followed, a 3xx fails with ErrUnexpectedRedirect carrying the Location
+//   - with ProviderSetting.MaxRetries, transient failures are retried with exponential backoff
+//   - with ProviderSetting.MaxConcurrentRequests, it waits for a free slot or the request context
+//   - with ProviderSetting.RateLimit, every attempt waits for the limiter or the request context
+//   - with ProviderSetting.RequestTimeout, the whole call fails with an error wrapping
+//     context.DeadlineExceeded once the timeout elapses
+func (r *Requester) Do(req *http.Request) (*Response, error) {
+	if r.timeout > 0 {
+		// WithTimeout keeps the caller's deadline when it is earlier
+		ctx, cancel := context.WithTimeout(req.Context(), r.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+	return r.doWithRetry(req)
+}
+
+// do sends req once, see Do
+func (r *Requester) do(req *http.Request) (*Response, error) {
+	if r.limiter != nil {
+		if err := r.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	if r.slots != nil {
+		select {
+		case r.slots <- struct{}{}:
+			defer func() { <-r.slots }()
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := context.AfterFunc(req.Context(), func() { resp.Body.Close() })
+	defer stop()
+
+	body, err := readBody(resp.Body, r.maxResponseBytes)
+	if err != nil {
+		DrainAndClose(req.Context(), resp.Body)
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if !r.followRedirects && isRedirect(resp.StatusCode) {
+		return nil, fmt.Errorf(
+			"%w: status %d to %q",
+			ErrUnexpectedRedirect,
+			resp.StatusCode,
+			resp.Header.Get("Location"),
+		)
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}, nil
+}
+
+// readBody reads body through a pooled buffer and returns an exactly sized copy,
+// avoiding the repeated growth allocations of io.ReadAll.
+// Reading stops one byte past limit, failing with ErrResponseTooLarge, unless limit is negative.
+// The buffer is reset and returned to the pool on every path
+func readBody(body io.Reader, limit int64) ([]byte, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBufferBytes {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if limit >= 0 {
+		body = io.LimitReader(body, limit+1)
+	}
+	if _, err := buf.ReadFrom(body); err != nil {
+		return nil, err
+	}
+	if limit >= 0 && int64(buf.Len()) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// DoWithFallback sends req like Do and, on a connection failure or a 5xx response,
+// resends it to each fallback URL in turn (e.g. regional failover hosts).
+// The last attempt's result is returned, a cancelled context stops the failover
+func (r *Requester) DoWithFallback(req *http.Request, fallbackURLs []string) (*Response, error) {
+	resp, err := r.Do(req)
+	for _, fallbackURL := range fallbackURLs {
+		if !shouldFailover(req.Context(), resp, err) {
+			break
+		}
+
+		target, parseErr := url.Parse(fallbackURL)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		fallbackReq := req.Clone(req.Context())
+		fallbackReq.URL = target
+		fallbackReq.Host = ""
+
+		resp, err = r.Do(fallbackReq)
+	}
+
+	return resp, err
+}
+
+// shouldFailover reports whether a result warrants trying the next host
+func shouldFailover(ctx context.Context, resp *Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, ErrUnexpectedRedirect)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// isRedirect reports whether status is a redirect the client would otherwise follow
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusSeeOther,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// DrainAndClose discards a bounded remainder of body and closes it.
+// Draining is skipped when ctx is already done since the connection cannot be reused anyway
+func DrainAndClose(ctx context.Context, body io.ReadCloser) {
+	if ctx.Err() == nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainBytes))
+	}
+	_ = body.Close()
+}
+
+// NewFormRequest builds a request carrying form as the query string for GET and DELETE,
+// or as an application/x-www-form-urlencoded body for any other method
+func NewFormRequest(
+	ctx context.Context,
+	method string,
+	endpoint string,
+	form url.Values,
+) (*http.Request, error) {
+	if method == http.MethodGet || method == http.MethodDelete {
+		target, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		query := target.Query()
+		for key, values := range form {
+			query[key] = values
+		}
+		target.RawQuery = query.Encode()
+
+		return http.NewRequestWithContext(ctx, method, target.String(), nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req, nil
+}
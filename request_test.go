@@ -0,0 +1,475 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/google"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// slowBody blocks every read until it is closed, like a stalled server connection
+type slowBody struct {
+	closed  chan struct{}
+	closes  atomic.Int32
+	payload io.Reader
+}
+
+func newSlowBody() *slowBody {
+	return &slowBody{closed: make(chan struct{})}
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	if b.payload != nil {
+		return b.payload.Read(p)
+	}
+	<-b.closed
+	return 0, errors.New("read on closed body")
+}
+
+func (b *slowBody) Close() error {
+	if b.closes.Add(1) == 1 {
+		close(b.closed)
+	}
+	return nil
+}
+
+func TestRequester_Do(t *testing.T) {
+	t.Run("reads and closes the body", func(t *testing.T) {
+		body := newSlowBody()
+		body.payload = strings.NewReader(`{"ok":true}`)
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+			})},
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "http://provider.test", nil)
+		resp, err := requester.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `{"ok":true}`, string(resp.Body))
+		assert.GreaterOrEqual(t, body.closes.Load(), int32(1))
+	})
+
+	t.Run("failed read does not leak into the next response", func(t *testing.T) {
+		calls := 0
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				calls++
+				body := io.Reader(strings.NewReader(`{"ok":true}`))
+				if calls == 1 {
+					body = io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errors.New("reset")))
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(body)}, nil
+			})},
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "http://provider.test", nil)
+		_, err := requester.Do(req)
+		assert.Error(t, err)
+
+		resp, err := requester.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"ok":true}`, string(resp.Body))
+	})
+
+	t.Run("releases a slow body when the context is cancelled", func(t *testing.T) {
+		body := newSlowBody()
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+			})},
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://provider.test", nil)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := requester.Do(req)
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			assert.Error(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Do did not return after the context was cancelled")
+		}
+		assert.GreaterOrEqual(t, body.closes.Load(), int32(1))
+	})
+}
+
+func TestRequester_DefaultClient(t *testing.T) {
+	// without a Client the default transport is used, so the mock is injected there
+	defaultTransport := http.DefaultTransport
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+	http.DefaultTransport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"ok":true}`))}, nil
+	})
+
+	requester := oauth2.NewRequester(oauth2.ProviderSetting{})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://provider.test", nil)
+	resp, err := requester.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, string(resp.Body))
+}
+
+func TestRequester_MaxConcurrentRequests(t *testing.T) {
+	const limit = 3
+
+	t.Run("no more than the limit are in flight", func(t *testing.T) {
+		var inFlight, peak atomic.Int32
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			MaxConcurrentRequests: limit,
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					seen := peak.Load()
+					if current <= seen || peak.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+			})},
+		})
+
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, _ := http.NewRequest(http.MethodGet, "http://provider.test", nil)
+				_, err := requester.Do(req)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		assert.LessOrEqual(t, peak.Load(), int32(limit))
+		assert.Equal(t, int32(limit), peak.Load(), "the limit should be reached under load")
+	})
+
+	t.Run("waiting respects the context", func(t *testing.T) {
+		release := make(chan struct{})
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			MaxConcurrentRequests: 1,
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				<-release
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+			})},
+		})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			req, _ := http.NewRequest(http.MethodGet, "http://provider.test", nil)
+			_, _ = requester.Do(req)
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://provider.test", nil)
+		_, err := requester.Do(req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(release)
+		<-done
+	})
+}
+
+func TestRequester_Redirects(t *testing.T) {
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/login" {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"text/html"}},
+				Body:       io.NopCloser(strings.NewReader("<html>login</html>")),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusFound,
+			Header:     http.Header{"Location": {"https://provider.test/login"}},
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+
+	t.Run("redirect is an error by default", func(t *testing.T) {
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client: &http.Client{Transport: transport},
+		})
+
+		req, _ := http.NewRequest(http.MethodPost, "https://provider.test/token", nil)
+		_, err := requester.Do(req)
+		assert.ErrorIs(t, err, oauth2.ErrUnexpectedRedirect)
+		assert.ErrorContains(t, err, "https://provider.test/login")
+	})
+
+	t.Run("following can be enabled", func(t *testing.T) {
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client:          &http.Client{Transport: transport},
+			FollowRedirects: true,
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "https://provider.test/userinfo", nil)
+		resp, err := requester.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "<html>login</html>", string(resp.Body))
+	})
+
+	t.Run("caller client is not modified", func(t *testing.T) {
+		client := &http.Client{Transport: transport}
+		_ = oauth2.NewRequester(oauth2.ProviderSetting{Client: client})
+		assert.Nil(t, client.CheckRedirect)
+	})
+}
+
+func TestRequester_DoWithFallback(t *testing.T) {
+	newRequester := func(responses map[string]int) (*oauth2.Requester, *[]string) {
+		var hosts []string
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				hosts = append(hosts, req.URL.Host)
+				status, ok := responses[req.URL.Host]
+				if !ok {
+					return nil, errors.New("connection refused")
+				}
+				return &http.Response{
+					StatusCode: status,
+					Body:       io.NopCloser(strings.NewReader(req.URL.Host)),
+				}, nil
+			})},
+		})
+		return requester, &hosts
+	}
+
+	tests := []struct {
+		name      string
+		responses map[string]int
+		wantHosts []string
+		wantBody  string
+		wantErr   bool
+	}{
+		{
+			name:      "primary succeeds",
+			responses: map[string]int{"primary.test": http.StatusOK, "fallback.test": http.StatusOK},
+			wantHosts: []string{"primary.test"},
+			wantBody:  "primary.test",
+		},
+		{
+			name:      "connection failure",
+			responses: map[string]int{"fallback.test": http.StatusOK},
+			wantHosts: []string{"primary.test", "fallback.test"},
+			wantBody:  "fallback.test",
+		},
+		{
+			name:      "server error",
+			responses: map[string]int{"primary.test": http.StatusBadGateway, "fallback.test": http.StatusOK},
+			wantHosts: []string{"primary.test", "fallback.test"},
+			wantBody:  "fallback.test",
+		},
+		{
+			name:      "client error is not retried",
+			responses: map[string]int{"primary.test": http.StatusUnauthorized, "fallback.test": http.StatusOK},
+			wantHosts: []string{"primary.test"},
+			wantBody:  "primary.test",
+		},
+		{
+			name:      "all hosts fail",
+			responses: map[string]int{},
+			wantHosts: []string{"primary.test", "fallback.test"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requester, hosts := newRequester(tt.responses)
+
+			req, _ := http.NewRequest(http.MethodGet, "http://primary.test/userinfo", nil)
+			resp, err := requester.DoWithFallback(req, []string{"http://fallback.test/userinfo"})
+			assert.Equal(t, tt.wantHosts, *hosts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(resp.Body))
+		})
+	}
+
+	t.Run("cancelled context stops failover", func(t *testing.T) {
+		requester, hosts := newRequester(map[string]int{"fallback.test": http.StatusOK})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://primary.test/userinfo", nil)
+		_, err := requester.DoWithFallback(req, []string{"http://fallback.test/userinfo"})
+		assert.Error(t, err)
+		assert.NotContains(t, *hosts, "fallback.test")
+	})
+}
+
+// BenchmarkRequester_Do measures reading a token-sized response, run with -benchmem
+func BenchmarkRequester_Do(b *testing.B) {
+	payload := []byte(`{"access_token":"` + strings.Repeat("a", 2048) + `","token_type":"Bearer"}`)
+	requester := oauth2.NewRequester(oauth2.ProviderSetting{
+		Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(payload)),
+			}, nil
+		})},
+	})
+	req, _ := http.NewRequest(http.MethodGet, "http://provider.test", nil)
+
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := requester.Do(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRequester_RequestTimeout(t *testing.T) {
+	// sleepyTransport answers after a second unless the request context ends first
+	sleepyTransport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-time.After(time.Second):
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	})
+
+	t.Run("provider call fails once the timeout elapses", func(t *testing.T) {
+		provider := google.NewProvider(oauth2.ProviderSetting{
+			Client:         &http.Client{Transport: sleepyTransport},
+			RequestTimeout: 20 * time.Millisecond,
+		})
+
+		start := time.Now()
+		_, err := provider.GetToken(context.Background(), "code")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("an earlier caller deadline wins", func(t *testing.T) {
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client:         &http.Client{Transport: sleepyTransport},
+			RequestTimeout: time.Minute,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://provider.test", nil)
+
+		start := time.Now()
+		_, err := requester.Do(req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("caller cancellation propagates", func(t *testing.T) {
+		requester := oauth2.NewRequester(oauth2.ProviderSetting{
+			Client:         &http.Client{Transport: sleepyTransport},
+			RequestTimeout: time.Minute,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://provider.test", nil)
+
+		_, err := requester.Do(req)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestRequester_MaxResponseBytes(t *testing.T) {
+	// newRequester answers every call with a body of size bytes, counting the calls
+	newRequester := func(size int, limit int64, calls *int) *oauth2.Requester {
+		return oauth2.NewRequester(oauth2.ProviderSetting{
+			Client: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				*calls++
+				body := io.NopCloser(io.LimitReader(zeroReader{}, int64(size)))
+				return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+			})},
+			MaxResponseBytes: limit,
+			MaxRetries:       2,
+			RetryBackoff:     time.Millisecond,
+		})
+	}
+
+	tests := []struct {
+		name    string
+		size    int
+		limit   int64
+		wantErr bool
+	}{
+		{name: "at the limit", size: 16, limit: 16},
+		{name: "over the limit", size: 17, limit: 16, wantErr: true},
+		{name: "over the default limit", size: oauth2.DefaultMaxResponseBytes + 1, wantErr: true},
+		{name: "unlimited", size: oauth2.DefaultMaxResponseBytes + 1, limit: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			requester := newRequester(tt.size, tt.limit, &calls)
+
+			req, _ := http.NewRequest(http.MethodGet, "http://provider.test", nil)
+			resp, err := requester.Do(req)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oauth2.ErrResponseTooLarge)
+				assert.Equal(t, 1, calls, "an oversized response is not retried")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, resp.Body, tt.size)
+		})
+	}
+}
+
+func TestGoogleProvider_ResponseTooLarge(t *testing.T) {
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"sub":"1","name":"` + strings.Repeat("a", 64) + `"}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+	provider := google.NewProvider(oauth2.ProviderSetting{Client: client, MaxResponseBytes: 32})
+
+	_, err := provider.GetUserInfo(context.Background(), "token")
+	assert.ErrorIs(t, err, oauth2.ErrResponseTooLarge)
+	assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+}
+
+// zeroReader is an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,53 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// SetResources adds every resource indicator to an authorization query or token request form.
+// Each must be an absolute URI without a fragment (RFC 8707 section 2), otherwise
+// ErrInvalidResource is returned and values is left untouched
+func SetResources(values url.Values, resources []string) error {
+	for _, resource := range resources {
+		parsed, err := url.Parse(resource)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+			return ErrInvalidResource
+		}
+	}
+
+	for _, resource := range resources {
+		values.Add("resource", resource)
+	}
+	return nil
+}
+
+// CheckResourceAudience verifies that a JWT access token is audience-restricted to every requested
+// resource. Opaque access tokens cannot be inspected and are accepted
+func CheckResourceAudience(accessToken string, resources []string) error {
+	if len(resources) == 0 || strings.Count(accessToken, ".") != 2 {
+		return nil
+	}
+
+	var claims struct {
+		Audience json.RawMessage `json:"aud"`
+	}
+	if err := DecodeJWTClaims(accessToken, &claims); err != nil {
+		return nil
+	}
+
+	audience := decodeAudience(claims.Audience)
+	if len(audience) == 0 {
+		return fmt.Errorf("%w: access token has no audience", ErrInvalidResource)
+	}
+
+	for _, resource := range resources {
+		if !slices.Contains(audience, resource) {
+			return fmt.Errorf("%w: access token audience does not include %s", ErrInvalidResource, resource)
+		}
+	}
+	return nil
+}
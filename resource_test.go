@@ -0,0 +1,75 @@
+package oauth2_test
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetResources(t *testing.T) {
+	options := oauth2.NewAuthOptions(
+		oauth2.WithResource("https://api.example.com"),
+		oauth2.WithResource("https://files.example.com/v1"),
+	)
+	want := []string{"https://api.example.com", "https://files.example.com/v1"}
+
+	t.Run("sent with the authorization and token requests", func(t *testing.T) {
+		query := url.Values{"response_type": {"code"}}
+		assert.NoError(t, oauth2.SetResources(query, options.Resources))
+		assert.Equal(t, want, query["resource"])
+
+		form := url.Values{"grant_type": {"authorization_code"}}
+		assert.NoError(t, oauth2.SetResources(form, options.Resources))
+		assert.Equal(t, want, form["resource"])
+	})
+
+	t.Run("no resources", func(t *testing.T) {
+		query := url.Values{}
+		assert.NoError(t, oauth2.SetResources(query, nil))
+		assert.False(t, query.Has("resource"))
+	})
+
+	for _, resource := range []string{"/relative", "https://api.example.com#section", "://bad"} {
+		t.Run("invalid "+resource, func(t *testing.T) {
+			query := url.Values{}
+			err := oauth2.SetResources(query, []string{"https://api.example.com", resource})
+			assert.ErrorIs(t, err, oauth2.ErrInvalidResource)
+			assert.False(t, query.Has("resource"))
+		})
+	}
+}
+
+func TestCheckResourceAudience(t *testing.T) {
+	accessToken := func(claims string) string {
+		return "header." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+	}
+	resources := []string{"https://api.example.com"}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "single audience", token: accessToken(`{"aud":"https://api.example.com"}`)},
+		{name: "audience list", token: accessToken(`{"aud":["other","https://api.example.com"]}`)},
+		{name: "opaque token", token: "opaque-access-token"},
+		{name: "other audience", token: accessToken(`{"aud":"https://other.example.com"}`), wantErr: true},
+		{name: "no audience", token: accessToken(`{"sub":"123"}`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := oauth2.CheckResourceAudience(tt.token, resources)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oauth2.ErrInvalidResource)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	assert.NoError(t, oauth2.CheckResourceAudience(accessToken(`{"aud":"other"}`), nil))
+}
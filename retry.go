@@ -0,0 +1,116 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryBackoff is the first retry delay when ProviderSetting.MaxRetries is set without RetryBackoff
+const DefaultRetryBackoff = 200 * time.Millisecond
+
+// noRetryKey is the context key marking requests that must be sent once
+type noRetryKey struct{}
+
+// WithoutRetry returns a context whose requests are sent once even with ProviderSetting.MaxRetries.
+// Every provider's GetToken uses it: the authorization code is single use, so a replay after a lost
+// response fails with invalid_grant or makes the provider revoke the tokens it issued
+func WithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// doWithRetry sends req with do, retrying up to r.maxRetries times on a network error or a
+// 429/500/502/503/504 response
+//   - the delay doubles from r.retryBackoff on each attempt, a Retry-After header takes precedence
+//   - retrying stops when the delay would outlast the context deadline, returning the last result
+//   - requests whose body cannot be replayed (no GetBody) are never retried
+//   - requests whose context comes from WithoutRetry (authorization code exchanges) are never retried
+func (r *Requester) doWithRetry(req *http.Request) (*Response, error) {
+	resp, err := r.do(req)
+	for attempt := 0; attempt < r.maxRetries && shouldRetry(req, resp, err); attempt++ {
+		if !sleep(req.Context(), r.retryDelay(attempt, resp)) {
+			break
+		}
+
+		retryReq := req.Clone(req.Context())
+		if req.Body != nil && req.Body != http.NoBody {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				break
+			}
+			retryReq.Body = body
+		}
+
+		resp, err = r.do(retryReq)
+	}
+
+	return resp, err
+}
+
+// shouldRetry reports whether a result is transient and req can be sent again
+func shouldRetry(req *http.Request, resp *Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if noRetry, _ := req.Context().Value(noRetryKey{}).(bool); noRetry {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil &&
+			!errors.Is(err, ErrUnexpectedRedirect) &&
+			!errors.Is(err, ErrResponseTooLarge)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns the wait before retry attempt (0-based)
+func (r *Requester) retryDelay(attempt int, resp *Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return wait
+		}
+	}
+	return r.retryBackoff << attempt
+}
+
+// parseRetryAfter reads a Retry-After value given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d, returning false without waiting when ctx would expire first
+// and false as soon as ctx is done
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,184 @@
+package oauth2_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyTransport answers with failures in order, then 200 with the request body echoed
+func flakyTransport(calls *int, bodies *[]string, failures ...func() (*http.Response, error)) roundTripperFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		*calls++
+		if req.Body != nil {
+			body, _ := io.ReadAll(req.Body)
+			*bodies = append(*bodies, string(body))
+		}
+		if *calls <= len(failures) {
+			return failures[*calls-1]()
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+	}
+}
+
+func statusResponse(code int, header http.Header) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		return &http.Response{StatusCode: code, Header: header, Body: io.NopCloser(strings.NewReader("fail"))}, nil
+	}
+}
+
+func TestRequester_Retry(t *testing.T) {
+	networkError := func() (*http.Response, error) { return nil, errors.New("connection reset") }
+
+	newRequester := func(transport roundTripperFunc, maxRetries int, backoff time.Duration) *oauth2.Requester {
+		return oauth2.NewRequester(oauth2.ProviderSetting{
+			Client:       &http.Client{Transport: transport},
+			MaxRetries:   maxRetries,
+			RetryBackoff: backoff,
+		})
+	}
+
+	t.Run("fails twice then succeeds", func(t *testing.T) {
+		var (
+			calls  int
+			bodies []string
+		)
+		requester := newRequester(
+			flakyTransport(&calls, &bodies, statusResponse(http.StatusServiceUnavailable, nil), networkError),
+			3,
+			time.Millisecond,
+		)
+
+		req, _ := http.NewRequest(http.MethodPost, "http://provider.test/token", strings.NewReader("code=abc"))
+		resp, err := requester.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, []string{"code=abc", "code=abc", "code=abc"}, bodies, "body must be replayed")
+	})
+
+	t.Run("retries are exhausted", func(t *testing.T) {
+		var (
+			calls  int
+			bodies []string
+		)
+		tooMany := statusResponse(http.StatusTooManyRequests, nil)
+		requester := newRequester(flakyTransport(&calls, &bodies, tooMany, tooMany, tooMany), 2, time.Millisecond)
+
+		req, _ := http.NewRequest(http.MethodGet, "http://provider.test/userinfo", nil)
+		resp, err := requester.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("definitive 400 is not retried", func(t *testing.T) {
+		var (
+			calls  int
+			bodies []string
+		)
+		requester := newRequester(flakyTransport(&calls, &bodies, statusResponse(http.StatusBadRequest, nil)), 3, time.Millisecond)
+
+		req, _ := http.NewRequest(http.MethodPost, "http://provider.test/token", strings.NewReader("code=abc"))
+		resp, err := requester.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("WithoutRetry sends once", func(t *testing.T) {
+		var (
+			calls  int
+			bodies []string
+		)
+		requester := newRequester(
+			flakyTransport(&calls, &bodies, statusResponse(http.StatusServiceUnavailable, nil), networkError),
+			3,
+			time.Millisecond,
+		)
+
+		ctx := oauth2.WithoutRetry(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://provider.test/token",
+			strings.NewReader("code=abc"))
+		resp, err := requester.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		var (
+			calls  int
+			bodies []string
+		)
+		requester := newRequester(flakyTransport(&calls, &bodies, networkError), 0, 0)
+
+		req, _ := http.NewRequest(http.MethodGet, "http://provider.test/userinfo", nil)
+		_, err := requester.Do(req)
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Retry-After takes precedence over the backoff", func(t *testing.T) {
+		var (
+			calls  int
+			bodies []string
+		)
+		retryNow := statusResponse(http.StatusServiceUnavailable, http.Header{"Retry-After": {"0"}})
+		requester := newRequester(flakyTransport(&calls, &bodies, retryNow), 1, time.Hour)
+
+		req, _ := http.NewRequest(http.MethodGet, "http://provider.test/userinfo", nil)
+		resp, err := requester.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("delay past the deadline returns the last result", func(t *testing.T) {
+		var (
+			calls  int
+			bodies []string
+		)
+		requester := newRequester(
+			flakyTransport(&calls, &bodies, statusResponse(http.StatusBadGateway, nil)),
+			3,
+			time.Hour,
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://provider.test/userinfo", nil)
+
+		start := time.Now()
+		resp, err := requester.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		assert.Equal(t, 1, calls)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("cancelled context stops retrying", func(t *testing.T) {
+		var (
+			calls  int
+			bodies []string
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelled := func() (*http.Response, error) {
+			cancel()
+			return nil, context.Canceled
+		}
+		requester := newRequester(flakyTransport(&calls, &bodies, cancelled), 3, time.Millisecond)
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://provider.test/userinfo", nil)
+		_, err := requester.Do(req)
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
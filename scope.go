@@ -0,0 +1,74 @@
+package oauth2
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// NormalizeScopes merges scope lists into a deduplicated list, splitting entries on whitespace
+// and keeping each scope at its first appearance so defaults stay ahead of extras
+//
+//	example:
+//	oauth2.NormalizeScopes([]string{"openid", "email"}, []string{"email profile"})
+//	// => [openid email profile]
+func NormalizeScopes(scopeSets ...[]string) []string {
+	seen := make(map[string]struct{})
+	normalized := make([]string, 0)
+
+	for _, scopes := range scopeSets {
+		for _, entry := range scopes {
+			for _, scope := range strings.Fields(entry) {
+				if _, ok := seen[scope]; ok {
+					continue
+				}
+				seen[scope] = struct{}{}
+				normalized = append(normalized, scope)
+			}
+		}
+	}
+
+	return normalized
+}
+
+// GrantedScopes returns the scopes granted on the token, or nil when the provider did not report them.
+// Both space (RFC 6749) and comma (e.g. GitHub) separated scope strings are accepted
+func GrantedScopes(token TokenInfo) []string {
+	if token == nil {
+		return nil
+	}
+	return ParseScopes(token.GetScope())
+}
+
+// ParseScopes splits a scope string returned by a provider into deduplicated scopes
+func ParseScopes(scope string) []string {
+	fields := strings.FieldsFunc(scope, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return NormalizeScopes(fields)
+}
+
+// DiffScopes compares the requested scopes with the granted ones for consent auditing
+//   - missing: requested but not granted (e.g. the user unchecked them)
+//   - extra: granted without being requested (e.g. previously granted, incremental consent)
+func DiffScopes(requested []string, granted []string) (missing []string, extra []string) {
+	requested = NormalizeScopes(requested)
+	granted = NormalizeScopes(granted)
+
+	for _, scope := range requested {
+		if !slices.Contains(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	for _, scope := range granted {
+		if !slices.Contains(requested, scope) {
+			extra = append(extra, scope)
+		}
+	}
+
+	return missing, extra
+}
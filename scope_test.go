@@ -0,0 +1,94 @@
+package oauth2_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs [][]string
+		want   string
+	}{
+		{
+			name:   "overlapping defaults and extras",
+			inputs: [][]string{{"openid", "email", "profile"}, {"profile", "drive.readonly", "openid"}},
+			want:   "openid email profile drive.readonly",
+		},
+		{
+			name:   "duplicates within one set",
+			inputs: [][]string{{"email", "email", "profile"}},
+			want:   "email profile",
+		},
+		{
+			name:   "space separated and blank entries",
+			inputs: [][]string{{"openid email", " ", ""}, {"email  profile"}},
+			want:   "openid email profile",
+		},
+		{
+			name: "empty",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := oauth2.NormalizeScopes(tt.inputs...)
+			assert.Equal(t, tt.want, strings.Join(got, " "))
+			assert.Equal(t, got, oauth2.NormalizeScopes(tt.inputs...), "result must be stable")
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	assert.Equal(t, []string{"openid", "email"}, oauth2.ParseScopes("openid email"))
+	assert.Equal(t, []string{"repo", "user:email"}, oauth2.ParseScopes("repo,user:email"))
+	assert.Equal(t, []string{"a", "b"}, oauth2.ParseScopes(" a, b  a "))
+	assert.Nil(t, oauth2.ParseScopes(""))
+}
+
+func TestDiffScopes(t *testing.T) {
+	tests := []struct {
+		name        string
+		requested   []string
+		granted     []string
+		wantMissing []string
+		wantExtra   []string
+	}{
+		{name: "both empty"},
+		{
+			name:        "nothing granted",
+			requested:   []string{"openid", "email"},
+			wantMissing: []string{"openid", "email"},
+		},
+		{
+			name:      "nothing requested",
+			granted:   []string{"openid"},
+			wantExtra: []string{"openid"},
+		},
+		{
+			name:      "identical",
+			requested: []string{"openid", "email"},
+			granted:   []string{"email", "openid"},
+		},
+		{
+			name:        "overlapping",
+			requested:   []string{"openid", "email", "drive.readonly"},
+			granted:     []string{"openid email", "calendar"},
+			wantMissing: []string{"drive.readonly"},
+			wantExtra:   []string{"calendar"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing, extra := oauth2.DiffScopes(tt.requested, tt.granted)
+			assert.Equal(t, tt.wantMissing, missing)
+			assert.Equal(t, tt.wantExtra, extra)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package oauth2
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Validate checks the setting before it reaches a provider, NewProviderByType calls it for you
+//   - RedirectURL, when set, must be an absolute http(s) URL with a host and no fragment,
+//     otherwise ErrInvalidRedirectURL is returned
+//   - plain http outside localhost, a trailing slash and upper-case scheme or host still work but
+//     commonly cause redirect_uri_mismatch, so they are logged as warnings to Logger
+func (s ProviderSetting) Validate() error {
+	if s.RedirectURL == "" {
+		return nil
+	}
+
+	redirectURL, err := parseRedirectURL(s.RedirectURL)
+	if err != nil {
+		return err
+	}
+
+	if s.Logger == nil {
+		return nil
+	}
+	if redirectURL.Scheme == "http" && !isLoopback(redirectURL.Hostname()) {
+		s.Logger.Warn("oauth2: redirect URL uses plain http outside localhost", "redirect_url", s.RedirectURL)
+	}
+	if strings.HasSuffix(redirectURL.Path, "/") {
+		s.Logger.Warn(
+			"oauth2: redirect URL ends with a slash, it must match the registered URL exactly",
+			"redirect_url", s.RedirectURL,
+		)
+	}
+	if redirectURL.Host != strings.ToLower(redirectURL.Host) || !strings.HasPrefix(s.RedirectURL, redirectURL.Scheme) {
+		s.Logger.Warn("oauth2: redirect URL scheme or host is not lower-case", "redirect_url", s.RedirectURL)
+	}
+	return nil
+}
+
+// ValidateCredentials runs Validate and checks the client credentials,
+// failing with ErrClientIDNotSet or ErrClientSecretNotSet.
+// The NewProviderWithError constructors call it
+func (s ProviderSetting) ValidateCredentials() error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if s.ClientID == "" {
+		return ErrClientIDNotSet
+	}
+	if s.ClientSecret == "" {
+		return ErrClientSecretNotSet
+	}
+	return nil
+}
+
+// CanonicalRedirectURL validates rawURL like ProviderSetting.Validate and lower-cases its scheme and host,
+// which are case-insensitive, so it can be compared with the URL registered at the provider
+//
+//	example:
+//	oauth2.CanonicalRedirectURL("HTTPS://App.Example.com/Callback")
+//	// => https://app.example.com/Callback
+func CanonicalRedirectURL(rawURL string) (string, error) {
+	redirectURL, err := parseRedirectURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	redirectURL.Scheme = strings.ToLower(redirectURL.Scheme)
+	redirectURL.Host = strings.ToLower(redirectURL.Host)
+	return redirectURL.String(), nil
+}
+
+// parseRedirectURL parses rawURL, rejecting relative, non-http(s) and fragment URLs
+func parseRedirectURL(rawURL string) (*url.URL, error) {
+	redirectURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRedirectURL, err.Error())
+	}
+
+	switch {
+	case !redirectURL.IsAbs() || redirectURL.Host == "":
+		return nil, fmt.Errorf("%w: %s is not an absolute URL", ErrInvalidRedirectURL, rawURL)
+	case !strings.EqualFold(redirectURL.Scheme, "https") && !strings.EqualFold(redirectURL.Scheme, "http"):
+		return nil, fmt.Errorf("%w: unsupported scheme %s", ErrInvalidRedirectURL, redirectURL.Scheme)
+	case redirectURL.Fragment != "" || strings.Contains(rawURL, "#"):
+		return nil, fmt.Errorf("%w: fragments are not allowed", ErrInvalidRedirectURL)
+	}
+	return redirectURL, nil
+}
+
+// isLoopback reports whether host is localhost or a loopback IP, where plain http is fine
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
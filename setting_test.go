@@ -0,0 +1,214 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/facebook"
+	"github.com/dings-things/oauth2/github"
+	"github.com/dings-things/oauth2/gitlab"
+	"github.com/dings-things/oauth2/google"
+	"github.com/dings-things/oauth2/kakao"
+	"github.com/dings-things/oauth2/naver"
+	"github.com/dings-things/oauth2/slack"
+	"github.com/dings-things/oauth2/twitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderSetting_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		redirectURL string
+		wantErr     bool
+		wantWarning string
+	}{
+		{name: "https", redirectURL: "https://app.example.com/callback"},
+		{name: "http on localhost", redirectURL: "http://localhost:8080/callback"},
+		{name: "http on loopback IP", redirectURL: "http://127.0.0.1/callback"},
+		{name: "not set", redirectURL: ""},
+		{name: "relative", redirectURL: "/callback", wantErr: true},
+		{name: "no host", redirectURL: "https:///callback", wantErr: true},
+		{name: "custom scheme", redirectURL: "myapp://callback", wantErr: true},
+		{name: "fragment", redirectURL: "https://app.example.com/callback#done", wantErr: true},
+		{name: "malformed", redirectURL: "https://app example.com/%zz", wantErr: true},
+		{name: "plain http", redirectURL: "http://app.example.com/callback", wantWarning: "plain http"},
+		{name: "trailing slash", redirectURL: "https://app.example.com/callback/", wantWarning: "ends with a slash"},
+		{name: "upper-case host", redirectURL: "https://App.Example.com/callback", wantWarning: "not lower-case"},
+		{name: "upper-case scheme", redirectURL: "HTTPS://app.example.com/callback", wantWarning: "not lower-case"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			setting := oauth2.ProviderSetting{
+				RedirectURL: tt.redirectURL,
+				Logger:      slog.New(slog.NewTextHandler(&logs, nil)),
+			}
+
+			err := setting.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oauth2.ErrInvalidRedirectURL)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.wantWarning == "" {
+				assert.Empty(t, logs.String())
+				return
+			}
+			assert.Contains(t, logs.String(), tt.wantWarning)
+		})
+	}
+
+	t.Run("warnings without a logger", func(t *testing.T) {
+		setting := oauth2.ProviderSetting{RedirectURL: "http://app.example.com/callback/"}
+		assert.NoError(t, setting.Validate())
+	})
+}
+
+func TestProviderSetting_ValidateCredentials(t *testing.T) {
+	valid := oauth2.ProviderSetting{
+		ClientID:     "client-id",
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example.com/callback",
+	}
+	assert.NoError(t, valid.ValidateCredentials())
+
+	noClientID := valid
+	noClientID.ClientID = ""
+	assert.ErrorIs(t, noClientID.ValidateCredentials(), oauth2.ErrClientIDNotSet)
+
+	noClientSecret := valid
+	noClientSecret.ClientSecret = ""
+	assert.ErrorIs(t, noClientSecret.ValidateCredentials(), oauth2.ErrClientSecretNotSet)
+
+	badRedirect := valid
+	badRedirect.RedirectURL = "/callback"
+	assert.ErrorIs(t, badRedirect.ValidateCredentials(), oauth2.ErrInvalidRedirectURL)
+}
+
+func TestNewProviderWithError(t *testing.T) {
+	constructors := map[oauth2.ProviderType]func(oauth2.ProviderSetting) (oauth2.Provider, error){
+		kakao.ProviderType:  kakao.NewProviderWithError,
+		naver.ProviderType:  naver.NewProviderWithError,
+		github.ProviderType: github.NewProviderWithError,
+		slack.ProviderType:  slack.NewProviderWithError,
+		twitch.ProviderType: twitch.NewProviderWithError,
+		google.ProviderType: func(setting oauth2.ProviderSetting) (oauth2.Provider, error) {
+			return google.NewProviderWithError(setting)
+		},
+		facebook.ProviderType: func(setting oauth2.ProviderSetting) (oauth2.Provider, error) {
+			return facebook.NewProviderWithError(setting)
+		},
+		gitlab.ProviderType: func(setting oauth2.ProviderSetting) (oauth2.Provider, error) {
+			return gitlab.NewProviderWithError(setting)
+		},
+	}
+	valid := oauth2.ProviderSetting{
+		ClientID:     "client-id",
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example.com/callback",
+	}
+
+	for providerType, newProvider := range constructors {
+		t.Run(string(providerType), func(t *testing.T) {
+			provider, err := newProvider(valid)
+			assert.NoError(t, err)
+			assert.Equal(t, providerType, provider.GetProvider())
+
+			noClientID := valid
+			noClientID.ClientID = ""
+			_, err = newProvider(noClientID)
+			assert.ErrorIs(t, err, oauth2.ErrClientIDNotSet)
+
+			var providerErr *oauth2.ProviderError
+			assert.ErrorAs(t, err, &providerErr)
+			assert.Equal(t, providerType, providerErr.Provider)
+
+			noClientSecret := valid
+			noClientSecret.ClientSecret = ""
+			_, err = newProvider(noClientSecret)
+			if providerType == kakao.ProviderType {
+				assert.NoError(t, err, "Kakao's client secret is optional")
+			} else {
+				assert.ErrorIs(t, err, oauth2.ErrClientSecretNotSet)
+			}
+
+			badRedirect := valid
+			badRedirect.RedirectURL = "myapp://callback"
+			_, err = newProvider(badRedirect)
+			assert.ErrorIs(t, err, oauth2.ErrInvalidRedirectURL)
+		})
+	}
+}
+
+func TestCanonicalRedirectURL(t *testing.T) {
+	canonical, err := oauth2.CanonicalRedirectURL("HTTPS://App.Example.com/Callback?next=/Home")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.example.com/Callback?next=/Home", canonical)
+
+	_, err = oauth2.CanonicalRedirectURL("/callback")
+	assert.ErrorIs(t, err, oauth2.ErrInvalidRedirectURL)
+}
+
+func TestProviderSetting_EndpointOverrides(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			w.Write([]byte(`{"access_token":"mock-access-token","token_type":"bearer"}`))
+		case "/userinfo":
+			assert.Equal(t, "Bearer mock-access-token", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"id":"123","email":"user@example.com","name":"Mock User"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	setting := oauth2.ProviderSetting{
+		Client:      server.Client(),
+		ClientID:    "client-id",
+		RedirectURL: "https://app.example.com/callback",
+		AuthURL:     server.URL + "/authorize",
+		TokenURL:    server.URL + "/token",
+		UserInfoURL: server.URL + "/userinfo",
+	}
+	generic, err := oauth2.NewGenericProvider(oauth2.GenericConfig{ProviderSetting: setting})
+	assert.NoError(t, err)
+
+	providers := []oauth2.Provider{
+		google.NewProvider(setting),
+		kakao.NewProvider(setting),
+		naver.NewProvider(setting),
+		github.NewProvider(setting),
+		facebook.NewProvider(setting),
+		generic,
+	}
+	for _, provider := range providers {
+		t.Run(string(provider.GetProvider()), func(t *testing.T) {
+			authURL, err := provider.GetAuthURL(ctx, "state")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(authURL, server.URL+"/authorize?"), authURL)
+
+			token, err := provider.GetToken(ctx, "code")
+			assert.NoError(t, err)
+			assert.Equal(t, "mock-access-token", token.GetAccessToken())
+		})
+	}
+
+	t.Run("full login against the mock server", func(t *testing.T) {
+		client := oauth2.NewClient(google.NewProvider(setting))
+
+		result, err := client.Authenticate(ctx, google.ProviderType, "code")
+		assert.NoError(t, err)
+		assert.Equal(t, "123", result.User.GetID())
+		assert.Equal(t, "user@example.com", result.User.GetEmail())
+	})
+}
@@ -0,0 +1,37 @@
+package oauth2
+
+import "context"
+
+// SimpleClient calls a Client with context.Background(), for callers without a context to pass.
+// Calls cannot be cancelled, bound them with ProviderSetting.RequestTimeout instead.
+// Client stays the primary API, reach it through Client for everything else
+//
+//	example:
+//	simple := oauth2.NewSimpleClient(client)
+//	authURL, err := simple.AuthURL(google.ProviderType, state)
+//	// on the callback
+//	token, err := simple.Token(google.ProviderType, code)
+//	user, err := simple.UserInfo(google.ProviderType, token.GetAccessToken())
+type SimpleClient struct {
+	Client Client
+}
+
+// NewSimpleClient wraps client
+func NewSimpleClient(client Client) *SimpleClient {
+	return &SimpleClient{Client: client}
+}
+
+// AuthURL generates the provider's authorization URL, see Client.RequestAuthURLWithError
+func (c *SimpleClient) AuthURL(provider ProviderType, state string, opts ...AuthOption) (string, error) {
+	return c.Client.RequestAuthURLWithError(context.Background(), provider, state, opts...)
+}
+
+// Token exchanges the authorization code for an access token, see Client.RequestToken
+func (c *SimpleClient) Token(provider ProviderType, code string, opts ...AuthOption) (TokenInfo, error) {
+	return c.Client.RequestToken(context.Background(), provider, code, opts...)
+}
+
+// UserInfo retrieves the user of the access token, see Client.RequestUserInfo
+func (c *SimpleClient) UserInfo(provider ProviderType, accessToken string) (UserInfo, error) {
+	return c.Client.RequestUserInfo(context.Background(), provider, accessToken)
+}
@@ -0,0 +1,89 @@
+package oauth2_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingClient records the calls SimpleClient delegates to it
+type recordingClient struct {
+	oauth2.Client
+	calls []string
+	ctxs  []context.Context
+}
+
+func (c *recordingClient) RequestAuthURLWithError(
+	ctx context.Context,
+	provider oauth2.ProviderType,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
+	c.calls = append(c.calls, "auth:"+string(provider)+":"+state)
+	c.ctxs = append(c.ctxs, ctx)
+	return "https://example.com/auth", nil
+}
+
+func (c *recordingClient) RequestToken(
+	ctx context.Context,
+	provider oauth2.ProviderType,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	c.calls = append(c.calls, "token:"+string(provider)+":"+code)
+	c.ctxs = append(c.ctxs, ctx)
+	return dummyToken{}, nil
+}
+
+func (c *recordingClient) RequestUserInfo(
+	ctx context.Context,
+	provider oauth2.ProviderType,
+	accessToken string,
+) (oauth2.UserInfo, error) {
+	c.calls = append(c.calls, "user:"+string(provider)+":"+accessToken)
+	c.ctxs = append(c.ctxs, ctx)
+	return nil, oauth2.ErrUserInfoRequestFailed
+}
+
+func TestSimpleClient(t *testing.T) {
+	client := &recordingClient{}
+	simple := oauth2.NewSimpleClient(client)
+
+	authURL, err := simple.AuthURL("google", "state")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/auth", authURL)
+
+	token, err := simple.Token("google", "code")
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token", token.GetAccessToken())
+
+	_, err = simple.UserInfo("google", "access-token")
+	assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed, "errors are passed through")
+
+	assert.Equal(t, []string{"auth:google:state", "token:google:code", "user:google:access-token"}, client.calls)
+	for _, ctx := range client.ctxs {
+		assert.Equal(t, context.Background(), ctx)
+	}
+
+	t.Run("over a real client", func(t *testing.T) {
+		simple := oauth2.NewSimpleClient(oauth2.NewClient(&mockProvider{
+			typ:            "naver",
+			authURL:        "https://naver.example.com/auth",
+			returnToken:    dummyToken{},
+			returnUserInfo: dummyUser{},
+		}))
+
+		authURL, err := simple.AuthURL("naver", "state")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://naver.example.com/auth", authURL)
+
+		user, err := simple.UserInfo("naver", "access-token")
+		assert.NoError(t, err)
+		assert.Equal(t, "id", user.GetID())
+
+		_, err = simple.Token("kakao", "code")
+		assert.ErrorIs(t, err, oauth2.ErrProviderNotSet)
+	})
+}
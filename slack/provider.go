@@ -0,0 +1,439 @@
+package slack
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// ProviderType is the identifier for the Slack OAuth2 provider
+	//   - REFS : https://api.slack.com/authentication/sign-in-with-slack
+	ProviderType oauth2.ProviderType = "slack"
+
+	// AuthURL is the endpoint to start the OAuth v2 authorization flow
+	AuthURL = "https://slack.com/oauth/v2/authorize"
+
+	// TokenURL is the endpoint to exchange the authorization code or a refresh token
+	TokenURL = "https://slack.com/api/oauth.v2.access"
+
+	// UserInfoURL is the endpoint returning the signed-in user and their workspace
+	UserInfoURL = "https://slack.com/api/users.identity"
+
+	// RevokeURL is the endpoint revoking the token used to call it
+	RevokeURL = "https://slack.com/api/auth.revoke"
+)
+
+// DefaultScopes are requested when ProviderSetting.Scopes is empty, WithScopes adds to them.
+// Replace it during initialization, before any provider is used
+var DefaultScopes = []string{"identity.basic", "identity.email", "identity.avatar", "identity.team"}
+
+type (
+	// WorkspaceUser is implemented by Slack users, exposing the workspace (team) they signed in with
+	WorkspaceUser interface {
+		GetTeamID() string
+		GetTeamName() string
+	}
+
+	// provider holds the configuration for Slack's OAuth2 implementation
+	provider struct {
+		requester    *oauth2.Requester
+		clientID     string
+		clientSecret string
+		redirectURL  string
+
+		revocationMethod string
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
+	}
+
+	// apiResponse is the envelope of every Slack Web API response,
+	// failures are reported with ok=false and an error code even on HTTP 200
+	apiResponse struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+
+	// userInfo represents the users.identity response, nesting the user and team objects
+	userInfo struct {
+		User struct {
+			ID       string `json:"id"`
+			Name     string `json:"name"`
+			Email    string `json:"email"`
+			Image192 string `json:"image_192"`
+		} `json:"user"`
+		Team struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"team"`
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
+	}
+
+	// token holds the token fields shared by the top level (bot or refreshed token)
+	// and authed_user (user token) of an oauth.v2.access response
+	token struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+		TokenType    string `json:"token_type"`
+	}
+
+	// tokenInfo represents the oauth.v2.access response. Sign in with Slack only asks for
+	// user scopes, so the user token in authed_user is promoted when there is no bot token
+	tokenInfo struct {
+		token
+		AuthedUser token `json:"authed_user"`
+
+		issuedAt time.Time
+	}
+)
+
+// provider and userInfo must keep implementing their interfaces
+var (
+	_ oauth2.Provider = (*provider)(nil)
+	_ WorkspaceUser   = userInfo{}
+)
+
+func init() {
+	oauth2.RegisterConstructor(ProviderType, NewProvider)
+}
+
+// NewProvider initializes and returns a new Slack OAuth2 provider.
+// ProviderSetting.StrictTokenType does not apply since Slack's token_type is bot or user
+func NewProvider(setting oauth2.ProviderSetting) oauth2.Provider {
+	return &provider{
+		requester:    oauth2.NewRequester(setting),
+		clientID:     setting.ClientID,
+		clientSecret: setting.ClientSecret,
+		redirectURL:  setting.RedirectURL,
+
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodPost),
+
+		authURL:  cmp.Or(setting.AuthURL, AuthURL),
+		tokenURL: cmp.Or(setting.TokenURL, TokenURL),
+
+		userInfoURL:          cmp.Or(setting.UserInfoURL, UserInfoURL),
+		userInfoFallbackURLs: setting.UserInfoFallbackURLs,
+	}
+}
+
+// NewProviderWithError is NewProvider failing with ErrClientIDNotSet, ErrClientSecretNotSet
+// or ErrInvalidRedirectURL instead of building a provider whose calls cannot succeed
+func NewProviderWithError(setting oauth2.ProviderSetting) (oauth2.Provider, error) {
+	if err := setting.ValidateCredentials(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+	return NewProvider(setting), nil
+}
+
+// GetUserInfo retrieves the user and workspace from users.identity using a user token
+func (s *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
+	if accessToken == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAccessToken, "")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.userInfoURL, nil)
+	if err != nil {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrUserInfoRequestFailed,
+			err.Error(),
+		)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	oauth2.SetAcceptLanguage(req)
+
+	end := s.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+	resp, err := s.requester.DoWithFallback(req, s.userInfoFallbackURLs)
+	end(resp, err)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	if err := checkResponse(resp, oauth2.OpGetUserInfo, oauth2.ErrUserInfoRequestFailed); err != nil {
+		return nil, err
+	}
+
+	var userInfo userInfo
+	if err := oauth2.DecodeJSONResponse(resp, &userInfo); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+	userInfo.raw = oauth2.DecodeRawUserInfo(resp.Body)
+	userInfo.nameStrategy = s.nameStrategy
+
+	return &userInfo, nil
+}
+
+// GetAuthURL constructs the Slack authorization URL for Sign in with Slack
+//   - ProviderSetting.Scopes replaces the identity.basic, identity.email, identity.avatar and
+//     identity.team defaults, WithScopes adds to them. They are sent as the comma-delimited user_scope
+//   - WithPrompt, WithPKCE and WithOfflineAccess are ignored since Slack does not support them
+func (s *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
+	if s.redirectURL == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
+	}
+	if s.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+
+	options := oauth2.NewAuthOptions(opts...)
+	if err := oauth2.ValidatePrompts(options.Prompts); err != nil {
+		return "", oauth2.WrapProviderError(ProviderType, err, strings.Join(options.Prompts, " "))
+	}
+
+	scopes := s.scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	query := url.Values{}
+	query.Set("client_id", s.clientID)
+	query.Set("redirect_uri", s.redirectURL)
+	query.Set("user_scope", strings.Join(oauth2.NormalizeScopes(scopes, options.Scopes), ","))
+	query.Set("state", state)
+	oauth2.SetAuthParams(query, options.AuthParams)
+
+	return oauth2.BuildAuthURL(ProviderType, s.authURL, query, s.authURLLimits)
+}
+
+// GetToken exchanges the authorization code for a user token from Slack
+func (s *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	if code == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
+	}
+	ctx = oauth2.WithoutRetry(ctx)
+
+	form := url.Values{}
+	form.Set("code", code)
+	form.Set("client_id", s.clientID)
+	form.Set("client_secret", s.clientSecret)
+	form.Set("redirect_uri", s.redirectURL)
+
+	return s.requestToken(ctx, oauth2.OpGetToken, form)
+}
+
+// RefreshToken exchanges a refresh token for a new access token.
+// Slack only issues refresh tokens to apps with token rotation enabled
+func (s *provider) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (oauth2.TokenInfo, error) {
+	if refreshToken == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyRefreshToken, "")
+	}
+
+	form := url.Values{}
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", s.clientID)
+	form.Set("client_secret", s.clientSecret)
+	form.Set("grant_type", "refresh_token")
+
+	return s.requestToken(ctx, oauth2.OpRefreshToken, form)
+}
+
+// requestToken posts form to oauth.v2.access and reports errors Slack returns with ok=false
+func (s *provider) requestToken(ctx context.Context, op string, form url.Values) (oauth2.TokenInfo, error) {
+	var tokenInfo tokenInfo
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, s.tokenURL, form)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRequestFailed,
+			err.Error(),
+		)
+	}
+
+	end := s.requester.Observe(ctx, ProviderType, op)
+	resp, err := s.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if err := checkResponse(resp, op, oauth2.ErrTokenRequestFailed); err != nil {
+		return tokenInfo, err
+	}
+
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	if tokenInfo.AccessToken == "" {
+		tokenInfo.token = tokenInfo.AuthedUser
+	}
+
+	// a refresh keeps the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = form.Get("refresh_token")
+	}
+
+	return tokenInfo, nil
+}
+
+// RevokeToken revokes the token with auth.revoke, which authenticates with the token itself
+func (s *provider) RevokeToken(ctx context.Context, accessToken string) error {
+	if accessToken == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRevocationFailed, "token is empty")
+	}
+
+	req, err := oauth2.NewFormRequest(ctx, s.revocationMethod, RevokeURL, url.Values{})
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := s.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRevocationFailed, err)
+	}
+
+	return checkResponse(resp, oauth2.OpRevokeToken, oauth2.ErrTokenRevocationFailed)
+}
+
+// checkResponse fails with base for a non-200 status, a body that is not JSON or an ok=false body,
+// whose error field becomes ProviderError.Code
+func checkResponse(resp *oauth2.Response, op string, base error) error {
+	if resp.StatusCode == http.StatusOK {
+		if err := oauth2.CheckJSONResponse(resp); err != nil {
+			return oauth2.WrapProviderCause(ProviderType, base, err)
+		}
+	}
+
+	var envelope apiResponse
+	if resp.StatusCode != http.StatusOK || oauth2.DecodeJSONResponse(resp, &envelope) != nil || !envelope.OK {
+		return oauth2.WrapResponseError(ProviderType, op, base, resp)
+	}
+	return nil
+}
+
+// CanRefresh reports whether token still holds a refresh token that has not expired
+func (s provider) CanRefresh(token oauth2.TokenInfo) bool { return oauth2.CanRefresh(token) }
+
+// SigningKeys is not supported since Slack's OAuth v2 flow issues no id_token
+func (s provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	return nil, oauth2.WrapProviderError(
+		ProviderType,
+		oauth2.ErrUnsupportedOperation,
+		"no JWKS endpoint",
+	)
+}
+
+// GetClientCredentialsToken is not supported, Slack apps get bot tokens by installation
+func (s provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrGrantNotSupported, "client_credentials")
+}
+
+// Unlink is not supported, Slack has no endpoint to disconnect a user from the app
+func (s provider) Unlink(ctx context.Context, accessToken string) error {
+	return oauth2.WrapProviderError(ProviderType, oauth2.ErrUnsupportedOperation, "unlink")
+}
+
+// GetProvider returns the provider type ("slack")
+func (s provider) GetProvider() oauth2.ProviderType { return ProviderType }
+
+// GetRedirectURL returns the configured redirect URL
+func (s provider) GetRedirectURL() string { return s.redirectURL }
+
+// GetID returns the user's Slack ID, unique within the workspace of GetTeamID
+func (s userInfo) GetID() string { return s.User.ID }
+
+// GetEmail returns the user's email address, empty without identity.email
+func (s userInfo) GetEmail() string { return s.User.Email }
+
+// GetName returns the user's display name or email depending on the NameStrategy
+func (s userInfo) GetName() string {
+	return oauth2.SelectName(s.nameStrategy, s.User.Name, "", s.User.Email)
+}
+
+// GetGender returns an empty string since Slack has no gender field
+func (s userInfo) GetGender() string { return "" }
+
+// GetGenderNormalized returns oauth2.GenderUnknown since there is no gender
+func (s userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.GenderUnknown }
+
+// GetProfileImage returns the 192px avatar URL, empty without identity.avatar
+func (s userInfo) GetProfileImage() string { return s.User.Image192 }
+
+// GetPhoneNumber returns an empty string since users.identity has no phone number
+func (s userInfo) GetPhoneNumber() string { return "" }
+
+// GetBirthday returns an empty string since Slack has no birthday field
+func (s userInfo) GetBirthday() string { return "" }
+
+// GetLocale returns an empty string since users.identity does not share a locale
+func (s userInfo) GetLocale() string { return "" }
+
+// IsEmailVerified reports false since users.identity does not say
+func (s userInfo) IsEmailVerified() bool { return false }
+
+// GetRaw returns the decoded users.identity response (e.g. "team" with its domain)
+func (s userInfo) GetRaw() map[string]any { return s.raw }
+
+// GetTeamID returns the ID of the workspace the user signed in with
+func (s userInfo) GetTeamID() string { return s.Team.ID }
+
+// GetTeamName returns the workspace name, empty without identity.team
+func (s userInfo) GetTeamName() string { return s.Team.Name }
+
+// GetAccessToken returns the OAuth2 access token
+func (s tokenInfo) GetAccessToken() string { return s.AccessToken }
+
+// GetRefreshToken returns the refresh token, empty without token rotation
+func (s tokenInfo) GetRefreshToken() string { return s.RefreshToken }
+
+// GetExpiry returns the token expiration time in seconds, 0 without token rotation
+func (s tokenInfo) GetExpiry() int { return s.ExpiresIn }
+
+// HasRefreshToken reports whether a refresh token was issued
+func (s tokenInfo) HasRefreshToken() bool { return s.RefreshToken != "" }
+
+// HasExpiry reports whether the access token expires, only with token rotation
+func (s tokenInfo) HasExpiry() bool { return s.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (s tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(s.issuedAt, s.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (s tokenInfo) IsExpired() bool { return oauth2.Expired(s.GetExpiresAt()) }
+
+// GetScope returns the comma-delimited scopes granted by the user
+func (s tokenInfo) GetScope() string { return s.Scope }
+
+// GetTokenType returns the token type ("user" or "bot")
+func (s tokenInfo) GetTokenType() string { return s.TokenType }
+
+// GetIDToken is always empty since the OAuth v2 flow issues no id_token
+func (s tokenInfo) GetIDToken() string { return "" }
@@ -0,0 +1,201 @@
+package slack_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newMockClient(fn roundTripperFunc) *http.Client {
+	return &http.Client{Transport: fn}
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestSlackProvider_GetUserInfo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("maps the nested user and team", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, slack.UserInfoURL, req.URL.String())
+			assert.Equal(t, "Bearer xoxp-token", req.Header.Get("Authorization"))
+			return jsonResponse(http.StatusOK, `{
+				"ok": true,
+				"user": {"id": "U0G9QF9C6", "name": "Sonny Whether", "email": "sonny@example.com",
+					"image_192": "https://avatars.example.com/192.png"},
+				"team": {"id": "T0G9PQBBK", "name": "Naval Supply", "domain": "navalsupply"}
+			}`), nil
+		})
+		provider := slack.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		user, err := provider.GetUserInfo(ctx, "xoxp-token")
+		assert.NoError(t, err)
+		assert.Equal(t, "U0G9QF9C6", user.GetID())
+		assert.Equal(t, "Sonny Whether", user.GetName())
+		assert.Equal(t, "sonny@example.com", user.GetEmail())
+		assert.Equal(t, "https://avatars.example.com/192.png", user.GetProfileImage())
+
+		workspaceUser, ok := user.(slack.WorkspaceUser)
+		assert.True(t, ok)
+		assert.Equal(t, "T0G9PQBBK", workspaceUser.GetTeamID())
+		assert.Equal(t, "Naval Supply", workspaceUser.GetTeamName())
+		assert.Equal(t, "navalsupply", user.GetRaw()["team"].(map[string]any)["domain"])
+	})
+
+	t.Run("ok false on HTTP 200", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{"ok":false,"error":"invalid_auth"}`), nil
+		})
+		provider := slack.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetUserInfo(ctx, "xoxp-token")
+		assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+
+		var providerErr *oauth2.ProviderError
+		assert.ErrorAs(t, err, &providerErr)
+		assert.Equal(t, "invalid_auth", providerErr.Code)
+		assert.Equal(t, oauth2.OpGetUserInfo, providerErr.Op)
+	})
+
+	t.Run("empty access token", func(t *testing.T) {
+		provider := slack.NewProvider(oauth2.ProviderSetting{})
+		_, err := provider.GetUserInfo(ctx, "")
+		assert.ErrorIs(t, err, oauth2.ErrEmptyAccessToken)
+	})
+}
+
+func TestSlackProvider_GetToken(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("promotes the user token", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, slack.TokenURL, req.URL.String())
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "code", req.PostForm.Get("code"))
+			assert.Equal(t, "secret", req.PostForm.Get("client_secret"))
+			return jsonResponse(http.StatusOK, `{
+				"ok": true,
+				"app_id": "A0KRD7HC3",
+				"authed_user": {"id": "U0G9QF9C6", "scope": "identity.basic,identity.email",
+					"access_token": "xoxp-token", "token_type": "user"},
+				"team": {"id": "T0G9PQBBK"}
+			}`), nil
+		})
+		provider := slack.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "client-id",
+			ClientSecret: "secret",
+			RedirectURL:  "https://app.example.com/callback",
+		})
+
+		token, err := provider.GetToken(ctx, "code")
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxp-token", token.GetAccessToken())
+		assert.False(t, token.HasRefreshToken())
+		assert.Equal(t, []string{"identity.basic", "identity.email"}, oauth2.GrantedScopes(token))
+	})
+
+	t.Run("ok false on HTTP 200", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{"ok":false,"error":"invalid_code"}`), nil
+		})
+		provider := slack.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetToken(ctx, "code")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+
+		var providerErr *oauth2.ProviderError
+		assert.ErrorAs(t, err, &providerErr)
+		assert.Equal(t, "invalid_code", providerErr.Code)
+	})
+
+	t.Run("refresh with token rotation", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "refresh_token", req.PostForm.Get("grant_type"))
+			return jsonResponse(http.StatusOK, `{"ok":true,"access_token":"xoxe.xoxp-new",`+
+				`"refresh_token":"xoxe-1-new","expires_in":43200,"token_type":"user"}`), nil
+		})
+		provider := slack.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		token, err := provider.RefreshToken(ctx, "xoxe-1-old")
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxe.xoxp-new", token.GetAccessToken())
+		assert.Equal(t, "xoxe-1-new", token.GetRefreshToken())
+		assert.False(t, token.GetExpiresAt().IsZero())
+	})
+
+	t.Run("code exchange is not retried", func(t *testing.T) {
+		calls := 0
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, slack.TokenURL, req.URL.String())
+			calls++
+			return jsonResponse(http.StatusServiceUnavailable, `{}`), nil
+		})
+		provider := slack.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "id",
+			ClientSecret: "secret",
+			RedirectURL:  "https://app.example.com/callback",
+			MaxRetries:   3,
+			RetryBackoff: time.Millisecond,
+		})
+
+		_, err := provider.GetToken(context.Background(), "code")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+		assert.Equal(t, 1, calls, "the single use code must not be replayed")
+	})
+}
+
+func TestSlackProvider_GetAuthURL(t *testing.T) {
+	provider := slack.NewProvider(oauth2.ProviderSetting{
+		ClientID:    "client-id",
+		RedirectURL: "https://app.example.com/callback",
+	})
+
+	authURL, err := provider.GetAuthURL(context.Background(), "state", oauth2.WithScopes("users:read"))
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, slack.AuthURL, parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "identity.basic,identity.email,identity.avatar,identity.team,users:read",
+		parsed.Query().Get("user_scope"))
+	assert.Equal(t, "state", parsed.Query().Get("state"))
+}
+
+func TestSlackProvider_RevokeToken(t *testing.T) {
+	ctx := context.Background()
+
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, slack.RevokeURL, req.URL.String())
+		if req.Header.Get("Authorization") != "Bearer xoxp-token" {
+			return jsonResponse(http.StatusOK, `{"ok":false,"error":"token_revoked"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"ok":true,"revoked":true}`), nil
+	})
+	provider := slack.NewProvider(oauth2.ProviderSetting{Client: client})
+
+	assert.NoError(t, provider.RevokeToken(ctx, "xoxp-token"))
+	assert.ErrorIs(t, provider.RevokeToken(ctx, "xoxp-other"), oauth2.ErrTokenRevocationFailed)
+}
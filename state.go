@@ -0,0 +1,139 @@
+package oauth2
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stateCookieMaxAge bounds how long a login may take between redirect and callback
+const stateCookieMaxAge = 5 * time.Minute
+
+// StateManager generates and verifies states. With a secret, states are HMAC-SHA256 signed
+// so a stateless server can verify the state returned on the callback without a cookie or store
+type StateManager struct {
+	secret []byte
+}
+
+// GenerateState returns a random, URL-safe state value with 128 bits of entropy.
+// The issue time is embedded as "<random>.<unix seconds>" so expiry can be enforced by ValidateState
+func GenerateState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b) + "." + strconv.FormatInt(time.Now().Unix(), 10), nil
+}
+
+// StateIssuedAt returns the issue time embedded in a state produced by GenerateState
+func StateIssuedAt(state string) (time.Time, bool) {
+	idx := strings.LastIndexByte(state, '.')
+	if idx < 0 {
+		return time.Time{}, false
+	}
+
+	unix, err := strconv.ParseInt(state[idx+1:], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+// ValidateState checks the state returned on the callback against the expected one
+//   - a missing or different state fails with ErrStateMismatch
+//   - when ttl is positive, a state older than ttl (or without an issue time) fails with ErrStateExpired
+func ValidateState(expected string, got string, ttl time.Duration) error {
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(got)) != 1 {
+		return ErrStateMismatch
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	issuedAt, ok := StateIssuedAt(got)
+	if !ok || time.Since(issuedAt) > ttl {
+		return ErrStateExpired
+	}
+	return nil
+}
+
+// NewStateManager returns a StateManager signing states with secret, use at least 32 random bytes.
+// Without a secret states are plain GenerateState values, which must also be compared with the
+// copy kept for the user (see ValidateState) since anyone can forge them
+//
+//	example:
+//	states := oauth2.NewStateManager(secret)
+//	authURL := client.RequestAuthURL(ctx, google.ProviderType, states.Generate())
+//	...
+//	if err := states.Verify(r.URL.Query().Get("state"), 5*time.Minute); err != nil { ... }
+func NewStateManager(secret []byte) *StateManager {
+	return &StateManager{secret: bytes.Clone(secret)}
+}
+
+// Generate returns a new state embedding its issue time, as "<random>.<signature>.<unix seconds>"
+// when signed. StateIssuedAt reads the issue time of both forms
+func (m *StateManager) Generate() string {
+	state, err := GenerateState()
+	if err != nil {
+		// crypto/rand only fails when the OS entropy source is unavailable
+		panic(err)
+	}
+	if len(m.secret) == 0 {
+		return state
+	}
+
+	random, issuedAt, _ := strings.Cut(state, ".")
+	return random + "." + signPayload(m.secret, random+"."+issuedAt) + "." + issuedAt
+}
+
+// Verify checks a state produced by Generate
+//   - a malformed state, or a signed one that was tampered with, fails with ErrStateMismatch
+//   - when maxAge is positive, a state older than maxAge fails with ErrStateExpired
+func (m *StateManager) Verify(state string, maxAge time.Duration) error {
+	parts := strings.Split(state, ".")
+	if parts[0] == "" {
+		return ErrStateMismatch
+	}
+	if len(m.secret) == 0 {
+		if len(parts) != 2 {
+			return ErrStateMismatch
+		}
+	} else if len(parts) != 3 ||
+		!hmac.Equal([]byte(parts[1]), []byte(signPayload(m.secret, parts[0]+"."+parts[2]))) {
+		return ErrStateMismatch
+	}
+
+	issuedAt, ok := StateIssuedAt(state)
+	if !ok {
+		return ErrStateMismatch
+	}
+	if maxAge > 0 && time.Since(issuedAt) > maxAge {
+		return ErrStateExpired
+	}
+	return nil
+}
+
+// newStateCookie builds the cookie carrying state to the callback handler
+func newStateCookie(state string, redirectURL string) *http.Cookie {
+	return &http.Cookie{
+		Name:     StateCookieName,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(stateCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(redirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// crossSiteCookie lets cookie ride the cross-site form_post callback, browsers require Secure with SameSite=None
+func crossSiteCookie(cookie *http.Cookie) {
+	cookie.SameSite = http.SameSiteNoneMode
+	cookie.Secure = true
+}
@@ -0,0 +1,147 @@
+package oauth2_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateState(t *testing.T) {
+	state, err := oauth2.GenerateState()
+	assert.NoError(t, err)
+
+	issuedAt, ok := oauth2.StateIssuedAt(state)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now(), issuedAt, 2*time.Second)
+}
+
+func TestValidateState(t *testing.T) {
+	fresh, err := oauth2.GenerateState()
+	assert.NoError(t, err)
+	expired := fmt.Sprintf("random.%d", time.Now().Add(-time.Hour).Unix())
+
+	tests := []struct {
+		name     string
+		expected string
+		got      string
+		ttl      time.Duration
+		wantErr  error
+	}{
+		{name: "fresh", expected: fresh, got: fresh, ttl: 5 * time.Minute},
+		{name: "expired", expected: expired, got: expired, ttl: 5 * time.Minute, wantErr: oauth2.ErrStateExpired},
+		{name: "expired without ttl", expected: expired, got: expired},
+		{name: "no issue time", expected: "random", got: "random", ttl: time.Minute, wantErr: oauth2.ErrStateExpired},
+		{name: "mismatch", expected: fresh, got: expired, ttl: time.Minute, wantErr: oauth2.ErrStateMismatch},
+		{name: "missing cookie", got: fresh, wantErr: oauth2.ErrStateMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := oauth2.ValidateState(tt.expected, tt.got, tt.ttl)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// signedState builds a signed state issued at issuedAt, as StateManager.Generate would
+func signedState(secret []byte, random string, issuedAt time.Time) string {
+	unix := fmt.Sprint(issuedAt.Unix())
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(random + "." + unix))
+	return random + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) + "." + unix
+}
+
+func TestStateManager(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	states := oauth2.NewStateManager(secret)
+
+	t.Run("round trip", func(t *testing.T) {
+		state := states.Generate()
+		assert.Len(t, strings.Split(state, "."), 3)
+		assert.NotEqual(t, state, states.Generate())
+		assert.NoError(t, states.Verify(state, time.Minute))
+
+		issuedAt, ok := oauth2.StateIssuedAt(state)
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now(), issuedAt, 2*time.Second)
+	})
+
+	t.Run("tampering is detected", func(t *testing.T) {
+		parts := strings.Split(states.Generate(), ".")
+		tampered := map[string]string{
+			"random":    "other." + parts[1] + "." + parts[2],
+			"signature": parts[0] + ".c2lnbmF0dXJl." + parts[2],
+			"timestamp": parts[0] + "." + parts[1] + "." + fmt.Sprint(time.Now().Add(time.Hour).Unix()),
+			"unsigned":  parts[0] + "." + parts[2],
+			"empty":     "",
+		}
+		for name, state := range tampered {
+			assert.ErrorIs(t, states.Verify(state, time.Minute), oauth2.ErrStateMismatch, name)
+		}
+
+		other := oauth2.NewStateManager([]byte("another secret of at least 32 bytes"))
+		assert.ErrorIs(t, other.Verify(states.Generate(), time.Minute), oauth2.ErrStateMismatch)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		expired := signedState(secret, "random", time.Now().Add(-time.Hour))
+		assert.ErrorIs(t, states.Verify(expired, 5*time.Minute), oauth2.ErrStateExpired)
+		assert.NoError(t, states.Verify(expired, 0), "a non-positive maxAge disables expiry")
+	})
+
+	t.Run("unsigned without a secret", func(t *testing.T) {
+		unsigned := oauth2.NewStateManager(nil)
+		state := unsigned.Generate()
+		assert.Len(t, strings.Split(state, "."), 2)
+		assert.NoError(t, unsigned.Verify(state, time.Minute))
+
+		expired := fmt.Sprintf("random.%d", time.Now().Add(-time.Hour).Unix())
+		assert.ErrorIs(t, unsigned.Verify(expired, time.Minute), oauth2.ErrStateExpired)
+		assert.ErrorIs(t, unsigned.Verify("random", time.Minute), oauth2.ErrStateMismatch)
+	})
+}
+
+func TestCallbackHandler_StateTTL(t *testing.T) {
+	client := oauth2.NewClient(&mockProvider{
+		typ:            "google",
+		returnToken:    dummyToken{},
+		returnUserInfo: dummyUser{},
+	})
+	handler := oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+		SuccessRedirect: "/dashboard",
+		StateTTL:        5 * time.Minute,
+	})
+
+	fresh, err := oauth2.GenerateState()
+	assert.NoError(t, err)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newCallbackRequest("/callback?provider=google&code=abc&state="+fresh, fresh))
+	assert.Equal(t, http.StatusFound, rec.Code)
+
+	expired := fmt.Sprintf("random.%d", time.Now().Add(-time.Hour).Unix())
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newCallbackRequest("/callback?provider=google&code=abc&state="+expired, expired))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	var got error
+	handler = oauth2.NewCallbackHandler(client, oauth2.CallbackConfig{
+		SuccessRedirect: "/dashboard",
+		StateTTL:        5 * time.Minute,
+		OnError:         func(w http.ResponseWriter, r *http.Request, err error) { got = err },
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), newCallbackRequest("/callback?provider=google&code=abc&state="+expired, expired))
+	assert.ErrorIs(t, got, oauth2.ErrStateExpired)
+}
@@ -0,0 +1,44 @@
+package oauth2
+
+import (
+	"strings"
+	"time"
+)
+
+// ValidateTokenType checks the token_type returned with an access token
+//   - lenient mode (strict=false) accepts anything, since some providers send odd values
+//   - strict mode accepts "Bearer" case-insensitively and a missing token_type, which implies Bearer
+func ValidateTokenType(tokenType string, strict bool) error {
+	if !strict || tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		return nil
+	}
+	return ErrUnsupportedTokenType
+}
+
+// CanRefresh reports whether token still holds a usable refresh token.
+// The refresh token expiry is only checked when the token implements RefreshExpiringToken
+func CanRefresh(token TokenInfo) bool {
+	if token == nil || !token.HasRefreshToken() {
+		return false
+	}
+
+	expiring, ok := token.(RefreshExpiringToken)
+	if !ok {
+		return true
+	}
+	return !Expired(expiring.GetRefreshTokenExpiresAt())
+}
+
+// ExpiryTime converts a relative expires_in received at issuedAt into an absolute time.
+// A non-positive expiresIn means the token does not expire and yields the zero time
+func ExpiryTime(issuedAt time.Time, expiresIn int) time.Time {
+	if expiresIn <= 0 {
+		return time.Time{}
+	}
+	return issuedAt.Add(time.Duration(expiresIn) * time.Second)
+}
+
+// Expired reports whether expiresAt has passed, the zero time never expires
+func Expired(expiresAt time.Time) bool {
+	return !expiresAt.IsZero() && !time.Now().Before(expiresAt)
+}
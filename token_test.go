@@ -0,0 +1,83 @@
+package oauth2_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTokenType(t *testing.T) {
+	tests := []struct {
+		tokenType string
+		strict    bool
+		wantErr   bool
+	}{
+		{tokenType: "Bearer", strict: true},
+		{tokenType: "bearer", strict: true},
+		{tokenType: "", strict: true},
+		{tokenType: "mac", strict: true, wantErr: true},
+		{tokenType: "DPoP", strict: true, wantErr: true},
+		{tokenType: "mac", strict: false},
+	}
+
+	for _, tt := range tests {
+		err := oauth2.ValidateTokenType(tt.tokenType, tt.strict)
+		if tt.wantErr {
+			assert.ErrorIs(t, err, oauth2.ErrUnsupportedTokenType, tt.tokenType)
+		} else {
+			assert.NoError(t, err, tt.tokenType)
+		}
+	}
+}
+
+type refreshToken struct {
+	dummyToken
+	refreshToken string
+	expiresAt    time.Time
+}
+
+func (r refreshToken) GetRefreshToken() string             { return r.refreshToken }
+func (r refreshToken) HasRefreshToken() bool               { return r.refreshToken != "" }
+func (r refreshToken) GetRefreshTokenExpiresAt() time.Time { return r.expiresAt }
+
+func TestCanRefresh(t *testing.T) {
+	tests := []struct {
+		name  string
+		token oauth2.TokenInfo
+		want  bool
+	}{
+		{name: "nil token"},
+		{name: "no refresh token", token: refreshToken{}},
+		{name: "unknown expiry", token: refreshToken{refreshToken: "r"}, want: true},
+		{name: "without expiry support", token: dummyToken{}, want: true},
+		{
+			name:  "valid refresh token",
+			token: refreshToken{refreshToken: "r", expiresAt: time.Now().Add(time.Hour)},
+			want:  true,
+		},
+		{
+			name:  "expired refresh token",
+			token: refreshToken{refreshToken: "r", expiresAt: time.Now().Add(-time.Hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, oauth2.CanRefresh(tt.token), tt.name)
+	}
+}
+
+func TestExpiryTime(t *testing.T) {
+	issuedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, issuedAt.Add(time.Hour), oauth2.ExpiryTime(issuedAt, 3600))
+	assert.True(t, oauth2.ExpiryTime(issuedAt, 0).IsZero(), "missing expires_in")
+	assert.True(t, oauth2.ExpiryTime(issuedAt, -1).IsZero())
+}
+
+func TestExpired(t *testing.T) {
+	assert.True(t, oauth2.Expired(time.Now().Add(-time.Second)))
+	assert.False(t, oauth2.Expired(time.Now().Add(time.Hour)))
+	assert.False(t, oauth2.Expired(time.Time{}), "no expiry never expires")
+}
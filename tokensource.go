@@ -0,0 +1,104 @@
+package oauth2
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultExpirySkew is how long before expiry a TokenSource refreshes the access token
+const DefaultExpirySkew = 10 * time.Second
+
+type (
+	// TokenSource returns a valid access token, refreshing it when it is about to expire
+	TokenSource interface {
+		Token() (TokenInfo, error)
+	}
+
+	// TokenSourceOption customizes NewTokenSource
+	TokenSourceOption func(*tokenSource)
+
+	// tokenSource caches a token and refreshes it through the client, safe for concurrent use
+	tokenSource struct {
+		ctx      context.Context
+		client   Client
+		provider ProviderType
+		skew     time.Duration
+		now      func() time.Time
+
+		mu    sync.Mutex
+		token TokenInfo
+	}
+)
+
+// WithExpirySkew refreshes the token skew before it expires instead of DefaultExpirySkew
+func WithExpirySkew(skew time.Duration) TokenSourceOption {
+	return func(s *tokenSource) {
+		s.skew = skew
+	}
+}
+
+// WithTokenSourceClock replaces time.Now when checking the token expiry, e.g. for tests
+func WithTokenSourceClock(now func() time.Time) TokenSourceOption {
+	return func(s *tokenSource) {
+		s.now = now
+	}
+}
+
+// NewTokenSource returns a TokenSource serving token until it is within the expiry skew of
+// GetExpiresAt, then refreshing it with client.RequestRefreshToken under ctx and caching the result.
+// A token without expiry is served as is, an expiring token without a usable refresh token
+// fails with ErrTokenExpired
+//
+//	example:
+//	source := oauth2.NewTokenSource(ctx, client, google.ProviderType, token)
+//	token, err := source.Token()
+//	if err != nil { ... }
+//	req.Header.Set("Authorization", "Bearer "+token.GetAccessToken())
+func NewTokenSource(
+	ctx context.Context,
+	client Client,
+	provider ProviderType,
+	token TokenInfo,
+	opts ...TokenSourceOption,
+) TokenSource {
+	source := &tokenSource{
+		ctx:      ctx,
+		client:   client,
+		provider: provider,
+		skew:     DefaultExpirySkew,
+		now:      time.Now,
+		token:    token,
+	}
+	for _, opt := range opts {
+		opt(source)
+	}
+	return source
+}
+
+// Token returns the cached token, refreshing it first when it is about to expire.
+// A failed refresh keeps the cached token for the next call
+func (s *tokenSource) Token() (TokenInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && !s.expiring(s.token) {
+		return s.token, nil
+	}
+	if !CanRefresh(s.token) {
+		return nil, ErrTokenExpired
+	}
+
+	token, err := s.client.RequestRefreshToken(s.ctx, s.provider, s.token.GetRefreshToken())
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+// expiring reports whether token expires within the skew, a token without expiry never does
+func (s *tokenSource) expiring(token TokenInfo) bool {
+	expiresAt := token.GetExpiresAt()
+	return !expiresAt.IsZero() && !s.now().Add(s.skew).Before(expiresAt)
+}
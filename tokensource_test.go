@@ -0,0 +1,164 @@
+package oauth2_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+type expiringToken struct {
+	dummyToken
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time
+}
+
+func (e expiringToken) GetAccessToken() string  { return e.accessToken }
+func (e expiringToken) GetRefreshToken() string { return e.refreshToken }
+func (e expiringToken) HasRefreshToken() bool   { return e.refreshToken != "" }
+func (e expiringToken) GetExpiresAt() time.Time { return e.expiresAt }
+
+// refreshingProvider issues a token valid for an hour from now() on every refresh
+type refreshingProvider struct {
+	mockProvider
+	now       func() time.Time
+	refreshes atomic.Int32
+	gotToken  atomic.Value
+	err       error
+}
+
+func (r *refreshingProvider) RefreshToken(ctx context.Context, refreshToken string) (oauth2.TokenInfo, error) {
+	r.refreshes.Add(1)
+	r.gotToken.Store(refreshToken)
+	if r.err != nil {
+		return nil, r.err
+	}
+	time.Sleep(10 * time.Millisecond)
+	return expiringToken{
+		accessToken:  "refreshed-access-token",
+		refreshToken: "rotated-refresh-token",
+		expiresAt:    r.now().Add(time.Hour),
+	}, nil
+}
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestTokenSource(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newSource := func(clock *fakeClock, provider *refreshingProvider, token oauth2.TokenInfo) oauth2.TokenSource {
+		provider.typ = "google"
+		provider.now = clock.Now
+		client := oauth2.NewClient(provider)
+		return oauth2.NewTokenSource(ctx, client, "google", token,
+			oauth2.WithExpirySkew(time.Minute),
+			oauth2.WithTokenSourceClock(clock.Now),
+		)
+	}
+	initial := expiringToken{
+		accessToken:  "access-token",
+		refreshToken: "refresh-token",
+		expiresAt:    start.Add(time.Hour),
+	}
+
+	t.Run("serves the cached token before expiry", func(t *testing.T) {
+		clock := &fakeClock{now: start}
+		provider := &refreshingProvider{}
+		source := newSource(clock, provider, initial)
+
+		clock.Advance(58 * time.Minute)
+		token, err := source.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token", token.GetAccessToken())
+		assert.Zero(t, provider.refreshes.Load())
+	})
+
+	t.Run("refreshes once within the skew under concurrent calls", func(t *testing.T) {
+		clock := &fakeClock{now: start}
+		provider := &refreshingProvider{}
+		source := newSource(clock, provider, initial)
+
+		clock.Advance(59*time.Minute + time.Second)
+
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				token, err := source.Token()
+				assert.NoError(t, err)
+				assert.Equal(t, "refreshed-access-token", token.GetAccessToken())
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), provider.refreshes.Load())
+		assert.Equal(t, "refresh-token", provider.gotToken.Load())
+
+		clock.Advance(time.Hour)
+		_, err := source.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), provider.refreshes.Load())
+		assert.Equal(t, "rotated-refresh-token", provider.gotToken.Load())
+	})
+
+	t.Run("a failed refresh is retried on the next call", func(t *testing.T) {
+		clock := &fakeClock{now: start.Add(2 * time.Hour)}
+		provider := &refreshingProvider{err: oauth2.ErrTokenRequestFailed}
+		source := newSource(clock, provider, initial)
+
+		_, err := source.Token()
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+
+		provider.err = nil
+		token, err := source.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, "refreshed-access-token", token.GetAccessToken())
+		assert.Equal(t, int32(2), provider.refreshes.Load())
+	})
+
+	t.Run("expired token without refresh token", func(t *testing.T) {
+		clock := &fakeClock{now: start.Add(2 * time.Hour)}
+		provider := &refreshingProvider{}
+		token := initial
+		token.refreshToken = ""
+		source := newSource(clock, provider, token)
+
+		_, err := source.Token()
+		assert.ErrorIs(t, err, oauth2.ErrTokenExpired)
+		assert.Zero(t, provider.refreshes.Load())
+	})
+
+	t.Run("token without expiry is never refreshed", func(t *testing.T) {
+		clock := &fakeClock{now: start.Add(100 * time.Hour)}
+		provider := &refreshingProvider{}
+		source := newSource(clock, provider, dummyToken{})
+
+		token, err := source.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token", token.GetAccessToken())
+		assert.Zero(t, provider.refreshes.Load())
+	})
+}
@@ -0,0 +1,61 @@
+package oauth2
+
+import (
+	"net/http"
+)
+
+// headerTransport sets header on every request that does not set it already
+type headerTransport struct {
+	base   http.RoundTripper
+	header http.Header
+}
+
+// WithUserAgent returns a copy of client sending ua as the User-Agent of every request,
+// e.g. for provider APIs rejecting anonymous clients such as GitHub's.
+// A nil client is replaced by one with DefaultClientTimeout, client itself is left unchanged
+//
+//	example:
+//	setting := oauth2.ProviderSetting{
+//	    Client: oauth2.WithUserAgent(http.DefaultClient, "my-app/1.0"),
+//	}
+func WithUserAgent(client *http.Client, ua string) *http.Client {
+	return WithHeader(client, "User-Agent", ua)
+}
+
+// WithHeader returns a copy of client adding the key header to every request, unless the
+// provider sets that header itself (e.g. Authorization). Decorators can be stacked.
+// A nil client is replaced by one with DefaultClientTimeout, client itself is left unchanged
+//
+//	example:
+//	client := oauth2.WithHeader(http.DefaultClient, "X-Request-Source", "login")
+//	client = oauth2.WithUserAgent(client, "my-app/1.0")
+func WithHeader(client *http.Client, key string, value string) *http.Client {
+	decorated := http.Client{Timeout: DefaultClientTimeout}
+	if client != nil {
+		decorated = *client
+	}
+
+	header := http.Header{}
+	header.Set(key, value)
+	decorated.Transport = &headerTransport{
+		base:   decorated.Transport,
+		header: header,
+	}
+	return &decorated
+}
+
+// RoundTrip sends a clone of req carrying the missing headers, req itself is not modified
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	clone := req.Clone(req.Context())
+	for key, values := range t.header {
+		if clone.Header.Get(key) == "" {
+			clone.Header[key] = values
+		}
+	}
+	return base.RoundTrip(clone)
+}
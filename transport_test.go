@@ -0,0 +1,61 @@
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHeader(t *testing.T) {
+	var got http.Header
+	base := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Clone()
+		body := `{"id":1,"login":"octocat","email":"octocat@example.com"}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+	})}
+
+	t.Run("headers reach the transport through a provider", func(t *testing.T) {
+		client := oauth2.WithUserAgent(oauth2.WithHeader(base, "X-Request-Source", "login"), "my-app/1.0")
+		provider := github.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetUserInfo(context.Background(), "access")
+		assert.NoError(t, err)
+		assert.Equal(t, "my-app/1.0", got.Get("User-Agent"))
+		assert.Equal(t, "login", got.Get("X-Request-Source"))
+		assert.Equal(t, "Bearer access", got.Get("Authorization"))
+	})
+
+	t.Run("provider headers win", func(t *testing.T) {
+		client := oauth2.WithHeader(base, "Authorization", "Bearer other")
+		provider := github.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetUserInfo(context.Background(), "access")
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer access", got.Get("Authorization"))
+	})
+
+	t.Run("the original request and client are untouched", func(t *testing.T) {
+		client := oauth2.WithUserAgent(base, "my-app/1.0")
+		req, err := http.NewRequest(http.MethodGet, "https://example.com", nil)
+		assert.NoError(t, err)
+
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Empty(t, req.Header.Get("User-Agent"))
+		assert.Equal(t, "my-app/1.0", got.Get("User-Agent"))
+		assert.IsType(t, roundTripperFunc(nil), base.Transport)
+	})
+
+	t.Run("nil client", func(t *testing.T) {
+		client := oauth2.WithUserAgent(nil, "my-app/1.0")
+		assert.Equal(t, oauth2.DefaultClientTimeout, client.Timeout)
+		assert.NotNil(t, client.Transport)
+	})
+}
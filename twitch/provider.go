@@ -0,0 +1,453 @@
+package twitch
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// ProviderType is the identifier for the Twitch OAuth2 provider
+	//   - REFS : https://dev.twitch.tv/docs/authentication/getting-tokens-oauth
+	ProviderType oauth2.ProviderType = "twitch"
+
+	// AuthURL is the endpoint to start the authorization code flow
+	AuthURL = "https://id.twitch.tv/oauth2/authorize"
+
+	// TokenURL is the endpoint to exchange the authorization code for an access token
+	TokenURL = "https://id.twitch.tv/oauth2/token"
+
+	// RevokeURL is the endpoint to revoke an access token
+	RevokeURL = "https://id.twitch.tv/oauth2/revoke"
+
+	// KeysURL is the JWKS endpoint serving the id_token signing keys
+	KeysURL = "https://id.twitch.tv/oauth2/keys"
+
+	// UserInfoURL is the Helix endpoint returning the user of the access token
+	UserInfoURL = "https://api.twitch.tv/helix/users"
+)
+
+// DefaultScopes are requested when ProviderSetting.Scopes is empty, WithScopes adds to them.
+// Replace it during initialization, before any provider is used
+var DefaultScopes = []string{"user:read:email"}
+
+type (
+	// provider holds the configuration for Twitch's OAuth2 implementation
+	provider struct {
+		requester    *oauth2.Requester
+		clientID     string
+		clientSecret string
+		redirectURL  string
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
+	}
+
+	// usersResponse is the Helix users response, listing the user of the access token in data
+	usersResponse struct {
+		Data []userInfo `json:"data"`
+	}
+
+	// userInfo represents an entry of the Helix users response
+	userInfo struct {
+		ID              string `json:"id"`
+		Login           string `json:"login"`
+		DisplayName     string `json:"display_name"`
+		Email           string `json:"email"`
+		ProfileImageURL string `json:"profile_image_url"`
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
+	}
+
+	// tokenInfo represents the token information returned from Twitch, whose scope is a JSON array
+	tokenInfo struct {
+		AccessToken  string   `json:"access_token"`
+		RefreshToken string   `json:"refresh_token"`
+		ExpiresIn    int      `json:"expires_in"`
+		Scope        []string `json:"scope"`
+		TokenType    string   `json:"token_type"`
+		IDToken      string   `json:"id_token"`
+
+		issuedAt time.Time
+	}
+)
+
+// provider must keep implementing oauth2.Provider
+var _ oauth2.Provider = (*provider)(nil)
+
+func init() {
+	oauth2.RegisterConstructor(ProviderType, NewProvider)
+}
+
+// NewProvider initializes and returns a new Twitch OAuth2 provider
+func NewProvider(setting oauth2.ProviderSetting) oauth2.Provider {
+	return &provider{
+		requester:    oauth2.NewRequester(setting),
+		clientID:     setting.ClientID,
+		clientSecret: setting.ClientSecret,
+		redirectURL:  setting.RedirectURL,
+
+		strictTokenType:  setting.StrictTokenType,
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodPost),
+
+		authURL:  cmp.Or(setting.AuthURL, AuthURL),
+		tokenURL: cmp.Or(setting.TokenURL, TokenURL),
+
+		userInfoURL:          cmp.Or(setting.UserInfoURL, UserInfoURL),
+		userInfoFallbackURLs: setting.UserInfoFallbackURLs,
+	}
+}
+
+// NewProviderWithError is NewProvider failing with ErrClientIDNotSet, ErrClientSecretNotSet
+// or ErrInvalidRedirectURL instead of building a provider whose calls cannot succeed
+func NewProviderWithError(setting oauth2.ProviderSetting) (oauth2.Provider, error) {
+	if err := setting.ValidateCredentials(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+	return NewProvider(setting), nil
+}
+
+// GetUserInfo retrieves the Twitch user from Helix, which requires the Client-Id header
+// next to the access token. An empty data array fails with ErrUserInfoRequestFailed
+func (t *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
+	if accessToken == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAccessToken, "")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.userInfoURL, nil)
+	if err != nil {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrUserInfoRequestFailed,
+			err.Error(),
+		)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Client-Id", t.clientID)
+	oauth2.SetAcceptLanguage(req)
+
+	end := t.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+	resp, err := t.requester.DoWithFallback(req, t.userInfoFallbackURLs)
+	end(resp, err)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpGetUserInfo,
+			oauth2.ErrUserInfoRequestFailed,
+			resp,
+		)
+	}
+
+	var users usersResponse
+	if err := oauth2.DecodeJSONResponse(resp, &users); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+	if len(users.Data) == 0 {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrUserInfoRequestFailed,
+			"no user in data",
+		)
+	}
+
+	userInfo := users.Data[0]
+	if raw := oauth2.DecodeRawUserInfo(resp.Body); raw != nil {
+		if data, ok := raw["data"].([]any); ok && len(data) > 0 {
+			userInfo.raw, _ = data[0].(map[string]any)
+		}
+	}
+	userInfo.nameStrategy = t.nameStrategy
+
+	return &userInfo, nil
+}
+
+// GetAuthURL constructs the Twitch authorization URL
+//   - ProviderSetting.Scopes replaces the user:read:email default, WithScopes adds to them
+//   - WithPrompt forwards login and consent as force_verify=true
+//   - WithNonce is sent for id_tokens requested with the openid scope
+//   - WithPKCE and WithOfflineAccess are ignored, Twitch always issues a refresh token
+func (t *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
+	if t.redirectURL == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
+	}
+	if t.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+
+	options := oauth2.NewAuthOptions(opts...)
+	if err := oauth2.ValidatePrompts(options.Prompts); err != nil {
+		return "", oauth2.WrapProviderError(ProviderType, err, strings.Join(options.Prompts, " "))
+	}
+
+	scopes := t.scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	query := url.Values{}
+	query.Set("client_id", t.clientID)
+	query.Set("redirect_uri", t.redirectURL)
+	query.Set("response_type", "code")
+	query.Set("scope", strings.Join(oauth2.NormalizeScopes(scopes, options.Scopes), " "))
+	query.Set("state", state)
+	if prompts := oauth2.SupportedPrompts(
+		options.Prompts,
+		oauth2.PromptLogin,
+		oauth2.PromptConsent,
+	); len(prompts) > 0 {
+		query.Set("force_verify", "true")
+	}
+	oauth2.SetNonce(query, options.Nonce)
+	oauth2.SetAuthParams(query, options.AuthParams)
+
+	return oauth2.BuildAuthURL(ProviderType, t.authURL, query, t.authURLLimits)
+}
+
+// GetToken exchanges the authorization code for an access token from Twitch
+func (t *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	if code == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
+	}
+	ctx = oauth2.WithoutRetry(ctx)
+
+	form := url.Values{}
+	form.Set("code", code)
+	form.Set("client_id", t.clientID)
+	form.Set("client_secret", t.clientSecret)
+	form.Set("redirect_uri", t.redirectURL)
+	form.Set("grant_type", "authorization_code")
+
+	return t.requestToken(ctx, oauth2.OpGetToken, form)
+}
+
+// RefreshToken exchanges a refresh token for a new access token from Twitch
+func (t *provider) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (oauth2.TokenInfo, error) {
+	if refreshToken == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyRefreshToken, "")
+	}
+
+	form := url.Values{}
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", t.clientID)
+	form.Set("client_secret", t.clientSecret)
+	form.Set("grant_type", "refresh_token")
+
+	return t.requestToken(ctx, oauth2.OpRefreshToken, form)
+}
+
+// GetClientCredentialsToken requests an app access token, e.g. for Helix calls without a user.
+// Twitch ignores scopes for app access tokens
+func (t *provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	form := url.Values{}
+	form.Set("client_id", t.clientID)
+	form.Set("client_secret", t.clientSecret)
+	form.Set("grant_type", "client_credentials")
+
+	return t.requestToken(ctx, oauth2.OpClientCredentials, form)
+}
+
+// requestToken posts form to the token endpoint and decodes the token response
+func (t *provider) requestToken(ctx context.Context, op string, form url.Values) (oauth2.TokenInfo, error) {
+	var tokenInfo tokenInfo
+
+	req, err := oauth2.NewFormRequest(ctx, http.MethodPost, t.tokenURL, form)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRequestFailed,
+			err.Error(),
+		)
+	}
+
+	end := t.requester.Observe(ctx, ProviderType, op)
+	resp, err := t.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
+			ProviderType,
+			op,
+			oauth2.ErrTokenRequestFailed,
+			resp,
+		)
+	}
+
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	// a refresh keeps the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = form.Get("refresh_token")
+	}
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, t.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
+	return tokenInfo, nil
+}
+
+// RevokeToken revokes an access token, identified together with the client ID
+func (t *provider) RevokeToken(ctx context.Context, token string) error {
+	if token == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRevocationFailed, "token is empty")
+	}
+
+	form := url.Values{}
+	form.Set("client_id", t.clientID)
+	form.Set("token", token)
+
+	req, err := oauth2.NewFormRequest(ctx, t.revocationMethod, RevokeURL, form)
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	resp, err := t.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRevocationFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpRevokeToken,
+			oauth2.ErrTokenRevocationFailed,
+			resp,
+		)
+	}
+
+	return nil
+}
+
+// CanRefresh reports whether token still holds a refresh token
+func (t provider) CanRefresh(token oauth2.TokenInfo) bool { return oauth2.CanRefresh(token) }
+
+// SigningKeys fetches the id_token signing keys served at KeysURL
+func (t *provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	keys, err := t.requester.FetchJWKS(ctx, KeysURL)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrSigningKeysFailed, err)
+	}
+	return keys, nil
+}
+
+// Unlink is not supported, users disconnect applications from their Twitch settings
+func (t provider) Unlink(ctx context.Context, accessToken string) error {
+	return oauth2.WrapProviderError(ProviderType, oauth2.ErrUnsupportedOperation, "unlink")
+}
+
+// GetProvider returns the provider type ("twitch")
+func (t provider) GetProvider() oauth2.ProviderType { return ProviderType }
+
+// GetRedirectURL returns the configured redirect URL
+func (t provider) GetRedirectURL() string { return t.redirectURL }
+
+// GetID returns the user's Twitch ID
+func (t userInfo) GetID() string { return t.ID }
+
+// GetEmail returns the user's email address, empty without the user:read:email scope
+func (t userInfo) GetEmail() string { return t.Email }
+
+// GetName returns the user's display name, login or email depending on the NameStrategy.
+// The login is treated as the nickname
+func (t userInfo) GetName() string {
+	return oauth2.SelectName(t.nameStrategy, t.DisplayName, t.Login, t.Email)
+}
+
+// GetGender returns an empty string since Twitch has no gender field
+func (t userInfo) GetGender() string { return "" }
+
+// GetGenderNormalized returns oauth2.GenderUnknown since there is no gender
+func (t userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.GenderUnknown }
+
+// GetProfileImage returns the user's profile image URL
+func (t userInfo) GetProfileImage() string { return t.ProfileImageURL }
+
+// GetPhoneNumber returns an empty string since Twitch does not share phone numbers
+func (t userInfo) GetPhoneNumber() string { return "" }
+
+// GetBirthday returns an empty string since Twitch does not share birthdays
+func (t userInfo) GetBirthday() string { return "" }
+
+// GetLocale returns an empty string since Twitch does not share a locale
+func (t userInfo) GetLocale() string { return "" }
+
+// IsEmailVerified reports false since Helix does not say, use the email_verified
+// id_token claim of the openid scope instead
+func (t userInfo) IsEmailVerified() bool { return false }
+
+// GetRaw returns the decoded user entry of the Helix response (e.g. "broadcaster_type")
+func (t userInfo) GetRaw() map[string]any { return t.raw }
+
+// GetAccessToken returns the OAuth2 access token
+func (t tokenInfo) GetAccessToken() string { return t.AccessToken }
+
+// GetRefreshToken returns the OAuth2 refresh token
+func (t tokenInfo) GetRefreshToken() string { return t.RefreshToken }
+
+// GetExpiry returns the token expiration time in seconds
+func (t tokenInfo) GetExpiry() int { return t.ExpiresIn }
+
+// HasRefreshToken reports whether a refresh token was issued, never for app access tokens
+func (t tokenInfo) HasRefreshToken() bool { return t.RefreshToken != "" }
+
+// HasExpiry reports whether the access token expires, false when expires_in was not returned
+func (t tokenInfo) HasExpiry() bool { return t.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (t tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(t.issuedAt, t.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (t tokenInfo) IsExpired() bool { return oauth2.Expired(t.GetExpiresAt()) }
+
+// GetScope returns the granted scopes joined with spaces
+func (t tokenInfo) GetScope() string { return strings.Join(t.Scope, " ") }
+
+// GetTokenType returns the token type (e.g. "bearer")
+func (t tokenInfo) GetTokenType() string { return t.TokenType }
+
+// GetIDToken returns the OpenID Connect id_token, issued with the openid scope
+func (t tokenInfo) GetIDToken() string { return t.IDToken }
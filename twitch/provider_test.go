@@ -0,0 +1,187 @@
+package twitch_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/twitch"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newMockClient(fn roundTripperFunc) *http.Client {
+	return &http.Client{Transport: fn}
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestTwitchProvider_GetUserInfo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sends the Client-Id header and unwraps data", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, twitch.UserInfoURL, req.URL.String())
+			assert.Equal(t, "Bearer access", req.Header.Get("Authorization"))
+			assert.Equal(t, "client-id", req.Header.Get("Client-Id"))
+			return jsonResponse(http.StatusOK, `{"data":[{"id":"141981764","login":"twitchdev",`+
+				`"display_name":"TwitchDev","email":"dev@example.com",`+
+				`"profile_image_url":"https://img","broadcaster_type":"partner"}]}`), nil
+		})
+		provider := twitch.NewProvider(oauth2.ProviderSetting{Client: client, ClientID: "client-id"})
+
+		user, err := provider.GetUserInfo(ctx, "access")
+		assert.NoError(t, err)
+		assert.Equal(t, "141981764", user.GetID())
+		assert.Equal(t, "TwitchDev", user.GetName())
+		assert.Equal(t, "dev@example.com", user.GetEmail())
+		assert.Equal(t, "https://img", user.GetProfileImage())
+		assert.Equal(t, oauth2.GenderUnknown, user.GetGenderNormalized())
+		assert.Equal(t, "partner", user.GetRaw()["broadcaster_type"])
+	})
+
+	t.Run("nickname strategy uses the login", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{"data":[{"id":"1","login":"twitchdev","display_name":"TwitchDev"}]}`), nil
+		})
+		provider := twitch.NewProvider(oauth2.ProviderSetting{Client: client, NameStrategy: oauth2.PreferNickname})
+
+		user, err := provider.GetUserInfo(ctx, "access")
+		assert.NoError(t, err)
+		assert.Equal(t, "twitchdev", user.GetName())
+	})
+
+	t.Run("empty data", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{"data":[]}`), nil
+		})
+		provider := twitch.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		user, err := provider.GetUserInfo(ctx, "access")
+		assert.Nil(t, user)
+		assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+	})
+
+	t.Run("missing Client-Id", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusUnauthorized,
+				`{"error":"Unauthorized","status":401,"message":"Client ID and OAuth token do not match"}`), nil
+		})
+		provider := twitch.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetUserInfo(ctx, "access")
+		assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+
+		var providerErr *oauth2.ProviderError
+		assert.ErrorAs(t, err, &providerErr)
+		assert.Equal(t, http.StatusUnauthorized, providerErr.StatusCode)
+	})
+
+	t.Run("empty access token", func(t *testing.T) {
+		provider := twitch.NewProvider(oauth2.ProviderSetting{})
+
+		_, err := provider.GetUserInfo(ctx, "")
+		assert.ErrorIs(t, err, oauth2.ErrEmptyAccessToken)
+	})
+}
+
+func TestTwitchProvider_GetToken(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("scope array", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, twitch.TokenURL, req.URL.String())
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "authorization_code", req.PostForm.Get("grant_type"))
+			assert.Equal(t, "secret", req.PostForm.Get("client_secret"))
+			return jsonResponse(http.StatusOK, `{"access_token":"access","refresh_token":"refresh",`+
+				`"expires_in":14400,"scope":["user:read:email","openid"],"token_type":"bearer"}`), nil
+		})
+		provider := twitch.NewProvider(oauth2.ProviderSetting{Client: client, ClientSecret: "secret"})
+
+		token, err := provider.GetToken(ctx, "code")
+		assert.NoError(t, err)
+		assert.Equal(t, "access", token.GetAccessToken())
+		assert.Equal(t, "refresh", token.GetRefreshToken())
+		assert.Equal(t, 14400, token.GetExpiry())
+		assert.True(t, provider.CanRefresh(token))
+
+		assert.Equal(t, "user:read:email openid", token.GetScope())
+		assert.Equal(t, "bearer", token.GetTokenType())
+	})
+
+	t.Run("app access token", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "client_credentials", req.PostForm.Get("grant_type"))
+			return jsonResponse(http.StatusOK, `{"access_token":"app","expires_in":5011271,"token_type":"bearer"}`), nil
+		})
+		provider := twitch.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		token, err := provider.GetClientCredentialsToken(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "app", token.GetAccessToken())
+		assert.False(t, token.HasRefreshToken())
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusBadRequest, `{"status":400,"message":"Invalid authorization code"}`), nil
+		})
+		provider := twitch.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetToken(ctx, "code")
+		assert.ErrorIs(t, err, oauth2.ErrTokenRequestFailed)
+	})
+}
+
+func TestTwitchProvider_GetAuthURL(t *testing.T) {
+	provider := twitch.NewProvider(oauth2.ProviderSetting{
+		ClientID:    "client-id",
+		RedirectURL: "https://app.example.com/callback",
+	})
+
+	authURL, err := provider.GetAuthURL(
+		context.Background(),
+		"state",
+		oauth2.WithScopes("openid"),
+		oauth2.WithPrompt(oauth2.PromptConsent),
+	)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(authURL, twitch.AuthURL+"?"), authURL)
+
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "user:read:email openid", parsed.Query().Get("scope"))
+	assert.Equal(t, "true", parsed.Query().Get("force_verify"))
+	assert.Equal(t, "client-id", parsed.Query().Get("client_id"))
+}
+
+func TestTwitchProvider_RevokeToken(t *testing.T) {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, twitch.RevokeURL, req.URL.String())
+		assert.NoError(t, req.ParseForm())
+		assert.Equal(t, "client-id", req.PostForm.Get("client_id"))
+		assert.Equal(t, "access", req.PostForm.Get("token"))
+		return jsonResponse(http.StatusOK, ``), nil
+	})
+	provider := twitch.NewProvider(oauth2.ProviderSetting{Client: client, ClientID: "client-id"})
+
+	assert.NoError(t, provider.RevokeToken(context.Background(), "access"))
+	assert.ErrorIs(t, provider.RevokeToken(context.Background(), ""), oauth2.ErrTokenRevocationFailed)
+}
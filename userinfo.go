@@ -0,0 +1,116 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Gender values returned by UserInfo.GetGenderNormalized
+const (
+	GenderMale    Gender = "male"
+	GenderFemale  Gender = "female"
+	GenderOther   Gender = "other"
+	GenderUnknown Gender = "unknown"
+)
+
+// Gender is a gender normalized across providers, see NormalizeGender
+type Gender string
+
+// UserInfoRequirements lists the UserInfo fields that must be non-empty for a usable profile,
+// e.g. before creating an account. The zero value requires nothing
+type UserInfoRequirements struct {
+	ID           bool
+	Email        bool
+	Name         bool
+	Gender       bool
+	ProfileImage bool
+	PhoneNumber  bool
+	Birthday     bool
+}
+
+// NumericID returns the provider-native numeric ID of user,
+// or (0, false) when the provider uses string IDs
+func NumericID(user UserInfo) (int64, bool) {
+	if numeric, ok := user.(NumericIDUser); ok {
+		return numeric.GetNumericID()
+	}
+	return 0, false
+}
+
+// Missing returns the names of the required fields user leaves empty, nil when it is complete
+func (r UserInfoRequirements) Missing(user UserInfo) []string {
+	fields := []struct {
+		name     string
+		required bool
+		value    func() string
+	}{
+		{name: "id", required: r.ID, value: user.GetID},
+		{name: "email", required: r.Email, value: user.GetEmail},
+		{name: "name", required: r.Name, value: user.GetName},
+		{name: "gender", required: r.Gender, value: user.GetGender},
+		{name: "profile_image", required: r.ProfileImage, value: user.GetProfileImage},
+		{name: "phone_number", required: r.PhoneNumber, value: user.GetPhoneNumber},
+		{name: "birthday", required: r.Birthday, value: user.GetBirthday},
+	}
+
+	var missing []string
+	for _, field := range fields {
+		if field.required && field.value() == "" {
+			missing = append(missing, field.name)
+		}
+	}
+	return missing
+}
+
+// NormalizeGender maps a raw provider gender to a Gender, case-insensitively
+//   - kakao, google, generic: male, female (and other for google)
+//   - naver: M, F and U (unknown)
+//
+// Empty and unrecognized values map to GenderUnknown
+func NormalizeGender(raw string) Gender {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "male", "m":
+		return GenderMale
+	case "female", "f":
+		return GenderFemale
+	case "other":
+		return GenderOther
+	default:
+		return GenderUnknown
+	}
+}
+
+// FormatBirthday combines a month and day (MMDD or MM-DD) with an optional year (YYYY)
+// into YYYY-MM-DD, or MM-DD without a valid year. It is empty when monthDay is malformed,
+// since a year alone is not a birthday
+func FormatBirthday(year string, monthDay string) string {
+	monthDay = strings.ReplaceAll(monthDay, "-", "")
+	if len(monthDay) != 4 || !isDigits(monthDay) {
+		return ""
+	}
+
+	birthday := monthDay[:2] + "-" + monthDay[2:]
+	if len(year) == 4 && isDigits(year) {
+		return year + "-" + birthday
+	}
+	return birthday
+}
+
+// isDigits reports whether s only contains ASCII digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeRawUserInfo decodes a userinfo response for UserInfo.GetRaw, nil unless body is a JSON object
+func DecodeRawUserInfo(body []byte) map[string]any {
+	var raw map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil
+	}
+	return raw
+}
@@ -0,0 +1,151 @@
+package oauth2_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+type numericUser struct {
+	dummyUser
+}
+
+func (n numericUser) GetNumericID() (int64, bool) { return 4200000000, true }
+
+func TestNumericID(t *testing.T) {
+	id, ok := oauth2.NumericID(numericUser{})
+	assert.True(t, ok)
+	assert.Equal(t, int64(4200000000), id)
+
+	id, ok = oauth2.NumericID(dummyUser{})
+	assert.False(t, ok)
+	assert.Zero(t, id)
+}
+
+type partialUser struct {
+	dummyUser
+}
+
+func (p partialUser) GetEmail() string        { return "" }
+func (p partialUser) GetProfileImage() string { return "" }
+func (p partialUser) GetPhoneNumber() string  { return "" }
+func (p partialUser) GetBirthday() string     { return "" }
+func (p partialUser) IsEmailVerified() bool   { return false }
+func (p partialUser) GetRaw() map[string]any  { return nil }
+
+func TestUserInfoRequirements_Missing(t *testing.T) {
+	tests := []struct {
+		name         string
+		user         oauth2.UserInfo
+		requirements oauth2.UserInfoRequirements
+		want         []string
+	}{
+		{name: "no requirements", user: partialUser{}},
+		{
+			name:         "satisfied",
+			user:         dummyUser{},
+			requirements: oauth2.UserInfoRequirements{ID: true, Email: true, Name: true},
+		},
+		{
+			name:         "unsatisfied",
+			user:         partialUser{},
+			requirements: oauth2.UserInfoRequirements{ID: true, Email: true, ProfileImage: true},
+			want:         []string{"email", "profile_image"},
+		},
+		{
+			name:         "phone number",
+			user:         partialUser{},
+			requirements: oauth2.UserInfoRequirements{PhoneNumber: true},
+			want:         []string{"phone_number"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.requirements.Missing(tt.user))
+		})
+	}
+}
+
+func TestOAuth2Client_AuthenticateUserInfoRequirements(t *testing.T) {
+	ctx := context.Background()
+	client := oauth2.NewClient(&mockProvider{
+		typ:            "kakao",
+		returnToken:    dummyToken{},
+		returnUserInfo: partialUser{},
+	})
+
+	result, err := client.Authenticate(ctx, "kakao", "code", oauth2.WithUserInfoRequirements(
+		oauth2.UserInfoRequirements{ID: true, Name: true},
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, "id", result.User.GetID())
+
+	_, err = client.Authenticate(ctx, "kakao", "code", oauth2.WithUserInfoRequirements(
+		oauth2.UserInfoRequirements{ID: true, Email: true},
+	))
+	assert.ErrorIs(t, err, oauth2.ErrIncompleteProfile)
+	assert.ErrorContains(t, err, "email")
+
+	_, lastErr := client.LastError("kakao")
+	assert.ErrorIs(t, lastErr, oauth2.ErrIncompleteProfile)
+}
+
+func TestNormalizeGender(t *testing.T) {
+	tests := []struct {
+		provider string
+		raw      string
+		want     oauth2.Gender
+	}{
+		{provider: "kakao", raw: "male", want: oauth2.GenderMale},
+		{provider: "kakao", raw: "female", want: oauth2.GenderFemale},
+		{provider: "naver", raw: "M", want: oauth2.GenderMale},
+		{provider: "naver", raw: "F", want: oauth2.GenderFemale},
+		{provider: "naver", raw: "U", want: oauth2.GenderUnknown},
+		{provider: "google", raw: "male", want: oauth2.GenderMale},
+		{provider: "google", raw: "female", want: oauth2.GenderFemale},
+		{provider: "google", raw: "other", want: oauth2.GenderOther},
+		{provider: "github", raw: "", want: oauth2.GenderUnknown},
+		{provider: "any", raw: "nonbinary", want: oauth2.GenderUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider+" "+tt.raw, func(t *testing.T) {
+			assert.Equal(t, tt.want, oauth2.NormalizeGender(tt.raw))
+		})
+	}
+}
+
+func TestFormatBirthday(t *testing.T) {
+	tests := []struct {
+		name     string
+		year     string
+		monthDay string
+		want     string
+	}{
+		{name: "kakao MMDD with year", year: "1990", monthDay: "0131", want: "1990-01-31"},
+		{name: "naver MM-DD with year", year: "1990", monthDay: "01-31", want: "1990-01-31"},
+		{name: "without year", monthDay: "1225", want: "12-25"},
+		{name: "malformed year is dropped", year: "90", monthDay: "12-25", want: "12-25"},
+		{name: "year alone", year: "1990"},
+		{name: "malformed birthday", year: "1990", monthDay: "Jan 31"},
+		{name: "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, oauth2.FormatBirthday(tt.year, tt.monthDay))
+		})
+	}
+}
+
+func TestDecodeRawUserInfo(t *testing.T) {
+	raw := oauth2.DecodeRawUserInfo([]byte(`{"id":"1","phone_verified":true,"address":{"country":"KR"}}`))
+	assert.Equal(t, true, raw["phone_verified"])
+	assert.Equal(t, map[string]any{"country": "KR"}, raw["address"])
+
+	assert.Nil(t, oauth2.DecodeRawUserInfo([]byte(`[1, 2]`)))
+	assert.Nil(t, oauth2.DecodeRawUserInfo([]byte(`not json`)))
+}
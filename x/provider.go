@@ -0,0 +1,469 @@
+package x
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dings-things/oauth2"
+)
+
+const (
+	// ProviderType is the identifier for the X (formerly Twitter) OAuth2 provider
+	//   - REFS : https://docs.x.com/resources/fundamentals/authentication/oauth-2-0/authorization-code
+	ProviderType oauth2.ProviderType = "x"
+
+	// AuthURL is the endpoint to start the authorization code flow
+	AuthURL = "https://twitter.com/i/oauth2/authorize"
+
+	// TokenURL is the endpoint to exchange the authorization code for an access token
+	TokenURL = "https://api.twitter.com/2/oauth2/token"
+
+	// RevokeURL is the endpoint to revoke an access or refresh token
+	RevokeURL = "https://api.twitter.com/2/oauth2/revoke"
+
+	// UserInfoURL is the endpoint returning the user of the access token with its profile image
+	UserInfoURL = "https://api.twitter.com/2/users/me?user.fields=profile_image_url"
+
+	// offlineAccessScope asks X for a refresh token
+	offlineAccessScope = "offline.access"
+)
+
+// DefaultScopes are requested when ProviderSetting.Scopes is empty, WithScopes adds to them.
+// Replace it during initialization, before any provider is used
+var DefaultScopes = []string{"tweet.read", "users.read"}
+
+type (
+	// provider holds the configuration for X's OAuth2 implementation
+	provider struct {
+		requester    *oauth2.Requester
+		clientID     string
+		clientSecret string
+		redirectURL  string
+
+		revocationMethod string
+		strictTokenType  bool
+		authURLLimits    oauth2.AuthURLLimits
+		nameStrategy     oauth2.NameStrategy
+		scopes           []string
+
+		authURL  string
+		tokenURL string
+
+		userInfoURL          string
+		userInfoFallbackURLs []string
+	}
+
+	// userResponse is the users/me response, wrapping the user in data
+	userResponse struct {
+		Data *userInfo `json:"data"`
+	}
+
+	// userInfo represents the user object of the users/me response
+	userInfo struct {
+		ID              string `json:"id"`
+		Name            string `json:"name"`
+		Username        string `json:"username"`
+		ProfileImageURL string `json:"profile_image_url"`
+
+		raw          map[string]any
+		nameStrategy oauth2.NameStrategy
+	}
+
+	// tokenInfo represents the token information returned from X
+	tokenInfo struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+		TokenType    string `json:"token_type"`
+
+		issuedAt time.Time
+	}
+)
+
+// provider must keep implementing oauth2.Provider
+var _ oauth2.Provider = (*provider)(nil)
+
+func init() {
+	oauth2.RegisterConstructor(ProviderType, NewProvider)
+}
+
+// NewProvider initializes and returns a new X OAuth2 provider.
+// ClientSecret is only set for confidential clients, public clients (e.g. native apps) leave it empty
+func NewProvider(setting oauth2.ProviderSetting) oauth2.Provider {
+	return &provider{
+		requester:    oauth2.NewRequester(setting),
+		clientID:     setting.ClientID,
+		clientSecret: setting.ClientSecret,
+		redirectURL:  setting.RedirectURL,
+
+		strictTokenType:  setting.StrictTokenType,
+		authURLLimits:    setting.AuthURLLimits,
+		nameStrategy:     setting.NameStrategy,
+		scopes:           setting.Scopes,
+		revocationMethod: cmp.Or(setting.RevocationMethod, http.MethodPost),
+
+		authURL:  cmp.Or(setting.AuthURL, AuthURL),
+		tokenURL: cmp.Or(setting.TokenURL, TokenURL),
+
+		userInfoURL:          cmp.Or(setting.UserInfoURL, UserInfoURL),
+		userInfoFallbackURLs: setting.UserInfoFallbackURLs,
+	}
+}
+
+// NewProviderWithError is NewProvider failing with ErrClientIDNotSet, ErrClientSecretNotSet
+// or ErrInvalidRedirectURL instead of building a provider whose calls cannot succeed
+func NewProviderWithError(setting oauth2.ProviderSetting) (oauth2.Provider, error) {
+	if err := setting.ValidateCredentials(); err != nil {
+		return nil, oauth2.WrapProviderError(ProviderType, err, "")
+	}
+	return NewProvider(setting), nil
+}
+
+// GetUserInfo retrieves the X user of the access token, unwrapping the data object.
+// A response without data fails with ErrUserInfoRequestFailed
+func (x *provider) GetUserInfo(ctx context.Context, accessToken string) (oauth2.UserInfo, error) {
+	if accessToken == "" {
+		return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAccessToken, "")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, x.userInfoURL, nil)
+	if err != nil {
+		return nil, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrUserInfoRequestFailed,
+			err.Error(),
+		)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	oauth2.SetAcceptLanguage(req)
+
+	end := x.requester.Observe(ctx, ProviderType, oauth2.OpGetUserInfo)
+	resp, err := x.requester.DoWithFallback(req, x.userInfoFallbackURLs)
+	end(resp, err)
+	if err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpGetUserInfo,
+			oauth2.ErrUserInfoRequestFailed,
+			resp,
+		)
+	}
+
+	var user userResponse
+	if err := oauth2.DecodeJSONResponse(resp, &user); err != nil {
+		return nil, oauth2.WrapProviderCause(ProviderType, oauth2.ErrUserInfoRequestFailed, err)
+	}
+	if user.Data == nil {
+		return nil, oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpGetUserInfo,
+			oauth2.ErrUserInfoRequestFailed,
+			resp,
+		)
+	}
+
+	userInfo := user.Data
+	if raw := oauth2.DecodeRawUserInfo(resp.Body); raw != nil {
+		userInfo.raw, _ = raw["data"].(map[string]any)
+	}
+	userInfo.nameStrategy = x.nameStrategy
+
+	return userInfo, nil
+}
+
+// GetAuthURL constructs the X authorization URL. X requires PKCE, so it fails with
+// ErrCodeVerifierNotSet unless WithPKCE is given
+//   - ProviderSetting.Scopes replaces the tweet.read and users.read defaults, WithScopes adds to them
+//   - offline.access (refresh token) is requested unless WithOfflineAccess(false) is given
+//   - WithPrompt and WithNonce are ignored
+func (x *provider) GetAuthURL(
+	ctx context.Context,
+	state string,
+	opts ...oauth2.AuthOption,
+) (string, error) {
+	if x.redirectURL == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrRedirectURLNotSet, "")
+	}
+	if x.clientID == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrClientIDNotSet, "")
+	}
+
+	options := oauth2.NewAuthOptions(opts...)
+	if options.CodeVerifier == "" {
+		return "", oauth2.WrapProviderError(ProviderType, oauth2.ErrCodeVerifierNotSet, "use WithPKCE")
+	}
+
+	scopes := x.scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	scopes = oauth2.NormalizeScopes(scopes, options.Scopes)
+	if options.OfflineAccess == nil || *options.OfflineAccess {
+		scopes = oauth2.NormalizeScopes(scopes, []string{offlineAccessScope})
+	} else {
+		scopes = slices.DeleteFunc(scopes, func(scope string) bool { return scope == offlineAccessScope })
+	}
+
+	query := url.Values{}
+	query.Set("client_id", x.clientID)
+	query.Set("redirect_uri", x.redirectURL)
+	query.Set("response_type", "code")
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	oauth2.SetCodeChallenge(query, options.CodeVerifier)
+	oauth2.SetAuthParams(query, options.AuthParams)
+
+	return oauth2.BuildAuthURL(ProviderType, x.authURL, query, x.authURLLimits)
+}
+
+// GetToken exchanges the authorization code and the WithPKCE verifier for an access token.
+// It fails with ErrCodeVerifierNotSet without a verifier, since X rejects the exchange
+func (x *provider) GetToken(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthOption,
+) (oauth2.TokenInfo, error) {
+	if code == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyAuthCode, "")
+	}
+	ctx = oauth2.WithoutRetry(ctx)
+
+	verifier := oauth2.NewAuthOptions(opts...).CodeVerifier
+	if verifier == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrCodeVerifierNotSet, "use WithPKCE")
+	}
+
+	form := url.Values{}
+	form.Set("code", code)
+	form.Set("redirect_uri", x.redirectURL)
+	form.Set("grant_type", "authorization_code")
+	oauth2.SetCodeVerifier(form, verifier)
+
+	return x.requestToken(ctx, oauth2.OpGetToken, form)
+}
+
+// RefreshToken exchanges a refresh token for a new access token from X,
+// which rotates the refresh token on every use
+func (x *provider) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (oauth2.TokenInfo, error) {
+	if refreshToken == "" {
+		return tokenInfo{}, oauth2.WrapProviderError(ProviderType, oauth2.ErrEmptyRefreshToken, "")
+	}
+
+	form := url.Values{}
+	form.Set("refresh_token", refreshToken)
+	form.Set("grant_type", "refresh_token")
+
+	return x.requestToken(ctx, oauth2.OpRefreshToken, form)
+}
+
+// GetClientCredentialsToken is not supported, X app-only tokens are issued for the API key
+// and secret rather than the OAuth2 client
+func (x provider) GetClientCredentialsToken(ctx context.Context, scopes ...string) (oauth2.TokenInfo, error) {
+	return nil, oauth2.WrapProviderError(ProviderType, oauth2.ErrGrantNotSupported, "client_credentials")
+}
+
+// requestToken posts form to the token endpoint and decodes the token response
+func (x *provider) requestToken(ctx context.Context, op string, form url.Values) (oauth2.TokenInfo, error) {
+	var tokenInfo tokenInfo
+
+	req, err := x.newClientRequest(ctx, http.MethodPost, x.tokenURL, form)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRequestFailed,
+			err.Error(),
+		)
+	}
+
+	end := x.requester.Observe(ctx, ProviderType, op)
+	resp, err := x.requester.Do(req)
+	end(resp, err)
+	if err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return tokenInfo, oauth2.WrapResponseError(
+			ProviderType,
+			op,
+			oauth2.ErrTokenRequestFailed,
+			resp,
+		)
+	}
+
+	if err := oauth2.DecodeJSONResponse(resp, &tokenInfo); err != nil {
+		return tokenInfo, oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRequestFailed, err)
+	}
+	tokenInfo.issuedAt = time.Now()
+
+	// a refresh keeps the current refresh token when the provider does not rotate it
+	if tokenInfo.RefreshToken == "" {
+		tokenInfo.RefreshToken = form.Get("refresh_token")
+	}
+
+	if err := oauth2.ValidateTokenType(tokenInfo.TokenType, x.strictTokenType); err != nil {
+		return tokenInfo, oauth2.WrapProviderError(ProviderType, err, tokenInfo.TokenType)
+	}
+
+	return tokenInfo, nil
+}
+
+// newClientRequest builds a form request authenticated as the client: confidential clients
+// use HTTP Basic auth with the client ID and secret, public clients send client_id in the form
+func (x *provider) newClientRequest(
+	ctx context.Context,
+	method string,
+	endpoint string,
+	form url.Values,
+) (*http.Request, error) {
+	if x.clientSecret == "" {
+		form.Set("client_id", x.clientID)
+	}
+
+	req, err := oauth2.NewFormRequest(ctx, method, endpoint, form)
+	if err != nil {
+		return nil, err
+	}
+	if x.clientSecret != "" {
+		req.SetBasicAuth(x.clientID, x.clientSecret)
+	}
+	return req, nil
+}
+
+// RevokeToken revokes an access or refresh token
+func (x *provider) RevokeToken(ctx context.Context, token string) error {
+	if token == "" {
+		return oauth2.WrapProviderError(ProviderType, oauth2.ErrTokenRevocationFailed, "token is empty")
+	}
+
+	form := url.Values{}
+	form.Set("token", token)
+
+	req, err := x.newClientRequest(ctx, x.revocationMethod, RevokeURL, form)
+	if err != nil {
+		return oauth2.WrapProviderError(
+			ProviderType,
+			oauth2.ErrTokenRevocationFailed,
+			err.Error(),
+		)
+	}
+
+	resp, err := x.requester.Do(req)
+	if err != nil {
+		return oauth2.WrapProviderCause(ProviderType, oauth2.ErrTokenRevocationFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return oauth2.WrapResponseError(
+			ProviderType,
+			oauth2.OpRevokeToken,
+			oauth2.ErrTokenRevocationFailed,
+			resp,
+		)
+	}
+
+	return nil
+}
+
+// CanRefresh reports whether token still holds a refresh token
+func (x provider) CanRefresh(token oauth2.TokenInfo) bool { return oauth2.CanRefresh(token) }
+
+// SigningKeys is not supported since X issues no id_token
+func (x provider) SigningKeys(ctx context.Context) ([]oauth2.PublicKeyInfo, error) {
+	return nil, oauth2.WrapProviderError(
+		ProviderType,
+		oauth2.ErrUnsupportedOperation,
+		"no JWKS endpoint",
+	)
+}
+
+// Unlink is not supported, users disconnect applications from their X settings
+func (x provider) Unlink(ctx context.Context, accessToken string) error {
+	return oauth2.WrapProviderError(ProviderType, oauth2.ErrUnsupportedOperation, "unlink")
+}
+
+// GetProvider returns the provider type ("x")
+func (x provider) GetProvider() oauth2.ProviderType { return ProviderType }
+
+// GetRedirectURL returns the configured redirect URL
+func (x provider) GetRedirectURL() string { return x.redirectURL }
+
+// GetID returns the user's X ID
+func (x userInfo) GetID() string { return x.ID }
+
+// GetEmail returns an empty string since users/me does not share the email address
+func (x userInfo) GetEmail() string { return "" }
+
+// GetName returns the user's display name or username depending on the NameStrategy.
+// The username (handle without "@") is treated as the nickname
+func (x userInfo) GetName() string {
+	return oauth2.SelectName(x.nameStrategy, x.Name, x.Username, "")
+}
+
+// GetGender returns an empty string since X has no gender field
+func (x userInfo) GetGender() string { return "" }
+
+// GetGenderNormalized returns oauth2.GenderUnknown since there is no gender
+func (x userInfo) GetGenderNormalized() oauth2.Gender { return oauth2.GenderUnknown }
+
+// GetProfileImage returns the user's profile image URL
+func (x userInfo) GetProfileImage() string { return x.ProfileImageURL }
+
+// GetPhoneNumber returns an empty string since X does not share phone numbers
+func (x userInfo) GetPhoneNumber() string { return "" }
+
+// GetBirthday returns an empty string since X does not share birthdays
+func (x userInfo) GetBirthday() string { return "" }
+
+// GetLocale returns an empty string since X does not share a locale
+func (x userInfo) GetLocale() string { return "" }
+
+// IsEmailVerified reports false since there is no email
+func (x userInfo) IsEmailVerified() bool { return false }
+
+// GetRaw returns the decoded user object of the response data
+func (x userInfo) GetRaw() map[string]any { return x.raw }
+
+// GetAccessToken returns the OAuth2 access token
+func (x tokenInfo) GetAccessToken() string { return x.AccessToken }
+
+// GetRefreshToken returns the OAuth2 refresh token
+func (x tokenInfo) GetRefreshToken() string { return x.RefreshToken }
+
+// GetExpiry returns the token expiration time in seconds
+func (x tokenInfo) GetExpiry() int { return x.ExpiresIn }
+
+// HasRefreshToken reports whether a refresh token was issued, only with the offline.access scope
+func (x tokenInfo) HasRefreshToken() bool { return x.RefreshToken != "" }
+
+// HasExpiry reports whether the access token expires, false when expires_in was not returned
+func (x tokenInfo) HasExpiry() bool { return x.ExpiresIn > 0 }
+
+// GetExpiresAt returns when the access token expires, zero when it does not
+func (x tokenInfo) GetExpiresAt() time.Time { return oauth2.ExpiryTime(x.issuedAt, x.ExpiresIn) }
+
+// IsExpired reports whether the access token has expired, never for tokens without expiry
+func (x tokenInfo) IsExpired() bool { return oauth2.Expired(x.GetExpiresAt()) }
+
+// GetScope returns the granted scopes, space-delimited
+func (x tokenInfo) GetScope() string { return x.Scope }
+
+// GetTokenType returns the token type (e.g. "bearer")
+func (x tokenInfo) GetTokenType() string { return x.TokenType }
+
+// GetIDToken returns an empty string since X issues no id_token
+func (x tokenInfo) GetIDToken() string { return "" }
@@ -0,0 +1,196 @@
+package x_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dings-things/oauth2"
+	"github.com/dings-things/oauth2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newMockClient(fn roundTripperFunc) *http.Client {
+	return &http.Client{Transport: fn}
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestXProvider_GetUserInfo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unwraps data", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, x.UserInfoURL, req.URL.String())
+			assert.Equal(t, "profile_image_url", req.URL.Query().Get("user.fields"))
+			assert.Equal(t, "Bearer access", req.Header.Get("Authorization"))
+			return jsonResponse(http.StatusOK, `{"data":{"id":"2244994945","name":"X Dev",`+
+				`"username":"XDevelopers","profile_image_url":"https://img"}}`), nil
+		})
+		provider := x.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		user, err := provider.GetUserInfo(ctx, "access")
+		assert.NoError(t, err)
+		assert.Equal(t, "2244994945", user.GetID())
+		assert.Equal(t, "X Dev", user.GetName())
+		assert.Equal(t, "https://img", user.GetProfileImage())
+		assert.Empty(t, user.GetEmail())
+		assert.Equal(t, "XDevelopers", user.GetRaw()["username"])
+	})
+
+	t.Run("nickname strategy uses the username", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{"data":{"id":"1","name":"X Dev","username":"XDevelopers"}}`), nil
+		})
+		provider := x.NewProvider(oauth2.ProviderSetting{Client: client, NameStrategy: oauth2.PreferNickname})
+
+		user, err := provider.GetUserInfo(ctx, "access")
+		assert.NoError(t, err)
+		assert.Equal(t, "XDevelopers", user.GetName())
+	})
+
+	t.Run("missing data", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusOK, `{"errors":[{"title":"Not Found Error"}]}`), nil
+		})
+		provider := x.NewProvider(oauth2.ProviderSetting{Client: client})
+
+		_, err := provider.GetUserInfo(ctx, "access")
+		assert.ErrorIs(t, err, oauth2.ErrUserInfoRequestFailed)
+	})
+
+	t.Run("empty access token", func(t *testing.T) {
+		provider := x.NewProvider(oauth2.ProviderSetting{})
+
+		_, err := provider.GetUserInfo(ctx, "")
+		assert.ErrorIs(t, err, oauth2.ErrEmptyAccessToken)
+	})
+}
+
+func TestXProvider_GetToken(t *testing.T) {
+	ctx := context.Background()
+	tokenBody := `{"token_type":"bearer","expires_in":7200,"access_token":"access",` +
+		`"scope":"tweet.read users.read offline.access","refresh_token":"refresh"}`
+
+	t.Run("confidential client uses basic auth", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, x.TokenURL, req.URL.String())
+			want := "Basic " + base64.StdEncoding.EncodeToString([]byte("client-id:client-secret"))
+			assert.Equal(t, want, req.Header.Get("Authorization"))
+
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "code", req.PostForm.Get("code"))
+			assert.Equal(t, "verifier", req.PostForm.Get("code_verifier"))
+			assert.Equal(t, "authorization_code", req.PostForm.Get("grant_type"))
+			assert.Empty(t, req.PostForm.Get("client_id"))
+			assert.Empty(t, req.PostForm.Get("client_secret"))
+			return jsonResponse(http.StatusOK, tokenBody), nil
+		})
+		provider := x.NewProvider(oauth2.ProviderSetting{
+			Client:       client,
+			ClientID:     "client-id",
+			ClientSecret: "client-secret",
+			RedirectURL:  "https://app.example.com/callback",
+		})
+
+		token, err := provider.GetToken(ctx, "code", oauth2.WithPKCE("verifier"))
+		assert.NoError(t, err)
+		assert.Equal(t, "access", token.GetAccessToken())
+		assert.Equal(t, "refresh", token.GetRefreshToken())
+		assert.Equal(t, "tweet.read users.read offline.access", token.GetScope())
+	})
+
+	t.Run("public client sends client_id", func(t *testing.T) {
+		client := newMockClient(func(req *http.Request) (*http.Response, error) {
+			_, _, ok := req.BasicAuth()
+			assert.False(t, ok)
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "client-id", req.PostForm.Get("client_id"))
+			return jsonResponse(http.StatusOK, tokenBody), nil
+		})
+		provider := x.NewProvider(oauth2.ProviderSetting{Client: client, ClientID: "client-id"})
+
+		_, err := provider.GetToken(ctx, "code", oauth2.WithPKCE("verifier"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("requires the code verifier", func(t *testing.T) {
+		provider := x.NewProvider(oauth2.ProviderSetting{ClientID: "client-id"})
+
+		_, err := provider.GetToken(ctx, "code")
+		assert.ErrorIs(t, err, oauth2.ErrCodeVerifierNotSet)
+	})
+}
+
+func TestXProvider_GetAuthURL(t *testing.T) {
+	ctx := context.Background()
+	provider := x.NewProvider(oauth2.ProviderSetting{
+		ClientID:    "client-id",
+		RedirectURL: "https://app.example.com/callback",
+	})
+
+	t.Run("sends the PKCE challenge", func(t *testing.T) {
+		authURL, err := provider.GetAuthURL(ctx, "state", oauth2.WithPKCE("verifier"), oauth2.WithScopes("like.read"))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(authURL, x.AuthURL+"?"), authURL)
+
+		parsed, err := url.Parse(authURL)
+		assert.NoError(t, err)
+		query := parsed.Query()
+		assert.Equal(t, oauth2.CodeChallengeS256("verifier"), query.Get("code_challenge"))
+		assert.Equal(t, oauth2.CodeChallengeMethodS256, query.Get("code_challenge_method"))
+		assert.Equal(t, "tweet.read users.read like.read offline.access", query.Get("scope"))
+		assert.Equal(t, "code", query.Get("response_type"))
+	})
+
+	t.Run("without offline access", func(t *testing.T) {
+		authURL, err := provider.GetAuthURL(ctx, "state", oauth2.WithPKCE("verifier"), oauth2.WithOfflineAccess(false))
+		assert.NoError(t, err)
+
+		parsed, err := url.Parse(authURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "tweet.read users.read", parsed.Query().Get("scope"))
+	})
+
+	t.Run("requires the code verifier", func(t *testing.T) {
+		_, err := provider.GetAuthURL(ctx, "state")
+		assert.ErrorIs(t, err, oauth2.ErrCodeVerifierNotSet)
+	})
+}
+
+func TestXProvider_RevokeToken(t *testing.T) {
+	client := newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, x.RevokeURL, req.URL.String())
+		clientID, secret, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "client-id", clientID)
+		assert.Equal(t, "client-secret", secret)
+		assert.NoError(t, req.ParseForm())
+		assert.Equal(t, "access", req.PostForm.Get("token"))
+		return jsonResponse(http.StatusOK, `{"revoked":true}`), nil
+	})
+	provider := x.NewProvider(oauth2.ProviderSetting{
+		Client:       client,
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+	})
+
+	assert.NoError(t, provider.RevokeToken(context.Background(), "access"))
+	assert.ErrorIs(t, provider.RevokeToken(context.Background(), ""), oauth2.ErrTokenRevocationFailed)
+}